@@ -0,0 +1,87 @@
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderS3    = "s3"
+	ProviderMinIO = "minio"
+	ProviderLocal = "local"
+)
+
+var (
+	ErrNotFound = errors.New("blob not found")
+	// ErrPresignNotSupported is returned by stores that cannot hand out
+	// URLs, whose blobs must be streamed instead.
+	ErrPresignNotSupported = errors.New("blob store cannot presign urls")
+)
+
+// BlobStore keeps files such as slip images under slash-separated keys.
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	// Get opens a blob for reading; the caller closes it.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes a blob; deleting a missing blob is not an error.
+	Delete(key string) error
+	// Presign returns a URL that reads the blob without credentials until
+	// expiry has passed.
+	Presign(key string, expiry time.Duration) (string, error)
+}
+
+// NewBlobStore builds the store named in the storage config. Without a
+// storage section, blobs go to the S3 bucket in the AWS config as before.
+func NewBlobStore(cfg *config.Storage, awsCfg *config.AWS) (BlobStore, error) {
+	if cfg == nil || cfg.Provider == "" {
+		if awsCfg == nil {
+			return nil, errors.New("aws config is required for s3 storage")
+		}
+		return NewS3Store(S3Options{
+			Region:          awsCfg.Region,
+			Bucket:          awsCfg.Bucket,
+			AccessKeyID:     awsCfg.AccessKeyID,
+			SecretAccessKey: awsCfg.SecretAccessKey,
+		})
+	}
+
+	switch cfg.Provider {
+	case ProviderS3, ProviderMinIO:
+		// MinIO serves buckets under the path rather than a subdomain.
+		return NewS3Store(S3Options{
+			Endpoint:        cfg.Endpoint,
+			Region:          cfg.Region,
+			Bucket:          cfg.Bucket,
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+			PathStyle:       cfg.Provider == ProviderMinIO,
+		})
+	case ProviderLocal:
+		return NewLocalStore(cfg.LocalDir)
+	default:
+		return nil, fmt.Errorf("unknown storage provider: %s", cfg.Provider)
+	}
+}
+
+// SafeName keeps a client's file name readable in a key while dropping
+// anything that could act as a path, using fallback when nothing is left.
+func SafeName(filename, fallback string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, path.Base(filename))
+	name = strings.TrimLeft(name, ".")
+	if name == "" || name == "_" {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,62 @@
+package blobstore
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStore_PutGetDelete(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.Put("slips/1_slip.jpg", []byte("image"), "image/jpeg"))
+
+	reader, err := store.Get("slips/1_slip.jpg")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, []byte("image"), data)
+
+	assert.NoError(t, store.Delete("slips/1_slip.jpg"))
+	_, err = store.Get("slips/1_slip.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete("slips/1_slip.jpg"))
+}
+
+func TestLocalStore_Put_Overwrites(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := NewLocalStore(dir)
+
+	assert.NoError(t, store.Put("a.txt", []byte("old"), ""))
+	assert.NoError(t, store.Put("a.txt", []byte("new"), ""))
+
+	data, _ := os.ReadFile(filepath.Join(dir, "a.txt"))
+	assert.Equal(t, "new", string(data))
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 1, len(entries))
+}
+
+func TestLocalStore_InvalidKey(t *testing.T) {
+	store, _ := NewLocalStore(t.TempDir())
+
+	for _, key := range []string{"", "../secret", "slips/../../secret", "/etc/passwd", "slips//a"} {
+		assert.Error(t, store.Put(key, []byte("x"), ""), key)
+	}
+}
+
+func TestLocalStore_Presign(t *testing.T) {
+	store, _ := NewLocalStore(t.TempDir())
+
+	_, err := store.Presign("a.txt", 0)
+
+	assert.ErrorIs(t, err, ErrPresignNotSupported)
+}
+
+func TestNewBlobStore_RequiresAWS(t *testing.T) {
+	_, err := NewBlobStore(nil, nil)
+
+	assert.EqualError(t, err, "aws config is required for s3 storage")
+}
@@ -0,0 +1,112 @@
+package blobstore
+
+import (
+	"bytes"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"io"
+	"net/http"
+	"time"
+)
+
+// S3Options point at AWS S3 or, with an endpoint, any S3-compatible store.
+// Without keys the SDK's default credential chain is used.
+type S3Options struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool
+}
+
+type s3Store struct {
+	client *s3.S3
+	bucket string
+}
+
+func NewS3Store(opts S3Options) (BlobStore, error) {
+	if opts.Bucket == "" {
+		return nil, errors.New("storage bucket is required")
+	}
+
+	awsConfig := &aws.Config{
+		Region:           aws.String(opts.Region),
+		S3ForcePathStyle: aws.Bool(opts.PathStyle),
+	}
+	if opts.Region == "" {
+		// S3-compatible stores ignore the region but the SDK needs one.
+		awsConfig.Region = aws.String("us-east-1")
+	}
+	if opts.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(opts.Endpoint)
+	}
+	if opts.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(opts.AccessKeyID, opts.SecretAccessKey, "")
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: s3.New(sess), bucket: opts.Bucket}, nil
+}
+
+func (s *s3Store) Put(key string, data []byte, contentType string) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	_, err := s.client.PutObject(input)
+	return err
+}
+
+func (s *s3Store) Get(key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return result.Body, nil
+}
+
+func (s *s3Store) Delete(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *s3Store) Presign(key string, expiry time.Duration) (string, error) {
+	req, _ := s.client.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
+func isNotFound(err error) bool {
+	var requestErr awserr.RequestFailure
+	if errors.As(err, &requestErr) && requestErr.StatusCode() == http.StatusNotFound {
+		return true
+	}
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound")
+}
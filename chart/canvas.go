@@ -0,0 +1,214 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type anchor int
+
+const (
+	anchorStart anchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// canvas is what a chart is drawn on. Positions are in pixels from the
+// top-left corner; a text position is where its baseline is anchored.
+type canvas interface {
+	polygon(points []float64, fill color.RGBA)
+	polyline(points []float64, width float64, stroke color.RGBA)
+	text(x, y, size float64, at anchor, fill color.RGBA, text string)
+	writeTo(w io.Writer) error
+}
+
+func rect(c canvas, x, y, width, height float64, fill color.RGBA) {
+	c.polygon([]float64{x, y, x + width, y, x + width, y + height, x, y + height}, fill)
+}
+
+// svgCanvas writes SVG. Text is left for the viewer to set, so only its
+// layout depends on the fonts.
+type svgCanvas struct {
+	buf bytes.Buffer
+}
+
+func newSVGCanvas(width, height int) *svgCanvas {
+	c := &svgCanvas{}
+	fmt.Fprintf(&c.buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`font-family="Sarabun, 'Noto Sans Thai', 'Helvetica Neue', Arial, sans-serif">`+"\n", width, height, width, height)
+	return c
+}
+
+func (c *svgCanvas) polygon(points []float64, fill color.RGBA) {
+	fmt.Fprintf(&c.buf, `<polygon points="%s" fill="%s"/>`+"\n", svgPoints(points), hexColor(fill))
+}
+
+func (c *svgCanvas) polyline(points []float64, width float64, stroke color.RGBA) {
+	fmt.Fprintf(&c.buf, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linejoin="round" stroke-linecap="round"/>`+"\n",
+		svgPoints(points), hexColor(stroke), num(width))
+}
+
+func (c *svgCanvas) text(x, y, size float64, at anchor, fill color.RGBA, text string) {
+	if text == "" {
+		return
+	}
+	textAnchor := ""
+	switch at {
+	case anchorMiddle:
+		textAnchor = ` text-anchor="middle"`
+	case anchorEnd:
+		textAnchor = ` text-anchor="end"`
+	}
+	fmt.Fprintf(&c.buf, `<text x="%s" y="%s" font-size="%s" fill="%s"%s>`, num(x), num(y), num(size), hexColor(fill), textAnchor)
+	xml.EscapeText(&c.buf, []byte(text))
+	c.buf.WriteString("</text>\n")
+}
+
+func (c *svgCanvas) writeTo(w io.Writer) error {
+	c.buf.WriteString("</svg>\n")
+	_, err := c.buf.WriteTo(w)
+	return err
+}
+
+// pngCanvas rasterizes onto an RGBA image with anti-aliasing.
+type pngCanvas struct {
+	img   *image.RGBA
+	fonts *fontSet
+}
+
+func newPNGCanvas(width, height int, fonts *fontSet) *pngCanvas {
+	return &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, width, height)), fonts: fonts}
+}
+
+func (c *pngCanvas) polygon(points []float64, fill color.RGBA) {
+	z := c.rasterizer()
+	addPolygon(z, points)
+	z.Draw(c.img, c.img.Bounds(), image.NewUniform(fill), image.Point{})
+}
+
+// polyline strokes each segment as a quad with a disc at every point for
+// round joins and caps. The pieces go into one rasterizer, so where they
+// overlap is not drawn twice.
+func (c *pngCanvas) polyline(points []float64, width float64, stroke color.RGBA) {
+	z := c.rasterizer()
+	half := width / 2
+	for i := 0; i+1 < len(points); i += 2 {
+		x1, y1 := points[i], points[i+1]
+		addPolygon(z, disc(x1, y1, half))
+		if i+3 >= len(points) {
+			break
+		}
+		x2, y2 := points[i+2], points[i+3]
+		length := math.Hypot(x2-x1, y2-y1)
+		if length == 0 {
+			continue
+		}
+		nx, ny := -(y2-y1)/length*half, (x2-x1)/length*half
+		addPolygon(z, []float64{x1 + nx, y1 + ny, x2 + nx, y2 + ny, x2 - nx, y2 - ny, x1 - nx, y1 - ny})
+	}
+	z.Draw(c.img, c.img.Bounds(), image.NewUniform(stroke), image.Point{})
+}
+
+func (c *pngCanvas) text(x, y, size float64, at anchor, fill color.RGBA, text string) {
+	switch at {
+	case anchorMiddle:
+		x -= c.fonts.width(text, size) / 2
+	case anchorEnd:
+		x -= c.fonts.width(text, size)
+	}
+	src := image.NewUniform(fill)
+	dot := fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}
+	for _, r := range text {
+		face := c.fonts.face(c.fonts.pick(r), size)
+		dr, mask, maskp, advance, ok := face.Glyph(dot, r)
+		if ok {
+			draw.DrawMask(c.img, dr, src, image.Point{}, mask, maskp, draw.Over)
+		}
+		dot.X += advance
+	}
+}
+
+func (c *pngCanvas) writeTo(w io.Writer) error {
+	return png.Encode(w, c.img)
+}
+
+func (c *pngCanvas) rasterizer() *vector.Rasterizer {
+	size := c.img.Bounds().Size()
+	z := vector.NewRasterizer(size.X, size.Y)
+	z.DrawOp = draw.Over
+	return z
+}
+
+// addPolygon adds a closed path, always wound the same way: the rasterizer
+// sums coverage with its sign, so overlapping shapes wound in opposite
+// directions would cancel out.
+func addPolygon(z *vector.Rasterizer, points []float64) {
+	n := len(points) / 2
+	if n < 3 {
+		return
+	}
+	var area float64
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		area += points[2*i]*points[2*j+1] - points[2*j]*points[2*i+1]
+	}
+	at := func(i int) (float32, float32) {
+		if area < 0 {
+			i = n - 1 - i
+		}
+		return float32(points[2*i]), float32(points[2*i+1])
+	}
+	z.MoveTo(at(0))
+	for i := 1; i < n; i++ {
+		z.LineTo(at(i))
+	}
+	z.ClosePath()
+}
+
+func disc(x, y, radius float64) []float64 {
+	const segments = 16
+	points := make([]float64, 0, 2*segments)
+	for i := 0; i < segments; i++ {
+		angle := 2 * math.Pi * float64(i) / segments
+		points = append(points, x+radius*math.Cos(angle), y+radius*math.Sin(angle))
+	}
+	return points
+}
+
+func svgPoints(points []float64) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(points); i += 2 {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(num(points[i]))
+		b.WriteByte(',')
+		b.WriteString(num(points[i+1]))
+	}
+	return b.String()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// num writes a coordinate to two decimals without trailing zeros.
+func num(value float64) string {
+	text := strconv.FormatFloat(value, 'f', 2, 64)
+	text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	if text == "" || text == "-0" {
+		return "0"
+	}
+	return text
+}
@@ -0,0 +1,425 @@
+// Package chart draws pie, bar and line charts as SVG or PNG, for clients
+// such as chat messages and emails that cannot draw charts themselves.
+package chart
+
+import (
+	"errors"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	KindPie  = "pie"
+	KindBar  = "bar"
+	KindLine = "line"
+
+	FormatSVG = "svg"
+	FormatPNG = "png"
+
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+
+	DefaultWidth  = 600
+	DefaultHeight = 400
+	MinWidth      = 200
+	MinHeight     = 150
+	MaxSize       = 2000
+)
+
+var (
+	ErrUnknownKind   = errors.New("chart kind is not supported")
+	ErrUnknownFormat = errors.New("chart format is not supported")
+	ErrInvalidSize   = errors.New("chart size is out of range")
+	ErrInvalidFont   = errors.New("chart font is not a TrueType font")
+)
+
+// Series is one set of values, one for each label. A line may have fewer
+// values than labels, such as a month in progress.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+// Chart is what to draw. A pie shows the first series, one slice per label;
+// bars and lines put the labels along the x axis.
+type Chart struct {
+	Kind   string
+	Title  string
+	Labels []string
+	Series []Series
+	// Empty is shown instead of a chart with nothing to show.
+	Empty string
+}
+
+type Theme struct {
+	Background color.RGBA
+	Text       color.RGBA
+	Muted      color.RGBA
+	Grid       color.RGBA
+	Palette    []color.RGBA
+}
+
+var Themes = map[string]Theme{
+	ThemeLight: {
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		Text:       color.RGBA{0x21, 0x25, 0x29, 0xff},
+		Muted:      color.RGBA{0x6c, 0x75, 0x7d, 0xff},
+		Grid:       color.RGBA{0xe9, 0xec, 0xef, 0xff},
+		Palette: []color.RGBA{
+			{0x19, 0x71, 0xc2, 0xff}, {0xf0, 0x8c, 0x00, 0xff}, {0x2f, 0x9e, 0x44, 0xff}, {0xe0, 0x31, 0x31, 0xff},
+			{0x70, 0x48, 0xe8, 0xff}, {0x0c, 0xa6, 0x78, 0xff}, {0xe8, 0x59, 0x0c, 0xff}, {0x86, 0x8e, 0x96, 0xff},
+		},
+	},
+	ThemeDark: {
+		Background: color.RGBA{0x1a, 0x1b, 0x1e, 0xff},
+		Text:       color.RGBA{0xe9, 0xec, 0xef, 0xff},
+		Muted:      color.RGBA{0x90, 0x92, 0x96, 0xff},
+		Grid:       color.RGBA{0x2c, 0x2e, 0x33, 0xff},
+		Palette: []color.RGBA{
+			{0x4d, 0xab, 0xf7, 0xff}, {0xff, 0xa9, 0x4d, 0xff}, {0x69, 0xdb, 0x7c, 0xff}, {0xff, 0x87, 0x87, 0xff},
+			{0x97, 0x75, 0xfa, 0xff}, {0x38, 0xd9, 0xa9, 0xff}, {0xff, 0x92, 0x2b, 0xff}, {0xad, 0xb5, 0xbd, 0xff},
+		},
+	},
+}
+
+// Options are how to draw a chart. A zero width, height or theme takes the
+// default, and Fonts, TrueType programs tried in order for each character,
+// default to the Go font, which has no Thai.
+type Options struct {
+	Format string
+	Width  int
+	Height int
+	Theme  string
+	Fonts  [][]byte
+}
+
+// ContentType is the media type of charts drawn in format.
+func ContentType(format string) string {
+	if format == FormatPNG {
+		return "image/png"
+	}
+	return "image/svg+xml"
+}
+
+// Render draws c to w.
+func Render(w io.Writer, c Chart, opts Options) error {
+	if opts.Width == 0 {
+		opts.Width = DefaultWidth
+	}
+	if opts.Height == 0 {
+		opts.Height = DefaultHeight
+	}
+	if opts.Width < MinWidth || opts.Height < MinHeight || opts.Width > MaxSize || opts.Height > MaxSize {
+		return ErrInvalidSize
+	}
+	if c.Kind != KindPie && c.Kind != KindBar && c.Kind != KindLine {
+		return ErrUnknownKind
+	}
+	theme, ok := Themes[opts.Theme]
+	if !ok {
+		theme = Themes[ThemeLight]
+	}
+	fonts, err := newFontSet(opts.Fonts)
+	if err != nil {
+		return err
+	}
+
+	var cv canvas
+	switch opts.Format {
+	case FormatSVG, "":
+		cv = newSVGCanvas(opts.Width, opts.Height)
+	case FormatPNG:
+		cv = newPNGCanvas(opts.Width, opts.Height, fonts)
+	default:
+		return ErrUnknownFormat
+	}
+
+	width, height := float64(opts.Width), float64(opts.Height)
+	// Text and spacing grow with the chart, within reason.
+	size := math.Max(10, math.Min(16, math.Min(width, height)/30))
+	l := &layout{canvas: cv, fonts: fonts, theme: theme, size: size}
+	rect(cv, 0, 0, width, height, theme.Background)
+
+	pad := size * 1.2
+	area := box{pad, pad, width - 2*pad, height - 2*pad}
+	if c.Title != "" {
+		title := size * 1.3
+		cv.text(area.x, area.y+title, title, anchorStart, theme.Text, fonts.fit(c.Title, title, area.w))
+		area.y += title + size
+		area.h -= title + size
+	}
+
+	if c.isEmpty() {
+		cv.text(area.x+area.w/2, area.y+area.h/2, size, anchorMiddle, theme.Muted, c.Empty)
+		return cv.writeTo(w)
+	}
+	switch c.Kind {
+	case KindPie:
+		l.pie(c, area)
+	case KindBar:
+		l.bars(c, area)
+	case KindLine:
+		l.lines(c, area)
+	}
+	return cv.writeTo(w)
+}
+
+func (c Chart) isEmpty() bool {
+	if len(c.Labels) == 0 {
+		return true
+	}
+	for _, series := range c.Series {
+		for _, value := range series.Values {
+			if value != 0 && (c.Kind != KindPie || value > 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+type box struct {
+	x, y, w, h float64
+}
+
+type layout struct {
+	canvas canvas
+	fonts  *fontSet
+	theme  Theme
+	size   float64
+}
+
+func (l *layout) color(i int) color.RGBA {
+	return l.theme.Palette[i%len(l.theme.Palette)]
+}
+
+// pie draws the first series clockwise from the top, with a legend beside it
+// on a wide chart and under it on a tall one.
+func (l *layout) pie(c Chart, area box) {
+	values := c.Series[0].Values
+	var total float64
+	for _, value := range values {
+		total += math.Max(value, 0)
+	}
+
+	row := l.size * 1.6
+	chartArea, legend := area, area
+	if area.w >= area.h {
+		legend.w = area.w * 0.42
+		legend.x = area.x + area.w - legend.w
+		chartArea.w = area.w - legend.w - l.size
+		legend.y += math.Max(0, (area.h-row*float64(len(values)))/2)
+	} else {
+		legend.h = math.Min(row*float64(len(values)), area.h/2)
+		legend.y = area.y + area.h - legend.h
+		chartArea.h = area.h - legend.h - l.size
+	}
+
+	cx, cy := chartArea.x+chartArea.w/2, chartArea.y+chartArea.h/2
+	radius := math.Min(chartArea.w, chartArea.h) / 2
+	start := -math.Pi / 2
+	var edges []float64
+	for i, value := range values {
+		if value <= 0 {
+			continue
+		}
+		sweep := value / total * 2 * math.Pi
+		steps := int(math.Ceil(sweep / (math.Pi / 90)))
+		points := []float64{cx, cy}
+		for step := 0; step <= steps; step++ {
+			angle := start + sweep*float64(step)/float64(steps)
+			points = append(points, cx+radius*math.Cos(angle), cy+radius*math.Sin(angle))
+		}
+		l.canvas.polygon(points, l.color(i))
+		if sweep < 2*math.Pi {
+			edges = append(edges, start)
+		}
+		start += sweep
+	}
+	// Thin gaps in the background color keep neighbouring slices apart.
+	for _, angle := range edges {
+		l.canvas.polyline([]float64{cx, cy, cx + radius*math.Cos(angle), cy + radius*math.Sin(angle)}, 1.5, l.theme.Background)
+	}
+
+	for i, label := range c.Labels {
+		y := legend.y + float64(i)*row
+		if y+row > legend.y+legend.h+1 || i >= len(values) {
+			break
+		}
+		swatch := l.size * 0.8
+		rect(l.canvas, legend.x, y+(row-swatch)/2, swatch, swatch, l.color(i))
+		share := ""
+		if total > 0 {
+			share = strconv.Itoa(int(math.Round(math.Max(values[i], 0)/total*100))) + "%"
+		}
+		baseline := y + row/2 + l.size*0.35
+		right := legend.x + legend.w
+		l.canvas.text(right, baseline, l.size, anchorEnd, l.theme.Muted, share)
+		space := legend.w - swatch - l.size*0.5 - l.fonts.width("100%", l.size) - l.size*0.5
+		l.canvas.text(legend.x+swatch+l.size*0.5, baseline, l.size, anchorStart, l.theme.Text, l.fonts.fit(label, l.size, space))
+	}
+}
+
+// bars draws each label's values side by side.
+func (l *layout) bars(c Chart, area box) {
+	plot, yOf := l.axes(c, area, true)
+	group := plot.w / float64(len(c.Labels))
+	width := group * 0.7 / float64(len(c.Series))
+	for i := range c.Labels {
+		x := plot.x + float64(i)*group + group*0.15
+		for j, series := range c.Series {
+			if i >= len(series.Values) {
+				continue
+			}
+			top, bottom := yOf(series.Values[i]), yOf(0)
+			if top > bottom {
+				top, bottom = bottom, top
+			}
+			rect(l.canvas, x+float64(j)*width, top, width, bottom-top, l.color(j))
+		}
+	}
+}
+
+// lines draws each series through its values in order.
+func (l *layout) lines(c Chart, area box) {
+	plot, yOf := l.axes(c, area, false)
+	step := 0.0
+	if len(c.Labels) > 1 {
+		step = plot.w / float64(len(c.Labels)-1)
+	}
+	stroke := math.Max(1.5, l.size/6)
+	for j, series := range c.Series {
+		var points []float64
+		for i, value := range series.Values {
+			if i >= len(c.Labels) {
+				break
+			}
+			x := plot.x + float64(i)*step
+			if len(c.Labels) == 1 {
+				x = plot.x + plot.w/2
+			}
+			points = append(points, x, yOf(value))
+		}
+		if len(points) == 2 {
+			// One point is drawn as a dot.
+			points = append(points, points[0], points[1])
+		}
+		l.canvas.polyline(points, stroke, l.color(j))
+	}
+}
+
+// axes draws the legend, grid and labels shared by bars and lines, and
+// returns the plot area with how to place a value in it. Bars sit between
+// grid labels; line points sit on them.
+func (l *layout) axes(c Chart, area box, between bool) (box, func(float64) float64) {
+	if len(c.Series) > 1 {
+		x := area.x
+		swatch := l.size * 0.8
+		for j, series := range c.Series {
+			rect(l.canvas, x, area.y, swatch, swatch, l.color(j))
+			l.canvas.text(x+swatch+l.size*0.4, area.y+swatch, l.size, anchorStart, l.theme.Text, series.Name)
+			x += swatch + l.size*1.6 + l.fonts.width(series.Name, l.size)
+		}
+		area.y += swatch + l.size
+		area.h -= swatch + l.size
+	}
+
+	low, high := 0.0, 0.0
+	for _, series := range c.Series {
+		for _, value := range series.Values {
+			low, high = math.Min(low, value), math.Max(high, value)
+		}
+	}
+	low, high, step := niceScale(low, high, 5)
+	var ticks []string
+	labelWidth := 0.0
+	for value := low; value <= high+step/2; value += step {
+		text := formatCompact(value)
+		ticks = append(ticks, text)
+		labelWidth = math.Max(labelWidth, l.fonts.width(text, l.size))
+	}
+
+	plot := box{area.x + labelWidth + l.size*0.6, area.y + l.size*0.5, 0, 0}
+	plot.w = area.x + area.w - plot.x
+	plot.h = area.y + area.h - l.size*1.8 - plot.y
+	yOf := func(value float64) float64 {
+		return plot.y + plot.h - (value-low)/(high-low)*plot.h
+	}
+	for i, text := range ticks {
+		y := yOf(low + float64(i)*step)
+		l.canvas.polyline([]float64{plot.x, y, plot.x + plot.w, y}, 1, l.theme.Grid)
+		l.canvas.text(plot.x-l.size*0.6, y+l.size*0.35, l.size, anchorEnd, l.theme.Muted, text)
+	}
+
+	// Labels are thinned out until they no longer overlap.
+	n := len(c.Labels)
+	slot := plot.w / float64(max(n, 1))
+	if !between && n > 1 {
+		slot = plot.w / float64(n-1)
+	}
+	widest := 0.0
+	for _, label := range c.Labels {
+		widest = math.Max(widest, l.fonts.width(label, l.size))
+	}
+	every := max(1, int(math.Ceil((widest+l.size)/slot)))
+	for i, label := range c.Labels {
+		if i%every != 0 {
+			continue
+		}
+		x := plot.x + float64(i)*slot
+		if between {
+			x += slot / 2
+		} else if n == 1 {
+			x = plot.x + plot.w/2
+		}
+		l.canvas.text(x, plot.y+plot.h+l.size*1.4, l.size, anchorMiddle, l.theme.Muted, label)
+	}
+	return plot, yOf
+}
+
+// niceScale widens low to high to round steps so about ticks grid lines
+// fall on readable values.
+func niceScale(low, high float64, ticks int) (float64, float64, float64) {
+	if low == high {
+		high = low + 1
+	}
+	step := niceNumber(niceNumber(high-low, false)/float64(ticks-1), true)
+	return math.Floor(low/step) * step, math.Ceil(high/step) * step, step
+}
+
+func niceNumber(value float64, round bool) float64 {
+	exponent := math.Floor(math.Log10(value))
+	fraction := value / math.Pow(10, exponent)
+	var nice float64
+	switch {
+	case round && fraction < 1.5, !round && fraction <= 1:
+		nice = 1
+	case round && fraction < 3, !round && fraction <= 2:
+		nice = 2
+	case round && fraction < 7, !round && fraction <= 5:
+		nice = 5
+	default:
+		nice = 10
+	}
+	return nice * math.Pow(10, exponent)
+}
+
+// formatCompact writes an axis value briefly, as 1.5k or 2M.
+func formatCompact(value float64) string {
+	suffix := ""
+	switch abs := math.Abs(value); {
+	case abs >= 1e6:
+		value, suffix = value/1e6, "M"
+	case abs >= 1e3:
+		value, suffix = value/1e3, "k"
+	}
+	text := strconv.FormatFloat(value, 'f', 1, 64)
+	text = strings.TrimSuffix(text, ".0")
+	if text == "-0" {
+		text = "0"
+	}
+	return text + suffix
+}
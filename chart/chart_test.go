@@ -0,0 +1,128 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testCharts() []Chart {
+	return []Chart{
+		{Kind: KindPie, Title: "Expenses", Labels: []string{"food", "transport", "shopping"}, Series: []Series{{Values: []float64{4500, 1100, 12000}}}},
+		{Kind: KindBar, Title: "Income and expenses", Labels: []string{"Jan", "Feb", "Mar"}, Series: []Series{
+			{Name: "Income", Values: []float64{30000, 30000, 31000}},
+			{Name: "Expenses", Values: []float64{18000, 22000, 18600}},
+		}},
+		{Kind: KindLine, Title: "Balance", Labels: []string{"1", "2", "3", "4"}, Series: []Series{{Name: "Balance", Values: []float64{4000, -2000, 1000}}}},
+	}
+}
+
+func TestRender_PNG(t *testing.T) {
+	for _, c := range testCharts() {
+		for _, size := range [][2]int{{600, 400}, {200, 400}, {MaxSize, MinHeight}} {
+			var buf bytes.Buffer
+			err := Render(&buf, c, Options{Format: FormatPNG, Width: size[0], Height: size[1], Theme: ThemeDark})
+			assert.NoError(t, err, c.Kind)
+
+			img, err := png.Decode(&buf)
+			assert.NoError(t, err, c.Kind)
+			assert.Equal(t, size[0], img.Bounds().Dx())
+			assert.Equal(t, size[1], img.Bounds().Dy())
+			r, g, b, _ := img.At(0, 0).RGBA()
+			background := Themes[ThemeDark].Background
+			assert.Equal(t, [3]uint8{background.R, background.G, background.B}, [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)})
+		}
+	}
+}
+
+func TestRender_PNG_PieSlices(t *testing.T) {
+	c := Chart{Kind: KindPie, Labels: []string{"a", "b"}, Series: []Series{{Values: []float64{3, 1}}}}
+	var buf bytes.Buffer
+	assert.NoError(t, Render(&buf, c, Options{Format: FormatPNG, Width: 300, Height: 400}))
+	img, err := png.Decode(&buf)
+	assert.NoError(t, err)
+
+	// A tall chart puts the legend underneath, leaving the pie centred at
+	// 150, 172. The first slice starts at the top and runs clockwise.
+	palette := Themes[ThemeLight].Palette
+	at := func(x, y int) [3]uint8 {
+		r, g, b, _ := img.At(x, y).RGBA()
+		return [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+	}
+	assert.Equal(t, [3]uint8{palette[0].R, palette[0].G, palette[0].B}, at(200, 122))
+	assert.Equal(t, [3]uint8{palette[1].R, palette[1].G, palette[1].B}, at(100, 122))
+}
+
+func TestRender_SVG(t *testing.T) {
+	for _, c := range testCharts() {
+		var buf bytes.Buffer
+		err := Render(&buf, c, Options{Format: FormatSVG})
+		assert.NoError(t, err, c.Kind)
+
+		// The output is well-formed XML.
+		decoder := xml.NewDecoder(bytes.NewReader(buf.Bytes()))
+		for {
+			_, err := decoder.Token()
+			if err != nil {
+				assert.Equal(t, "EOF", err.Error(), c.Kind)
+				break
+			}
+		}
+		assert.True(t, strings.HasPrefix(buf.String(), `<svg xmlns="http://www.w3.org/2000/svg" width="600" height="400"`))
+		assert.Contains(t, buf.String(), ">"+c.Title+"</text>")
+	}
+}
+
+func TestRender_SVG_EscapesText(t *testing.T) {
+	c := Chart{Kind: KindPie, Title: `<b>"Tom & Jerry"</b>`, Labels: []string{"อาหาร"}, Series: []Series{{Values: []float64{1}}}}
+	var buf bytes.Buffer
+	assert.NoError(t, Render(&buf, c, Options{}))
+	assert.Contains(t, buf.String(), "&lt;b&gt;&#34;Tom &amp; Jerry&#34;&lt;/b&gt;")
+	assert.Contains(t, buf.String(), ">อาหาร</text>")
+	assert.Contains(t, buf.String(), ">100%</text>")
+}
+
+func TestRender_Empty(t *testing.T) {
+	c := Chart{Kind: KindBar, Labels: []string{"Jan"}, Series: []Series{{Values: []float64{0}}}, Empty: "No data"}
+	var buf bytes.Buffer
+	assert.NoError(t, Render(&buf, c, Options{}))
+	assert.Contains(t, buf.String(), `text-anchor="middle">No data</text>`)
+	assert.NotContains(t, buf.String(), "<polyline")
+}
+
+func TestRender_Invalid(t *testing.T) {
+	c := testCharts()[0]
+	assert.ErrorIs(t, Render(&bytes.Buffer{}, c, Options{Width: MinWidth - 1}), ErrInvalidSize)
+	assert.ErrorIs(t, Render(&bytes.Buffer{}, c, Options{Height: MaxSize + 1}), ErrInvalidSize)
+	assert.ErrorIs(t, Render(&bytes.Buffer{}, c, Options{Format: "gif"}), ErrUnknownFormat)
+	assert.ErrorIs(t, Render(&bytes.Buffer{}, c, Options{Fonts: [][]byte{[]byte("not a font")}}), ErrInvalidFont)
+	c.Kind = "radar"
+	assert.ErrorIs(t, Render(&bytes.Buffer{}, c, Options{}), ErrUnknownKind)
+}
+
+func TestNiceScale(t *testing.T) {
+	tests := []struct {
+		low, high                   float64
+		wantLow, wantHigh, wantStep float64
+	}{
+		{0, 45000, 0, 50000, 10000},
+		{-2000, 34000, -10000, 40000, 10000},
+		{0, 0, 0, 1, 0.2},
+		{0, 7.3, 0, 8, 2},
+	}
+	for _, test := range tests {
+		low, high, step := niceScale(test.low, test.high, 5)
+		assert.Equal(t, [3]float64{test.wantLow, test.wantHigh, test.wantStep}, [3]float64{low, high, step})
+	}
+}
+
+func TestFormatCompact(t *testing.T) {
+	assert.Equal(t, "0", formatCompact(0))
+	assert.Equal(t, "950", formatCompact(950))
+	assert.Equal(t, "1.5k", formatCompact(1500))
+	assert.Equal(t, "-10k", formatCompact(-10000))
+	assert.Equal(t, "2M", formatCompact(2000000))
+}
@@ -0,0 +1,86 @@
+package chart
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"strings"
+)
+
+// fontSet measures and draws text in the first of its fonts that has each
+// character, so a Latin font can be paired with a Thai one.
+type fontSet struct {
+	fonts  []*sfnt.Font
+	buffer sfnt.Buffer
+	faces  map[faceKey]font.Face
+}
+
+type faceKey struct {
+	index int
+	size  float64
+}
+
+func newFontSet(programs [][]byte) (*fontSet, error) {
+	if len(programs) == 0 {
+		programs = [][]byte{goregular.TTF}
+	}
+	set := &fontSet{faces: make(map[faceKey]font.Face)}
+	for _, data := range programs {
+		parsed, err := opentype.Parse(data)
+		if err != nil {
+			return nil, ErrInvalidFont
+		}
+		set.fonts = append(set.fonts, parsed)
+	}
+	return set, nil
+}
+
+// pick returns the index of the font that draws r, or the first font's when
+// none does.
+func (s *fontSet) pick(r rune) int {
+	for i, f := range s.fonts {
+		glyph, err := f.GlyphIndex(&s.buffer, r)
+		if err == nil && glyph != 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+func (s *fontSet) face(index int, size float64) font.Face {
+	key := faceKey{index, size}
+	face, ok := s.faces[key]
+	if !ok {
+		// A font that parsed always makes a face.
+		face, _ = opentype.NewFace(s.fonts[index], &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+		s.faces[key] = face
+	}
+	return face
+}
+
+// width is how wide text is drawn at size, in pixels.
+func (s *fontSet) width(text string, size float64) float64 {
+	var width float64
+	for _, r := range text {
+		advance, _ := s.face(s.pick(r), size).GlyphAdvance(r)
+		width += float64(advance) / 64
+	}
+	return width
+}
+
+// fit shortens text with an ellipsis until it is at most width wide.
+func (s *fontSet) fit(text string, size, width float64) string {
+	if s.width(text, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "…"
+		if s.width(candidate, size) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+)
+
+// cachePattern matches every key the repositories cache reads under, and
+// nothing else kept in redis.
+const cachePattern = "get-*"
+
+const flushBatch = 500
+
+func cacheFlush(e *env, args []string) error {
+	fs := e.flags()
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	redisClient := newRedisClient()
+	defer redisClient.Close()
+	deleted, err := flushCache(context.Background(), redisClient)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "deleted %d cached keys\n", deleted)
+	return nil
+}
+
+// flushCache deletes the cached keys a batch at a time, scanning rather than
+// listing them so a large cache does not block redis.
+func flushCache(ctx context.Context, redisClient *redis.Client) (int64, error) {
+	var deleted int64
+	keys := make([]string, 0, flushBatch)
+	del := func() error {
+		if len(keys) == 0 {
+			return nil
+		}
+		n, err := redisClient.Del(ctx, keys...).Result()
+		deleted += n
+		keys = keys[:0]
+		return err
+	}
+
+	iter := redisClient.Scan(ctx, 0, cachePattern, flushBatch).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == flushBatch {
+			if err := del(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, del()
+}
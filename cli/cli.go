@@ -0,0 +1,182 @@
+// Package cli is the jod-jod command line: the server and the tasks run
+// beside it, such as migrations and seeding. Every command reads the same
+// config.yaml through config.Load.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+type command struct {
+	name string
+	// args are the arguments after the flags, if the command takes any.
+	args    string
+	summary string
+	run     func(e *env, args []string) error
+}
+
+var commands = []command{
+	{name: "serve", summary: "start the server; the default when no command is given", run: serve},
+	{name: "migrate up", summary: "apply pending migrations", run: migrateUp},
+	{name: "migrate down", args: "[steps]", summary: "revert the last migration, or the last steps of them", run: migrateDown},
+	{name: "migrate status", summary: "list migrations and whether each is applied", run: migrateStatus},
+	{name: "migrate new", args: "<name>", summary: "create empty up and down scripts", run: migrateNew},
+	{name: "seed", summary: "create demo users with months of transactions", run: seed},
+	{name: "user create-admin", summary: "create the operator's account", run: userCreateAdmin},
+	{name: "user reset-password", summary: "set a user's password", run: userResetPassword},
+	{name: "cache flush", summary: "delete everything cached in redis", run: cacheFlush},
+	{name: "reports recompute", summary: "rebuild net worth history from the current transactions", run: reportsRecompute},
+	{name: "config validate", summary: "check config.yaml and say what is wrong with it", run: configValidate},
+}
+
+var (
+	// errUsage means the command was misused and has said how.
+	errUsage = errors.New("usage")
+	errHelp  = errors.New("help")
+)
+
+// env is what a running command writes to.
+type env struct {
+	command command
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// Run runs the command args name and returns the exit code: 0 on success, 1
+// when the command fails and 2 when it is misused.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(stdout, "")
+		return 0
+	}
+
+	c, rest, ok := find(args)
+	if !ok {
+		if isGroup(args[0]) {
+			fmt.Fprintf(stderr, "jod-jod %s needs a subcommand\n\n", args[0])
+			printUsage(stderr, args[0])
+		} else {
+			fmt.Fprintf(stderr, "jod-jod: unknown command %q\n\n", args[0])
+			printUsage(stderr, "")
+		}
+		return 2
+	}
+
+	e := &env{command: c, stdout: stdout, stderr: stderr}
+	err := c.run(e, rest)
+	switch {
+	case err == nil, errors.Is(err, errHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	}
+	fmt.Fprintf(stderr, "jod-jod %s: %v\n", c.name, err)
+	return 1
+}
+
+// find picks the command whose name args starts with and returns the
+// arguments after it.
+func find(args []string) (command, []string, bool) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func isGroup(word string) bool {
+	for _, c := range commands {
+		if strings.HasPrefix(c.name, word+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// printUsage lists the commands, or only those in group when it is set.
+func printUsage(w io.Writer, group string) {
+	fmt.Fprintln(w, "usage: jod-jod <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	for _, c := range commands {
+		if group != "" && !strings.HasPrefix(c.name, group+" ") {
+			continue
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", strings.TrimSpace(c.name+" "+c.args), c.summary)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run jod-jod <command> -h to see its flags.")
+}
+
+// flags returns the flag set for the running command, which prints the
+// command's usage when it is misused.
+func (e *env) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet("jod-jod "+e.command.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		var hasFlags bool
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		usage := "jod-jod " + e.command.name
+		if hasFlags {
+			usage += " [flags]"
+		}
+		fmt.Fprintln(e.stderr, strings.TrimSpace("usage: "+usage+" "+e.command.args))
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args, leaving at most maxArgs of them after the flags.
+func (e *env) parse(fs *flag.FlagSet, args []string, maxArgs int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return errUsage
+	}
+	if fs.NArg() > maxArgs {
+		return e.misused(fs, "unexpected argument %q", fs.Arg(maxArgs))
+	}
+	return nil
+}
+
+// misused reports what was wrong with the command line and how to use the
+// command.
+func (e *env) misused(fs *flag.FlagSet, format string, a ...any) error {
+	fmt.Fprintf(e.stderr, "jod-jod %s: %s\n", e.command.name, fmt.Sprintf(format, a...))
+	fs.Usage()
+	return errUsage
+}
+
+// logger is the logger services are given, writing to stderr so stdout
+// keeps only what the command prints.
+func (e *env) logger() echo.Logger {
+	logger := echo.New().Logger
+	logger.SetOutput(e.stderr)
+	return logger
+}
+
+// newRedisClient connects to the cache the server uses.
+func newRedisClient() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+}
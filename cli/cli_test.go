@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Help(t *testing.T) {
+	code, stdout, _ := run("help")
+
+	assert.Equal(t, 0, code)
+	for _, c := range commands {
+		assert.Contains(t, stdout, c.name)
+	}
+}
+
+func TestRun_UnknownCommand(t *testing.T) {
+	code, _, stderr := run("start")
+
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "start"`)
+}
+
+func TestRun_GroupWithoutSubcommand(t *testing.T) {
+	code, _, stderr := run("user")
+
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "user create-admin")
+	assert.Contains(t, stderr, "user reset-password")
+	assert.NotContains(t, stderr, "migrate up")
+}
+
+func TestRun_CommandHelp(t *testing.T) {
+	code, _, stderr := run("seed", "-h")
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stderr, "usage: jod-jod seed [flags]")
+	assert.Contains(t, stderr, "-months")
+}
+
+func TestRun_Misused(t *testing.T) {
+	tests := [][]string{
+		{"migrate", "up", "now"},
+		{"migrate", "down", "0"},
+		{"migrate", "new"},
+		{"seed", "-users", "0"},
+		{"seed", "-bogus"},
+		{"user", "create-admin", "-username", "root"},
+		{"user", "reset-password"},
+		{"reports", "recompute", "-from", "2024-02-30"},
+		{"reports", "recompute", "-from", "2024-03-01", "-to", "2024-02-01"},
+	}
+	for _, args := range tests {
+		code, stdout, stderr := run(args...)
+
+		assert.Equal(t, 2, code, strings.Join(args, " "))
+		assert.Empty(t, stdout)
+		assert.Contains(t, stderr, "usage: jod-jod ", strings.Join(args, " "))
+	}
+}
+
+func TestRun_MigrateNew(t *testing.T) {
+	dir := t.TempDir()
+	code, stdout, _ := run("migrate", "new", "-dir", dir, "add_notes")
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "0001_add_notes.up.sql")
+	_, err := os.Stat(filepath.Join(dir, "0001_add_notes.down.sql"))
+	assert.NoError(t, err)
+}
+
+func TestGeneratePassword(t *testing.T) {
+	first, err := generatePassword()
+	assert.NoError(t, err)
+	second, err := generatePassword()
+	assert.NoError(t, err)
+
+	assert.Equal(t, passwordLength, len(first))
+	assert.NotEqual(t, first, second)
+	assert.Empty(t, strings.Trim(first, passwordAlphabet))
+}
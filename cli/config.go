@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/go-playground/validator/v10"
+	"strings"
+)
+
+func configValidate(e *env, args []string) error {
+	fs := e.flags()
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	_, err := config.Load()
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		for _, fieldErr := range invalid {
+			fmt.Fprintf(e.stderr, "%s %s\n", strings.TrimPrefix(fieldErr.Namespace(), "Config."), describe(fieldErr))
+		}
+		return fmt.Errorf("config has %d problems", len(invalid))
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stdout, "config is valid")
+	return nil
+}
+
+// describe says what a field failed in words, for the tags config uses.
+func describe(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "required_if":
+		if field, value, ok := strings.Cut(fieldErr.Param(), " "); ok {
+			return fmt.Sprintf("is required when %s is %s", field, value)
+		}
+		return "is required"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldErr.Param(), " ", ", "))
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fieldErr.Param())
+	}
+	return fmt.Sprintf("fails %s", fieldErr.Tag())
+}
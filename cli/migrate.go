@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/db"
+	"github.com/Montheankul-K/jod-jod/db/migration"
+	"strconv"
+	"text/tabwriter"
+)
+
+func migrateUp(e *env, args []string) error {
+	fs := e.flags()
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	runner, err := newMigrationRunner()
+	if err != nil {
+		return err
+	}
+	applied, err := runner.Up()
+	for _, m := range applied {
+		fmt.Fprintf(e.stdout, "applied %s\n", m)
+	}
+	if err == nil && len(applied) == 0 {
+		fmt.Fprintln(e.stdout, "no pending migrations")
+	}
+	return err
+}
+
+func migrateDown(e *env, args []string) error {
+	fs := e.flags()
+	if err := e.parse(fs, args, 1); err != nil {
+		return err
+	}
+	steps := 1
+	if fs.NArg() > 0 {
+		var err error
+		steps, err = strconv.Atoi(fs.Arg(0))
+		if err != nil || steps < 1 {
+			return e.misused(fs, "steps must be a positive number")
+		}
+	}
+
+	runner, err := newMigrationRunner()
+	if err != nil {
+		return err
+	}
+	reverted, err := runner.Down(steps)
+	for _, m := range reverted {
+		fmt.Fprintf(e.stdout, "reverted %s\n", m)
+	}
+	return err
+}
+
+func migrateStatus(e *env, args []string) error {
+	fs := e.flags()
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	runner, err := newMigrationRunner()
+	if err != nil {
+		return err
+	}
+	statuses, err := runner.Status()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "-"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, status.State, appliedAt)
+	}
+	return w.Flush()
+}
+
+// migrateNew works on the source tree and needs no database.
+func migrateNew(e *env, args []string) error {
+	fs := e.flags()
+	dir := fs.String("dir", migration.Dir, "where to write the scripts")
+	if err := e.parse(fs, args, 1); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return e.misused(fs, "a migration name is required")
+	}
+
+	up, down, err := migration.Create(*dir, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "created %s\ncreated %s\n", up, down)
+	return nil
+}
+
+func newMigrationRunner() (*migration.Runner, error) {
+	migrations, err := migration.Embedded()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	database := db.InitDatabase(cfg.Database)
+	return migration.NewRunner(migration.NewPostgresStore(database.Connect()), migrations), nil
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/db"
+	"github.com/Montheankul-K/jod-jod/domains/networth"
+	"github.com/Montheankul-K/jod-jod/repository/networth_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"time"
+)
+
+// reportsRecompute rebuilds the net worth snapshots, the one report kept
+// rather than worked out on request, after transactions were changed behind
+// the server's back, as by seed or a restore.
+func reportsRecompute(e *env, args []string) error {
+	fs := e.flags()
+	userId := fs.Uint("user", 0, "only recompute this user's; every user's when 0")
+	fromFlag := fs.String("from", "", "first day to rebuild, as 2006-01-02; as far back as allowed, two years, when empty")
+	toFlag := fs.String("to", "", "last day to rebuild, as 2006-01-02; today when empty")
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	to := time.Now()
+	var from time.Time
+	var err error
+	if *toFlag != "" {
+		if to, err = time.ParseInLocation("2006-01-02", *toFlag, time.Local); err != nil {
+			return e.misused(fs, "-to must be a date such as 2006-01-02")
+		}
+	}
+	if *fromFlag != "" {
+		if from, err = time.ParseInLocation("2006-01-02", *fromFlag, time.Local); err != nil {
+			return e.misused(fs, "-from must be a date such as 2006-01-02")
+		}
+		if from.After(to) {
+			return e.misused(fs, "-from is after -to")
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	database := db.InitDatabase(cfg.Database)
+	logger := e.logger()
+	redisClient := newRedisClient()
+	defer redisClient.Close()
+	transactionRepository := transaction_repository.NewTransactionRepository(database.Connect(), logger, redisClient)
+	netWorthRepository := networth_repository.NewNetWorthRepository(database.Connect(), logger, redisClient)
+	netWorthService := networth.NewNetWorthService(netWorthRepository, transactionRepository, logger)
+
+	spenderIds := []uint{*userId}
+	if *userId == 0 {
+		if spenderIds, err = netWorthRepository.GetSpenderIds(); err != nil {
+			return errors.New("failed to get users")
+		}
+	}
+
+	var failed int
+	for _, spenderId := range spenderIds {
+		if err = netWorthService.RecomputeSnapshots(spenderId, from, to); err != nil {
+			fmt.Fprintf(e.stderr, "user %d: %v\n", spenderId, err)
+			failed++
+		}
+	}
+	fmt.Fprintf(e.stdout, "recomputed the net worth history of %d of %d users\n", len(spenderIds)-failed, len(spenderIds))
+	if failed > 0 {
+		return fmt.Errorf("failed to recompute %d users", failed)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/db"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/user"
+	"github.com/Montheankul-K/jod-jod/repository/user_repository"
+	"gorm.io/gorm"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	maxSeedUsers  = 100
+	maxSeedMonths = 36
+	seedTimezone  = "Asia/Bangkok"
+)
+
+var (
+	seedFirstnames = []string{"Somchai", "Malee", "Anan", "Kanya", "Niran", "Ploy", "Thanawat", "Siriporn", "Krit", "Nattaya"}
+	seedLastnames  = []string{"Srisuk", "Wongsawat", "Chaiyaporn", "Rattanakul", "Boonmee", "Saelim", "Thongdee", "Kaewkla"}
+)
+
+// merchantRange is a place money is spent at and what it usually costs.
+type merchantRange struct {
+	name     string
+	min, max float64
+}
+
+var (
+	breakfastPlaces = []merchantRange{{"7-Eleven", 35, 90}, {"Cafe Amazon", 55, 120}, {"Jok Prince", 50, 80}}
+	lunchPlaces     = []merchantRange{{"Food court", 50, 120}, {"LINE MAN", 90, 220}, {"Khao Man Gai Pratunam", 50, 80}}
+	dinnerPlaces    = []merchantRange{{"MK Restaurants", 250, 700}, {"LINE MAN", 120, 350}, {"Tops", 150, 600}, {"Street food", 60, 150}}
+	shoppingPlaces  = []merchantRange{{"Shopee", 150, 1500}, {"Lazada", 200, 2500}, {"Uniqlo", 590, 1990}, {"Central", 300, 3500}}
+	healthPlaces    = []merchantRange{{"Boots", 120, 600}, {"Fascino", 80, 400}, {"Bumrungrad Hospital", 900, 4500}}
+	outingPlaces    = []merchantRange{{"Major Cineplex", 220, 600}, {"Bar", 400, 1500}, {"Karaoke", 300, 900}}
+)
+
+// seedBudgets are the monthly limits each demo user gets.
+var seedBudgets = map[string]float64{"food": 9000, "transport": 2500, "shopping": 5000}
+
+func seed(e *env, args []string) error {
+	fs := e.flags()
+	users := fs.Int("users", 3, "how many demo users to create")
+	months := fs.Int("months", 6, "months of transactions each user gets, ending today")
+	password := fs.String("password", "demo1234", "the demo users' password")
+	randomSeed := fs.Int64("seed", 1, "random seed; the same seed makes the same data")
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *users < 1 || *users > maxSeedUsers {
+		return e.misused(fs, "-users must be between 1 and %d", maxSeedUsers)
+	}
+	if *months < 1 || *months > maxSeedMonths {
+		return e.misused(fs, "-months must be between 1 and %d", maxSeedMonths)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	database := db.InitDatabase(cfg.Database)
+	conn := database.Connect()
+	logger := e.logger()
+	redisClient := newRedisClient()
+	defer redisClient.Close()
+	userService := user.NewUserService(user_repository.NewUserRepository(conn, logger, redisClient), logger)
+
+	loc, err := time.LoadLocation(seedTimezone)
+	if err != nil {
+		loc = time.Local
+	}
+	now := time.Now().In(loc)
+	from := time.Date(now.Year(), now.Month()-time.Month(*months-1), 1, 0, 0, 0, 0, loc)
+	rng := rand.New(rand.NewSource(*randomSeed))
+
+	var created int
+	for i := 1; i <= *users; i++ {
+		// Every user draws from rng whether or not it is created, so the
+		// others come out the same.
+		req := demoUser(rng, i, *password)
+		txns := demoTransactions(rng, from, now)
+
+		var count int64
+		if err = conn.Model(&entities.Users{}).Where("username = ?", req.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			fmt.Fprintf(e.stdout, "skipped %s, who already exists\n", req.Username)
+			continue
+		}
+
+		userId, err := userService.CreateUser(req)
+		if err != nil {
+			return fmt.Errorf("%s: %w", req.Username, err)
+		}
+		if err = saveDemoData(conn, int(userId), txns); err != nil {
+			return fmt.Errorf("%s: %w", req.Username, err)
+		}
+		created++
+		fmt.Fprintf(e.stdout, "created %s with id %d and %d transactions\n", req.Username, userId, len(txns))
+	}
+	if created == 0 {
+		return nil
+	}
+
+	// Lists cached before the seed would leave the new rows out.
+	if _, err = flushCache(context.Background(), redisClient); err != nil {
+		fmt.Fprintf(e.stderr, "failed to flush the cache, run jod-jod cache flush: %v\n", err)
+	}
+	fmt.Fprintf(e.stdout, "log in with password %q; run jod-jod reports recompute to build their net worth history\n", *password)
+	return nil
+}
+
+func saveDemoData(conn *gorm.DB, spenderId int, txns []entities.Transaction) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		for i := range txns {
+			txns[i].SpenderId = spenderId
+		}
+		if err := tx.CreateInBatches(txns, 200).Error; err != nil {
+			return err
+		}
+		for category, limit := range seedBudgets {
+			budget := entities.Budget{Category: category, MonthlyLimit: limit, SpenderId: spenderId}
+			if err := tx.Create(&budget).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func demoUser(rng *rand.Rand, n int, password string) user.Users {
+	language := "th"
+	if n%2 == 0 {
+		language = "en"
+	}
+	return user.Users{
+		Firstname: seedFirstnames[rng.Intn(len(seedFirstnames))],
+		Lastname:  seedLastnames[rng.Intn(len(seedLastnames))],
+		Email:     fmt.Sprintf("demo%d@example.com", n),
+		Username:  fmt.Sprintf("demo%d", n),
+		Password:  password,
+		Timezone:  seedTimezone,
+		Language:  language,
+	}
+}
+
+// demoTransactions makes a Bangkok office worker's transactions from from up
+// to now: a monthly salary and bills, meals most days, commutes on weekdays
+// and the odd purchase or night out.
+func demoTransactions(rng *rand.Rand, from, now time.Time) []entities.Transaction {
+	loc := now.Location()
+	salary := float64(25000 + rng.Intn(36)*1000)
+	rent := math.Round(salary*(0.2+rng.Float64()*0.15)/500) * 500
+	drivesToWork := rng.Intn(4) == 0
+
+	var txns []entities.Transaction
+	add := func(day time.Time, hour int, txnType, category, merchant, note string, amount float64) {
+		date := day.Add(time.Duration(hour)*time.Hour + time.Duration(rng.Intn(60))*time.Minute)
+		if date.After(now) {
+			return
+		}
+		txns = append(txns, entities.Transaction{
+			Date:            date,
+			Amount:          amount,
+			Category:        category,
+			TransactionType: txnType,
+			Note:            note,
+			Merchant:        merchant,
+		})
+	}
+	spend := func(day time.Time, hour int, category string, places []merchantRange) {
+		place := places[rng.Intn(len(places))]
+		add(day, hour, "expense", category, place.name, "", math.Round(place.min+rng.Float64()*(place.max-place.min)))
+	}
+
+	freelanceDay := 0
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc); !day.After(now); day = day.AddDate(0, 0, 1) {
+		if day.Day() == 1 {
+			add(day, 9, "expense", "housing", "", "rent", rent)
+			freelanceDay = 0
+			if rng.Intn(3) == 0 {
+				freelanceDay = 2 + rng.Intn(26)
+			}
+		}
+		switch day.Day() {
+		case 5:
+			add(day, 19, "expense", "bills", "MEA", "electricity", math.Round((600+rng.Float64()*1400)*100)/100)
+		case 10:
+			add(day, 20, "expense", "bills", "True Online", "internet", 599)
+		case 12:
+			add(day, 20, "expense", "bills", "AIS", "phone", 399)
+		case 15:
+			add(day, 21, "expense", "entertainment", "Netflix", "", 419)
+		case 20:
+			add(day, 21, "expense", "entertainment", "Spotify", "", 149)
+		case 25:
+			add(day, 8, "income", "salary", "", "salary", salary)
+		}
+		if day.Day() == freelanceDay {
+			add(day, 14, "income", "freelance", "", "freelance work", float64(2000+rng.Intn(27)*500))
+		}
+
+		weekend := day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+		if rng.Float64() < 0.7 {
+			spend(day, 7, "food", breakfastPlaces)
+		}
+		spend(day, 12, "food", lunchPlaces)
+		if rng.Float64() < 0.6 {
+			spend(day, 19, "food", dinnerPlaces)
+		}
+		switch {
+		case !weekend && drivesToWork && rng.Intn(5) == 0:
+			add(day, 18, "expense", "transport", "PTT", "fuel", float64(800+rng.Intn(13)*50))
+		case !weekend && !drivesToWork:
+			add(day, 8, "expense", "transport", "BTS", "", float64(17+rng.Intn(46)))
+			add(day, 18, "expense", "transport", "BTS", "", float64(17+rng.Intn(46)))
+		case weekend && rng.Intn(3) == 0:
+			add(day, 16, "expense", "transport", "Grab", "", float64(90+rng.Intn(160)))
+		}
+		if rng.Float64() < 0.12 {
+			spend(day, 13+rng.Intn(9), "shopping", shoppingPlaces)
+		}
+		if rng.Float64() < 0.04 {
+			spend(day, 10+rng.Intn(8), "health", healthPlaces)
+		}
+		if weekend && rng.Float64() < 0.4 {
+			spend(day, 20, "entertainment", outingPlaces)
+		}
+	}
+	return txns
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDemoTransactions(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*60*60)
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, loc)
+	now := time.Date(2024, 3, 20, 12, 0, 0, 0, loc)
+	txns := demoTransactions(rand.New(rand.NewSource(1)), from, now)
+
+	salaries := make(map[time.Month]int)
+	categories := make(map[string]bool)
+	for _, txn := range txns {
+		assert.False(t, txn.Date.Before(from), txn.Date)
+		assert.False(t, txn.Date.After(now), txn.Date)
+		assert.Greater(t, txn.Amount, 0.0)
+		assert.Contains(t, []string{"income", "expense"}, txn.TransactionType)
+		if txn.Category == "salary" {
+			salaries[txn.Date.Month()]++
+		}
+		categories[txn.Category] = true
+	}
+	// March's salary is paid on the 25th, after now.
+	assert.Equal(t, map[time.Month]int{time.January: 1, time.February: 1}, salaries)
+	for _, category := range []string{"housing", "bills", "food", "transport", "entertainment"} {
+		assert.True(t, categories[category], category)
+	}
+}
+
+func TestDemoTransactions_SameSeed(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	first := demoTransactions(rand.New(rand.NewSource(7)), from, now)
+	second := demoTransactions(rand.New(rand.NewSource(7)), from, now)
+	other := demoTransactions(rand.New(rand.NewSource(8)), from, now)
+
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first, other)
+}
+
+func TestDemoUser(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	first := demoUser(rng, 1, "secret")
+	second := demoUser(rng, 2, "secret")
+
+	assert.Equal(t, "demo1", first.Username)
+	assert.Equal(t, "demo1@example.com", first.Email)
+	assert.Equal(t, "secret", first.Password)
+	assert.Equal(t, "th", first.Language)
+	assert.Equal(t, "en", second.Language)
+	assert.Equal(t, seedTimezone, second.Timezone)
+}
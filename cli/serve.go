@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/db"
+	"github.com/Montheankul-K/jod-jod/db/migration"
+	"github.com/Montheankul-K/jod-jod/server"
+)
+
+func serve(e *env, args []string) error {
+	fs := e.flags()
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	database := db.InitDatabase(cfg.Database)
+	if cfg.Database.AutoMigrate {
+		if err = migration.Migrate(database); err != nil {
+			return err
+		}
+	}
+
+	srv := server.InitServer(cfg, database)
+	return srv.Start()
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/db"
+	"github.com/Montheankul-K/jod-jod/domains/user"
+	"github.com/Montheankul-K/jod-jod/repository/user_repository"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"math/big"
+)
+
+const (
+	passwordLength   = 16
+	passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// userCreateAdmin creates the operator's account without going through the
+// API. The app has no roles yet, so the account is an ordinary user.
+func userCreateAdmin(e *env, args []string) error {
+	fs := e.flags()
+	username := fs.String("username", "", "the admin's username")
+	email := fs.String("email", "", "the admin's email")
+	firstname := fs.String("firstname", "Admin", "the admin's first name")
+	lastname := fs.String("lastname", "User", "the admin's last name")
+	password := fs.String("password", "", "the admin's password; one is generated and printed when empty")
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *username == "" {
+		return e.misused(fs, "-username is required")
+	}
+	if *email == "" {
+		return e.misused(fs, "-email is required")
+	}
+
+	userService, err := newUserService(e)
+	if err != nil {
+		return err
+	}
+	generated := *password == ""
+	if generated {
+		if *password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+	req := user.Users{
+		Firstname: *firstname,
+		Lastname:  *lastname,
+		Email:     *email,
+		Username:  *username,
+		Password:  *password,
+	}
+	if err = validator.New().Struct(&req); err != nil {
+		return e.misused(fs, "%v", err)
+	}
+	userId, err := userService.CreateUser(req)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "created %s with id %d\n", *username, userId)
+	if generated {
+		fmt.Fprintf(e.stdout, "password: %s\n", *password)
+	}
+	return nil
+}
+
+func userResetPassword(e *env, args []string) error {
+	fs := e.flags()
+	username := fs.String("username", "", "whose password to reset")
+	password := fs.String("password", "", "the new password; one is generated and printed when empty")
+	if err := e.parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *username == "" {
+		return e.misused(fs, "-username is required")
+	}
+
+	userService, err := newUserService(e)
+	if err != nil {
+		return err
+	}
+	generated := *password == ""
+	if generated {
+		if *password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+	err = userService.ResetPassword(*username, *password)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("user %s not found", *username)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "reset the password of %s\n", *username)
+	if generated {
+		fmt.Fprintf(e.stdout, "password: %s\n", *password)
+	}
+	return nil
+}
+
+func newUserService(e *env) (user.IUserService, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	database := db.InitDatabase(cfg.Database)
+	logger := e.logger()
+	userRepository := user_repository.NewUserRepository(database.Connect(), logger, newRedisClient())
+	return user.NewUserService(userRepository, logger), nil
+}
+
+// generatePassword leaves out characters easily mistaken for one another,
+// since the password is read off a terminal.
+func generatePassword() (string, error) {
+	password := make([]byte, passwordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 	"strings"
@@ -16,6 +17,9 @@ type (
 		Password string `mapstructure:"password" validate:"required"`
 		Database string `mapstructure:"database" validate:"required"`
 		SSLMode  string `mapstructure:"sslmode" validate:"required"`
+		// AutoMigrate applies pending migrations when the server starts;
+		// otherwise they are applied with the migrate command.
+		AutoMigrate bool `mapstructure:"auto_migrate"`
 	}
 
 	Server struct {
@@ -30,6 +34,8 @@ type (
 		Secret string `mapstructure:"secret" validate:"required"`
 	}
 
+	// AWS is only required for S3 storage without a storage section and for
+	// Textract.
 	AWS struct {
 		Region          string `mapstructure:"region" validate:"required"`
 		Bucket          string `mapstructure:"bucket" validate:"required"`
@@ -38,38 +44,150 @@ type (
 		SecretAccessKey string `mapstructure:"secret_access_key" validate:"required"`
 	}
 
+	// OCR is optional; without it slips are read with AWS Textract.
+	OCR struct {
+		Provider      string `mapstructure:"provider" validate:"omitempty,oneof=textract tesseract fixture"`
+		TesseractPath string `mapstructure:"tesseract_path"`
+		Languages     string `mapstructure:"languages"`
+		FixtureDir    string `mapstructure:"fixture_dir" validate:"required_if=Provider fixture"`
+	}
+
+	// Storage is optional; without it blobs are kept in the AWS bucket.
+	Storage struct {
+		Provider        string `mapstructure:"provider" validate:"omitempty,oneof=s3 minio local"`
+		Endpoint        string `mapstructure:"endpoint" validate:"required_if=Provider minio"`
+		Region          string `mapstructure:"region"`
+		Bucket          string `mapstructure:"bucket" validate:"required_if=Provider s3,required_if=Provider minio"`
+		AccessKeyID     string `mapstructure:"access_key_id"`
+		SecretAccessKey string `mapstructure:"secret_access_key"`
+		LocalDir        string `mapstructure:"local_dir" validate:"required_if=Provider local"`
+		SlipPath        string `mapstructure:"slip_path"`
+	}
+
+	// Attachment is optional; without it the service defaults apply and files
+	// are not virus scanned.
+	Attachment struct {
+		MaxSize           int64  `mapstructure:"max_size" validate:"omitempty,gt=0"`
+		MaxPerTransaction int    `mapstructure:"max_per_transaction" validate:"omitempty,gt=0"`
+		Scanner           string `mapstructure:"scanner" validate:"omitempty,oneof=none clamav"`
+		ClamAVAddress     string `mapstructure:"clamav_address" validate:"required_if=Scanner clamav"`
+	}
+
+	// Notification is optional; without it notifications are only logged.
+	Notification struct {
+		Channel      string `mapstructure:"channel" validate:"omitempty,oneof=log webhook email"`
+		WebhookURL   string `mapstructure:"webhook_url" validate:"required_if=Channel webhook"`
+		SMTPHost     string `mapstructure:"smtp_host" validate:"required_if=Channel email"`
+		SMTPPort     int    `mapstructure:"smtp_port" validate:"required_if=Channel email"`
+		SMTPUsername string `mapstructure:"smtp_username"`
+		SMTPPassword string `mapstructure:"smtp_password"`
+		From         string `mapstructure:"from" validate:"required_if=Channel email"`
+	}
+
+	// Inbox is optional; without it email-in is off. Mail reaches the app
+	// through the ingest endpoint, guarded by IngestSecret, or through the
+	// SMTP listener when SMTPAddress is set.
+	Inbox struct {
+		Domain       string `mapstructure:"domain" validate:"required"`
+		IngestSecret string `mapstructure:"ingest_secret"`
+		SMTPAddress  string `mapstructure:"smtp_address"`
+		MaxSize      int64  `mapstructure:"max_size" validate:"omitempty,gt=0"`
+	}
+
+	// Statement is optional. Statements are set in the Go fonts, which have
+	// no Thai, so FontPath should name a TrueType font that does, such as
+	// Sarabun or Noto Sans Thai; without it a few common system paths are
+	// tried.
+	Statement struct {
+		FontPath     string `mapstructure:"font_path"`
+		BoldFontPath string `mapstructure:"bold_font_path"`
+	}
+
 	Config struct {
-		Database *Database `mapstructure:"database" validate:"required"`
-		Server   *Server   `mapstructure:"server" validate:"required"`
-		Auth     *Auth     `mapstructure:"auth" validate:"required"`
-		AWS      *AWS      `mapstructure:"aws" validate:"required"`
+		Database     *Database     `mapstructure:"database" validate:"required"`
+		Server       *Server       `mapstructure:"server" validate:"required"`
+		Auth         *Auth         `mapstructure:"auth" validate:"required"`
+		AWS          *AWS          `mapstructure:"aws"`
+		OCR          *OCR          `mapstructure:"ocr"`
+		Storage      *Storage      `mapstructure:"storage"`
+		Attachment   *Attachment   `mapstructure:"attachment"`
+		Notification *Notification `mapstructure:"notification"`
+		Inbox        *Inbox        `mapstructure:"inbox"`
+		Statement    *Statement    `mapstructure:"statement"`
 	}
 )
 
 var (
-	once sync.Once
-	cfg  *Config
+	once    sync.Once
+	cfg     *Config
+	loadErr error
 )
 
-func GetConfig() *Config {
+// Load reads and checks config.yaml, or the environment variables that
+// override it, the first time it is called; later calls return the same
+// result.
+func Load() (*Config, error) {
 	once.Do(func() {
-		viper.SetConfigName("config")
-		viper.SetConfigType("yaml")
-		viper.AddConfigPath("./")
-		viper.AutomaticEnv()
-		viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-		if err := viper.ReadInConfig(); err != nil {
-			panic(err)
-		}
-
-		if err := viper.Unmarshal(&cfg); err != nil {
-			panic(err)
-		}
-
-		validate := validator.New()
-		if err := validate.Struct(cfg); err != nil {
-			panic(err)
-		}
+		cfg, loadErr = load()
 	})
-	return cfg
+	return cfg, loadErr
+}
+
+// GetConfig is Load for callers that cannot go on without a config.
+func GetConfig() *Config {
+	c, err := Load()
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func load() (*Config, error) {
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("./")
+	viper.AutomaticEnv()
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+
+	var c *Config
+	if err := viper.Unmarshal(&c); err != nil {
+		return nil, err
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(c); err != nil {
+		return nil, err
+	}
+	if err := c.checkAWS(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkAWS requires the AWS section only when something still uses it.
+func (c *Config) checkAWS() error {
+	if c.AWS != nil {
+		return nil
+	}
+	if c.Storage == nil || c.Storage.Provider == "" {
+		return errors.New("aws config is required when storage is not configured")
+	}
+	if c.OCR == nil || c.OCR.Provider == "" || c.OCR.Provider == "textract" {
+		return errors.New("aws config is required for textract ocr")
+	}
+	return nil
+}
+
+// SlipPath is the folder slip images are stored under.
+func (c *Config) SlipPath() string {
+	if c.Storage != nil && c.Storage.SlipPath != "" {
+		return c.Storage.SlipPath
+	}
+	if c.AWS != nil && c.AWS.BucketSlipPath != "" {
+		return c.AWS.BucketSlipPath
+	}
+	return "slips"
 }
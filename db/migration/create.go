@@ -0,0 +1,57 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidName = errors.New("migration name may only have letters, digits, spaces, dashes and underscores")
+
+	nameSeparators = regexp.MustCompile(`[\s-]+`)
+	validName      = regexp.MustCompile(`^[a-z0-9_]+$`)
+)
+
+// Create writes empty up and down scripts for a new migration in dir,
+// numbered after the newest there, and returns their paths.
+func Create(dir, name string) (string, string, error) {
+	name = nameSeparators.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "_")
+	if !validName.MatchString(name) {
+		return "", "", ErrInvalidName
+	}
+	existing, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	version := int64(1)
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	m := Migration{Version: version, Name: name}
+	up := filepath.Join(dir, m.String()+".up.sql")
+	down := filepath.Join(dir, m.String()+".down.sql")
+	scripts := map[string]string{
+		up:   fmt.Sprintf("-- %s: what this migration changes.\n", name),
+		down: fmt.Sprintf("-- %s: undo the up script.\n", name),
+	}
+	for _, file := range []string{up, down} {
+		// O_EXCL leaves a script that is already there alone.
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		_, err = f.WriteString(scripts[file])
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return up, down, nil
+}
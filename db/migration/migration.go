@@ -1,16 +1,142 @@
+// Package migration keeps the database schema up to date with versioned SQL
+// scripts. Each migration is a pair of files, <version>_<name>.up.sql and
+// <version>_<name>.down.sql, applied in version order and recorded in the
+// schema_migrations table with a checksum of its up script, so a script
+// edited after it ran is caught rather than silently skipped.
 package migration
 
 import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"github.com/Montheankul-K/jod-jod/db"
-	"github.com/Montheankul-K/jod-jod/domains/transaction"
-	"github.com/Montheankul-K/jod-jod/domains/user"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Dir is where migrations live in the source tree, for migrate new.
+const Dir = "db/migration/sql"
+
+// noTransaction marks a script that must run outside a transaction, such as
+// one creating an index concurrently.
+const noTransaction = "-- migrate:no-transaction"
+
+//go:embed sql/*.sql
+var files embed.FS
+
+var (
+	ErrChecksumMismatch = errors.New("migration has changed since it was applied")
+	ErrNoDownScript     = errors.New("migration has no down script")
+
+	fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+)
+
+type Migration struct {
+	Version       int64
+	Name          string
+	Up            string
+	Down          string
+	NoTransaction bool
+}
+
+// Checksum identifies the up script; only what has been applied matters.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Record is a migration as the schema table remembers it.
+type Record struct {
+	Version   int64     `gorm:"column:version"`
+	Name      string    `gorm:"column:name"`
+	Checksum  string    `gorm:"column:checksum"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+// Migrate applies the pending migrations built into the binary. Replicas
+// starting together take turns, and all but the first find nothing to do.
 func Migrate(db db.DB) error {
-	err := db.Connect().AutoMigrate(&user.Users{}, &transaction.Transaction{})
+	migrations, err := Embedded()
 	if err != nil {
-		return errors.New("cannot migrate database")
+		return err
 	}
-	return nil
+	_, err = NewRunner(NewPostgresStore(db.Connect()), migrations).Up()
+	return err
+}
+
+// Load reads the migrations in fsys's root, in version order. Every
+// migration needs an up script; the down script is optional, though without
+// one the migration cannot be reverted.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.up.sql or .down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration file %s has an invalid version", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+			m.NoTransaction = isNoTransaction(m.Up)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %s has no up script", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Embedded returns the migrations built into the binary.
+func Embedded() ([]Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+func isNoTransaction(script string) bool {
+	return strings.HasPrefix(script, noTransaction)
 }
@@ -0,0 +1,216 @@
+package migration
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// memoryStore keeps the schema table in memory and logs the scripts run.
+type memoryStore struct {
+	records map[int64]Record
+	ran     []string
+	failOn  string
+	locks   int
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[int64]Record)}
+}
+
+func (s *memoryStore) Lock(fn func(Session) error) error {
+	s.locks++
+	return fn(s)
+}
+
+func (s *memoryStore) Applied() ([]Record, error) {
+	records := make([]Record, 0, len(s.records))
+	for _, record := range s.records {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	return records, nil
+}
+
+func (s *memoryStore) Apply(m Migration) error {
+	if m.Up == s.failOn {
+		return errors.New("syntax error")
+	}
+	s.ran = append(s.ran, m.Up)
+	s.records[m.Version] = Record{Version: m.Version, Name: m.Name, Checksum: m.Checksum(), AppliedAt: time.Now()}
+	return nil
+}
+
+func (s *memoryStore) Revert(m Migration) error {
+	s.ran = append(s.ran, m.Down)
+	delete(s.records, m.Version)
+	return nil
+}
+
+func testMigrations(t *testing.T) []Migration {
+	migrations, err := Load(fstest.MapFS{
+		"0002_add_notes.up.sql":   {Data: []byte("ALTER TABLE a ADD notes text;")},
+		"0002_add_notes.down.sql": {Data: []byte("ALTER TABLE a DROP notes;")},
+		"0001_create_a.up.sql":    {Data: []byte("CREATE TABLE a (id int);")},
+		"0001_create_a.down.sql":  {Data: []byte("DROP TABLE a;")},
+		"0003_index_a.up.sql":     {Data: []byte(noTransaction + "\nCREATE INDEX CONCURRENTLY a_notes ON a (notes);")},
+		"README.md":               {Data: []byte("not a migration")},
+	})
+	assert.NoError(t, err)
+	return migrations
+}
+
+func TestLoad(t *testing.T) {
+	migrations := testMigrations(t)
+
+	assert.Equal(t, 3, len(migrations))
+	assert.Equal(t, Migration{Version: 1, Name: "create_a", Up: "CREATE TABLE a (id int);", Down: "DROP TABLE a;"}, migrations[0])
+	assert.Equal(t, "0002_add_notes", migrations[1].String())
+	assert.False(t, migrations[1].NoTransaction)
+	assert.True(t, migrations[2].NoTransaction)
+	assert.Equal(t, "", migrations[2].Down)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name":      {"create_a.up.sql": {Data: []byte("SELECT 1;")}},
+		"no up script":  {"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")}},
+		"two names":     {"0001_a.up.sql": {Data: []byte("SELECT 1;")}, "0001_b.down.sql": {Data: []byte("SELECT 1;")}},
+		"zero version":  {"0000_a.up.sql": {Data: []byte("SELECT 1;")}},
+		"upper case":    {"0001_Create.up.sql": {Data: []byte("SELECT 1;")}},
+		"empty up file": {"0001_a.up.sql": {Data: []byte("  \n")}},
+	}
+	for name, fsys := range tests {
+		_, err := Load(fsys)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	migrations, err := Embedded()
+	assert.NoError(t, err)
+	assert.Equal(t, "0001_initial_schema", migrations[0].String())
+	for _, m := range migrations {
+		assert.NotEmpty(t, m.Down, m.String())
+	}
+}
+
+func TestRunner_Up(t *testing.T) {
+	store := newMemoryStore()
+	runner := NewRunner(store, testMigrations(t))
+
+	applied, err := runner.Up()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(applied))
+	assert.Equal(t, 3, len(store.records))
+
+	// Nothing is left to apply the second time.
+	applied, err = runner.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Equal(t, 3, len(store.ran))
+	assert.Equal(t, 2, store.locks)
+}
+
+func TestRunner_Up_OutOfOrder(t *testing.T) {
+	store := newMemoryStore()
+	migrations := testMigrations(t)
+	assert.NoError(t, store.Apply(migrations[0]))
+	assert.NoError(t, store.Apply(migrations[2]))
+
+	applied, err := NewRunner(store, migrations).Up()
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{migrations[1]}, applied)
+}
+
+func TestRunner_Up_ChecksumMismatch(t *testing.T) {
+	store := newMemoryStore()
+	migrations := testMigrations(t)
+	assert.NoError(t, store.Apply(migrations[0]))
+
+	migrations[0].Up = "CREATE TABLE a (id bigint);"
+	applied, err := NewRunner(store, migrations).Up()
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Empty(t, applied)
+	assert.Equal(t, 1, len(store.records))
+}
+
+func TestRunner_Up_Fails(t *testing.T) {
+	store := newMemoryStore()
+	migrations := testMigrations(t)
+	store.failOn = migrations[1].Up
+
+	applied, err := NewRunner(store, migrations).Up()
+	assert.ErrorContains(t, err, "failed to apply migration 0002_add_notes: syntax error")
+	assert.Equal(t, []Migration{migrations[0]}, applied)
+}
+
+func TestRunner_Down(t *testing.T) {
+	store := newMemoryStore()
+	migrations := testMigrations(t)
+	runner := NewRunner(store, migrations[:2])
+	_, err := runner.Up()
+	assert.NoError(t, err)
+
+	reverted, err := runner.Down(5)
+	assert.NoError(t, err)
+	assert.Equal(t, []Migration{migrations[1], migrations[0]}, reverted)
+	assert.Empty(t, store.records)
+	assert.Equal(t, []string{migrations[0].Up, migrations[1].Up, migrations[1].Down, migrations[0].Down}, store.ran)
+}
+
+func TestRunner_Down_NoScript(t *testing.T) {
+	store := newMemoryStore()
+	runner := NewRunner(store, testMigrations(t))
+	_, err := runner.Up()
+	assert.NoError(t, err)
+
+	reverted, err := runner.Down(1)
+	assert.ErrorIs(t, err, ErrNoDownScript)
+	assert.Empty(t, reverted)
+	assert.Equal(t, 3, len(store.records))
+}
+
+func TestRunner_Status(t *testing.T) {
+	store := newMemoryStore()
+	migrations := testMigrations(t)
+	assert.NoError(t, store.Apply(migrations[0]))
+	assert.NoError(t, store.Apply(migrations[1]))
+	assert.NoError(t, store.Apply(Migration{Version: 9, Name: "from_newer_release", Up: "SELECT 1;"}))
+	migrations[1].Up = "ALTER TABLE a ADD notes varchar;"
+
+	statuses, err := NewRunner(store, migrations).Status()
+	assert.NoError(t, err)
+
+	states := make([]string, len(statuses))
+	for i, status := range statuses {
+		states[i] = status.Name + " " + status.State
+	}
+	assert.Equal(t, []string{"create_a applied", "add_notes changed", "index_a pending", "from_newer_release missing"}, states)
+	assert.Nil(t, statuses[2].AppliedAt)
+	assert.NotNil(t, statuses[3].AppliedAt)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	up, down, err := Create(dir, "Add Account-Notes")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0001_add_account_notes.up.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "0001_add_account_notes.down.sql"), down)
+
+	up, _, err = Create(dir, "drop notes")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0002_drop_notes.up.sql"), up)
+
+	migrations, err := Load(os.DirFS(dir))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(migrations))
+
+	_, _, err = Create(dir, "drop; notes")
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
@@ -0,0 +1,151 @@
+package migration
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	StateApplied = "applied"
+	StatePending = "pending"
+	// StateChanged is an applied migration whose up script no longer matches
+	// its checksum.
+	StateChanged = "changed"
+	// StateMissing is an applied migration this build has no file for, as
+	// after rolling back to an older release.
+	StateMissing = "missing"
+)
+
+type Status struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+// Runner applies and reverts migrations against a store.
+type Runner struct {
+	store      Store
+	migrations []Migration
+}
+
+// NewRunner works with migrations, which must be in version order as Load
+// returns them.
+func NewRunner(store Store, migrations []Migration) *Runner {
+	return &Runner{store: store, migrations: migrations}
+}
+
+// Up applies every pending migration in version order and returns those
+// applied. A migration older than the newest applied one, as when branches
+// are merged, is still applied. Nothing is applied while an applied script
+// has changed.
+func (r *Runner) Up() ([]Migration, error) {
+	var done []Migration
+	err := r.store.Lock(func(session Session) error {
+		applied, err := r.applied(session)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			record, ok := applied[m.Version]
+			if ok && record.Checksum != m.Checksum() {
+				return fmt.Errorf("%s: %w", m, ErrChecksumMismatch)
+			}
+		}
+		for _, m := range r.migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err = session.Apply(m); err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", m, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the steps most recently applied migrations, newest first,
+// and returns those reverted.
+func (r *Runner) Down(steps int) ([]Migration, error) {
+	byVersion := make(map[int64]Migration, len(r.migrations))
+	for _, m := range r.migrations {
+		byVersion[m.Version] = m
+	}
+
+	var done []Migration
+	err := r.store.Lock(func(session Session) error {
+		applied, err := r.applied(session)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions[:min(steps, len(versions))] {
+			m, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is not in this build, so it cannot be reverted", version, applied[version].Name)
+			}
+			if applied[version].Checksum != m.Checksum() {
+				return fmt.Errorf("%s: %w", m, ErrChecksumMismatch)
+			}
+			if m.Down == "" {
+				return fmt.Errorf("%s: %w", m, ErrNoDownScript)
+			}
+			if err = session.Revert(m); err != nil {
+				return fmt.Errorf("failed to revert migration %s: %w", m, err)
+			}
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every migration known to the build or the database in
+// version order.
+func (r *Runner) Status() ([]Status, error) {
+	var statuses []Status
+	err := r.store.Lock(func(session Session) error {
+		applied, err := r.applied(session)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.migrations {
+			status := Status{Version: m.Version, Name: m.Name, State: StatePending}
+			if record, ok := applied[m.Version]; ok {
+				status.State = StateApplied
+				if record.Checksum != m.Checksum() {
+					status.State = StateChanged
+				}
+				status.AppliedAt = &record.AppliedAt
+				delete(applied, m.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, record := range applied {
+			statuses = append(statuses, Status{Version: record.Version, Name: record.Name, State: StateMissing, AppliedAt: &record.AppliedAt})
+		}
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, err
+}
+
+func (r *Runner) applied(session Session) (map[int64]Record, error) {
+	records, err := session.Applied()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	applied := make(map[int64]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
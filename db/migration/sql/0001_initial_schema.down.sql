@@ -0,0 +1,19 @@
+-- Drops every table jod-jod has, and all data in them.
+DROP TABLE IF EXISTS "accounts";
+DROP TABLE IF EXISTS "import_matches";
+DROP TABLE IF EXISTS "import_batches";
+DROP TABLE IF EXISTS "inbound_emails";
+DROP TABLE IF EXISTS "inbox_tokens";
+DROP TABLE IF EXISTS "transaction_items";
+DROP TABLE IF EXISTS "attachments";
+DROP TABLE IF EXISTS "slip_batches";
+DROP TABLE IF EXISTS "slip_jobs";
+DROP TABLE IF EXISTS "digest_deliveries";
+DROP TABLE IF EXISTS "budgets";
+DROP TABLE IF EXISTS "net_worth_snapshots";
+DROP TABLE IF EXISTS "net_worth_items";
+DROP TABLE IF EXISTS "detected_subscriptions";
+DROP TABLE IF EXISTS "transaction_anomalies";
+DROP TABLE IF EXISTS "recurring_rules";
+DROP TABLE IF EXISTS "transactions";
+DROP TABLE IF EXISTS "users";
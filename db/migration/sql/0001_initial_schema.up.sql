@@ -0,0 +1,322 @@
+-- The schema as gorm's AutoMigrate left it, so a database it created can
+-- take this migration as applied: every statement is skipped when what it
+-- creates already exists.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "firstname" varchar NOT NULL,
+    "lastname" varchar NOT NULL,
+    "email" varchar NOT NULL,
+    "username" varchar NOT NULL,
+    "password" varchar NOT NULL,
+    "timezone" varchar(50) DEFAULT 'Asia/Bangkok',
+    "language" varchar(5) DEFAULT 'th',
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_users_email" UNIQUE ("email"),
+    CONSTRAINT "uni_users_username" UNIQUE ("username")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "transactions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "date" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "amount" decimal(10,2) DEFAULT 0,
+    "category" varchar(50) DEFAULT 'other',
+    "transaction_type" varchar(20) NOT NULL,
+    "note" varchar(255),
+    "image_url" varchar(255),
+    "thumbnail_url" varchar(255),
+    "bank_code" varchar(20),
+    "trans_ref" varchar(50),
+    "image_hash" varchar(16),
+    "merchant" varchar(100),
+    "tax_id" varchar(13),
+    "vat" decimal(10,2) DEFAULT 0,
+    "import_batch_id" bigint,
+    "account_id" bigint,
+    "fit_id" varchar(255),
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_transaction_account_fit_id" ON "transactions" ("account_id","fit_id");
+CREATE INDEX IF NOT EXISTS "idx_transaction_import_batch" ON "transactions" ("import_batch_id");
+CREATE INDEX IF NOT EXISTS "idx_transaction_trans_ref" ON "transactions" ("trans_ref");
+CREATE INDEX IF NOT EXISTS "idx_transactions_deleted_at" ON "transactions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "recurring_rules" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(100) NOT NULL,
+    "amount" decimal(10,2) DEFAULT 0,
+    "category" varchar(50) DEFAULT 'other',
+    "transaction_type" varchar(20) NOT NULL,
+    "frequency" varchar(20) NOT NULL,
+    "interval" bigint DEFAULT 1,
+    "next_date" timestamp NOT NULL,
+    "end_date" timestamp,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_recurring_rules_deleted_at" ON "recurring_rules" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "transaction_anomalies" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "transaction_id" bigint NOT NULL,
+    "spender_id" bigint NOT NULL,
+    "kind" varchar(30) NOT NULL,
+    "category" varchar(50),
+    "month" varchar(7),
+    "amount" decimal(10,2) DEFAULT 0,
+    "baseline" decimal(10,2) DEFAULT 0,
+    "ratio" decimal(10,2) DEFAULT 0,
+    "reason" varchar(255),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_transaction_anomalies_deleted_at" ON "transaction_anomalies" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "detected_subscriptions" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "signature" varchar(255) NOT NULL,
+    "name" varchar(255),
+    "category" varchar(50),
+    "amount" decimal(10,2) DEFAULT 0,
+    "cadence" varchar(20) NOT NULL,
+    "occurrences" bigint DEFAULT 0,
+    "last_date" timestamp,
+    "next_date" timestamp,
+    "annual_cost" decimal(12,2) DEFAULT 0,
+    "status" varchar(20) DEFAULT 'detected',
+    "recurring_rule_id" bigint,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_detected_subscriptions_deleted_at" ON "detected_subscriptions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "net_worth_items" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(100) NOT NULL,
+    "kind" varchar(20) NOT NULL,
+    "value" decimal(14,2) DEFAULT 0,
+    "start_date" timestamp DEFAULT CURRENT_TIMESTAMP,
+    "end_date" timestamp,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_net_worth_items_deleted_at" ON "net_worth_items" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "net_worth_snapshots" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "date" date NOT NULL,
+    "cash_balance" decimal(14,2) DEFAULT 0,
+    "assets" decimal(14,2) DEFAULT 0,
+    "liabilities" decimal(14,2) DEFAULT 0,
+    "net_worth" decimal(14,2) DEFAULT 0,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_net_worth_spender_date" ON "net_worth_snapshots" ("date","spender_id");
+CREATE INDEX IF NOT EXISTS "idx_net_worth_snapshots_deleted_at" ON "net_worth_snapshots" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "budgets" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "category" varchar(50) NOT NULL,
+    "monthly_limit" decimal(10,2) DEFAULT 0,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_budget_spender_category" ON "budgets" ("category","spender_id");
+CREATE INDEX IF NOT EXISTS "idx_budgets_deleted_at" ON "budgets" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "digest_deliveries" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "month" varchar(7) NOT NULL,
+    "channel" varchar(20) NOT NULL,
+    "sent_at" timestamp NOT NULL,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_digest_delivery" ON "digest_deliveries" ("month","channel","spender_id");
+CREATE INDEX IF NOT EXISTS "idx_digest_deliveries_deleted_at" ON "digest_deliveries" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "slip_jobs" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "status" varchar(20) NOT NULL,
+    "filename" varchar(255),
+    "image" bytea,
+    "force" boolean DEFAULT false,
+    "draft" text,
+    "attempts" bigint DEFAULT 0,
+    "next_run_at" timestamp NOT NULL,
+    "locked_until" timestamp,
+    "last_error" varchar(255),
+    "transaction_id" bigint,
+    "duplicate_of" bigint,
+    "batch_id" bigint,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_slip_job_due" ON "slip_jobs" ("status","next_run_at");
+CREATE INDEX IF NOT EXISTS "idx_slip_jobs_deleted_at" ON "slip_jobs" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_slip_jobs_spender_id" ON "slip_jobs" ("spender_id");
+CREATE INDEX IF NOT EXISTS "idx_slip_job_batch" ON "slip_jobs" ("batch_id");
+
+CREATE TABLE IF NOT EXISTS "slip_batches" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "file_count" bigint NOT NULL,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_slip_batches_spender_id" ON "slip_batches" ("spender_id");
+CREATE INDEX IF NOT EXISTS "idx_slip_batches_deleted_at" ON "slip_batches" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "attachments" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "transaction_id" bigint NOT NULL,
+    "filename" varchar(255) NOT NULL,
+    "content_type" varchar(100) NOT NULL,
+    "size" bigint NOT NULL,
+    "blob_key" varchar(255) NOT NULL,
+    "scan_status" varchar(20),
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_attachment_transaction" ON "attachments" ("transaction_id");
+CREATE INDEX IF NOT EXISTS "idx_attachments_deleted_at" ON "attachments" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "transaction_items" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "transaction_id" bigint NOT NULL,
+    "name" varchar(255),
+    "quantity" decimal(10,3) DEFAULT 1,
+    "unit_price" decimal(10,2) DEFAULT 0,
+    "amount" decimal(10,2) DEFAULT 0,
+    "category" varchar(50) DEFAULT 'other',
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_transaction_item_transaction" ON "transaction_items" ("transaction_id");
+CREATE INDEX IF NOT EXISTS "idx_transaction_items_deleted_at" ON "transaction_items" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "inbox_tokens" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "token" varchar(32) NOT NULL,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_inbox_tokens_deleted_at" ON "inbox_tokens" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_inbox_tokens_spender_id" ON "inbox_tokens" ("spender_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_inbox_tokens_token" ON "inbox_tokens" ("token");
+
+CREATE TABLE IF NOT EXISTS "inbound_emails" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "message_id" varchar(255),
+    "sender" varchar(255),
+    "subject" varchar(255),
+    "received_at" timestamp NOT NULL,
+    "blob_key" varchar(255) NOT NULL,
+    "size" bigint NOT NULL,
+    "template" varchar(50),
+    "status" varchar(20) NOT NULL,
+    "draft" text,
+    "transaction_id" bigint,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_inbound_email_message" ON "inbound_emails" ("message_id","spender_id");
+CREATE INDEX IF NOT EXISTS "idx_inbound_emails_deleted_at" ON "inbound_emails" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "import_batches" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "filename" varchar(255),
+    "format" varchar(20) NOT NULL,
+    "row_count" bigint NOT NULL,
+    "skipped_count" bigint DEFAULT 0,
+    "duplicate_count" bigint DEFAULT 0,
+    "matched_count" bigint DEFAULT 0,
+    "account_id" bigint,
+    "first_date" timestamp,
+    "last_date" timestamp,
+    "status" varchar(20) NOT NULL,
+    "rolled_back_at" timestamp,
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_batches_spender_id" ON "import_batches" ("spender_id");
+CREATE INDEX IF NOT EXISTS "idx_import_batches_deleted_at" ON "import_batches" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "import_matches" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "import_batch_id" bigint NOT NULL,
+    "transaction_id" bigint NOT NULL,
+    "fit_id" varchar(255),
+    "account_set" boolean DEFAULT false,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_matches_import_batch_id" ON "import_matches" ("import_batch_id");
+CREATE INDEX IF NOT EXISTS "idx_import_matches_deleted_at" ON "import_matches" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "accounts" (
+    "id" bigserial,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "name" varchar(100) NOT NULL,
+    "kind" varchar(20) NOT NULL,
+    "bank_code" varchar(20),
+    "number" varchar(50),
+    "currency" varchar(3) DEFAULT 'THB',
+    "spender_id" bigint NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_accounts_spender_id" ON "accounts" ("spender_id");
+CREATE INDEX IF NOT EXISTS "idx_accounts_deleted_at" ON "accounts" ("deleted_at");
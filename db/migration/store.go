@@ -0,0 +1,93 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// lockKey is the advisory lock migrations take; any number works so long as
+// nothing else in the database uses it.
+const lockKey int64 = 7_204_519_883
+
+// Store is where migrations are applied and recorded.
+type Store interface {
+	// Lock runs fn while holding a lock that other replicas wait for, with a
+	// session bound to the connection holding it.
+	Lock(fn func(Session) error) error
+}
+
+type Session interface {
+	// Applied returns the recorded migrations, creating the schema table
+	// first if need be.
+	Applied() ([]Record, error)
+	// Apply runs the up script and records it, in one transaction unless
+	// the migration asks not to be.
+	Apply(m Migration) error
+	// Revert runs the down script and forgets the migration.
+	Revert(m Migration) error
+}
+
+type postgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) Store {
+	return &postgresStore{db: db}
+}
+
+// Lock holds a session-level advisory lock on one pooled connection, since
+// the lock belongs to the connection that took it.
+func (s *postgresStore) Lock(fn func(Session) error) error {
+	return s.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+		return fn(&postgresSession{conn: conn})
+	})
+}
+
+type postgresSession struct {
+	conn *gorm.DB
+}
+
+func (s *postgresSession) Applied() ([]Record, error) {
+	err := s.conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version bigint PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    checksum varchar(64) NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now()
+)`).Error
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	err = s.conn.Table("schema_migrations").Order("version").Find(&records).Error
+	return records, err
+}
+
+func (s *postgresSession) Apply(m Migration) error {
+	return s.run(m.NoTransaction, func(tx *gorm.DB) error {
+		if err := tx.Exec(m.Up).Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+			m.Version, m.Name, m.Checksum(), time.Now()).Error
+	})
+}
+
+func (s *postgresSession) Revert(m Migration) error {
+	return s.run(m.NoTransaction || isNoTransaction(m.Down), func(tx *gorm.DB) error {
+		if err := tx.Exec(m.Down).Error; err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version).Error
+	})
+}
+
+func (s *postgresSession) run(noTransaction bool, fn func(tx *gorm.DB) error) error {
+	if noTransaction {
+		return fn(s.conn)
+	}
+	return s.conn.Transaction(fn)
+}
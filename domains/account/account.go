@@ -0,0 +1,29 @@
+package account
+
+import "time"
+
+const (
+	KindBank       = "bank"
+	KindCreditCard = "credit_card"
+	KindCash       = "cash"
+
+	DefaultCurrency = "THB"
+)
+
+type Account struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Kind     string `json:"kind" validate:"required,oneof=bank credit_card cash"`
+	BankCode string `json:"bank_code" validate:"max=20"`
+	Number   string `json:"number" validate:"max=50"`
+	Currency string `json:"currency" validate:"omitempty,len=3"`
+}
+
+type GetAccountResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	BankCode  string    `json:"bank_code"`
+	Number    string    `json:"number"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
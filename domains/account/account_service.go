@@ -0,0 +1,111 @@
+package account
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/account_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"strings"
+)
+
+type IAccountService interface {
+	GetAccounts(spenderId uint) ([]GetAccountResponse, error)
+	GetAccount(spenderId, accountId uint) (*GetAccountResponse, error)
+	CreateAccount(spenderId uint, req Account) (uint, error)
+	DeleteAccount(spenderId, accountId uint) error
+}
+
+type accountService struct {
+	accountRepository account_repository.IAccountRepository
+	logger            echo.Logger
+}
+
+func NewAccountService(accountRepository account_repository.IAccountRepository, logger echo.Logger) IAccountService {
+	return &accountService{
+		accountRepository: accountRepository,
+		logger:            logger,
+	}
+}
+
+func (s *accountService) GetAccounts(spenderId uint) ([]GetAccountResponse, error) {
+	results, err := s.accountRepository.GetAccounts(spenderId)
+	if err != nil {
+		return nil, errors.New("failed to get accounts")
+	}
+
+	newResults := make([]GetAccountResponse, 0, len(results))
+	for _, value := range results {
+		newResults = append(newResults, accountResponse(value))
+	}
+	return newResults, nil
+}
+
+func (s *accountService) GetAccount(spenderId, accountId uint) (*GetAccountResponse, error) {
+	result, err := s.getAccount(spenderId, accountId)
+	if err != nil {
+		return nil, err
+	}
+	response := accountResponse(*result)
+	return &response, nil
+}
+
+func (s *accountService) CreateAccount(spenderId uint, req Account) (uint, error) {
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	result, err := s.accountRepository.SaveAccount(entities.Account{
+		Name:      strings.TrimSpace(req.Name),
+		Kind:      req.Kind,
+		BankCode:  req.BankCode,
+		Number:    strings.TrimSpace(req.Number),
+		Currency:  currency,
+		SpenderId: int(spenderId),
+	})
+	if err != nil {
+		return 0, errors.New("failed to save account")
+	}
+	s.logger.Infof("saved account with ID: %d success", result)
+	return result, nil
+}
+
+// DeleteAccount removes an account. Its transactions stay, off any account.
+func (s *accountService) DeleteAccount(spenderId, accountId uint) error {
+	if _, err := s.getAccount(spenderId, accountId); err != nil {
+		return err
+	}
+	if err := s.accountRepository.DeleteAccount(accountId); err != nil {
+		return errors.New("failed to delete account")
+	}
+	s.logger.Infof("delete account with account id: %d success", accountId)
+	return nil
+}
+
+// getAccount loads one of the spender's accounts. Someone else's is reported
+// as missing so IDs cannot be probed.
+func (s *accountService) getAccount(spenderId, accountId uint) (*entities.Account, error) {
+	result, err := s.accountRepository.GetAccount(accountId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get account")
+	}
+	if uint(result.SpenderId) != spenderId {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return result, nil
+}
+
+func accountResponse(value entities.Account) GetAccountResponse {
+	return GetAccountResponse{
+		ID:        value.ID,
+		Name:      value.Name,
+		Kind:      value.Kind,
+		BankCode:  value.BankCode,
+		Number:    value.Number,
+		Currency:  value.Currency,
+		CreatedAt: value.CreatedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package account
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestAccountService_CreateAccount_DefaultCurrency(t *testing.T) {
+	mockRepo := new(mocks.AccountRepositoryMock)
+	mockRepo.On("SaveAccount", mock.MatchedBy(func(req entities.Account) bool {
+		return req.Currency == DefaultCurrency && req.SpenderId == 1 && req.Number == "1234567890"
+	})).Return(uint(3), nil)
+	service := NewAccountService(mockRepo, echo.New().Logger)
+
+	result, err := service.CreateAccount(1, Account{Name: "KBank savings", Kind: KindBank, Number: " 1234567890 "})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAccountService_GetAccount_NotOwner(t *testing.T) {
+	mockRepo := new(mocks.AccountRepositoryMock)
+	mockRepo.On("GetAccount", uint(3)).Return(&entities.Account{SpenderId: 2}, nil)
+	service := NewAccountService(mockRepo, echo.New().Logger)
+
+	_, err := service.GetAccount(1, 3)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestAccountService_DeleteAccount_NotOwner(t *testing.T) {
+	mockRepo := new(mocks.AccountRepositoryMock)
+	mockRepo.On("GetAccount", uint(3)).Return(&entities.Account{SpenderId: 2}, nil)
+	service := NewAccountService(mockRepo, echo.New().Logger)
+
+	err := service.DeleteAccount(1, 3)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	mockRepo.AssertNotCalled(t, "DeleteAccount", mock.Anything)
+}
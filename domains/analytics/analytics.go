@@ -0,0 +1,36 @@
+package analytics
+
+import "errors"
+
+const (
+	ChartCategory = "category"
+	ChartMonthly  = "monthly"
+	ChartBalance  = "balance"
+
+	LanguageThai    = "th"
+	LanguageEnglish = "en"
+
+	DefaultMonths = 6
+)
+
+var ErrFutureMonth = errors.New("month has not started")
+
+// GetChartRequest picks a chart and how to draw it. Month defaults to the
+// current one; the monthly chart ends at it and covers Months months.
+type GetChartRequest struct {
+	SpenderId uint   `validate:"required"`
+	Chart     string `validate:"required,oneof=category monthly balance"`
+	Format    string `validate:"omitempty,oneof=svg png"`
+	Theme     string `validate:"omitempty,oneof=light dark"`
+	Width     int    `validate:"omitempty,min=200,max=2000"`
+	Height    int    `validate:"omitempty,min=150,max=2000"`
+	Month     string `validate:"omitempty,datetime=2006-01"`
+	Months    int    `validate:"omitempty,min=2,max=24"`
+	TxnType   string `validate:"omitempty,oneof=income expense"`
+	Language  string `validate:"omitempty,oneof=th en"`
+}
+
+type ChartFile struct {
+	ContentType string
+	Data        []byte
+}
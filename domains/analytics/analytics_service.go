@@ -0,0 +1,340 @@
+package analytics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/chart"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/digest_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimezone = "Asia/Bangkok"
+	// maxSlices is how many categories a pie shows before the smallest are
+	// put together.
+	maxSlices = 8
+)
+
+type IAnalyticsService interface {
+	GetChart(req GetChartRequest) (*ChartFile, error)
+}
+
+type analyticsService struct {
+	transactionRepository transaction_repository.ITransactionRepository
+	digestRepository      digest_repository.IDigestRepository
+	fonts                 [][]byte
+	logger                echo.Logger
+	now                   func() time.Time
+}
+
+// NewAnalyticsService draws charts with fonts, TrueType programs tried in
+// order for each character, so PNG charts can show Thai.
+func NewAnalyticsService(transactionRepository transaction_repository.ITransactionRepository, digestRepository digest_repository.IDigestRepository, fonts [][]byte, logger echo.Logger) IAnalyticsService {
+	return &analyticsService{
+		transactionRepository: transactionRepository,
+		digestRepository:      digestRepository,
+		fonts:                 fonts,
+		logger:                logger,
+		now:                   time.Now,
+	}
+}
+
+// GetChart draws one of the spender's charts in their timezone and, by
+// default, language.
+func (s *analyticsService) GetChart(req GetChartRequest) (*ChartFile, error) {
+	recipient, err := s.digestRepository.GetRecipient(req.SpenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get user")
+	}
+
+	loc := loadLocation(recipient.Timezone)
+	now := s.now().In(loc)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	if req.Month != "" {
+		month, err = time.ParseInLocation("2006-01", req.Month, loc)
+		if err != nil {
+			return nil, errors.New("month is invalid")
+		}
+	}
+	if month.After(now) {
+		return nil, ErrFutureMonth
+	}
+	language := req.Language
+	if language == "" {
+		language = recipient.Language
+	}
+	text := chartLabels[normalizeLanguage(language)]
+
+	history, err := s.transactionRepository.GetAllBySpenderId(req.SpenderId)
+	if err != nil {
+		return nil, errors.New("failed to get transaction")
+	}
+
+	var c chart.Chart
+	switch req.Chart {
+	case ChartCategory:
+		c = categoryChart(history, month, req.TxnType, text)
+	case ChartMonthly:
+		months := req.Months
+		if months == 0 {
+			months = DefaultMonths
+		}
+		c = monthlyChart(history, month, months, text)
+	case ChartBalance:
+		c = balanceChart(history, month, now, text)
+	default:
+		return nil, errors.New("chart is invalid")
+	}
+	c.Empty = text.empty
+
+	format := req.Format
+	if format == "" {
+		format = chart.FormatSVG
+	}
+	var buf bytes.Buffer
+	err = chart.Render(&buf, c, chart.Options{
+		Format: format,
+		Width:  req.Width,
+		Height: req.Height,
+		Theme:  req.Theme,
+		Fonts:  s.fonts,
+	})
+	if err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to render chart")
+	}
+	return &ChartFile{ContentType: chart.ContentType(format), Data: buf.Bytes()}, nil
+}
+
+// categoryChart is a pie of the month's expenses, or income, by category.
+func categoryChart(history []entities.GetAllResponse, month time.Time, txnType string, text labels) chart.Chart {
+	if txnType == "" {
+		txnType = "expense"
+	}
+	title := text.expenseByCategory
+	if txnType == "income" {
+		title = text.incomeByCategory
+	}
+	c := chart.Chart{Kind: chart.KindPie, Title: title + " · " + text.month(month)}
+
+	end := month.AddDate(0, 1, 0)
+	totals := make(map[string]float64)
+	for _, value := range history {
+		if value.Date == nil || !strings.EqualFold(value.TransactionType, txnType) {
+			continue
+		}
+		date := value.Date.In(month.Location())
+		if !date.Before(month) && date.Before(end) {
+			totals[value.Category] += value.Amount
+		}
+	}
+	categories := make([]string, 0, len(totals))
+	for category := range totals {
+		categories = append(categories, category)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if totals[categories[i]] != totals[categories[j]] {
+			return totals[categories[i]] > totals[categories[j]]
+		}
+		return categories[i] < categories[j]
+	})
+
+	var values []float64
+	for i, category := range categories {
+		if i == maxSlices-1 && len(categories) > maxSlices {
+			var rest float64
+			for _, other := range categories[i:] {
+				rest += totals[other]
+			}
+			c.Labels = append(c.Labels, text.other)
+			values = append(values, roundAmount(rest))
+			break
+		}
+		c.Labels = append(c.Labels, category)
+		values = append(values, roundAmount(totals[category]))
+	}
+	c.Series = []chart.Series{{Name: title, Values: values}}
+	return c
+}
+
+// monthlyChart puts income and expenses side by side for the months up to
+// and including month.
+func monthlyChart(history []entities.GetAllResponse, month time.Time, months int, text labels) chart.Chart {
+	first := month.AddDate(0, 1-months, 0)
+	income := make([]float64, months)
+	expense := make([]float64, months)
+	for _, value := range history {
+		if value.Date == nil {
+			continue
+		}
+		date := value.Date.In(month.Location())
+		i := (date.Year()-first.Year())*12 + int(date.Month()) - int(first.Month())
+		if i < 0 || i >= months {
+			continue
+		}
+		switch strings.ToLower(value.TransactionType) {
+		case "income":
+			income[i] += value.Amount
+		case "expense":
+			expense[i] += value.Amount
+		}
+	}
+
+	c := chart.Chart{Kind: chart.KindBar, Title: text.incomeAndExpenses}
+	// Years are shown only when the months run across one.
+	withYear := first.Year() != month.Year()
+	for i := 0; i < months; i++ {
+		c.Labels = append(c.Labels, text.shortMonth(first.AddDate(0, i, 0), withYear))
+		income[i], expense[i] = roundAmount(income[i]), roundAmount(expense[i])
+	}
+	c.Series = []chart.Series{{Name: text.income, Values: income}, {Name: text.expense, Values: expense}}
+	return c
+}
+
+// balanceChart follows the balance, income less expenses over all of the
+// spender's history, at the end of each day of the month up to today.
+func balanceChart(history []entities.GetAllResponse, month time.Time, now time.Time, text labels) chart.Chart {
+	end := month.AddDate(0, 1, 0)
+	days := end.AddDate(0, 0, -1).Day()
+	shown := days
+	if now.Before(end) {
+		shown = now.Day()
+	}
+
+	var opening float64
+	daily := make([]float64, days)
+	for _, value := range history {
+		if value.Date == nil {
+			continue
+		}
+		var signed float64
+		switch strings.ToLower(value.TransactionType) {
+		case "income":
+			signed = value.Amount
+		case "expense":
+			signed = -value.Amount
+		}
+		date := value.Date.In(month.Location())
+		switch {
+		case date.Before(month):
+			opening += signed
+		case date.Before(end):
+			daily[date.Day()-1] += signed
+		}
+	}
+
+	c := chart.Chart{Kind: chart.KindLine, Title: text.balance + " · " + text.month(month)}
+	balance := opening
+	values := make([]float64, shown)
+	for day := 0; day < days; day++ {
+		c.Labels = append(c.Labels, strconv.Itoa(day+1))
+		balance += daily[day]
+		if day < shown {
+			values[day] = roundAmount(balance)
+		}
+	}
+	c.Series = []chart.Series{{Name: text.balance, Values: values}}
+	return c
+}
+
+type labels struct {
+	language          string
+	expenseByCategory string
+	incomeByCategory  string
+	incomeAndExpenses string
+	income            string
+	expense           string
+	balance           string
+	other             string
+	empty             string
+}
+
+var chartLabels = map[string]labels{
+	LanguageEnglish: {
+		language:          LanguageEnglish,
+		expenseByCategory: "Expenses by category",
+		incomeByCategory:  "Income by category",
+		incomeAndExpenses: "Income and expenses",
+		income:            "Income",
+		expense:           "Expenses",
+		balance:           "Balance",
+		other:             "Other",
+		empty:             "No transactions",
+	},
+	LanguageThai: {
+		language:          LanguageThai,
+		expenseByCategory: "รายจ่ายตามหมวดหมู่",
+		incomeByCategory:  "รายรับตามหมวดหมู่",
+		incomeAndExpenses: "รายรับและรายจ่าย",
+		income:            "รายรับ",
+		expense:           "รายจ่าย",
+		balance:           "ยอดคงเหลือ",
+		other:             "อื่น ๆ",
+		empty:             "ไม่มีรายการ",
+	},
+}
+
+var thaiMonths = [...]string{
+	"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+	"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม",
+}
+
+var thaiShortMonths = [...]string{
+	"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.",
+	"ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค.",
+}
+
+func (l labels) month(t time.Time) string {
+	if l.language == LanguageThai {
+		return fmt.Sprintf("%s %d", thaiMonths[t.Month()-1], t.Year()+543)
+	}
+	return t.Format("January 2006")
+}
+
+func (l labels) shortMonth(t time.Time, withYear bool) string {
+	if l.language == LanguageThai {
+		if withYear {
+			return fmt.Sprintf("%s %02d", thaiShortMonths[t.Month()-1], (t.Year()+543)%100)
+		}
+		return thaiShortMonths[t.Month()-1]
+	}
+	if withYear {
+		return t.Format("Jan 06")
+	}
+	return t.Format("Jan")
+}
+
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		name = defaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// Bangkok has no DST, so a fixed offset is exact if tzdata is missing.
+		return time.FixedZone(defaultTimezone, 7*60*60)
+	}
+	return loc
+}
+
+func normalizeLanguage(language string) string {
+	if language == LanguageEnglish {
+		return LanguageEnglish
+	}
+	return LanguageThai
+}
+
+func roundAmount(value float64) float64 {
+	return math.Round(value*100) / 100
+}
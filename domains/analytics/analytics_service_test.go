@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"bytes"
+	"github.com/Montheankul-K/jod-jod/chart"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"image/png"
+	"testing"
+	"time"
+)
+
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+func analyticsTxn(id uint, date time.Time, amount float64, category, txnType string) entities.GetAllResponse {
+	return entities.GetAllResponse{ID: id, Date: &date, Amount: amount, Category: category, TransactionType: txnType}
+}
+
+func analyticsHistory() []entities.GetAllResponse {
+	return []entities.GetAllResponse{
+		analyticsTxn(1, time.Date(2023, 12, 25, 12, 0, 0, 0, bangkok), 5000, "salary", "income"),
+		analyticsTxn(2, time.Date(2024, 1, 26, 12, 0, 0, 0, bangkok), 1000, "food", "expense"),
+		analyticsTxn(3, time.Date(2024, 3, 1, 8, 0, 0, 0, bangkok), 30000, "salary", "income"),
+		analyticsTxn(4, time.Date(2024, 3, 10, 9, 0, 0, 0, bangkok), 700, "food", "expense"),
+		analyticsTxn(5, time.Date(2024, 3, 10, 9, 0, 0, 0, bangkok), 300, "transport", "expense"),
+		analyticsTxn(6, time.Date(2024, 3, 20, 19, 0, 0, 0, bangkok), 900, "food", "expense"),
+		// 23:30 on 31 March in Bangkok is still March even though it is
+		// already 1 April in UTC+9.
+		analyticsTxn(7, time.Date(2024, 4, 1, 1, 30, 0, 0, time.FixedZone("JST", 9*60*60)), 100, "shopping", "expense"),
+		analyticsTxn(8, time.Date(2024, 4, 2, 12, 0, 0, 0, bangkok), 9999, "food", "expense"),
+	}
+}
+
+func TestCategoryChart(t *testing.T) {
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)
+
+	c := categoryChart(analyticsHistory(), month, "", chartLabels[LanguageEnglish])
+
+	assert.Equal(t, chart.KindPie, c.Kind)
+	assert.Equal(t, "Expenses by category · March 2024", c.Title)
+	assert.Equal(t, []string{"food", "transport", "shopping"}, c.Labels)
+	assert.Equal(t, []float64{1600, 300, 100}, c.Series[0].Values)
+
+	c = categoryChart(analyticsHistory(), month, "income", chartLabels[LanguageThai])
+	assert.Equal(t, "รายรับตามหมวดหมู่ · มีนาคม 2567", c.Title)
+	assert.Equal(t, []string{"salary"}, c.Labels)
+}
+
+func TestCategoryChart_Other(t *testing.T) {
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)
+	var history []entities.GetAllResponse
+	for i, category := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		history = append(history, analyticsTxn(uint(i+1), month.AddDate(0, 0, i), float64(100-i), category, "expense"))
+	}
+
+	c := categoryChart(history, month, "expense", chartLabels[LanguageEnglish])
+
+	assert.Equal(t, []string{"a", "b", "c", "d", "e", "f", "g", "Other"}, c.Labels)
+	assert.Equal(t, float64(93+92+91), c.Series[0].Values[7])
+}
+
+func TestMonthlyChart(t *testing.T) {
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)
+
+	c := monthlyChart(analyticsHistory(), month, 4, chartLabels[LanguageEnglish])
+
+	assert.Equal(t, chart.KindBar, c.Kind)
+	assert.Equal(t, []string{"Dec 23", "Jan 24", "Feb 24", "Mar 24"}, c.Labels)
+	assert.Equal(t, []float64{5000, 0, 0, 30000}, c.Series[0].Values)
+	assert.Equal(t, []float64{0, 1000, 0, 2000}, c.Series[1].Values)
+
+	c = monthlyChart(analyticsHistory(), month, 3, chartLabels[LanguageThai])
+	assert.Equal(t, []string{"ม.ค.", "ก.พ.", "มี.ค."}, c.Labels)
+}
+
+func TestBalanceChart(t *testing.T) {
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)
+
+	c := balanceChart(analyticsHistory(), month, time.Date(2024, 5, 1, 0, 0, 0, 0, bangkok), chartLabels[LanguageEnglish])
+	assert.Equal(t, 31, len(c.Labels))
+	values := c.Series[0].Values
+	assert.Equal(t, 31, len(values))
+	assert.Equal(t, 34000.0, values[0])
+	assert.Equal(t, 33000.0, values[9])
+	assert.Equal(t, 32000.0, values[30])
+
+	// A month in progress stops at today.
+	c = balanceChart(analyticsHistory(), month, time.Date(2024, 3, 12, 8, 0, 0, 0, bangkok), chartLabels[LanguageEnglish])
+	assert.Equal(t, 31, len(c.Labels))
+	assert.Equal(t, 12, len(c.Series[0].Values))
+}
+
+func newTestService(now time.Time) (*analyticsService, *mocks.TransactionRepositoryMock, *mocks.DigestRepositoryMock) {
+	transactionRepository := new(mocks.TransactionRepositoryMock)
+	digestRepository := new(mocks.DigestRepositoryMock)
+	service := NewAnalyticsService(transactionRepository, digestRepository, nil, echo.New().Logger).(*analyticsService)
+	service.now = func() time.Time { return now }
+	return service, transactionRepository, digestRepository
+}
+
+func TestAnalyticsService_GetChart(t *testing.T) {
+	service, transactionRepository, digestRepository := newTestService(time.Date(2024, 3, 15, 12, 0, 0, 0, bangkok))
+	digestRepository.On("GetRecipient", uint(1)).Return(&entities.DigestRecipient{ID: 1, Language: LanguageEnglish}, nil)
+	transactionRepository.On("GetAllBySpenderId", uint(1)).Return(analyticsHistory(), nil)
+
+	file, err := service.GetChart(GetChartRequest{SpenderId: 1, Chart: ChartCategory})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", file.ContentType)
+	assert.Contains(t, string(file.Data), ">Expenses by category · March 2024</text>")
+
+	file, err = service.GetChart(GetChartRequest{SpenderId: 1, Chart: ChartMonthly, Format: chart.FormatPNG, Width: 320, Height: 200, Theme: chart.ThemeDark})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", file.ContentType)
+	img, err := png.Decode(bytes.NewReader(file.Data))
+	assert.NoError(t, err)
+	assert.Equal(t, 320, img.Bounds().Dx())
+	assert.Equal(t, 200, img.Bounds().Dy())
+
+	file, err = service.GetChart(GetChartRequest{SpenderId: 1, Chart: ChartBalance, Month: "2023-11", Language: LanguageThai})
+	assert.NoError(t, err)
+	assert.Contains(t, string(file.Data), ">ไม่มีรายการ</text>")
+}
+
+func TestAnalyticsService_GetChart_FutureMonth(t *testing.T) {
+	service, _, digestRepository := newTestService(time.Date(2024, 3, 31, 23, 0, 0, 0, bangkok))
+	digestRepository.On("GetRecipient", uint(1)).Return(&entities.DigestRecipient{ID: 1}, nil)
+
+	_, err := service.GetChart(GetChartRequest{SpenderId: 1, Chart: ChartCategory, Month: "2024-04"})
+	assert.ErrorIs(t, err, ErrFutureMonth)
+}
+
+func TestAnalyticsService_GetChart_RecordNotFound(t *testing.T) {
+	service, _, digestRepository := newTestService(time.Date(2024, 3, 15, 12, 0, 0, 0, bangkok))
+	digestRepository.On("GetRecipient", uint(1)).Return((*entities.DigestRecipient)(nil), gorm.ErrRecordNotFound)
+
+	_, err := service.GetChart(GetChartRequest{SpenderId: 1, Chart: ChartCategory})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
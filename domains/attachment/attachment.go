@@ -0,0 +1,41 @@
+package attachment
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	DefaultMaxSize           = 10 << 20
+	DefaultMaxPerTransaction = 10
+
+	ScanClean   = "clean"
+	ScanSkipped = "skipped"
+)
+
+var (
+	ErrTooLarge        = errors.New("attachment is too large")
+	ErrTooMany         = errors.New("transaction has too many attachments")
+	ErrUnsupportedType = errors.New("attachment type is not supported")
+	ErrInvalidPDF      = errors.New("pdf is damaged")
+)
+
+type GetAttachmentResponse struct {
+	ID            uint      `json:"id"`
+	TransactionId uint      `json:"transaction_id"`
+	Filename      string    `json:"filename"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	ScanStatus    string    `json:"scan_status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AttachmentFile is either a URL to redirect to or content to stream; the
+// caller closes Body.
+type AttachmentFile struct {
+	URL         string
+	Body        io.ReadCloser
+	ContentType string
+	Filename    string
+}
@@ -0,0 +1,289 @@
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/imageproc"
+	"github.com/Montheankul-K/jod-jod/repository/attachment_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/Montheankul-K/jod-jod/virusscan"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// fileURLExpiry is how long a presigned attachment URL stays valid.
+const fileURLExpiry = 5 * time.Minute
+
+// allowedTypes maps the sniffed types that may be attached to the extension
+// they are stored with.
+var allowedTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type IAttachmentService interface {
+	Upload(spenderId, txnId uint, filename string, data []byte) (*GetAttachmentResponse, error)
+	GetAttachments(spenderId, txnId uint) ([]GetAttachmentResponse, error)
+	GetFile(spenderId, txnId, attachmentId uint) (*AttachmentFile, error)
+	Delete(spenderId, txnId, attachmentId uint) error
+	MaxSize() int64
+}
+
+type attachmentService struct {
+	attachmentRepository  attachment_repository.IAttachmentRepository
+	transactionRepository transaction_repository.ITransactionRepository
+	blobStore             blobstore.BlobStore
+	scanner               virusscan.Scanner
+	maxSize               int64
+	maxPerTransaction     int
+	logger                echo.Logger
+}
+
+func NewAttachmentService(cfg *config.Attachment, attachmentRepository attachment_repository.IAttachmentRepository, transactionRepository transaction_repository.ITransactionRepository, blobStore blobstore.BlobStore, scanner virusscan.Scanner, logger echo.Logger) IAttachmentService {
+	service := &attachmentService{
+		attachmentRepository:  attachmentRepository,
+		transactionRepository: transactionRepository,
+		blobStore:             blobStore,
+		scanner:               scanner,
+		maxSize:               DefaultMaxSize,
+		maxPerTransaction:     DefaultMaxPerTransaction,
+		logger:                logger,
+	}
+	if cfg != nil && cfg.MaxSize > 0 {
+		service.maxSize = cfg.MaxSize
+	}
+	if cfg != nil && cfg.MaxPerTransaction > 0 {
+		service.maxPerTransaction = cfg.MaxPerTransaction
+	}
+	return service
+}
+
+func (s *attachmentService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Upload checks a file by its content rather than its name, scans it and
+// stores it against the transaction. Images are re-encoded so their EXIF
+// metadata is not kept.
+func (s *attachmentService) Upload(spenderId, txnId uint, filename string, data []byte) (*GetAttachmentResponse, error) {
+	if err := s.checkOwner(spenderId, txnId); err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return nil, ErrTooLarge
+	}
+
+	existing, err := s.attachmentRepository.GetByTransactionId(txnId)
+	if err != nil {
+		return nil, errors.New("failed to get attachments")
+	}
+	if len(existing) >= s.maxPerTransaction {
+		return nil, ErrTooMany
+	}
+
+	contentType, ext, data, err := sniff(data)
+	if err != nil {
+		return nil, err
+	}
+
+	scanStatus := ScanClean
+	if s.scanner.Name() == virusscan.ScannerNone {
+		scanStatus = ScanSkipped
+	}
+	if err = s.scanner.Scan(data); err != nil {
+		var infected *virusscan.InfectedError
+		if errors.As(err, &infected) {
+			s.logger.Warnf("rejected attachment %q for transaction %d: %v", filename, txnId, err)
+			return nil, err
+		}
+		s.logger.Error(err)
+		return nil, errors.New("failed to scan attachment")
+	}
+
+	name := blobstore.SafeName(filename, "attachment")
+	name = strings.TrimSuffix(name, path.Ext(name)) + ext
+	key := fmt.Sprintf("attachments/%d/%d/%d_%s", spenderId, txnId, time.Now().UnixNano(), name)
+	if err = s.blobStore.Put(key, data, contentType); err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to upload attachment")
+	}
+
+	attachment := entities.Attachment{
+		TransactionId: txnId,
+		Filename:      name,
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		BlobKey:       key,
+		ScanStatus:    scanStatus,
+		SpenderId:     int(spenderId),
+	}
+	result, err := s.attachmentRepository.SaveAttachment(attachment)
+	if err != nil {
+		s.deleteBlob(key)
+		return nil, errors.New("failed to save attachment")
+	}
+	s.logger.Infof("saved attachment with ID: %d success", result)
+
+	attachment.ID = result
+	attachment.CreatedAt = time.Now()
+	response := attachmentResponse(attachment)
+	return &response, nil
+}
+
+func (s *attachmentService) GetAttachments(spenderId, txnId uint) ([]GetAttachmentResponse, error) {
+	if err := s.checkOwner(spenderId, txnId); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.attachmentRepository.GetByTransactionId(txnId)
+	if err != nil {
+		return nil, errors.New("failed to get attachments")
+	}
+
+	result := make([]GetAttachmentResponse, 0, len(attachments))
+	for _, value := range attachments {
+		result = append(result, attachmentResponse(value))
+	}
+	return result, nil
+}
+
+func (s *attachmentService) GetFile(spenderId, txnId, attachmentId uint) (*AttachmentFile, error) {
+	attachment, err := s.getAttachment(spenderId, txnId, attachmentId)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.blobStore.Presign(attachment.BlobKey, fileURLExpiry)
+	if err == nil {
+		return &AttachmentFile{URL: url}, nil
+	}
+	if !errors.Is(err, blobstore.ErrPresignNotSupported) {
+		s.logger.Error(err)
+		return nil, errors.New("failed to presign attachment")
+	}
+
+	body, err := s.blobStore.Get(attachment.BlobKey)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		s.logger.Error(err)
+		return nil, errors.New("failed to get attachment")
+	}
+	return &AttachmentFile{
+		Body:        body,
+		ContentType: attachment.ContentType,
+		Filename:    attachment.Filename,
+	}, nil
+}
+
+func (s *attachmentService) Delete(spenderId, txnId, attachmentId uint) error {
+	attachment, err := s.getAttachment(spenderId, txnId, attachmentId)
+	if err != nil {
+		return err
+	}
+
+	if err = s.attachmentRepository.DeleteAttachment(txnId, attachmentId); err != nil {
+		return errors.New("failed to delete attachment")
+	}
+	s.deleteBlob(attachment.BlobKey)
+	s.logger.Infof("delete attachment with attachment id: %d success", attachmentId)
+	return nil
+}
+
+// checkOwner reports someone else's transaction as not found, so IDs cannot
+// be probed.
+func (s *attachmentService) checkOwner(spenderId, txnId uint) error {
+	txn, err := s.transactionRepository.GetTxn(txnId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to get transaction")
+	}
+	if uint(txn.SpenderId) != spenderId {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *attachmentService) getAttachment(spenderId, txnId, attachmentId uint) (*entities.Attachment, error) {
+	if err := s.checkOwner(spenderId, txnId); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.attachmentRepository.GetAttachment(attachmentId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get attachment")
+	}
+	if attachment.TransactionId != txnId {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return attachment, nil
+}
+
+// deleteBlob only logs a failure: the row is what users see, and an orphaned
+// blob costs storage but nothing else.
+func (s *attachmentService) deleteBlob(key string) {
+	if err := s.blobStore.Delete(key); err != nil {
+		s.logger.Errorf("failed to delete blob %s: %v", key, err)
+	}
+}
+
+// sniff works out a file's type from its first bytes and returns the bytes
+// to store, which for a photo is a copy without metadata.
+func sniff(data []byte) (string, string, []byte, error) {
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return "", "", nil, ErrUnsupportedType
+	}
+
+	switch contentType {
+	case "application/pdf":
+		if !isPDF(data) {
+			return "", "", nil, ErrInvalidPDF
+		}
+	case "image/jpeg", "image/webp":
+		if processed, err := imageproc.Process(data); err == nil {
+			return processed.ContentType, allowedTypes[processed.ContentType], processed.Image, nil
+		}
+	}
+	return contentType, ext, data, nil
+}
+
+// isPDF checks for a PDF header and an end-of-file marker near the end,
+// which a truncated upload lacks.
+func isPDF(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return false
+	}
+	tail := data[max(0, len(data)-1024):]
+	return bytes.Contains(tail, []byte("%%EOF"))
+}
+
+func attachmentResponse(value entities.Attachment) GetAttachmentResponse {
+	return GetAttachmentResponse{
+		ID:            value.ID,
+		TransactionId: value.TransactionId,
+		Filename:      value.Filename,
+		ContentType:   value.ContentType,
+		Size:          value.Size,
+		ScanStatus:    value.ScanStatus,
+		CreatedAt:     value.CreatedAt,
+	}
+}
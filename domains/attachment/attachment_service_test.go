@@ -0,0 +1,175 @@
+package attachment
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/Montheankul-K/jod-jod/virusscan"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"testing"
+)
+
+var pdf = []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\ntrailer\n<<>>\n%%EOF\n")
+
+type scannerStub struct {
+	err error
+}
+
+func (scannerStub) Name() string {
+	return virusscan.ScannerClamAV
+}
+
+func (s scannerStub) Scan(data []byte) error {
+	return s.err
+}
+
+func newTestService(t *testing.T, attachmentRepo *mocks.AttachmentRepositoryMock, txnRepo *mocks.TransactionRepositoryMock, scanner virusscan.Scanner) (IAttachmentService, blobstore.BlobStore) {
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+	txnRepo.On("GetTxn", uint(5)).Return(&entities.Transaction{SpenderId: 1}, nil)
+	cfg := &config.Attachment{MaxSize: 1024, MaxPerTransaction: 2}
+	return NewAttachmentService(cfg, attachmentRepo, txnRepo, store, scanner, echo.New().Logger), store
+}
+
+func TestAttachmentService_Upload_PDF(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	attachmentRepo.On("GetByTransactionId", uint(5)).Return([]entities.Attachment{}, nil)
+	var saved entities.Attachment
+	attachmentRepo.On("SaveAttachment", mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(0).(entities.Attachment)
+	}).Return(uint(9), nil)
+	service, store := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+
+	result, err := service.Upload(1, 5, "../receipt.PDF", pdf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), result.ID)
+	assert.Equal(t, "application/pdf", result.ContentType)
+	assert.Equal(t, ScanClean, result.ScanStatus)
+	assert.True(t, strings.HasPrefix(saved.BlobKey, "attachments/1/5/"))
+	assert.True(t, strings.HasSuffix(saved.BlobKey, "_receipt.pdf"))
+	body, err := store.Get(saved.BlobKey)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, pdf, data)
+}
+
+func TestAttachmentService_Upload_OtherSpender(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	service, _ := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+
+	_, err := service.Upload(2, 5, "receipt.pdf", pdf)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestAttachmentService_Upload_TooLarge(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	service, _ := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+
+	_, err := service.Upload(1, 5, "receipt.pdf", make([]byte, 2048))
+
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestAttachmentService_Upload_TooMany(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	attachmentRepo.On("GetByTransactionId", uint(5)).Return([]entities.Attachment{{}, {}}, nil)
+	service, _ := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+
+	_, err := service.Upload(1, 5, "receipt.pdf", pdf)
+
+	assert.ErrorIs(t, err, ErrTooMany)
+}
+
+func TestAttachmentService_Upload_UnsupportedType(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	attachmentRepo.On("GetByTransactionId", uint(5)).Return([]entities.Attachment{}, nil)
+	service, _ := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+
+	// A script renamed to .pdf is caught by its content.
+	_, err := service.Upload(1, 5, "receipt.pdf", []byte("#!/bin/sh\nrm -rf /\n"))
+
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestAttachmentService_Upload_TruncatedPDF(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	attachmentRepo.On("GetByTransactionId", uint(5)).Return([]entities.Attachment{}, nil)
+	service, _ := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+
+	_, err := service.Upload(1, 5, "receipt.pdf", pdf[:20])
+
+	assert.ErrorIs(t, err, ErrInvalidPDF)
+}
+
+func TestAttachmentService_Upload_Infected(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	attachmentRepo.On("GetByTransactionId", uint(5)).Return([]entities.Attachment{}, nil)
+	service, _ := newTestService(t, attachmentRepo, txnRepo, scannerStub{err: &virusscan.InfectedError{Signature: "Eicar"}})
+
+	_, err := service.Upload(1, 5, "receipt.pdf", pdf)
+
+	var infected *virusscan.InfectedError
+	assert.True(t, errors.As(err, &infected))
+	attachmentRepo.AssertNotCalled(t, "SaveAttachment", mock.Anything)
+}
+
+func TestAttachmentService_GetFile_Streamed(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	service, store := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+	assert.NoError(t, store.Put("attachments/1/5/a.pdf", pdf, "application/pdf"))
+	attachmentRepo.On("GetAttachment", uint(9)).Return(&entities.Attachment{
+		TransactionId: 5, Filename: "a.pdf", ContentType: "application/pdf", BlobKey: "attachments/1/5/a.pdf",
+	}, nil)
+
+	result, err := service.GetFile(1, 5, 9)
+
+	assert.NoError(t, err)
+	defer result.Body.Close()
+	assert.Equal(t, "application/pdf", result.ContentType)
+	assert.Equal(t, "a.pdf", result.Filename)
+}
+
+func TestAttachmentService_Delete_OtherTransaction(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	attachmentRepo.On("GetAttachment", uint(9)).Return(&entities.Attachment{TransactionId: 6}, nil)
+	service, _ := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+
+	err := service.Delete(1, 5, 9)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	attachmentRepo.AssertNotCalled(t, "DeleteAttachment", mock.Anything, mock.Anything)
+}
+
+func TestAttachmentService_Delete_Success(t *testing.T) {
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	txnRepo := new(mocks.TransactionRepositoryMock)
+	service, store := newTestService(t, attachmentRepo, txnRepo, scannerStub{})
+	assert.NoError(t, store.Put("attachments/1/5/a.pdf", pdf, "application/pdf"))
+	attachmentRepo.On("GetAttachment", uint(9)).Return(&entities.Attachment{TransactionId: 5, BlobKey: "attachments/1/5/a.pdf"}, nil)
+	attachmentRepo.On("DeleteAttachment", uint(5), uint(9)).Return(nil)
+
+	err := service.Delete(1, 5, 9)
+
+	assert.NoError(t, err)
+	_, err = store.Get("attachments/1/5/a.pdf")
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+}
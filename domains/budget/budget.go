@@ -0,0 +1,16 @@
+package budget
+
+import "gorm.io/gorm"
+
+type Budget struct {
+	gorm.Model
+	Category     string  `json:"category" validate:"required"`
+	MonthlyLimit float64 `json:"monthly_limit" validate:"required,gt=0"`
+	SpenderId    int     `json:"spender_id" validate:"required"`
+}
+
+type GetBudgetResponse struct {
+	ID           uint    `json:"id"`
+	Category     string  `json:"category"`
+	MonthlyLimit float64 `json:"monthly_limit"`
+}
@@ -0,0 +1,71 @@
+package budget
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/budget_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type IBudgetService interface {
+	GetBudgets(spenderId uint) ([]GetBudgetResponse, error)
+	SetBudget(req Budget) (uint, error)
+	DeleteBudget(spenderId, budgetId uint) error
+}
+
+type budgetService struct {
+	budgetRepository budget_repository.IBudgetRepository
+	logger           echo.Logger
+}
+
+func NewBudgetService(budgetRepository budget_repository.IBudgetRepository, logger echo.Logger) IBudgetService {
+	return &budgetService{
+		budgetRepository: budgetRepository,
+		logger:           logger,
+	}
+}
+
+func (s *budgetService) GetBudgets(spenderId uint) ([]GetBudgetResponse, error) {
+	results, err := s.budgetRepository.GetBySpenderId(spenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get budgets")
+	}
+
+	var newResults []GetBudgetResponse
+	for _, value := range results {
+		result := GetBudgetResponse{
+			ID:           value.ID,
+			Category:     value.Category,
+			MonthlyLimit: value.MonthlyLimit,
+		}
+		newResults = append(newResults, result)
+	}
+	return newResults, nil
+}
+
+func (s *budgetService) SetBudget(req Budget) (uint, error) {
+	budget := entities.Budget{
+		Category:     req.Category,
+		MonthlyLimit: req.MonthlyLimit,
+		SpenderId:    req.SpenderId,
+	}
+	result, err := s.budgetRepository.SaveBudget(budget)
+	if err != nil {
+		return 0, errors.New("failed to save budget")
+	}
+	s.logger.Infof("saved budget with ID: %d success", result)
+	return result, nil
+}
+
+func (s *budgetService) DeleteBudget(spenderId, budgetId uint) error {
+	err := s.budgetRepository.DeleteBudget(spenderId, budgetId)
+	if err != nil {
+		return errors.New("failed to delete budget")
+	}
+	s.logger.Infof("delete budget with budget id: %d success", budgetId)
+	return nil
+}
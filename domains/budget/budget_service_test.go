@@ -0,0 +1,65 @@
+package budget
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestBudgetService_GetBudgets_Success(t *testing.T) {
+	mockRepo := new(mocks.BudgetRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetBySpenderId", uint(1)).Return([]entities.Budget{
+		{Category: "food", MonthlyLimit: 6000},
+	}, nil)
+	service := NewBudgetService(mockRepo, logger)
+
+	result, err := service.GetBudgets(uint(1))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "food", result[0].Category)
+	assert.Equal(t, float64(6000), result[0].MonthlyLimit)
+}
+
+func TestBudgetService_GetBudgets_Error(t *testing.T) {
+	mockRepo := new(mocks.BudgetRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetBySpenderId", uint(1)).Return([]entities.Budget{}, errors.New("some error"))
+	service := NewBudgetService(mockRepo, logger)
+
+	_, err := service.GetBudgets(uint(1))
+
+	assert.EqualError(t, err, "failed to get budgets")
+}
+
+func TestBudgetService_SetBudget_Success(t *testing.T) {
+	mockRepo := new(mocks.BudgetRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("SaveBudget", mock.Anything).Return(uint(1), nil)
+	service := NewBudgetService(mockRepo, logger)
+
+	result, err := service.SetBudget(Budget{Category: "food", MonthlyLimit: 6000, SpenderId: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), result)
+}
+
+func TestBudgetService_DeleteBudget_Error(t *testing.T) {
+	mockRepo := new(mocks.BudgetRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("DeleteBudget", uint(1), uint(2)).Return(errors.New("some error"))
+	service := NewBudgetService(mockRepo, logger)
+
+	err := service.DeleteBudget(uint(1), uint(2))
+
+	assert.EqualError(t, err, "failed to delete budget")
+}
@@ -0,0 +1,67 @@
+package digest
+
+import "time"
+
+const (
+	LanguageThai    = "th"
+	LanguageEnglish = "en"
+)
+
+type GetDigestRequest struct {
+	SpenderId uint   `validate:"required"`
+	Month     string `validate:"omitempty,datetime=2006-01"`
+	Language  string `validate:"omitempty,oneof=th en"`
+}
+
+type CategoryTotal struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Share    float64 `json:"share"`
+}
+
+type ExpenseItem struct {
+	ID       uint      `json:"id"`
+	Date     time.Time `json:"date"`
+	Category string    `json:"category"`
+	Note     string    `json:"note"`
+	Amount   float64   `json:"amount"`
+}
+
+type BudgetOutcome struct {
+	Category  string  `json:"category"`
+	Limit     float64 `json:"limit"`
+	Spent     float64 `json:"spent"`
+	Remaining float64 `json:"remaining"`
+	Exceeded  bool    `json:"exceeded"`
+}
+
+type CategoryChange struct {
+	Category      string  `json:"category"`
+	Previous      float64 `json:"previous"`
+	Current       float64 `json:"current"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+type DigestReport struct {
+	SpenderId            uint             `json:"spender_id"`
+	Name                 string           `json:"name"`
+	Month                string           `json:"month"`
+	Language             string           `json:"language"`
+	TotalIncome          float64          `json:"total_income"`
+	TotalExpense         float64          `json:"total_expense"`
+	Net                  float64          `json:"net"`
+	PreviousIncome       float64          `json:"previous_income"`
+	PreviousExpense      float64          `json:"previous_expense"`
+	ExpenseChangePercent float64          `json:"expense_change_percent"`
+	TopCategories        []CategoryTotal  `json:"top_categories"`
+	BiggestExpenses      []ExpenseItem    `json:"biggest_expenses"`
+	Budgets              []BudgetOutcome  `json:"budgets"`
+	Changes              []CategoryChange `json:"changes"`
+}
+
+type GetDigestResponse struct {
+	Report  DigestReport `json:"report"`
+	Subject string       `json:"subject"`
+	Text    string       `json:"text"`
+}
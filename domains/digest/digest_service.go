@@ -0,0 +1,367 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/chart"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/notification"
+	"github.com/Montheankul-K/jod-jod/repository/budget_repository"
+	"github.com/Montheankul-K/jod-jod/repository/digest_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimezone = "Asia/Bangkok"
+	// sendHour is the local hour on the first of the month from which the
+	// previous month's digest is due.
+	sendHour = 8
+	// topCategoryLimit and biggestExpenseLimit cap the lists in a digest.
+	topCategoryLimit    = 5
+	biggestExpenseLimit = 3
+	changeLimit         = 3
+	// A category change is notable when it moves by at least this share of the
+	// previous month and by at least changeMinAmount baht.
+	changeMinPercent = 25.0
+	changeMinAmount  = 500.0
+)
+
+type IDigestService interface {
+	GetDigest(req GetDigestRequest) (*GetDigestResponse, error)
+	SendDueDigests(now time.Time) error
+}
+
+type digestService struct {
+	transactionRepository transaction_repository.ITransactionRepository
+	budgetRepository      budget_repository.IBudgetRepository
+	digestRepository      digest_repository.IDigestRepository
+	notifier              notification.INotifier
+	fonts                 [][]byte
+	logger                echo.Logger
+}
+
+// NewDigestService draws the chart sent with each digest with fonts,
+// TrueType programs tried in order for each character, so it can show Thai.
+func NewDigestService(transactionRepository transaction_repository.ITransactionRepository, budgetRepository budget_repository.IBudgetRepository, digestRepository digest_repository.IDigestRepository, notifier notification.INotifier, fonts [][]byte, logger echo.Logger) IDigestService {
+	return &digestService{
+		transactionRepository: transactionRepository,
+		budgetRepository:      budgetRepository,
+		digestRepository:      digestRepository,
+		notifier:              notifier,
+		fonts:                 fonts,
+		logger:                logger,
+	}
+}
+
+// GetDigest builds the digest for a month, defaulting to the last complete
+// month in the spender's timezone and to their preferred language.
+func (s *digestService) GetDigest(req GetDigestRequest) (*GetDigestResponse, error) {
+	recipient, err := s.digestRepository.GetRecipient(req.SpenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get user")
+	}
+
+	loc := loadLocation(recipient.Timezone)
+	month := previousMonth(time.Now().In(loc))
+	if req.Month != "" {
+		month, err = time.ParseInLocation("2006-01", req.Month, loc)
+		if err != nil {
+			return nil, errors.New("month is invalid")
+		}
+	}
+
+	language := req.Language
+	if language == "" {
+		language = recipient.Language
+	}
+	return s.generate(*recipient, month, loc, language)
+}
+
+// SendDueDigests is run hourly. A digest falls due once it is past sendHour on
+// the first of the month in the spender's own timezone, and the delivery log
+// keeps a missed or repeated tick from sending twice.
+func (s *digestService) SendDueDigests(now time.Time) error {
+	recipients, err := s.digestRepository.GetRecipients()
+	if err != nil {
+		return errors.New("failed to get users")
+	}
+
+	var failed int
+	for _, recipient := range recipients {
+		loc := loadLocation(recipient.Timezone)
+		local := now.In(loc)
+		if local.Day() != 1 || local.Hour() < sendHour {
+			continue
+		}
+
+		month := previousMonth(local)
+		if err = s.send(recipient, month, loc); err != nil {
+			s.logger.Errorf("failed to send digest to spender %d: %v", recipient.ID, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to send %d digests", failed)
+	}
+	return nil
+}
+
+func (s *digestService) send(recipient entities.DigestRecipient, month time.Time, loc *time.Location) error {
+	monthKey := month.Format("2006-01")
+	channel := s.notifier.Channel()
+	delivered, err := s.digestRepository.IsDelivered(recipient.ID, monthKey, channel)
+	if err != nil {
+		return err
+	}
+	if delivered {
+		return nil
+	}
+
+	digest, err := s.generate(recipient, month, loc, recipient.Language)
+	if err != nil {
+		return err
+	}
+
+	msg := notification.Message{
+		SpenderId: recipient.ID,
+		To:        recipient.Email,
+		Subject:   digest.Subject,
+		Body:      digest.Text,
+	}
+	if len(digest.Report.TopCategories) > 0 {
+		// A digest whose chart cannot be drawn is still worth sending.
+		data, err := renderChart(digest.Report, s.fonts)
+		if err != nil {
+			s.logger.Errorf("failed to draw digest chart for spender %d: %v", recipient.ID, err)
+		} else {
+			msg.Attachments = append(msg.Attachments, notification.Attachment{
+				Filename:    fmt.Sprintf("spending-%s.png", monthKey),
+				ContentType: chart.ContentType(chart.FormatPNG),
+				Data:        data,
+			})
+		}
+	}
+	if err = s.notifier.Send(msg); err != nil {
+		return err
+	}
+
+	return s.digestRepository.SaveDelivery(entities.DigestDelivery{
+		Month:     monthKey,
+		Channel:   channel,
+		SentAt:    time.Now(),
+		SpenderId: int(recipient.ID),
+	})
+}
+
+func (s *digestService) generate(recipient entities.DigestRecipient, month time.Time, loc *time.Location, language string) (*GetDigestResponse, error) {
+	history, err := s.transactionRepository.GetAllBySpenderId(recipient.ID)
+	if err != nil {
+		return nil, errors.New("failed to get transaction")
+	}
+
+	budgets, err := s.budgetRepository.GetBySpenderId(recipient.ID)
+	if err != nil {
+		return nil, errors.New("failed to get budgets")
+	}
+
+	report := buildReport(history, budgets, month, loc)
+	report.SpenderId = recipient.ID
+	report.Name = recipient.Firstname
+	report.Language = normalizeLanguage(language)
+
+	subject, text, err := render(report)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to render digest")
+	}
+	return &GetDigestResponse{
+		Report:  report,
+		Subject: subject,
+		Text:    text,
+	}, nil
+}
+
+// buildReport summarises the month starting at month against the one before
+// it. Transactions are bucketed by their date in loc so a late-night expense
+// lands in the month the spender saw it in.
+func buildReport(history []entities.GetAllResponse, budgets []entities.Budget, month time.Time, loc *time.Location) DigestReport {
+	current := month.Format("2006-01")
+	previous := month.AddDate(0, -1, 0).Format("2006-01")
+	report := DigestReport{Month: current}
+
+	currentByCategory := make(map[string]float64)
+	previousByCategory := make(map[string]float64)
+	for _, value := range history {
+		if value.Date == nil {
+			continue
+		}
+
+		isExpense := strings.ToLower(value.TransactionType) == "expense"
+		isIncome := strings.ToLower(value.TransactionType) == "income"
+		switch value.Date.In(loc).Format("2006-01") {
+		case current:
+			if isIncome {
+				report.TotalIncome += value.Amount
+			}
+			if isExpense {
+				report.TotalExpense += value.Amount
+				currentByCategory[value.Category] += value.Amount
+				report.BiggestExpenses = append(report.BiggestExpenses, ExpenseItem{
+					ID:       value.ID,
+					Date:     value.Date.In(loc),
+					Category: value.Category,
+					Note:     value.Note,
+					Amount:   value.Amount,
+				})
+			}
+		case previous:
+			if isIncome {
+				report.PreviousIncome += value.Amount
+			}
+			if isExpense {
+				report.PreviousExpense += value.Amount
+				previousByCategory[value.Category] += value.Amount
+			}
+		}
+	}
+
+	report.Net = report.TotalIncome - report.TotalExpense
+	if report.PreviousExpense > 0 {
+		report.ExpenseChangePercent = percentChange(report.PreviousExpense, report.TotalExpense)
+	}
+
+	for category, amount := range currentByCategory {
+		var share float64
+		if report.TotalExpense > 0 {
+			share = roundAmount(amount / report.TotalExpense * 100)
+		}
+		report.TopCategories = append(report.TopCategories, CategoryTotal{
+			Category: category,
+			Amount:   roundAmount(amount),
+			Share:    share,
+		})
+	}
+	sort.Slice(report.TopCategories, func(i, j int) bool {
+		if report.TopCategories[i].Amount != report.TopCategories[j].Amount {
+			return report.TopCategories[i].Amount > report.TopCategories[j].Amount
+		}
+		return report.TopCategories[i].Category < report.TopCategories[j].Category
+	})
+	if len(report.TopCategories) > topCategoryLimit {
+		report.TopCategories = report.TopCategories[:topCategoryLimit]
+	}
+
+	sort.SliceStable(report.BiggestExpenses, func(i, j int) bool {
+		return report.BiggestExpenses[i].Amount > report.BiggestExpenses[j].Amount
+	})
+	if len(report.BiggestExpenses) > biggestExpenseLimit {
+		report.BiggestExpenses = report.BiggestExpenses[:biggestExpenseLimit]
+	}
+
+	for _, budget := range budgets {
+		spent := roundAmount(currentByCategory[budget.Category])
+		report.Budgets = append(report.Budgets, BudgetOutcome{
+			Category:  budget.Category,
+			Limit:     budget.MonthlyLimit,
+			Spent:     spent,
+			Remaining: roundAmount(budget.MonthlyLimit - spent),
+			Exceeded:  spent > budget.MonthlyLimit,
+		})
+	}
+
+	report.Changes = notableChanges(previousByCategory, currentByCategory)
+	report.TotalIncome = roundAmount(report.TotalIncome)
+	report.TotalExpense = roundAmount(report.TotalExpense)
+	report.Net = roundAmount(report.Net)
+	report.PreviousIncome = roundAmount(report.PreviousIncome)
+	report.PreviousExpense = roundAmount(report.PreviousExpense)
+	return report
+}
+
+func notableChanges(previous, current map[string]float64) []CategoryChange {
+	categories := make(map[string]bool)
+	for category := range previous {
+		categories[category] = true
+	}
+	for category := range current {
+		categories[category] = true
+	}
+
+	var changes []CategoryChange
+	for category := range categories {
+		change := current[category] - previous[category]
+		if math.Abs(change) < changeMinAmount {
+			continue
+		}
+
+		// A category with no spending last month has no meaningful percentage,
+		// so it is notable on the amount alone.
+		var changePercent float64
+		if previous[category] > 0 {
+			changePercent = percentChange(previous[category], current[category])
+			if math.Abs(changePercent) < changeMinPercent {
+				continue
+			}
+		}
+
+		changes = append(changes, CategoryChange{
+			Category:      category,
+			Previous:      roundAmount(previous[category]),
+			Current:       roundAmount(current[category]),
+			Change:        roundAmount(change),
+			ChangePercent: changePercent,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if math.Abs(changes[i].Change) != math.Abs(changes[j].Change) {
+			return math.Abs(changes[i].Change) > math.Abs(changes[j].Change)
+		}
+		return changes[i].Category < changes[j].Category
+	})
+	if len(changes) > changeLimit {
+		changes = changes[:changeLimit]
+	}
+	return changes
+}
+
+func percentChange(previous, current float64) float64 {
+	return roundAmount((current - previous) / previous * 100)
+}
+
+func previousMonth(local time.Time) time.Time {
+	return time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, local.Location()).AddDate(0, -1, 0)
+}
+
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		name = defaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// Bangkok has no DST, so a fixed offset is exact if tzdata is missing.
+		return time.FixedZone(defaultTimezone, 7*60*60)
+	}
+	return loc
+}
+
+func normalizeLanguage(language string) string {
+	if language == LanguageEnglish {
+		return LanguageEnglish
+	}
+	return LanguageThai
+}
+
+func roundAmount(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,186 @@
+package digest
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/notification"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+type notifierStub struct {
+	sent []notification.Message
+	err  error
+}
+
+func (n *notifierStub) Channel() string {
+	return notification.ChannelLog
+}
+
+func (n *notifierStub) Send(msg notification.Message) error {
+	n.sent = append(n.sent, msg)
+	return n.err
+}
+
+func digestTxn(id uint, date time.Time, amount float64, category, txnType string) entities.GetAllResponse {
+	return entities.GetAllResponse{ID: id, Date: &date, Amount: amount, Category: category, TransactionType: txnType}
+}
+
+func digestHistory() []entities.GetAllResponse {
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	return []entities.GetAllResponse{
+		digestTxn(1, time.Date(2024, 2, 10, 12, 0, 0, 0, bangkok), 30000, "salary", "income"),
+		digestTxn(2, time.Date(2024, 2, 12, 12, 0, 0, 0, bangkok), 2000, "food", "expense"),
+		digestTxn(3, time.Date(2024, 2, 20, 12, 0, 0, 0, bangkok), 1000, "transport", "expense"),
+		digestTxn(4, time.Date(2024, 3, 10, 12, 0, 0, 0, bangkok), 30000, "salary", "income"),
+		digestTxn(5, time.Date(2024, 3, 11, 12, 0, 0, 0, bangkok), 4500, "food", "expense"),
+		digestTxn(6, time.Date(2024, 3, 15, 12, 0, 0, 0, bangkok), 1100, "transport", "expense"),
+		digestTxn(7, time.Date(2024, 3, 18, 12, 0, 0, 0, bangkok), 12000, "shopping", "expense"),
+		// 23:30 on 31 March in Bangkok is still March even though it is
+		// already 1 April in UTC+9.
+		digestTxn(8, time.Date(2024, 4, 1, 1, 30, 0, 0, time.FixedZone("JST", 9*60*60)), 400, "food", "expense"),
+		digestTxn(9, time.Date(2024, 4, 2, 12, 0, 0, 0, bangkok), 9999, "food", "expense"),
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)
+	budgets := []entities.Budget{
+		{Category: "food", MonthlyLimit: 4000},
+		{Category: "transport", MonthlyLimit: 2000},
+	}
+
+	report := buildReport(digestHistory(), budgets, month, bangkok)
+
+	assert.Equal(t, "2024-03", report.Month)
+	assert.Equal(t, 30000.0, report.TotalIncome)
+	assert.Equal(t, 18000.0, report.TotalExpense)
+	assert.Equal(t, 12000.0, report.Net)
+	assert.Equal(t, 3000.0, report.PreviousExpense)
+	assert.Equal(t, 500.0, report.ExpenseChangePercent)
+
+	assert.Equal(t, 3, len(report.TopCategories))
+	assert.Equal(t, "shopping", report.TopCategories[0].Category)
+	assert.Equal(t, "food", report.TopCategories[1].Category)
+	assert.Equal(t, 4900.0, report.TopCategories[1].Amount)
+
+	assert.Equal(t, 3, len(report.BiggestExpenses))
+	assert.Equal(t, uint(7), report.BiggestExpenses[0].ID)
+
+	assert.Equal(t, BudgetOutcome{Category: "food", Limit: 4000, Spent: 4900, Remaining: -900, Exceeded: true}, report.Budgets[0])
+	assert.Equal(t, BudgetOutcome{Category: "transport", Limit: 2000, Spent: 1100, Remaining: 900}, report.Budgets[1])
+
+	assert.Equal(t, 2, len(report.Changes))
+	assert.Equal(t, "shopping", report.Changes[0].Category)
+	assert.Equal(t, "food", report.Changes[1].Category)
+	assert.Equal(t, 145.0, report.Changes[1].ChangePercent)
+}
+
+func TestRender(t *testing.T) {
+	bangkok := time.FixedZone("ICT", 7*60*60)
+	report := buildReport(digestHistory(), nil, time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok), bangkok)
+	report.Name = "Somchai"
+
+	report.Language = LanguageEnglish
+	subject, text, err := render(report)
+	assert.NoError(t, err)
+	assert.Equal(t, "Your spending digest for March 2024", subject)
+	assert.Contains(t, text, "You earned 30,000.00 THB and spent 18,000.00 THB, saving 12,000.00 THB.")
+	assert.Contains(t, text, "1. shopping: 12,000.00 THB")
+
+	report.Language = LanguageThai
+	subject, text, err = render(report)
+	assert.NoError(t, err)
+	assert.Equal(t, "สรุปการใช้จ่ายประจำเดือนมีนาคม 2567", subject)
+	assert.True(t, strings.Contains(text, "รายรับ 30,000.00 บาท"))
+}
+
+func TestFormatMoney(t *testing.T) {
+	assert.Equal(t, "0.00", formatMoney(0))
+	assert.Equal(t, "999.50", formatMoney(999.5))
+	assert.Equal(t, "1,000.00", formatMoney(1000))
+	assert.Equal(t, "-1,234,567.89", formatMoney(-1234567.89))
+}
+
+func TestDigestService_SendDueDigests(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockBudgetRepo := new(mocks.BudgetRepositoryMock)
+	mockDigestRepo := new(mocks.DigestRepositoryMock)
+	notifier := &notifierStub{}
+	logger := echo.New().Logger
+
+	// 01:30 UTC on 1 April is 08:30 in Bangkok but only 21:30 on 31 March in
+	// New York, so only the first user is due.
+	now := time.Date(2024, 4, 1, 1, 30, 0, 0, time.UTC)
+	mockDigestRepo.On("GetRecipients").Return([]entities.DigestRecipient{
+		{ID: 1, Firstname: "Somchai", Email: "somchai@example.com", Timezone: "Asia/Bangkok", Language: "th"},
+		{ID: 2, Firstname: "Jane", Email: "jane@example.com", Timezone: "America/New_York", Language: "en"},
+	}, nil)
+	mockDigestRepo.On("IsDelivered", uint(1), "2024-03", notification.ChannelLog).Return(false, nil)
+	mockDigestRepo.On("SaveDelivery", mock.Anything).Return(nil)
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(digestHistory(), nil)
+	mockBudgetRepo.On("GetBySpenderId", uint(1)).Return([]entities.Budget{}, nil)
+	service := NewDigestService(mockTxnRepo, mockBudgetRepo, mockDigestRepo, notifier, nil, logger)
+
+	err := service.SendDueDigests(now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(notifier.sent))
+	assert.Equal(t, "somchai@example.com", notifier.sent[0].To)
+	assert.Equal(t, 1, len(notifier.sent[0].Attachments))
+	assert.Equal(t, "spending-2024-03.png", notifier.sent[0].Attachments[0].Filename)
+	assert.True(t, bytes.HasPrefix(notifier.sent[0].Attachments[0].Data, []byte("\x89PNG")))
+	mockDigestRepo.AssertNotCalled(t, "IsDelivered", uint(2), mock.Anything, mock.Anything)
+	mockDigestRepo.AssertCalled(t, "SaveDelivery", mock.MatchedBy(func(req entities.DigestDelivery) bool {
+		return req.SpenderId == 1 && req.Month == "2024-03"
+	}))
+}
+
+func TestDigestService_SendDueDigests_AlreadyDelivered(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockBudgetRepo := new(mocks.BudgetRepositoryMock)
+	mockDigestRepo := new(mocks.DigestRepositoryMock)
+	notifier := &notifierStub{}
+	logger := echo.New().Logger
+
+	now := time.Date(2024, 4, 1, 5, 0, 0, 0, time.UTC)
+	mockDigestRepo.On("GetRecipients").Return([]entities.DigestRecipient{
+		{ID: 1, Timezone: "Asia/Bangkok", Language: "th"},
+	}, nil)
+	mockDigestRepo.On("IsDelivered", uint(1), "2024-03", notification.ChannelLog).Return(true, nil)
+	service := NewDigestService(mockTxnRepo, mockBudgetRepo, mockDigestRepo, notifier, nil, logger)
+
+	err := service.SendDueDigests(now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(notifier.sent))
+}
+
+func TestDigestService_SendDueDigests_NotifierError(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockBudgetRepo := new(mocks.BudgetRepositoryMock)
+	mockDigestRepo := new(mocks.DigestRepositoryMock)
+	notifier := &notifierStub{err: errors.New("some error")}
+	logger := echo.New().Logger
+
+	now := time.Date(2024, 4, 1, 5, 0, 0, 0, time.UTC)
+	mockDigestRepo.On("GetRecipients").Return([]entities.DigestRecipient{
+		{ID: 1, Timezone: "Asia/Bangkok", Language: "th"},
+	}, nil)
+	mockDigestRepo.On("IsDelivered", uint(1), "2024-03", notification.ChannelLog).Return(false, nil)
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(digestHistory(), nil)
+	mockBudgetRepo.On("GetBySpenderId", uint(1)).Return([]entities.Budget{}, nil)
+	service := NewDigestService(mockTxnRepo, mockBudgetRepo, mockDigestRepo, notifier, nil, logger)
+
+	err := service.SendDueDigests(now)
+
+	assert.EqualError(t, err, "failed to send 1 digests")
+	mockDigestRepo.AssertNotCalled(t, "SaveDelivery", mock.Anything)
+}
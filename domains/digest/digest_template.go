@@ -0,0 +1,109 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/chart"
+	"math"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var thaiMonths = [...]string{
+	"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+	"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม",
+}
+
+var templates = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"money":     formatMoney,
+	"abs":       math.Abs,
+	"monthName": monthName,
+	"inc":       func(i int) int { return i + 1 },
+}).ParseFS(templateFS, "templates/*.tmpl"))
+
+// render executes digest_<language>.tmpl, whose "subject" and "body" blocks
+// become the message subject and text.
+func render(report DigestReport) (string, string, error) {
+	name := fmt.Sprintf("digest_%s.tmpl", report.Language)
+	tmpl := templates.Lookup(name)
+	if tmpl == nil {
+		return "", "", fmt.Errorf("template %s not found", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject_"+report.Language, report); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body_"+report.Language, report); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()) + "\n", nil
+}
+
+var chartLabels = map[string][2]string{
+	LanguageThai:    {"รายจ่ายตามหมวดหมู่", "อื่น ๆ"},
+	LanguageEnglish: {"Expenses by category", "Other"},
+}
+
+// renderChart draws the month's spending by category as a PNG of the pie the
+// chart API serves, with what the top categories leave out as one slice.
+func renderChart(report DigestReport, fonts [][]byte) ([]byte, error) {
+	labels := chartLabels[report.Language]
+	c := chart.Chart{Kind: chart.KindPie, Title: labels[0] + " · " + monthName(report.Month, report.Language)}
+	values := make([]float64, 0, len(report.TopCategories)+1)
+	rest := report.TotalExpense
+	for _, total := range report.TopCategories {
+		c.Labels = append(c.Labels, total.Category)
+		values = append(values, total.Amount)
+		rest -= total.Amount
+	}
+	if rest >= 0.01 {
+		c.Labels = append(c.Labels, labels[1])
+		values = append(values, math.Round(rest*100)/100)
+	}
+	c.Series = []chart.Series{{Name: labels[0], Values: values}}
+
+	var buf bytes.Buffer
+	if err := chart.Render(&buf, c, chart.Options{Format: chart.FormatPNG, Fonts: fonts}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// monthName turns "2024-03" into "March 2024", or "มีนาคม 2567" in Thai, which
+// counts years in the Buddhist era.
+func monthName(month, language string) string {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+	if language == LanguageThai {
+		return fmt.Sprintf("%s %d", thaiMonths[t.Month()-1], t.Year()+543)
+	}
+	return t.Format("January 2006")
+}
+
+// formatMoney renders an amount with thousands separators and two decimals.
+func formatMoney(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	formatted := fmt.Sprintf("%.2f", amount)
+	whole, fraction := formatted[:len(formatted)-3], formatted[len(formatted)-3:]
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String() + fraction
+}
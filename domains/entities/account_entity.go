@@ -0,0 +1,15 @@
+package entities
+
+import "gorm.io/gorm"
+
+// Account is a bank account, card or wallet that statements are imported
+// into. Number is the account ID the bank's statements carry.
+type Account struct {
+	gorm.Model
+	Name      string `gorm:"type:varchar(100); not null; column:name" json:"name"`
+	Kind      string `gorm:"type:varchar(20); not null; column:kind" json:"kind"`
+	BankCode  string `gorm:"type:varchar(20); column:bank_code" json:"bank_code"`
+	Number    string `gorm:"type:varchar(50); column:number" json:"number"`
+	Currency  string `gorm:"type:varchar(3); default:'THB'; column:currency" json:"currency"`
+	SpenderId int    `gorm:"type:int; not null; index; column:spender_id" json:"spender_id"`
+}
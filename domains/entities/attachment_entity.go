@@ -0,0 +1,14 @@
+package entities
+
+import "gorm.io/gorm"
+
+type Attachment struct {
+	gorm.Model
+	TransactionId uint   `gorm:"type:int; not null; index:idx_attachment_transaction; column:transaction_id" json:"transaction_id"`
+	Filename      string `gorm:"type:varchar(255); not null; column:filename" json:"filename"`
+	ContentType   string `gorm:"type:varchar(100); not null; column:content_type" json:"content_type"`
+	Size          int64  `gorm:"type:bigint; not null; column:size" json:"size"`
+	BlobKey       string `gorm:"type:varchar(255); not null; column:blob_key" json:"-"`
+	ScanStatus    string `gorm:"type:varchar(20); column:scan_status" json:"scan_status"`
+	SpenderId     int    `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
+}
@@ -0,0 +1,10 @@
+package entities
+
+import "gorm.io/gorm"
+
+type Budget struct {
+	gorm.Model
+	Category     string  `gorm:"type:varchar(50); not null; uniqueIndex:idx_budget_spender_category; column:category" json:"category"`
+	MonthlyLimit float64 `gorm:"type:decimal(10,2); default:0.00; column:monthly_limit" json:"monthly_limit"`
+	SpenderId    int     `gorm:"type:int; not null; uniqueIndex:idx_budget_spender_category; column:spender_id" json:"spender_id"`
+}
@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// DigestDelivery records a sent digest so the hourly job never sends the same
+// month twice over one channel.
+type DigestDelivery struct {
+	gorm.Model
+	Month     string    `gorm:"type:varchar(7); not null; uniqueIndex:idx_digest_delivery; column:month" json:"month"`
+	Channel   string    `gorm:"type:varchar(20); not null; uniqueIndex:idx_digest_delivery; column:channel" json:"channel"`
+	SentAt    time.Time `gorm:"type:timestamp; not null; column:sent_at" json:"sent_at"`
+	SpenderId int       `gorm:"type:int; not null; uniqueIndex:idx_digest_delivery; column:spender_id" json:"spender_id"`
+}
+
+type DigestRecipient struct {
+	ID        uint   `gorm:"column:id" json:"id"`
+	Firstname string `gorm:"column:firstname" json:"firstname"`
+	Email     string `gorm:"column:email" json:"email"`
+	Timezone  string `gorm:"column:timezone" json:"timezone"`
+	Language  string `gorm:"column:language" json:"language"`
+}
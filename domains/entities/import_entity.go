@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// ImportBatch is one file imported into a spender's transactions. Every
+// transaction it created carries its ID, so the import can be rolled back as
+// a unit.
+type ImportBatch struct {
+	gorm.Model
+	Filename     string `gorm:"type:varchar(255); column:filename" json:"filename"`
+	Format       string `gorm:"type:varchar(20); not null; column:format" json:"format"`
+	RowCount     int    `gorm:"type:int; not null; column:row_count" json:"row_count"`
+	SkippedCount int    `gorm:"type:int; default:0; column:skipped_count" json:"skipped_count"`
+	// DuplicateCount counts statement rows imported before, and MatchedCount
+	// rows reconciled with a manual entry instead of being added.
+	DuplicateCount int        `gorm:"type:int; default:0; column:duplicate_count" json:"duplicate_count"`
+	MatchedCount   int        `gorm:"type:int; default:0; column:matched_count" json:"matched_count"`
+	AccountId      *uint      `gorm:"type:int; column:account_id" json:"account_id"`
+	FirstDate      time.Time  `gorm:"type:timestamp; column:first_date" json:"first_date"`
+	LastDate       time.Time  `gorm:"type:timestamp; column:last_date" json:"last_date"`
+	Status         string     `gorm:"type:varchar(20); not null; column:status" json:"status"`
+	RolledBackAt   *time.Time `gorm:"type:timestamp; column:rolled_back_at" json:"rolled_back_at"`
+	SpenderId      int        `gorm:"type:int; not null; index; column:spender_id" json:"spender_id"`
+}
+
+// ImportMatch records a manual entry that an import reconciled with a
+// statement row, so rolling the import back can unlink it again.
+type ImportMatch struct {
+	gorm.Model
+	ImportBatchId uint   `gorm:"type:int; not null; index; column:import_batch_id" json:"import_batch_id"`
+	TransactionId uint   `gorm:"type:int; not null; column:transaction_id" json:"transaction_id"`
+	FitId         string `gorm:"type:varchar(255); column:fit_id" json:"fit_id"`
+	// AccountSet is whether the match also put the entry on the account.
+	AccountSet bool `gorm:"type:boolean; default:false; column:account_set" json:"account_set"`
+}
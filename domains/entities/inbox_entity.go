@@ -0,0 +1,31 @@
+package entities
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// InboxToken is the secret part of a spender's inbox address,
+// <token>@<inbox domain>.
+type InboxToken struct {
+	gorm.Model
+	Token     string `gorm:"type:varchar(32); not null; uniqueIndex; column:token" json:"token"`
+	SpenderId int    `gorm:"type:int; not null; uniqueIndex; column:spender_id" json:"spender_id"`
+}
+
+// InboundEmail is an email sent to a spender's inbox and the draft
+// transaction read from it. The raw message is kept in the blob store.
+type InboundEmail struct {
+	gorm.Model
+	MessageId     string    `gorm:"type:varchar(255); index:idx_inbound_email_message; column:message_id" json:"message_id"`
+	Sender        string    `gorm:"type:varchar(255); column:sender" json:"sender"`
+	Subject       string    `gorm:"type:varchar(255); column:subject" json:"subject"`
+	ReceivedAt    time.Time `gorm:"type:timestamp; not null; column:received_at" json:"received_at"`
+	BlobKey       string    `gorm:"type:varchar(255); not null; column:blob_key" json:"-"`
+	Size          int64     `gorm:"type:bigint; not null; column:size" json:"size"`
+	Template      string    `gorm:"type:varchar(50); column:template" json:"template"`
+	Status        string    `gorm:"type:varchar(20); not null; column:status" json:"status"`
+	Draft         string    `gorm:"type:text; column:draft" json:"draft"`
+	TransactionId *uint     `gorm:"type:int; column:transaction_id" json:"transaction_id"`
+	SpenderId     int       `gorm:"type:int; not null; index:idx_inbound_email_message; column:spender_id" json:"spender_id"`
+}
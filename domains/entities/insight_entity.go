@@ -0,0 +1,16 @@
+package entities
+
+import "gorm.io/gorm"
+
+type TransactionAnomaly struct {
+	gorm.Model
+	TransactionId uint    `gorm:"type:int; not null; column:transaction_id" json:"transaction_id"`
+	SpenderId     int     `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
+	Kind          string  `gorm:"type:varchar(30); not null; column:kind" json:"kind"`
+	Category      string  `gorm:"type:varchar(50); column:category" json:"category"`
+	Month         string  `gorm:"type:varchar(7); column:month" json:"month"`
+	Amount        float64 `gorm:"type:decimal(10,2); default:0.00; column:amount" json:"amount"`
+	Baseline      float64 `gorm:"type:decimal(10,2); default:0.00; column:baseline" json:"baseline"`
+	Ratio         float64 `gorm:"type:decimal(10,2); default:0.00; column:ratio" json:"ratio"`
+	Reason        string  `gorm:"type:varchar(255); column:reason" json:"reason"`
+}
@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type NetWorthItem struct {
+	gorm.Model
+	Name      string     `gorm:"type:varchar(100); not null; column:name" json:"name"`
+	Kind      string     `gorm:"type:varchar(20); not null; column:kind" json:"kind"`
+	Value     float64    `gorm:"type:decimal(14,2); default:0.00; column:value" json:"value"`
+	StartDate time.Time  `gorm:"type:timestamp; default:CURRENT_TIMESTAMP; column:start_date" json:"start_date"`
+	EndDate   *time.Time `gorm:"type:timestamp; column:end_date" json:"end_date"`
+	SpenderId int        `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
+}
+
+type NetWorthSnapshot struct {
+	gorm.Model
+	Date        time.Time `gorm:"type:date; not null; uniqueIndex:idx_net_worth_spender_date; column:date" json:"date"`
+	CashBalance float64   `gorm:"type:decimal(14,2); default:0.00; column:cash_balance" json:"cash_balance"`
+	Assets      float64   `gorm:"type:decimal(14,2); default:0.00; column:assets" json:"assets"`
+	Liabilities float64   `gorm:"type:decimal(14,2); default:0.00; column:liabilities" json:"liabilities"`
+	NetWorth    float64   `gorm:"type:decimal(14,2); default:0.00; column:net_worth" json:"net_worth"`
+	SpenderId   int       `gorm:"type:int; not null; uniqueIndex:idx_net_worth_spender_date; column:spender_id" json:"spender_id"`
+}
@@ -0,0 +1,35 @@
+package entities
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type RecurringRule struct {
+	gorm.Model
+	Name            string     `gorm:"type:varchar(100); not null; column:name" json:"name"`
+	Amount          float64    `gorm:"type:decimal(10,2); default:0.00; column:amount" json:"amount"`
+	Category        string     `gorm:"type:varchar(50); default:'other'; column:category" json:"category"`
+	TransactionType string     `gorm:"type:varchar(20); not null; column:transaction_type" json:"transaction_type"`
+	Frequency       string     `gorm:"type:varchar(20); not null; column:frequency" json:"frequency"`
+	Interval        int        `gorm:"type:int; default:1; column:interval" json:"interval"`
+	NextDate        time.Time  `gorm:"type:timestamp; not null; column:next_date" json:"next_date"`
+	EndDate         *time.Time `gorm:"type:timestamp; column:end_date" json:"end_date"`
+	SpenderId       int        `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
+}
+
+type DetectedSubscription struct {
+	gorm.Model
+	Signature       string    `gorm:"type:varchar(255); not null; column:signature" json:"signature"`
+	Name            string    `gorm:"type:varchar(255); column:name" json:"name"`
+	Category        string    `gorm:"type:varchar(50); column:category" json:"category"`
+	Amount          float64   `gorm:"type:decimal(10,2); default:0.00; column:amount" json:"amount"`
+	Cadence         string    `gorm:"type:varchar(20); not null; column:cadence" json:"cadence"`
+	Occurrences     int       `gorm:"type:int; default:0; column:occurrences" json:"occurrences"`
+	LastDate        time.Time `gorm:"type:timestamp; column:last_date" json:"last_date"`
+	NextDate        time.Time `gorm:"type:timestamp; column:next_date" json:"next_date"`
+	AnnualCost      float64   `gorm:"type:decimal(12,2); default:0.00; column:annual_cost" json:"annual_cost"`
+	Status          string    `gorm:"type:varchar(20); default:'detected'; column:status" json:"status"`
+	RecurringRuleId *uint     `gorm:"type:int; column:recurring_rule_id" json:"recurring_rule_id"`
+	SpenderId       int       `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
+}
@@ -0,0 +1,33 @@
+package entities
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// SlipJob is a slip upload waiting for or going through processing. The
+// table is the queue: workers lease due rows through LockedUntil, and a row
+// whose lease has run out is claimed again.
+type SlipJob struct {
+	gorm.Model
+	Status        string     `gorm:"type:varchar(20); not null; index:idx_slip_job_due,priority:1; column:status" json:"status"`
+	Filename      string     `gorm:"type:varchar(255); column:filename" json:"filename"`
+	Image         []byte     `gorm:"type:bytea; column:image" json:"-"`
+	Force         bool       `gorm:"type:boolean; default:false; column:force" json:"force"`
+	Draft         string     `gorm:"type:text; column:draft" json:"draft"`
+	Attempts      int        `gorm:"type:int; default:0; column:attempts" json:"attempts"`
+	NextRunAt     time.Time  `gorm:"type:timestamp; not null; index:idx_slip_job_due,priority:2; column:next_run_at" json:"next_run_at"`
+	LockedUntil   *time.Time `gorm:"type:timestamp; column:locked_until" json:"locked_until"`
+	LastError     string     `gorm:"type:varchar(255); column:last_error" json:"last_error"`
+	TransactionId *uint      `gorm:"type:int; column:transaction_id" json:"transaction_id"`
+	DuplicateOf   *uint      `gorm:"type:int; column:duplicate_of" json:"duplicate_of"`
+	BatchId       *uint      `gorm:"type:int; index:idx_slip_job_batch; column:batch_id" json:"batch_id"`
+	SpenderId     int        `gorm:"type:int; not null; index; column:spender_id" json:"spender_id"`
+}
+
+// SlipBatch groups the jobs of slips uploaded together.
+type SlipBatch struct {
+	gorm.Model
+	FileCount int `gorm:"type:int; not null; column:file_count" json:"file_count"`
+	SpenderId int `gorm:"type:int; not null; index; column:spender_id" json:"spender_id"`
+}
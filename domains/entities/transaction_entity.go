@@ -13,9 +13,25 @@ type Transaction struct {
 	TransactionType string    `gorm:"type:varchar(20); not null; column:transaction_type" json:"transaction_type"`
 	Note            string    `gorm:"type:varchar(255); column:note" json:"note"`
 	ImageUrl        string    `gorm:"type:varchar(255); column:image_url" json:"image_url"`
+	ThumbnailUrl    string    `gorm:"type:varchar(255); column:thumbnail_url" json:"thumbnail_url"`
+	BankCode        string    `gorm:"type:varchar(20); column:bank_code" json:"bank_code"`
+	TransRef        string    `gorm:"type:varchar(50); index:idx_transaction_trans_ref; column:trans_ref" json:"trans_ref"`
+	ImageHash       string    `gorm:"type:varchar(16); column:image_hash" json:"image_hash"`
+	Merchant        string    `gorm:"type:varchar(100); column:merchant" json:"merchant"`
+	TaxId           string    `gorm:"type:varchar(13); column:tax_id" json:"tax_id"`
+	VAT             float64   `gorm:"type:decimal(10,2); default:0.00; column:vat" json:"vat"`
+	ImportBatchId   *uint     `gorm:"type:int; index:idx_transaction_import_batch; column:import_batch_id" json:"import_batch_id"`
+	AccountId       *uint     `gorm:"type:int; index:idx_transaction_account_fit_id; column:account_id" json:"account_id"`
+	FitId           string    `gorm:"type:varchar(255); index:idx_transaction_account_fit_id; column:fit_id" json:"fit_id"`
 	SpenderId       int       `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
 }
 
+type SlipHash struct {
+	ID        uint    `gorm:"column:id" json:"id"`
+	Amount    float64 `gorm:"column:amount" json:"amount"`
+	ImageHash string  `gorm:"column:image_hash" json:"image_hash"`
+}
+
 type GetAllTxnFilter struct {
 	Date     *time.Time `gorm:"column:date" query:"date"`
 	Category string     `gorm:"column:category" query:"category"`
@@ -43,6 +59,8 @@ type GetAllResponse struct {
 	Date            *time.Time `gorm:"column:date" json:"date"`
 	Amount          float64    `gorm:"column:amount" json:"amount"`
 	Category        string     `gorm:"column:category" json:"category"`
+	Note            string     `gorm:"column:note" json:"note"`
+	Merchant        string     `gorm:"column:merchant" json:"merchant"`
 	ImageUrl        string     `gorm:"column:image_url" json:"image_url"`
 	TransactionType string     `gorm:"column:transaction_type" json:"transaction_type"`
 }
@@ -73,3 +91,13 @@ type GetByCategoryResponse struct {
 	Amount   float64   `gorm:"column: amount" json:"amount"`
 	ImageUrl string    `gorm:"column: image_url" json:"image_url"`
 }
+
+// ExportTxn is a transaction with the account it is on, which journal
+// exports name its postings after. The account fields are empty when the
+// transaction is on none.
+type ExportTxn struct {
+	Transaction
+	AccountName     string `gorm:"column:account_name"`
+	AccountKind     string `gorm:"column:account_kind"`
+	AccountCurrency string `gorm:"column:account_currency"`
+}
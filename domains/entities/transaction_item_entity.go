@@ -0,0 +1,16 @@
+package entities
+
+import "gorm.io/gorm"
+
+// TransactionItem is one line of an itemized receipt. Each line keeps its own
+// category so a mixed basket can be split across budgets.
+type TransactionItem struct {
+	gorm.Model
+	TransactionId uint    `gorm:"type:int; not null; index:idx_transaction_item_transaction; column:transaction_id" json:"transaction_id"`
+	Name          string  `gorm:"type:varchar(255); column:name" json:"name"`
+	Quantity      float64 `gorm:"type:decimal(10,3); default:1; column:quantity" json:"quantity"`
+	UnitPrice     float64 `gorm:"type:decimal(10,2); default:0.00; column:unit_price" json:"unit_price"`
+	Amount        float64 `gorm:"type:decimal(10,2); default:0.00; column:amount" json:"amount"`
+	Category      string  `gorm:"type:varchar(50); default:'other'; column:category" json:"category"`
+	SpenderId     int     `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
+}
@@ -12,6 +12,8 @@ type Users struct {
 	Email     string `gorm:"type:varchar; not null; unique; column:email" validate:"required" json:"email"`
 	Username  string `gorm:"type:varchar; not null; unique; column:username" validate:"required" json:"username"`
 	Password  string `gorm:"type:varchar; not null; column:password" validate:"required" json:"password"`
+	Timezone  string `gorm:"type:varchar(50); default:'Asia/Bangkok'; column:timezone" json:"timezone"`
+	Language  string `gorm:"type:varchar(5); default:'th'; column:language" json:"language"`
 }
 
 type Claims struct {
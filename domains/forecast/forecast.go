@@ -0,0 +1,34 @@
+package forecast
+
+import "time"
+
+type ForecastRequest struct {
+	SpenderId      uint `json:"spender_id" validate:"required"`
+	Days           int  `json:"days" validate:"gte=1,lte=365"`
+	LookbackMonths int  `json:"lookback_months" validate:"gte=1,lte=24"`
+}
+
+type CategoryBaseline struct {
+	Category    string  `json:"category"`
+	DailyMean   float64 `json:"daily_mean"`
+	DailyStdDev float64 `json:"daily_std_dev"`
+}
+
+type DailyForecast struct {
+	Date              time.Time `json:"date"`
+	ScheduledIncome   float64   `json:"scheduled_income"`
+	ScheduledExpense  float64   `json:"scheduled_expense"`
+	BaselineExpense   float64   `json:"baseline_expense"`
+	ExpectedBalance   float64   `json:"expected_balance"`
+	LowBalance        float64   `json:"low_balance"`
+	HighBalance       float64   `json:"high_balance"`
+	ProjectedNegative bool      `json:"projected_negative"`
+}
+
+type ForecastResponse struct {
+	SpenderId         uint               `json:"spender_id"`
+	StartingBalance   float64            `json:"starting_balance"`
+	Baselines         []CategoryBaseline `json:"baselines"`
+	Days              []DailyForecast    `json:"days"`
+	FirstNegativeDate *time.Time         `json:"first_negative_date"`
+}
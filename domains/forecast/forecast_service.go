@@ -0,0 +1,227 @@
+package forecast
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/recurring"
+	"github.com/Montheankul-K/jod-jod/repository/recurring_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bandZScore widens the expected balance into an ~80% interval (±1.28σ).
+const bandZScore = 1.2816
+
+// recurringAmountTolerance is how close (as a fraction of the rule amount) a
+// historical expense must be to a recurring rule to count as that rule rather
+// than discretionary spend.
+const recurringAmountTolerance = 0.1
+
+type IForecastService interface {
+	GetForecast(req ForecastRequest) (*ForecastResponse, error)
+}
+
+type forecastService struct {
+	transactionRepository transaction_repository.ITransactionRepository
+	recurringRepository   recurring_repository.IRecurringRepository
+	logger                echo.Logger
+}
+
+func NewForecastService(transactionRepository transaction_repository.ITransactionRepository, recurringRepository recurring_repository.IRecurringRepository, logger echo.Logger) IForecastService {
+	return &forecastService{
+		transactionRepository: transactionRepository,
+		recurringRepository:   recurringRepository,
+		logger:                logger,
+	}
+}
+
+func (s *forecastService) GetForecast(req ForecastRequest) (*ForecastResponse, error) {
+	txns, err := s.transactionRepository.GetAllBySpenderId(req.SpenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get transaction")
+	}
+
+	rules, err := s.recurringRepository.GetBySpenderId(req.SpenderId)
+	if err != nil {
+		return nil, errors.New("failed to get recurring rules")
+	}
+
+	now := time.Now()
+	today := startOfDay(now)
+	startingBalance := currentBalance(txns, now)
+	baselines := buildBaselines(txns, rules, today.AddDate(0, -req.LookbackMonths, 0), today)
+	days, firstNegative := projectBalance(startingBalance, today, req.Days, baselines, rules)
+
+	s.logger.Infof("get forecast of spender id: %d for %d days success", req.SpenderId, req.Days)
+	return &ForecastResponse{
+		SpenderId:         req.SpenderId,
+		StartingBalance:   roundAmount(startingBalance),
+		Baselines:         baselines,
+		Days:              days,
+		FirstNegativeDate: firstNegative,
+	}, nil
+}
+
+func currentBalance(txns []entities.GetAllResponse, now time.Time) float64 {
+	var balance float64
+	for _, txn := range txns {
+		if txn.Date != nil && txn.Date.After(now) {
+			continue
+		}
+		switch strings.ToLower(txn.TransactionType) {
+		case "income":
+			balance += txn.Amount
+		case "expense":
+			balance -= txn.Amount
+		}
+	}
+	return balance
+}
+
+// buildBaselines computes the mean and standard deviation of daily
+// discretionary spend per category over [from, to). Days without spending
+// count as zero so sparse categories are not overstated, and expenses that
+// match a recurring rule are left out because the rule already schedules them.
+func buildBaselines(txns []entities.GetAllResponse, rules []entities.RecurringRule, from, to time.Time) []CategoryBaseline {
+	windowDays := int(to.Sub(from).Hours() / 24)
+	if windowDays <= 0 {
+		return nil
+	}
+
+	daily := make(map[string][]float64)
+	for _, txn := range txns {
+		if txn.Date == nil || strings.ToLower(txn.TransactionType) != "expense" {
+			continue
+		}
+		if txn.Date.Before(from) || !txn.Date.Before(to) {
+			continue
+		}
+		if coveredByRule(txn, rules) {
+			continue
+		}
+		if _, ok := daily[txn.Category]; !ok {
+			daily[txn.Category] = make([]float64, windowDays)
+		}
+		index := int(startOfDay(*txn.Date).Sub(from).Hours() / 24)
+		if index >= 0 && index < windowDays {
+			daily[txn.Category][index] += txn.Amount
+		}
+	}
+
+	var baselines []CategoryBaseline
+	for category, totals := range daily {
+		mean, stdDev := meanStdDev(totals)
+		baselines = append(baselines, CategoryBaseline{
+			Category:    category,
+			DailyMean:   roundAmount(mean),
+			DailyStdDev: roundAmount(stdDev),
+		})
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		return baselines[i].Category < baselines[j].Category
+	})
+	return baselines
+}
+
+func coveredByRule(txn entities.GetAllResponse, rules []entities.RecurringRule) bool {
+	for _, rule := range rules {
+		if !strings.EqualFold(rule.TransactionType, txn.TransactionType) || rule.Category != txn.Category {
+			continue
+		}
+		if math.Abs(txn.Amount-rule.Amount) <= rule.Amount*recurringAmountTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// projectBalance walks forward one day at a time from start, applying the
+// scheduled recurring flows and the discretionary baseline. Daily baseline
+// variances are assumed independent, so the band grows with the square root of
+// the horizon.
+func projectBalance(startingBalance float64, start time.Time, days int, baselines []CategoryBaseline, rules []entities.RecurringRule) ([]DailyForecast, *time.Time) {
+	end := start.AddDate(0, 0, days+1)
+	scheduledIncome := make(map[string]float64)
+	scheduledExpense := make(map[string]float64)
+	for _, rule := range rules {
+		for _, date := range recurring.Occurrences(rule, start.AddDate(0, 0, 1), end) {
+			key := date.Format("2006-01-02")
+			if strings.EqualFold(rule.TransactionType, "income") {
+				scheduledIncome[key] += rule.Amount
+			} else {
+				scheduledExpense[key] += rule.Amount
+			}
+		}
+	}
+
+	var dailyMean, dailyVariance float64
+	for _, baseline := range baselines {
+		dailyMean += baseline.DailyMean
+		dailyVariance += baseline.DailyStdDev * baseline.DailyStdDev
+	}
+
+	var results []DailyForecast
+	var firstNegative *time.Time
+	balance := startingBalance
+	var variance float64
+	for d := 1; d <= days; d++ {
+		date := start.AddDate(0, 0, d)
+		key := date.Format("2006-01-02")
+		income := scheduledIncome[key]
+		expense := scheduledExpense[key]
+
+		balance += income - expense - dailyMean
+		variance += dailyVariance
+		band := bandZScore * math.Sqrt(variance)
+
+		negative := balance < 0
+		if negative && firstNegative == nil {
+			negativeDate := date
+			firstNegative = &negativeDate
+		}
+		results = append(results, DailyForecast{
+			Date:              date,
+			ScheduledIncome:   roundAmount(income),
+			ScheduledExpense:  roundAmount(expense),
+			BaselineExpense:   roundAmount(dailyMean),
+			ExpectedBalance:   roundAmount(balance),
+			LowBalance:        roundAmount(balance - band),
+			HighBalance:       roundAmount(balance + band),
+			ProjectedNegative: negative,
+		})
+	}
+	return results, firstNegative
+}
+
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)))
+}
+
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
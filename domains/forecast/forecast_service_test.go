@@ -0,0 +1,113 @@
+package forecast
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestForecastService_GetForecast_Success(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockRecurringRepo := new(mocks.RecurringRepositoryMock)
+	logger := echo.New().Logger
+
+	date := time.Now().AddDate(0, 0, -10)
+	mockTxnRepo.On("GetAllBySpenderId", mock.Anything).Return([]entities.GetAllResponse{
+		{ID: uint(1), Date: &date, Amount: 1000, Category: "salary", TransactionType: "income"},
+		{ID: uint(2), Date: &date, Amount: 300, Category: "food", TransactionType: "expense"},
+	}, nil)
+	mockRecurringRepo.On("GetBySpenderId", mock.Anything).Return([]entities.RecurringRule{}, nil)
+	service := NewForecastService(mockTxnRepo, mockRecurringRepo, logger)
+
+	req := ForecastRequest{
+		SpenderId:      uint(1),
+		Days:           14,
+		LookbackMonths: 1,
+	}
+	result, err := service.GetForecast(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, float64(700), result.StartingBalance)
+	assert.Equal(t, 14, len(result.Days))
+	assert.Equal(t, 1, len(result.Baselines))
+	assert.Equal(t, "food", result.Baselines[0].Category)
+	assert.True(t, result.Days[13].ExpectedBalance < 700)
+	assert.True(t, result.Days[13].LowBalance < result.Days[13].ExpectedBalance)
+	assert.True(t, result.Days[13].HighBalance > result.Days[13].ExpectedBalance)
+}
+
+func TestForecastService_GetForecast_TransactionError(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockRecurringRepo := new(mocks.RecurringRepositoryMock)
+	logger := echo.New().Logger
+
+	mockTxnRepo.On("GetAllBySpenderId", mock.Anything).Return([]entities.GetAllResponse{}, errors.New("some error"))
+	service := NewForecastService(mockTxnRepo, mockRecurringRepo, logger)
+
+	_, err := service.GetForecast(ForecastRequest{SpenderId: uint(1), Days: 30, LookbackMonths: 3})
+
+	assert.EqualError(t, err, "failed to get transaction")
+}
+
+func TestForecastService_GetForecast_RecurringError(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockRecurringRepo := new(mocks.RecurringRepositoryMock)
+	logger := echo.New().Logger
+
+	mockTxnRepo.On("GetAllBySpenderId", mock.Anything).Return([]entities.GetAllResponse{}, nil)
+	mockRecurringRepo.On("GetBySpenderId", mock.Anything).Return([]entities.RecurringRule{}, errors.New("some error"))
+	service := NewForecastService(mockTxnRepo, mockRecurringRepo, logger)
+
+	_, err := service.GetForecast(ForecastRequest{SpenderId: uint(1), Days: 30, LookbackMonths: 3})
+
+	assert.EqualError(t, err, "failed to get recurring rules")
+}
+
+func TestProjectBalance_FlagsFirstNegativeDate(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	rules := []entities.RecurringRule{
+		{Amount: 500, TransactionType: "expense", Frequency: "once", NextDate: start.AddDate(0, 0, 3)},
+	}
+
+	days, firstNegative := projectBalance(400, start, 5, nil, rules)
+
+	assert.Equal(t, 5, len(days))
+	assert.Equal(t, float64(400), days[1].ExpectedBalance)
+	assert.Equal(t, float64(-100), days[2].ExpectedBalance)
+	assert.True(t, days[2].ProjectedNegative)
+	assert.Equal(t, start.AddDate(0, 0, 3), *firstNegative)
+}
+
+func TestProjectBalance_NeverNegative(t *testing.T) {
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	baselines := []CategoryBaseline{{Category: "food", DailyMean: 10, DailyStdDev: 5}}
+
+	days, firstNegative := projectBalance(1000, start, 10, baselines, nil)
+
+	assert.Nil(t, firstNegative)
+	assert.Equal(t, float64(900), days[9].ExpectedBalance)
+}
+
+func TestBuildBaselines_SkipsRecurringExpenses(t *testing.T) {
+	to := time.Date(2024, time.June, 11, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -10)
+	date := from.AddDate(0, 0, 2)
+	txns := []entities.GetAllResponse{
+		{Date: &date, Amount: 100, Category: "food", TransactionType: "expense"},
+		{Date: &date, Amount: 299, Category: "streaming", TransactionType: "expense"},
+	}
+	rules := []entities.RecurringRule{
+		{Amount: 299, Category: "streaming", TransactionType: "expense", Frequency: "monthly", NextDate: date},
+	}
+
+	baselines := buildBaselines(txns, rules, from, to)
+
+	assert.Equal(t, 1, len(baselines))
+	assert.Equal(t, "food", baselines[0].Category)
+	assert.Equal(t, float64(10), baselines[0].DailyMean)
+}
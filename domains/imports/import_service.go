@@ -0,0 +1,665 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/networth"
+	"github.com/Montheankul-K/jod-jod/exportfile"
+	"github.com/Montheankul-K/jod-jod/importfile"
+	"github.com/Montheankul-K/jod-jod/repository/account_repository"
+	"github.com/Montheankul-K/jod-jod/repository/import_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"math"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// firstDataLine is the line of the first row under a CSV header.
+	firstDataLine = 2
+	// reconcileDays is how many days apart a statement row and a manual entry
+	// may be and still match, since banks post card payments late.
+	reconcileDays = 3
+	// amountTolerance absorbs float error when comparing amounts in baht.
+	amountTolerance = 0.005
+)
+
+// bangkok is where statement dates are compared. It has no DST, so a fixed
+// offset is exact.
+var bangkok = time.FixedZone("Asia/Bangkok", 7*60*60)
+
+type IImportService interface {
+	PreviewCSV(data []byte, opts CSVOptions) (*PreviewResponse, error)
+	CommitCSV(spenderId uint, filename string, data []byte, opts CSVOptions) (*GetBatchResponse, error)
+	GetBatches(spenderId uint) ([]GetBatchResponse, error)
+	GetBatch(spenderId, batchId uint) (*GetBatchResponse, error)
+	Rollback(spenderId, batchId uint) (*GetBatchResponse, error)
+	PreviewStatement(spenderId uint, filename string, data []byte, opts StatementOptions) (*StatementPreviewResponse, error)
+	CommitStatement(spenderId uint, filename string, data []byte, opts StatementOptions) (*GetBatchResponse, error)
+	PreviewJournal(spenderId uint, data []byte) (*StatementPreviewResponse, error)
+	CommitJournal(spenderId uint, filename string, data []byte, opts JournalOptions) (*GetBatchResponse, error)
+}
+
+type importService struct {
+	importRepository  import_repository.IImportRepository
+	accountRepository account_repository.IAccountRepository
+	netWorthService   networth.INetWorthService
+	blobStore         blobstore.BlobStore
+	logger            echo.Logger
+	now               func() time.Time
+}
+
+func NewImportService(importRepository import_repository.IImportRepository, accountRepository account_repository.IAccountRepository, netWorthService networth.INetWorthService, blobStore blobstore.BlobStore, logger echo.Logger) IImportService {
+	return &importService{
+		importRepository:  importRepository,
+		accountRepository: accountRepository,
+		netWorthService:   netWorthService,
+		blobStore:         blobStore,
+		logger:            logger,
+		now:               time.Now,
+	}
+}
+
+// PreviewCSV reads a CSV file as CommitCSV would, without saving anything.
+func (s *importService) PreviewCSV(data []byte, opts CSVOptions) (*PreviewResponse, error) {
+	file, err := importfile.ReadCSV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := opts.Mapping
+	if mapping == nil {
+		mapping = importfile.ProposeMapping(file.Header)
+	}
+	preview := &PreviewResponse{
+		Encoding:  file.Encoding,
+		Delimiter: file.Delimiter,
+		Header:    file.Header,
+		Mapping:   mapping,
+		Total:     len(file.Rows),
+		Rows:      []importfile.Record{},
+	}
+	if err = mapping.Validate(len(file.Header)); err != nil {
+		preview.MappingError = err.Error()
+		return preview, nil
+	}
+
+	preview.Rows = mapping.Records(file.Rows, firstDataLine, importfile.Options{
+		DateOrder:   opts.DateOrder,
+		DefaultType: opts.DefaultType,
+	})
+	for _, record := range preview.Rows {
+		if record.Valid() {
+			preview.Valid++
+		} else {
+			preview.Invalid++
+		}
+	}
+	return preview, nil
+}
+
+// CommitCSV saves the rows of a CSV file as one import. Invalid rows stop the
+// import unless opts says to skip them.
+func (s *importService) CommitCSV(spenderId uint, filename string, data []byte, opts CSVOptions) (*GetBatchResponse, error) {
+	preview, err := s.PreviewCSV(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	if preview.MappingError != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMapping, preview.MappingError)
+	}
+	if preview.Invalid > 0 && !opts.SkipInvalid {
+		return nil, &InvalidRowsError{Invalid: preview.Invalid, Preview: preview}
+	}
+
+	batch := entities.ImportBatch{
+		Filename:  blobstore.SafeName(filename, FormatCSV),
+		Format:    FormatCSV,
+		SpenderId: int(spenderId),
+	}
+	var txns []entities.Transaction
+	for _, record := range preview.Rows {
+		if !record.Valid() {
+			batch.SkippedCount++
+			continue
+		}
+		txns = append(txns, newTransaction(spenderId, record))
+		widenPeriod(&batch, record.Date)
+	}
+	if len(txns) == 0 {
+		return nil, ErrNothingToImport
+	}
+	return s.save(batch, txns, nil)
+}
+
+// PreviewStatement reads an OFX, QFX or QIF statement for one of the
+// spender's accounts and works out which rows are new, which were imported
+// before, and which match an entry the spender typed in by hand.
+func (s *importService) PreviewStatement(spenderId uint, filename string, data []byte, opts StatementOptions) (*StatementPreviewResponse, error) {
+	preview, _, err := s.planStatement(spenderId, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	preview.Format = statementFormat(preview.Format, filename)
+	return preview, nil
+}
+
+// CommitStatement adds the new rows of a statement to the account and links
+// the matched manual entries to their statement rows, so neither is counted
+// twice and the next download of an overlapping period skips them.
+func (s *importService) CommitStatement(spenderId uint, filename string, data []byte, opts StatementOptions) (*GetBatchResponse, error) {
+	preview, matches, err := s.planStatement(spenderId, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	if preview.Invalid > 0 && !opts.SkipInvalid {
+		return nil, &InvalidRowsError{Invalid: preview.Invalid, Preview: preview}
+	}
+
+	format := statementFormat(preview.Format, filename)
+	accountId := opts.AccountId
+	batch := entities.ImportBatch{
+		Filename:       blobstore.SafeName(filename, format),
+		Format:         format,
+		SkippedCount:   preview.Invalid,
+		DuplicateCount: preview.Duplicate,
+		MatchedCount:   preview.Matched,
+		AccountId:      &accountId,
+		SpenderId:      int(spenderId),
+	}
+	var txns []entities.Transaction
+	for _, row := range preview.Rows {
+		if row.Status != RowNew {
+			continue
+		}
+		txn := newTransaction(spenderId, row.Record)
+		txn.AccountId = &accountId
+		txn.FitId = row.FitId
+		txns = append(txns, txn)
+		widenPeriod(&batch, row.Date)
+	}
+	if len(txns) == 0 && len(matches) == 0 {
+		if preview.Duplicate > 0 {
+			return nil, ErrNothingNew
+		}
+		return nil, ErrNothingToImport
+	}
+	if len(txns) == 0 {
+		widenPeriod(&batch, preview.StartDate)
+		widenPeriod(&batch, preview.EndDate)
+	}
+	return s.save(batch, txns, matches)
+}
+
+// planStatement reads a statement and decides each row's status. Rows are
+// duplicates when the account already has their FITID, or an earlier row of
+// the file had it. New rows then claim, closest date first, a manual entry of
+// the same type and amount within reconcileDays; each entry matches once.
+func (s *importService) planStatement(spenderId uint, data []byte, opts StatementOptions) (*StatementPreviewResponse, []entities.ImportMatch, error) {
+	account, err := s.getAccount(spenderId, opts.AccountId)
+	if err != nil {
+		return nil, nil, err
+	}
+	statement, err := importfile.ReadStatement(data, opts.DateOrder)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !sameAccount(account.Number, statement.Account) {
+		return nil, nil, ErrAccountMismatch
+	}
+	if statement.Currency != "" && account.Currency != "" && !strings.EqualFold(statement.Currency, account.Currency) {
+		return nil, nil, ErrCurrencyMismatch
+	}
+
+	preview := &StatementPreviewResponse{
+		Format:    statement.Format,
+		Encoding:  statement.Encoding,
+		Account:   statement.Account,
+		Currency:  statement.Currency,
+		StartDate: statement.StartDate,
+		EndDate:   statement.EndDate,
+		Total:     len(statement.Records),
+		Rows:      make([]StatementRow, 0, len(statement.Records)),
+	}
+	var fitIds []string
+	for _, record := range statement.Records {
+		if record.Valid() {
+			fitIds = append(fitIds, record.FitId)
+		}
+	}
+	existing, err := s.importRepository.GetFitIds(account.ID, fitIds)
+	if err != nil {
+		return nil, nil, errors.New("failed to check imported rows")
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, fitId := range existing {
+		seen[fitId] = true
+	}
+
+	var newRows []int
+	for _, record := range statement.Records {
+		row := StatementRow{Record: record, Status: RowNew}
+		switch {
+		case !record.Valid():
+			row.Status = RowInvalid
+			preview.Invalid++
+		case seen[record.FitId]:
+			row.Status = RowDuplicate
+			preview.Duplicate++
+		default:
+			seen[record.FitId] = true
+			newRows = append(newRows, len(preview.Rows))
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+
+	var matches []entities.ImportMatch
+	if len(newRows) > 0 {
+		from := statement.StartDate.AddDate(0, 0, -reconcileDays)
+		to := statement.EndDate.AddDate(0, 0, reconcileDays+1)
+		candidates, err := s.importRepository.GetUnreconciled(spenderId, account.ID, from, to)
+		if err != nil {
+			return nil, nil, errors.New("failed to get entries to reconcile")
+		}
+		matches = reconcile(preview.Rows, newRows, candidates)
+	}
+	for _, row := range preview.Rows {
+		if row.Status == RowNew {
+			preview.New++
+		}
+	}
+	preview.Matched = len(matches)
+	return preview, matches, nil
+}
+
+// PreviewJournal reads a beancount journal as CommitJournal would, without
+// saving anything.
+func (s *importService) PreviewJournal(spenderId uint, data []byte) (*StatementPreviewResponse, error) {
+	preview, _, err := s.planJournal(spenderId, data)
+	return preview, err
+}
+
+// CommitJournal adds the new entries of a beancount journal. Entries paid
+// from or into a journal account named like one of the spender's accounts,
+// as the journal export names them but in any case, are put on that account.
+func (s *importService) CommitJournal(spenderId uint, filename string, data []byte, opts JournalOptions) (*GetBatchResponse, error) {
+	preview, accounts, err := s.planJournal(spenderId, data)
+	if err != nil {
+		return nil, err
+	}
+	if preview.Invalid > 0 && !opts.SkipInvalid {
+		return nil, &InvalidRowsError{Invalid: preview.Invalid, Preview: preview}
+	}
+
+	batch := entities.ImportBatch{
+		Filename:       blobstore.SafeName(filename, FormatBeancount),
+		Format:         FormatBeancount,
+		SkippedCount:   preview.Invalid,
+		DuplicateCount: preview.Duplicate,
+		SpenderId:      int(spenderId),
+	}
+	var txns []entities.Transaction
+	for _, row := range preview.Rows {
+		if row.Status != RowNew {
+			continue
+		}
+		txn := newTransaction(spenderId, row.Record)
+		txn.FitId = row.FitId
+		if accountId, ok := accounts[strings.ToLower(row.Account)]; ok {
+			txn.AccountId = &accountId
+		}
+		txns = append(txns, txn)
+		widenPeriod(&batch, row.Date)
+	}
+	if len(txns) == 0 {
+		if preview.Duplicate > 0 {
+			return nil, ErrNothingNew
+		}
+		return nil, ErrNothingToImport
+	}
+	return s.save(batch, txns, nil)
+}
+
+// planJournal reads a journal and decides each entry's status. An entry is
+// a duplicate when the spender already has its FITID, or when it carries the
+// ID of one of the spender's transactions with the same type and amount, as
+// an exported journal read back does. It also returns the spender's accounts
+// by their lowercased journal names.
+func (s *importService) planJournal(spenderId uint, data []byte) (*StatementPreviewResponse, map[string]uint, error) {
+	statement, err := importfile.ReadBeancount(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	accounts, err := s.accountRepository.GetAccounts(spenderId)
+	if err != nil {
+		return nil, nil, errors.New("failed to get accounts")
+	}
+	accountIds := make(map[string]uint, len(accounts))
+	for _, account := range accounts {
+		name := exportfile.LedgerAccount(exportfile.Account{ID: account.ID, Name: account.Name, Kind: account.Kind})
+		accountIds[strings.ToLower(name)] = account.ID
+	}
+
+	var ids []uint
+	var fitIds []string
+	for _, record := range statement.Records {
+		if !record.Valid() {
+			continue
+		}
+		if record.SourceId != 0 {
+			ids = append(ids, record.SourceId)
+		}
+		fitIds = append(fitIds, record.FitId)
+	}
+	existing, err := s.importRepository.GetExisting(spenderId, ids, fitIds)
+	if err != nil {
+		return nil, nil, errors.New("failed to check imported rows")
+	}
+	byId := make(map[uint]entities.Transaction, len(existing))
+	seen := make(map[string]uint, len(existing))
+	for _, txn := range existing {
+		byId[txn.ID] = txn
+		if txn.FitId != "" {
+			seen[txn.FitId] = txn.ID
+		}
+	}
+
+	preview := &StatementPreviewResponse{
+		Format:    statement.Format,
+		Encoding:  statement.Encoding,
+		Currency:  statement.Currency,
+		StartDate: statement.StartDate,
+		EndDate:   statement.EndDate,
+		Total:     len(statement.Records),
+		Rows:      make([]StatementRow, 0, len(statement.Records)),
+	}
+	inFile := make(map[string]bool)
+	for _, record := range statement.Records {
+		row := StatementRow{Record: record, Status: RowNew}
+		source, exported := byId[record.SourceId]
+		exported = exported && strings.EqualFold(source.TransactionType, record.TransactionType) &&
+			math.Abs(source.Amount-record.Amount) < amountTolerance
+		switch {
+		case !record.Valid():
+			row.Status = RowInvalid
+			preview.Invalid++
+		case exported:
+			row.Status = RowDuplicate
+			row.TransactionId = source.ID
+			preview.Duplicate++
+		case seen[record.FitId] != 0:
+			row.Status = RowDuplicate
+			row.TransactionId = seen[record.FitId]
+			preview.Duplicate++
+		case inFile[record.FitId]:
+			row.Status = RowDuplicate
+			preview.Duplicate++
+		default:
+			inFile[record.FitId] = true
+			preview.New++
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+	return preview, accountIds, nil
+}
+
+// reconcile matches the rows at indexes to candidates and marks them.
+func reconcile(rows []StatementRow, indexes []int, candidates []entities.Transaction) []entities.ImportMatch {
+	used := make(map[uint]bool)
+	var matches []entities.ImportMatch
+	for _, i := range indexes {
+		row := &rows[i]
+		best := -1
+		bestDays := reconcileDays + 1
+		for j, candidate := range candidates {
+			if used[candidate.ID] || candidate.TransactionType != row.TransactionType {
+				continue
+			}
+			if math.Abs(candidate.Amount-row.Amount) >= amountTolerance {
+				continue
+			}
+			if days := daysApart(candidate.Date, row.Date); days < bestDays {
+				best, bestDays = j, days
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		candidate := candidates[best]
+		used[candidate.ID] = true
+		row.Status = RowMatched
+		row.TransactionId = candidate.ID
+		matches = append(matches, entities.ImportMatch{
+			TransactionId: candidate.ID,
+			FitId:         row.FitId,
+			AccountSet:    candidate.AccountId == nil,
+		})
+	}
+	return matches
+}
+
+// save stores a batch with its transactions and matches and refreshes net
+// worth from its first day.
+func (s *importService) save(batch entities.ImportBatch, txns []entities.Transaction, matches []entities.ImportMatch) (*GetBatchResponse, error) {
+	batch.RowCount = len(txns)
+	batch.Status = StatusCommitted
+	result, err := s.importRepository.CreateBatch(batch, txns, matches)
+	if err != nil {
+		if errors.Is(err, import_repository.ErrAlreadyReconciled) {
+			return nil, ErrReconcileConflict
+		}
+		return nil, errors.New("failed to save import")
+	}
+	s.logger.Infof("imported %d transactions as batch %d, matched %d, skipped %d", batch.RowCount, result, len(matches), batch.SkippedCount)
+	if len(txns) > 0 {
+		s.recomputeNetWorth(uint(batch.SpenderId), batch.FirstDate)
+	}
+
+	batch.ID = result
+	batch.CreatedAt = s.now()
+	return batchResponse(&batch), nil
+}
+
+func (s *importService) GetBatches(spenderId uint) ([]GetBatchResponse, error) {
+	batches, err := s.importRepository.GetBatches(spenderId)
+	if err != nil {
+		return nil, errors.New("failed to get imports")
+	}
+
+	result := make([]GetBatchResponse, 0, len(batches))
+	for _, batch := range batches {
+		result = append(result, *batchResponse(&batch))
+	}
+	return result, nil
+}
+
+func (s *importService) GetBatch(spenderId, batchId uint) (*GetBatchResponse, error) {
+	batch, err := s.getBatch(spenderId, batchId)
+	if err != nil {
+		return nil, err
+	}
+	return batchResponse(batch), nil
+}
+
+// Rollback deletes every transaction an import created, including any edited
+// since, with the files attached to them, and keeps the batch as a record
+// that it was rolled back.
+func (s *importService) Rollback(spenderId, batchId uint) (*GetBatchResponse, error) {
+	batch, err := s.getBatch(spenderId, batchId)
+	if err != nil {
+		return nil, err
+	}
+	if batch.Status == StatusRolledBack {
+		return nil, ErrRolledBack
+	}
+
+	now := s.now()
+	batch.Status = StatusRolledBack
+	batch.RolledBackAt = &now
+	deleted, blobKeys, err := s.importRepository.RollbackBatch(*batch)
+	if err != nil {
+		return nil, errors.New("failed to roll back import")
+	}
+	s.logger.Infof("rolled back import %d, deleted %d transactions", batch.ID, deleted)
+	s.deleteFiles(blobKeys)
+	s.recomputeNetWorth(spenderId, batch.FirstDate)
+	return batchResponse(batch), nil
+}
+
+// deleteFiles runs after the rollback is committed, so a failure is only
+// logged: it leaves an orphaned blob, not a half rolled back import.
+func (s *importService) deleteFiles(keys []string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.blobStore.Delete(key); err != nil {
+			s.logger.Errorf("failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// getBatch loads one of the spender's imports. Someone else's is reported as
+// missing so IDs cannot be probed.
+func (s *importService) getBatch(spenderId, batchId uint) (*entities.ImportBatch, error) {
+	batch, err := s.importRepository.GetBatch(batchId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get import")
+	}
+	if uint(batch.SpenderId) != spenderId {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return batch, nil
+}
+
+// getAccount loads one of the spender's accounts, reporting someone else's
+// as missing.
+func (s *importService) getAccount(spenderId, accountId uint) (*entities.Account, error) {
+	account, err := s.accountRepository.GetAccount(accountId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, errors.New("failed to get account")
+	}
+	if uint(account.SpenderId) != spenderId {
+		return nil, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// recomputeNetWorth refreshes the snapshots from the first day an import
+// touched up to yesterday, as for a backdated manual entry. A failure is only
+// logged since the import itself went through.
+func (s *importService) recomputeNetWorth(spenderId uint, from time.Time) {
+	now := s.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if from.IsZero() || !from.Before(today) {
+		return
+	}
+	if err := s.netWorthService.RecomputeSnapshots(spenderId, from, today.AddDate(0, 0, -1)); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+func newTransaction(spenderId uint, record importfile.Record) entities.Transaction {
+	return entities.Transaction{
+		Date:            record.Date,
+		Amount:          record.Amount,
+		Category:        record.Category,
+		TransactionType: record.TransactionType,
+		Note:            record.Note,
+		Merchant:        record.Merchant,
+		TransRef:        record.TransRef,
+		BankCode:        record.BankCode,
+		TaxId:           record.TaxId,
+		SpenderId:       int(spenderId),
+	}
+}
+
+// statementFormat tells QFX, which is OFX as Quicken names it, by the
+// file's extension.
+func statementFormat(format, filename string) string {
+	if format == FormatOFX && strings.EqualFold(path.Ext(filename), "."+FormatQFX) {
+		return FormatQFX
+	}
+	return format
+}
+
+func widenPeriod(batch *entities.ImportBatch, date time.Time) {
+	if date.IsZero() {
+		return
+	}
+	if batch.FirstDate.IsZero() || date.Before(batch.FirstDate) {
+		batch.FirstDate = date
+	}
+	if date.After(batch.LastDate) {
+		batch.LastDate = date
+	}
+}
+
+// sameAccount compares an account's number with a statement's. Statements,
+// card ones especially, often mask all but the last digits, so a masked
+// number matches on those; an account or statement without a number
+// matches anything.
+func sameAccount(number, statement string) bool {
+	number, statement = accountDigits(number), accountDigits(statement)
+	if number == "" || statement == "" {
+		return true
+	}
+	masked := strings.LastIndexAny(statement, "X*")
+	if masked < 0 {
+		return number == statement
+	}
+	visible := statement[masked+1:]
+	return visible != "" && strings.HasSuffix(number, visible)
+}
+
+func accountDigits(number string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == 'X', r == '*':
+			return r
+		case r == 'x':
+			return 'X'
+		}
+		return -1
+	}, number)
+}
+
+// daysApart counts calendar days between two dates in Bangkok.
+func daysApart(a, b time.Time) int {
+	a, b = a.In(bangkok), b.In(bangkok)
+	dayA := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	dayB := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(dayA.Sub(dayB).Hours() / 24)
+	if days < 0 {
+		return -days
+	}
+	return days
+}
+
+func batchResponse(batch *entities.ImportBatch) *GetBatchResponse {
+	return &GetBatchResponse{
+		ID:             batch.ID,
+		Filename:       batch.Filename,
+		Format:         batch.Format,
+		RowCount:       batch.RowCount,
+		SkippedCount:   batch.SkippedCount,
+		DuplicateCount: batch.DuplicateCount,
+		MatchedCount:   batch.MatchedCount,
+		AccountId:      batch.AccountId,
+		FirstDate:      batch.FirstDate,
+		LastDate:       batch.LastDate,
+		Status:         batch.Status,
+		CreatedAt:      batch.CreatedAt,
+		RolledBackAt:   batch.RolledBackAt,
+	}
+}
@@ -0,0 +1,353 @@
+package imports
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/networth"
+	"github.com/Montheankul-K/jod-jod/importfile"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type netWorthServiceStub struct {
+	networth.INetWorthService
+	from *time.Time
+}
+
+func (s *netWorthServiceStub) RecomputeSnapshots(spenderId uint, from, to time.Time) error {
+	s.from = &from
+	return nil
+}
+
+const statementCSV = "วันที่,รายการ,ถอน,ฝาก\n" +
+	"01/03/2567,ค่ากาแฟ,45.00,\n" +
+	"02/03/2567,เงินเดือน,,\"30,000.00\"\n"
+
+const statementOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>THB
+<BANKACCTFROM><BANKID>004<ACCTID>012-3-45678-9<ACCTTYPE>SAVINGS</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240301<DTEND>20240331
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240305<TRNAMT>-45.00<FITID>K1<NAME>Cafe</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240325<TRNAMT>30000.00<FITID>K2<NAME>Salary</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240328<TRNAMT>-120.00<FITID>K3<NAME>Market</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>
+`
+
+func newTestService(importRepo *mocks.ImportRepositoryMock, netWorth *netWorthServiceStub) *importService {
+	return newStatementTestService(importRepo, new(mocks.AccountRepositoryMock), netWorth)
+}
+
+func newStatementTestService(importRepo *mocks.ImportRepositoryMock, accountRepo *mocks.AccountRepositoryMock, netWorth *netWorthServiceStub) *importService {
+	service := NewImportService(importRepo, accountRepo, netWorth, nil, echo.New().Logger).(*importService)
+	service.now = func() time.Time {
+		return time.Date(2024, time.April, 1, 9, 0, 0, 0, time.UTC)
+	}
+	return service
+}
+
+func TestImportService_PreviewCSV_ProposesMapping(t *testing.T) {
+	service := newTestService(new(mocks.ImportRepositoryMock), &netWorthServiceStub{})
+
+	result, err := service.PreviewCSV([]byte(statementCSV), CSVOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, importfile.Mapping{importfile.FieldDate: 0, importfile.FieldNote: 1, importfile.FieldDebit: 2, importfile.FieldCredit: 3}, result.Mapping)
+	assert.Empty(t, result.MappingError)
+	assert.Equal(t, 2, result.Valid)
+	assert.Equal(t, 0, result.Invalid)
+	assert.Equal(t, importfile.TypeExpense, result.Rows[0].TransactionType)
+	assert.Equal(t, 30000.0, result.Rows[1].Amount)
+	assert.Equal(t, importfile.TypeIncome, result.Rows[1].TransactionType)
+}
+
+func TestImportService_PreviewCSV_UnusableMapping(t *testing.T) {
+	service := newTestService(new(mocks.ImportRepositoryMock), &netWorthServiceStub{})
+
+	result, err := service.PreviewCSV([]byte("a,b\n1,2\n"), CSVOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "mapping has no date column", result.MappingError)
+	assert.Equal(t, []string{"a", "b"}, result.Header)
+	assert.Empty(t, result.Rows)
+}
+
+func TestImportService_CommitCSV_Success(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	netWorth := &netWorthServiceStub{}
+	importRepo.On("CreateBatch", mock.MatchedBy(func(batch entities.ImportBatch) bool {
+		return batch.SpenderId == 1 && batch.RowCount == 2 && batch.Status == StatusCommitted
+	}), mock.MatchedBy(func(txns []entities.Transaction) bool {
+		return len(txns) == 2 && txns[0].Amount == 45 && txns[0].SpenderId == 1 && txns[1].TransactionType == importfile.TypeIncome
+	}), mock.Anything).Return(uint(7), nil)
+	service := newTestService(importRepo, netWorth)
+
+	result, err := service.CommitCSV(1, "statement.csv", []byte(statementCSV), CSVOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), result.ID)
+	assert.Equal(t, 2, result.RowCount)
+	assert.Equal(t, FormatCSV, result.Format)
+	assert.Equal(t, 1, result.FirstDate.Day())
+	assert.Equal(t, 2, result.LastDate.Day())
+	assert.NotNil(t, netWorth.from)
+	importRepo.AssertExpectations(t)
+}
+
+func TestImportService_CommitCSV_InvalidRows(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	service := newTestService(importRepo, &netWorthServiceStub{})
+	data := []byte(statementCSV + "not a date,ค่าข้าว,60,\n")
+
+	_, err := service.CommitCSV(1, "statement.csv", data, CSVOptions{})
+
+	var invalid *InvalidRowsError
+	assert.True(t, errors.As(err, &invalid))
+	assert.Equal(t, 1, invalid.Invalid)
+	assert.Equal(t, 4, invalid.Preview.(*PreviewResponse).Rows[2].Line)
+	importRepo.AssertNotCalled(t, "CreateBatch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportService_CommitCSV_SkipInvalid(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	importRepo.On("CreateBatch", mock.MatchedBy(func(batch entities.ImportBatch) bool {
+		return batch.RowCount == 2 && batch.SkippedCount == 1
+	}), mock.Anything, mock.Anything).Return(uint(8), nil)
+	service := newTestService(importRepo, &netWorthServiceStub{})
+	data := []byte(statementCSV + "not a date,ค่าข้าว,60,\n")
+
+	result, err := service.CommitCSV(1, "statement.csv", data, CSVOptions{SkipInvalid: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.SkippedCount)
+}
+
+func TestImportService_CommitCSV_InvalidMapping(t *testing.T) {
+	service := newTestService(new(mocks.ImportRepositoryMock), &netWorthServiceStub{})
+
+	_, err := service.CommitCSV(1, "statement.csv", []byte(statementCSV), CSVOptions{
+		Mapping: importfile.Mapping{importfile.FieldDate: 0, importfile.FieldAmount: 9},
+	})
+
+	assert.ErrorIs(t, err, ErrInvalidMapping)
+}
+
+func TestImportService_CommitCSV_NothingToImport(t *testing.T) {
+	service := newTestService(new(mocks.ImportRepositoryMock), &netWorthServiceStub{})
+
+	_, err := service.CommitCSV(1, "statement.csv", []byte("date,amount\nsoon,1\n"), CSVOptions{SkipInvalid: true})
+
+	assert.ErrorIs(t, err, ErrNothingToImport)
+}
+
+func TestImportService_Rollback_Success(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	netWorth := &netWorthServiceStub{}
+	firstDate := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	batch := &entities.ImportBatch{Model: gorm.Model{ID: 7}, Status: StatusCommitted, SpenderId: 1, FirstDate: firstDate}
+	importRepo.On("GetBatch", uint(7)).Return(batch, nil)
+	importRepo.On("RollbackBatch", mock.MatchedBy(func(req entities.ImportBatch) bool {
+		return req.Status == StatusRolledBack && req.RolledBackAt != nil
+	})).Return(int64(2), []string{"attachments/1/receipt.pdf", ""}, nil)
+	service := newTestService(importRepo, netWorth)
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+	assert.NoError(t, store.Put("attachments/1/receipt.pdf", []byte("%PDF-1.4"), "application/pdf"))
+	service.blobStore = store
+
+	result, err := service.Rollback(1, 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRolledBack, result.Status)
+	assert.Equal(t, firstDate, *netWorth.from)
+	_, err = store.Get("attachments/1/receipt.pdf")
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+	importRepo.AssertExpectations(t)
+}
+
+func TestImportService_Rollback_AlreadyRolledBack(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	importRepo.On("GetBatch", uint(7)).Return(&entities.ImportBatch{Status: StatusRolledBack, SpenderId: 1}, nil)
+	service := newTestService(importRepo, &netWorthServiceStub{})
+
+	_, err := service.Rollback(1, 7)
+
+	assert.ErrorIs(t, err, ErrRolledBack)
+	importRepo.AssertNotCalled(t, "RollbackBatch", mock.Anything)
+}
+
+func TestImportService_GetBatch_NotOwner(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	importRepo.On("GetBatch", uint(7)).Return(&entities.ImportBatch{SpenderId: 2}, nil)
+	service := newTestService(importRepo, &netWorthServiceStub{})
+
+	_, err := service.GetBatch(1, 7)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestImportService_CommitStatement_SkipsDuplicatesAndReconciles(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	accountRepo := new(mocks.AccountRepositoryMock)
+	netWorth := &netWorthServiceStub{}
+	accountRepo.On("GetAccount", uint(3)).Return(&entities.Account{Model: gorm.Model{ID: 3}, Number: "0123456789", Currency: "THB", SpenderId: 1}, nil)
+	importRepo.On("GetFitIds", uint(3), []string{"K1", "K2", "K3"}).Return([]string{"K1"}, nil)
+	manual := entities.Transaction{
+		Model:           gorm.Model{ID: 40},
+		Date:            time.Date(2024, time.March, 26, 18, 0, 0, 0, bangkok),
+		Amount:          30000,
+		TransactionType: importfile.TypeIncome,
+	}
+	importRepo.On("GetUnreconciled", uint(1), uint(3), mock.Anything, mock.Anything).Return([]entities.Transaction{manual}, nil)
+	importRepo.On("CreateBatch", mock.MatchedBy(func(batch entities.ImportBatch) bool {
+		return batch.Format == FormatOFX && batch.RowCount == 1 && batch.DuplicateCount == 1 && batch.MatchedCount == 1 && *batch.AccountId == 3
+	}), mock.MatchedBy(func(txns []entities.Transaction) bool {
+		return len(txns) == 1 && txns[0].FitId == "K3" && txns[0].Amount == 120 && *txns[0].AccountId == 3
+	}), []entities.ImportMatch{{TransactionId: 40, FitId: "K2", AccountSet: true}}).Return(uint(9), nil)
+	service := newStatementTestService(importRepo, accountRepo, netWorth)
+
+	result, err := service.CommitStatement(1, "march.ofx", []byte(statementOFX), StatementOptions{AccountId: 3})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), result.ID)
+	assert.Equal(t, 1, result.MatchedCount)
+	assert.Equal(t, 28, netWorth.from.Day())
+	importRepo.AssertExpectations(t)
+}
+
+func TestImportService_PreviewStatement_Statuses(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	accountRepo := new(mocks.AccountRepositoryMock)
+	accountRepo.On("GetAccount", uint(3)).Return(&entities.Account{Model: gorm.Model{ID: 3}, SpenderId: 1}, nil)
+	importRepo.On("GetFitIds", uint(3), mock.Anything).Return([]string{"K1"}, nil)
+	importRepo.On("GetUnreconciled", uint(1), uint(3), mock.Anything, mock.Anything).Return([]entities.Transaction{}, nil)
+	service := newStatementTestService(importRepo, accountRepo, &netWorthServiceStub{})
+
+	result, err := service.PreviewStatement(1, "march.QFX", []byte(statementOFX), StatementOptions{AccountId: 3})
+
+	assert.NoError(t, err)
+	assert.Equal(t, FormatQFX, result.Format)
+	assert.Equal(t, "012-3-45678-9", result.Account)
+	assert.Equal(t, []string{RowDuplicate, RowNew, RowNew}, []string{result.Rows[0].Status, result.Rows[1].Status, result.Rows[2].Status})
+	assert.Equal(t, 2, result.New)
+	assert.Equal(t, 1, result.Duplicate)
+}
+
+func TestImportService_CommitStatement_NothingNew(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	accountRepo := new(mocks.AccountRepositoryMock)
+	accountRepo.On("GetAccount", uint(3)).Return(&entities.Account{Model: gorm.Model{ID: 3}, SpenderId: 1}, nil)
+	importRepo.On("GetFitIds", uint(3), mock.Anything).Return([]string{"K1", "K2", "K3"}, nil)
+	service := newStatementTestService(importRepo, accountRepo, &netWorthServiceStub{})
+
+	_, err := service.CommitStatement(1, "march.ofx", []byte(statementOFX), StatementOptions{AccountId: 3})
+
+	assert.ErrorIs(t, err, ErrNothingNew)
+	importRepo.AssertNotCalled(t, "GetUnreconciled", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportService_PreviewStatement_AccountMismatch(t *testing.T) {
+	accountRepo := new(mocks.AccountRepositoryMock)
+	accountRepo.On("GetAccount", uint(3)).Return(&entities.Account{Model: gorm.Model{ID: 3}, Number: "9999999999", SpenderId: 1}, nil)
+	service := newStatementTestService(new(mocks.ImportRepositoryMock), accountRepo, &netWorthServiceStub{})
+
+	_, err := service.PreviewStatement(1, "march.ofx", []byte(statementOFX), StatementOptions{AccountId: 3})
+
+	assert.ErrorIs(t, err, ErrAccountMismatch)
+}
+
+func TestImportService_PreviewStatement_NotOwner(t *testing.T) {
+	accountRepo := new(mocks.AccountRepositoryMock)
+	accountRepo.On("GetAccount", uint(3)).Return(&entities.Account{SpenderId: 2}, nil)
+	service := newStatementTestService(new(mocks.ImportRepositoryMock), accountRepo, &netWorthServiceStub{})
+
+	_, err := service.PreviewStatement(1, "march.ofx", []byte(statementOFX), StatementOptions{AccountId: 3})
+
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+}
+
+const journal = `option "operating_currency" "THB"
+
+2024-03-05 * "Cafe" "latte"
+  jodjod-id: "12"
+  category: "food"
+  time: "08:30:00"
+  Expenses:Food  45.00 THB
+  Assets:Bank:KBank-Savings
+
+2024-03-06 * "Market"
+  fit-id: "K1"
+  Expenses:Food  120.00 THB
+  Assets:Cash
+
+2024-03-25 * "Acme" "salary"
+  Assets:Bank:KBank-Savings  30000.00 THB
+  Income:Salary
+
+2024-03-26 * "Card payment"
+  Liabilities:CreditCard:Ktc  500.00 THB
+  Assets:Bank:KBank-Savings
+`
+
+func TestImportService_CommitJournal_SkipsExportedAndMapsAccounts(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	accountRepo := new(mocks.AccountRepositoryMock)
+	netWorth := &netWorthServiceStub{}
+	accountRepo.On("GetAccounts", uint(1)).Return([]entities.Account{{Model: gorm.Model{ID: 3}, Name: "kbank savings", Kind: "bank", SpenderId: 1}}, nil)
+	exported := entities.Transaction{Model: gorm.Model{ID: 12}, Amount: 45, TransactionType: importfile.TypeExpense, SpenderId: 1}
+	importRepo.On("GetExisting", uint(1), []uint{12}, mock.Anything).Return([]entities.Transaction{exported}, nil)
+	importRepo.On("CreateBatch", mock.MatchedBy(func(batch entities.ImportBatch) bool {
+		return batch.Format == FormatBeancount && batch.RowCount == 2 && batch.DuplicateCount == 1 && batch.SkippedCount == 1 && batch.AccountId == nil
+	}), mock.MatchedBy(func(txns []entities.Transaction) bool {
+		return len(txns) == 2 && txns[0].FitId == "K1" && txns[0].AccountId == nil &&
+			txns[1].Category == "salary" && txns[1].TransactionType == importfile.TypeIncome && *txns[1].AccountId == 3
+	}), []entities.ImportMatch(nil)).Return(uint(9), nil)
+	service := newStatementTestService(importRepo, accountRepo, netWorth)
+
+	_, err := service.CommitJournal(1, "books.beancount", []byte(journal), JournalOptions{})
+	var invalid *InvalidRowsError
+	assert.ErrorAs(t, err, &invalid)
+
+	result, err := service.CommitJournal(1, "books.beancount", []byte(journal), JournalOptions{SkipInvalid: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), result.ID)
+	assert.Equal(t, 6, netWorth.from.Day())
+	importRepo.AssertExpectations(t)
+}
+
+func TestImportService_PreviewJournal_IdOfAnotherTransaction(t *testing.T) {
+	importRepo := new(mocks.ImportRepositoryMock)
+	accountRepo := new(mocks.AccountRepositoryMock)
+	accountRepo.On("GetAccounts", uint(1)).Return([]entities.Account{}, nil)
+	// The spender's transaction 12 is not the exported one, so the entry is new.
+	other := entities.Transaction{Model: gorm.Model{ID: 12}, Amount: 999, TransactionType: importfile.TypeExpense, SpenderId: 1}
+	importRepo.On("GetExisting", uint(1), []uint{12}, mock.Anything).Return([]entities.Transaction{other}, nil)
+	service := newStatementTestService(importRepo, accountRepo, &netWorthServiceStub{})
+
+	result, err := service.PreviewJournal(1, []byte(journal))
+
+	assert.NoError(t, err)
+	assert.Equal(t, FormatBeancount, result.Format)
+	assert.Equal(t, []string{RowNew, RowNew, RowNew, RowInvalid}, []string{result.Rows[0].Status, result.Rows[1].Status, result.Rows[2].Status, result.Rows[3].Status})
+	assert.Equal(t, 3, result.New)
+}
+
+func TestSameAccount(t *testing.T) {
+	assert.True(t, sameAccount("012-3-45678-9", "0123456789"))
+	assert.True(t, sameAccount("4111111111114321", "XXXXXXXXXXXX4321"))
+	assert.True(t, sameAccount("", "0123456789"))
+	assert.False(t, sameAccount("4111111111110000", "xxxx-4321"))
+	assert.False(t, sameAccount("0123456789", "0123456788"))
+}
@@ -0,0 +1,131 @@
+package imports
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/importfile"
+	"time"
+)
+
+const (
+	FormatCSV = "csv"
+	FormatOFX = importfile.FormatOFX
+	FormatQFX = "qfx"
+	FormatQIF = importfile.FormatQIF
+
+	FormatBeancount = importfile.FormatBeancount
+
+	// MaxFileSize caps an uploaded import file.
+	MaxFileSize = 10 << 20
+
+	StatusCommitted  = "committed"
+	StatusRolledBack = "rolled_back"
+
+	// What importing a statement row would do.
+	RowNew       = "new"
+	RowDuplicate = "duplicate"
+	RowMatched   = "matched"
+	RowInvalid   = "invalid"
+)
+
+var (
+	ErrInvalidMapping  = errors.New("import mapping is invalid")
+	ErrNothingToImport = errors.New("import has no valid rows")
+	ErrRolledBack      = errors.New("import is already rolled back")
+	ErrNothingNew      = errors.New("statement has no new rows")
+
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrAccountMismatch   = errors.New("statement is for another account")
+	ErrCurrencyMismatch  = errors.New("statement currency does not match the account")
+	ErrReconcileConflict = errors.New("an entry was reconciled by another import, try again")
+)
+
+// InvalidRowsError is returned when an import is committed with rows that
+// cannot be read and without skipping them. Preview lists the problems; it is
+// a *PreviewResponse or a *StatementPreviewResponse.
+type InvalidRowsError struct {
+	Invalid int
+	Preview interface{}
+}
+
+func (e *InvalidRowsError) Error() string {
+	return fmt.Sprintf("import has %d invalid rows", e.Invalid)
+}
+
+// CSVOptions say how a CSV file is read. Without a mapping, the proposed one
+// is used.
+type CSVOptions struct {
+	Mapping     importfile.Mapping `json:"mapping"`
+	DateOrder   string             `json:"date_order" validate:"omitempty,oneof=dmy mdy ymd"`
+	DefaultType string             `json:"default_type" validate:"omitempty,oneof=income expense"`
+	SkipInvalid bool               `json:"skip_invalid"`
+}
+
+// PreviewResponse is what an import would save. MappingError is set, and
+// Rows left empty, when the mapping cannot be used, so the client can show
+// the header and let the spender map it.
+type PreviewResponse struct {
+	Encoding     string              `json:"encoding"`
+	Delimiter    string              `json:"delimiter"`
+	Header       []string            `json:"header"`
+	Mapping      importfile.Mapping  `json:"mapping"`
+	MappingError string              `json:"mapping_error,omitempty"`
+	Total        int                 `json:"total"`
+	Valid        int                 `json:"valid"`
+	Invalid      int                 `json:"invalid"`
+	Rows         []importfile.Record `json:"rows"`
+}
+
+// StatementOptions say which account a statement is imported into and how
+// to read its dates where the format leaves that open.
+type StatementOptions struct {
+	AccountId   uint   `json:"account_id" validate:"required"`
+	DateOrder   string `json:"date_order" validate:"omitempty,oneof=dmy mdy ymd"`
+	SkipInvalid bool   `json:"skip_invalid"`
+}
+
+// JournalOptions say how a beancount journal is imported.
+type JournalOptions struct {
+	SkipInvalid bool `json:"skip_invalid"`
+}
+
+// StatementRow is a statement entry and what importing it would do. A
+// matched row is reconciled with the manual entry TransactionId instead of
+// being added; a journal row that was exported from jod-jod is a duplicate
+// of TransactionId.
+type StatementRow struct {
+	importfile.Record
+	Status        string `json:"status"`
+	TransactionId uint   `json:"transaction_id,omitempty"`
+}
+
+type StatementPreviewResponse struct {
+	Format    string         `json:"format"`
+	Encoding  string         `json:"encoding"`
+	Account   string         `json:"account"`
+	Currency  string         `json:"currency"`
+	StartDate time.Time      `json:"start_date"`
+	EndDate   time.Time      `json:"end_date"`
+	Total     int            `json:"total"`
+	New       int            `json:"new"`
+	Duplicate int            `json:"duplicate"`
+	Matched   int            `json:"matched"`
+	Invalid   int            `json:"invalid"`
+	Rows      []StatementRow `json:"rows"`
+}
+
+type GetBatchResponse struct {
+	ID             uint       `json:"id"`
+	Filename       string     `json:"filename"`
+	Format         string     `json:"format"`
+	RowCount       int        `json:"row_count"`
+	SkippedCount   int        `json:"skipped_count"`
+	DuplicateCount int        `json:"duplicate_count"`
+	MatchedCount   int        `json:"matched_count"`
+	AccountId      *uint      `json:"account_id,omitempty"`
+	FirstDate      time.Time  `json:"first_date"`
+	LastDate       time.Time  `json:"last_date"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	RolledBackAt   *time.Time `json:"rolled_back_at,omitempty"`
+}
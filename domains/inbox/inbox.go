@@ -0,0 +1,39 @@
+package inbox
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the largest email accepted when the config sets none.
+	DefaultMaxSize = 10 << 20
+
+	StatusDraft     = "draft"
+	StatusSaved     = "saved"
+	StatusDiscarded = "discarded"
+)
+
+var (
+	// ErrUnknownRecipient is returned when no recipient of an email is a
+	// spender's inbox address.
+	ErrUnknownRecipient = errors.New("inbox address not found")
+	ErrTooLarge         = errors.New("email is too large")
+	ErrNotDraft         = errors.New("email is not a draft")
+)
+
+type GetInboxResponse struct {
+	Address string `json:"address"`
+}
+
+type GetEmailResponse struct {
+	ID            uint                    `json:"id"`
+	Sender        string                  `json:"sender"`
+	Subject       string                  `json:"subject"`
+	ReceivedAt    time.Time               `json:"received_at"`
+	Template      string                  `json:"template"`
+	Status        string                  `json:"status"`
+	Draft         *transaction.EmailDraft `json:"draft,omitempty"`
+	TransactionId *uint                   `json:"transaction_id,omitempty"`
+}
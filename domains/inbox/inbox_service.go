@@ -0,0 +1,422 @@
+package inbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/domains/attachment"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"github.com/Montheankul-K/jod-jod/mailparse"
+	"github.com/Montheankul-K/jod-jod/repository/attachment_repository"
+	"github.com/Montheankul-K/jod-jod/repository/inbox_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// tokenBytes gives 16 hex characters, too many to guess.
+	tokenBytes = 8
+	// Column sizes of the email and transaction fields copied from a message.
+	headerSize   = 255
+	merchantSize = 100
+	transRefSize = 50
+)
+
+type IInboxService interface {
+	GetInbox(spenderId uint) (*GetInboxResponse, error)
+	RotateToken(spenderId uint) (*GetInboxResponse, error)
+	Receive(recipients []string, raw []byte) (*GetEmailResponse, error)
+	GetDrafts(spenderId uint) ([]GetEmailResponse, error)
+	GetEmail(spenderId, emailId uint) (*GetEmailResponse, error)
+	GetRaw(spenderId, emailId uint) (io.ReadCloser, error)
+	CorrectDraft(spenderId, emailId uint, correction transaction.DraftCorrection) (*GetEmailResponse, error)
+	ApproveDraft(spenderId, emailId uint, force bool) (uint, error)
+	DiscardDraft(spenderId, emailId uint) error
+}
+
+type inboxService struct {
+	domain               string
+	maxSize              int64
+	inboxRepository      inbox_repository.IInboxRepository
+	attachmentRepository attachment_repository.IAttachmentRepository
+	transactionService   transaction.ITransactionService
+	blobStore            blobstore.BlobStore
+	logger               echo.Logger
+	now                  func() time.Time
+}
+
+func NewInboxService(cfg *config.Inbox, inboxRepository inbox_repository.IInboxRepository, attachmentRepository attachment_repository.IAttachmentRepository, transactionService transaction.ITransactionService, blobStore blobstore.BlobStore, logger echo.Logger) IInboxService {
+	maxSize := int64(DefaultMaxSize)
+	if cfg.MaxSize > 0 {
+		maxSize = cfg.MaxSize
+	}
+	return &inboxService{
+		domain:               strings.ToLower(cfg.Domain),
+		maxSize:              maxSize,
+		inboxRepository:      inboxRepository,
+		attachmentRepository: attachmentRepository,
+		transactionService:   transactionService,
+		blobStore:            blobStore,
+		logger:               logger,
+		now:                  time.Now,
+	}
+}
+
+// GetInbox returns the spender's inbox address, giving them one on first use.
+func (s *inboxService) GetInbox(spenderId uint) (*GetInboxResponse, error) {
+	token, err := s.inboxRepository.GetToken(spenderId)
+	if err == nil {
+		return s.inboxResponse(token.Token), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("failed to get inbox")
+	}
+	return s.RotateToken(spenderId)
+}
+
+// RotateToken gives the spender a new inbox address. Mail to the old one is
+// refused from then on, which is the way to stop spam reaching it.
+func (s *inboxService) RotateToken(spenderId uint) (*GetInboxResponse, error) {
+	value, err := newToken()
+	if err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to create inbox address")
+	}
+	if err = s.inboxRepository.SaveToken(entities.InboxToken{Token: value, SpenderId: int(spenderId)}); err != nil {
+		return nil, errors.New("failed to create inbox address")
+	}
+	s.logger.Infof("inbox address of spender %d rotated", spenderId)
+	return s.inboxResponse(value), nil
+}
+
+func (s *inboxService) inboxResponse(token string) *GetInboxResponse {
+	return &GetInboxResponse{Address: token + "@" + s.domain}
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Receive stores an email sent to an inbox address and reads a draft
+// transaction from it. The envelope recipients come first, since a forward
+// keeps the original To header; the message's own recipient headers are
+// tried after them. An email already received, by its Message-ID, is
+// returned as it was rather than stored again, so a retried delivery is
+// harmless.
+func (s *inboxService) Receive(recipients []string, raw []byte) (*GetEmailResponse, error) {
+	if int64(len(raw)) > s.maxSize {
+		return nil, ErrTooLarge
+	}
+	msg, err := mailparse.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.findToken(append(recipients, msg.Recipients...))
+	if err != nil {
+		return nil, err
+	}
+	spenderId := uint(token.SpenderId)
+
+	if msg.MessageId != "" {
+		existing, err := s.inboxRepository.GetEmailByMessageId(spenderId, msg.MessageId)
+		if err == nil {
+			s.logger.Infof("email %s already received as %d", msg.MessageId, existing.ID)
+			return emailResponse(existing), nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("failed to receive email")
+		}
+	}
+
+	receivedAt := s.now()
+	draft, template := readDraft(msg, receivedAt)
+	encoded, _ := json.Marshal(draft)
+
+	key := fmt.Sprintf("inbox/%d/%d.eml", spenderId, receivedAt.UnixNano())
+	if err = s.blobStore.Put(key, raw, "message/rfc822"); err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to store email")
+	}
+
+	email := entities.InboundEmail{
+		MessageId:  truncate(msg.MessageId, headerSize),
+		Sender:     truncate(msg.From, headerSize),
+		Subject:    truncate(msg.Subject, headerSize),
+		ReceivedAt: receivedAt,
+		BlobKey:    key,
+		Size:       int64(len(raw)),
+		Template:   template,
+		Status:     StatusDraft,
+		Draft:      string(encoded),
+		SpenderId:  int(spenderId),
+	}
+	result, err := s.inboxRepository.CreateEmail(email)
+	if err != nil {
+		s.deleteBlob(key)
+		return nil, errors.New("failed to receive email")
+	}
+	s.logger.Infof("received email with ID: %d from %s using template %s", result, msg.From, template)
+
+	email.ID = result
+	return emailResponse(&email), nil
+}
+
+// findToken returns the inbox token of the first recipient at the inbox
+// domain that has one. Both token@domain and name+token@domain are accepted,
+// so the address can be given a label a person recognizes.
+func (s *inboxService) findToken(recipients []string) (*entities.InboxToken, error) {
+	for _, recipient := range recipients {
+		local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(recipient)), "@")
+		if !ok || domain != s.domain {
+			continue
+		}
+		if i := strings.LastIndex(local, "+"); i >= 0 {
+			local = local[i+1:]
+		}
+		if local == "" {
+			continue
+		}
+
+		token, err := s.inboxRepository.GetTokenByValue(local)
+		if err == nil {
+			return token, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("failed to find inbox")
+		}
+	}
+	return nil, ErrUnknownRecipient
+}
+
+// readDraft reads the payment in a message. An email whose amount cannot be
+// found still becomes a draft, flagged for the spender to fill in.
+func readDraft(msg *mailparse.Message, receivedAt time.Time) (transaction.EmailDraft, string) {
+	notice, err := mailparse.Extract(msg)
+	draft := transaction.EmailDraft{
+		Date:            notice.Date,
+		Amount:          notice.Amount,
+		TransactionType: notice.TransactionType,
+		Category:        notice.Category,
+		Merchant:        truncate(notice.Merchant, merchantSize),
+		BankCode:        notice.BankCode,
+		TransRef:        emailRef(notice),
+	}
+	if errors.Is(err, mailparse.ErrAmountNotFound) {
+		draft.Issues = append(draft.Issues, transaction.IssueAmountMissing)
+	}
+	if draft.Date.IsZero() {
+		draft.Date = receivedAt
+		draft.Issues = append(draft.Issues, transaction.IssueDateMissing)
+	}
+	return draft, notice.Template
+}
+
+// emailRef is the reference duplicates are found by. A bank's reference is
+// the one its slip carries, so a transfer saved from both a slip and an email
+// is caught. A shop's order number is only unique within the shop.
+func emailRef(notice *mailparse.Notice) string {
+	if notice.Reference == "" {
+		return ""
+	}
+	if notice.Kind == mailparse.KindTransfer {
+		return truncate(notice.Reference, transRefSize)
+	}
+	return truncate(notice.Template+":"+notice.Reference, transRefSize)
+}
+
+func (s *inboxService) GetDrafts(spenderId uint) ([]GetEmailResponse, error) {
+	emails, err := s.inboxRepository.GetEmailsByStatus(spenderId, StatusDraft)
+	if err != nil {
+		return nil, errors.New("failed to get email drafts")
+	}
+
+	result := make([]GetEmailResponse, 0, len(emails))
+	for _, email := range emails {
+		result = append(result, *emailResponse(&email))
+	}
+	return result, nil
+}
+
+func (s *inboxService) GetEmail(spenderId, emailId uint) (*GetEmailResponse, error) {
+	email, err := s.getEmail(spenderId, emailId)
+	if err != nil {
+		return nil, err
+	}
+	return emailResponse(email), nil
+}
+
+// GetRaw opens the email as it was received; the caller closes it. The email
+// of a saved draft lives on as the transaction's attachment, and is gone once
+// that is deleted.
+func (s *inboxService) GetRaw(spenderId, emailId uint) (io.ReadCloser, error) {
+	email, err := s.getEmail(spenderId, emailId)
+	if err != nil {
+		return nil, err
+	}
+	if email.Status == StatusDiscarded {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	body, err := s.blobStore.Get(email.BlobKey)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		s.logger.Error(err)
+		return nil, errors.New("failed to get email")
+	}
+	return body, nil
+}
+
+func (s *inboxService) CorrectDraft(spenderId, emailId uint, correction transaction.DraftCorrection) (*GetEmailResponse, error) {
+	email, err := s.getDraft(spenderId, emailId)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := emailDraft(email)
+	draft.Apply(correction)
+	encoded, _ := json.Marshal(draft)
+	email.Draft = string(encoded)
+	if err = s.inboxRepository.UpdateEmail(*email); err != nil {
+		return nil, errors.New("failed to correct email draft")
+	}
+	return emailResponse(email), nil
+}
+
+// ApproveDraft saves the draft as a transaction with the email attached. A
+// draft matching a saved transaction is saved only with force.
+func (s *inboxService) ApproveDraft(spenderId, emailId uint, force bool) (uint, error) {
+	email, err := s.getDraft(spenderId, emailId)
+	if err != nil {
+		return 0, err
+	}
+
+	draft := emailDraft(email)
+	if draft.Amount <= 0 {
+		return 0, transaction.ErrDraftIncomplete
+	}
+	txnId, err := s.transactionService.SaveEmail(spenderId, draft, force)
+	if err != nil {
+		return 0, err
+	}
+
+	// The transaction is saved by now, so a missing attachment is only
+	// logged; the email can still be read from the inbox.
+	file := entities.Attachment{
+		TransactionId: txnId,
+		Filename:      blobstore.SafeName(email.Subject+".eml", "email.eml"),
+		ContentType:   "message/rfc822",
+		Size:          email.Size,
+		BlobKey:       email.BlobKey,
+		ScanStatus:    attachment.ScanSkipped,
+		SpenderId:     int(spenderId),
+	}
+	if _, err = s.attachmentRepository.SaveAttachment(file); err != nil {
+		s.logger.Errorf("failed to attach email %d to transaction %d: %v", email.ID, txnId, err)
+	}
+
+	email.Status = StatusSaved
+	email.TransactionId = &txnId
+	if err = s.inboxRepository.UpdateEmail(*email); err != nil {
+		s.logger.Errorf("failed to update email %d: %v", email.ID, err)
+	}
+	s.logger.Infof("email %d saved as transaction %d", email.ID, txnId)
+	return txnId, nil
+}
+
+// DiscardDraft drops an email that holds no payment, and the stored copy
+// with it.
+func (s *inboxService) DiscardDraft(spenderId, emailId uint) error {
+	email, err := s.getDraft(spenderId, emailId)
+	if err != nil {
+		return err
+	}
+
+	email.Status = StatusDiscarded
+	if err = s.inboxRepository.UpdateEmail(*email); err != nil {
+		return errors.New("failed to discard email draft")
+	}
+	s.deleteBlob(email.BlobKey)
+	s.logger.Infof("email %d discarded", email.ID)
+	return nil
+}
+
+// getEmail loads one of the spender's emails. Someone else's is reported as
+// missing so IDs cannot be probed.
+func (s *inboxService) getEmail(spenderId, emailId uint) (*entities.InboundEmail, error) {
+	email, err := s.inboxRepository.GetEmail(emailId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get email")
+	}
+	if uint(email.SpenderId) != spenderId {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return email, nil
+}
+
+func (s *inboxService) getDraft(spenderId, emailId uint) (*entities.InboundEmail, error) {
+	email, err := s.getEmail(spenderId, emailId)
+	if err != nil {
+		return nil, err
+	}
+	if email.Status != StatusDraft {
+		return nil, ErrNotDraft
+	}
+	return email, nil
+}
+
+func (s *inboxService) deleteBlob(key string) {
+	if err := s.blobStore.Delete(key); err != nil {
+		s.logger.Errorf("failed to delete email %s: %v", key, err)
+	}
+}
+
+func emailDraft(email *entities.InboundEmail) transaction.EmailDraft {
+	var draft transaction.EmailDraft
+	if email.Draft != "" {
+		_ = json.Unmarshal([]byte(email.Draft), &draft)
+	}
+	return draft
+}
+
+func emailResponse(email *entities.InboundEmail) *GetEmailResponse {
+	result := &GetEmailResponse{
+		ID:            email.ID,
+		Sender:        email.Sender,
+		Subject:       email.Subject,
+		ReceivedAt:    email.ReceivedAt,
+		Template:      email.Template,
+		Status:        email.Status,
+		TransactionId: email.TransactionId,
+	}
+	if email.Draft != "" {
+		draft := emailDraft(email)
+		result.Draft = &draft
+	}
+	return result
+}
+
+func truncate(text string, size int) string {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text
+	}
+	return string(runes[:size])
+}
@@ -0,0 +1,216 @@
+package inbox
+
+import (
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"github.com/Montheankul-K/jod-jod/mailparse"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const grabEmail = "From: Grab <no-reply@grab.com>\r\n" +
+	"To: someone@example.com\r\n" +
+	"Subject: Your Grab E-Receipt\r\n" +
+	"Date: Fri, 15 Mar 2024 18:45:00 +0700\r\n" +
+	"Message-ID: <grab-1@grab.com>\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"Booking ID: A-5XKQ2T9GW\r\n" +
+	"Total Paid: THB 154.00\r\n"
+
+// transactionServiceStub stands in for the email step of the transaction
+// service; any other method panics.
+type transactionServiceStub struct {
+	transaction.ITransactionService
+	saveErr   error
+	savedWith *transaction.EmailDraft
+}
+
+func (s *transactionServiceStub) SaveEmail(spenderId uint, draft transaction.EmailDraft, force bool) (uint, error) {
+	s.savedWith = &draft
+	if s.saveErr != nil {
+		return 0, s.saveErr
+	}
+	return 42, nil
+}
+
+var now = time.Date(2024, 3, 16, 9, 0, 0, 0, time.UTC)
+
+func newTestService(t *testing.T, inboxRepo *mocks.InboxRepositoryMock, attachmentRepo *mocks.AttachmentRepositoryMock, txn *transactionServiceStub) (*inboxService, blobstore.BlobStore) {
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+	cfg := &config.Inbox{Domain: "In.Example.com", MaxSize: 4096}
+	service := NewInboxService(cfg, inboxRepo, attachmentRepo, txn, store, echo.New().Logger).(*inboxService)
+	service.now = func() time.Time { return now }
+	return service, store
+}
+
+func TestInboxService_GetInbox_CreatesToken(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetToken", uint(1)).Return((*entities.InboxToken)(nil), gorm.ErrRecordNotFound)
+	inboxRepo.On("SaveToken", mock.MatchedBy(func(token entities.InboxToken) bool {
+		return token.SpenderId == 1 && len(token.Token) == 2*tokenBytes
+	})).Return(nil)
+	service, _ := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	result, err := service.GetInbox(1)
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(result.Address, "@in.example.com"))
+	inboxRepo.AssertExpectations(t)
+}
+
+func TestInboxService_Receive_Success(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetTokenByValue", "abc123").Return(&entities.InboxToken{Token: "abc123", SpenderId: 1}, nil)
+	inboxRepo.On("GetEmailByMessageId", uint(1), "grab-1@grab.com").Return((*entities.InboundEmail)(nil), gorm.ErrRecordNotFound)
+	var created entities.InboundEmail
+	inboxRepo.On("CreateEmail", mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(0).(entities.InboundEmail)
+	}).Return(uint(9), nil)
+	service, store := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	result, err := service.Receive([]string{"receipts+ABC123@in.example.com"}, []byte(grabEmail))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), result.ID)
+	assert.Equal(t, StatusDraft, result.Status)
+	assert.Equal(t, "grab", result.Template)
+	assert.Equal(t, float64(154), result.Draft.Amount)
+	assert.Equal(t, "Grab", result.Draft.Merchant)
+	assert.Equal(t, "grab:A-5XKQ2T9GW", result.Draft.TransRef)
+	assert.Empty(t, result.Draft.Issues)
+	assert.True(t, strings.HasPrefix(created.BlobKey, "inbox/1/"))
+	body, err := store.Get(created.BlobKey)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, grabEmail, string(data))
+}
+
+func TestInboxService_Receive_AlreadyReceived(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetTokenByValue", "abc123").Return(&entities.InboxToken{Token: "abc123", SpenderId: 1}, nil)
+	existing := &entities.InboundEmail{Status: StatusSaved, SpenderId: 1}
+	existing.ID = 9
+	inboxRepo.On("GetEmailByMessageId", uint(1), "grab-1@grab.com").Return(existing, nil)
+	service, _ := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	result, err := service.Receive([]string{"abc123@in.example.com"}, []byte(grabEmail))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), result.ID)
+	inboxRepo.AssertNotCalled(t, "CreateEmail", mock.Anything)
+}
+
+func TestInboxService_Receive_UnknownRecipient(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetTokenByValue", "nobody").Return((*entities.InboxToken)(nil), gorm.ErrRecordNotFound)
+	service, _ := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	_, err := service.Receive([]string{"nobody@in.example.com", "abc123@elsewhere.com"}, []byte(grabEmail))
+
+	assert.ErrorIs(t, err, ErrUnknownRecipient)
+	inboxRepo.AssertNotCalled(t, "GetTokenByValue", "abc123")
+}
+
+func TestInboxService_Receive_Invalid(t *testing.T) {
+	service, _ := newTestService(t, new(mocks.InboxRepositoryMock), new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	_, err := service.Receive([]string{"abc123@in.example.com"}, []byte("not an email"))
+
+	assert.ErrorIs(t, err, mailparse.ErrInvalidMessage)
+}
+
+func TestInboxService_Receive_TooLarge(t *testing.T) {
+	service, _ := newTestService(t, new(mocks.InboxRepositoryMock), new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	_, err := service.Receive([]string{"abc123@in.example.com"}, []byte(strings.Repeat("a", 5000)))
+
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestInboxService_ApproveDraft_Success(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	email := &entities.InboundEmail{
+		Subject:   "Your Grab E-Receipt",
+		BlobKey:   "inbox/1/1.eml",
+		Size:      300,
+		Status:    StatusDraft,
+		Draft:     `{"amount":154,"category":"transport","transaction_type":"expense","merchant":"Grab"}`,
+		SpenderId: 1,
+	}
+	email.ID = 9
+	inboxRepo.On("GetEmail", uint(9)).Return(email, nil)
+	var updated entities.InboundEmail
+	inboxRepo.On("UpdateEmail", mock.Anything).Run(func(args mock.Arguments) {
+		updated = args.Get(0).(entities.InboundEmail)
+	}).Return(nil)
+	attachmentRepo := new(mocks.AttachmentRepositoryMock)
+	attachmentRepo.On("SaveAttachment", mock.MatchedBy(func(file entities.Attachment) bool {
+		return file.TransactionId == 42 && file.BlobKey == "inbox/1/1.eml" && file.ContentType == "message/rfc822" && file.Filename == "Your_Grab_E-Receipt.eml"
+	})).Return(uint(3), nil)
+	txn := &transactionServiceStub{}
+	service, _ := newTestService(t, inboxRepo, attachmentRepo, txn)
+
+	result, err := service.ApproveDraft(1, 9, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), result)
+	assert.Equal(t, "Grab", txn.savedWith.Merchant)
+	assert.Equal(t, StatusSaved, updated.Status)
+	assert.Equal(t, uint(42), *updated.TransactionId)
+	attachmentRepo.AssertExpectations(t)
+}
+
+func TestInboxService_ApproveDraft_Incomplete(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetEmail", uint(9)).Return(&entities.InboundEmail{Status: StatusDraft, Draft: `{"issues":["amount_missing"]}`, SpenderId: 1}, nil)
+	service, _ := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	_, err := service.ApproveDraft(1, 9, false)
+
+	assert.ErrorIs(t, err, transaction.ErrDraftIncomplete)
+}
+
+func TestInboxService_ApproveDraft_Duplicate(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetEmail", uint(9)).Return(&entities.InboundEmail{Status: StatusDraft, Draft: `{"amount":154}`, SpenderId: 1}, nil)
+	txn := &transactionServiceStub{saveErr: &transaction.DuplicateError{TransactionId: 5, Reason: transaction.DuplicateReasonReference}}
+	service, _ := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), txn)
+
+	_, err := service.ApproveDraft(1, 9, false)
+
+	var duplicate *transaction.DuplicateError
+	assert.ErrorAs(t, err, &duplicate)
+	inboxRepo.AssertNotCalled(t, "UpdateEmail", mock.Anything)
+}
+
+func TestInboxService_DiscardDraft_NotDraft(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetEmail", uint(9)).Return(&entities.InboundEmail{Status: StatusSaved, SpenderId: 1}, nil)
+	service, _ := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	err := service.DiscardDraft(1, 9)
+
+	assert.ErrorIs(t, err, ErrNotDraft)
+}
+
+func TestInboxService_GetEmail_OtherSpender(t *testing.T) {
+	inboxRepo := new(mocks.InboxRepositoryMock)
+	inboxRepo.On("GetEmail", uint(9)).Return(&entities.InboundEmail{Status: StatusDraft, SpenderId: 2}, nil)
+	service, _ := newTestService(t, inboxRepo, new(mocks.AttachmentRepositoryMock), &transactionServiceStub{})
+
+	_, err := service.GetEmail(1, 9)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,21 @@
+package insight
+
+import "time"
+
+const (
+	AnomalyKindTransaction   = "transaction"
+	AnomalyKindCategoryMonth = "category_month"
+)
+
+type GetAnomalyResponse struct {
+	ID            uint      `json:"id"`
+	TransactionId uint      `json:"transaction_id"`
+	Kind          string    `json:"kind"`
+	Category      string    `json:"category"`
+	Month         string    `json:"month,omitempty"`
+	Amount        float64   `json:"amount"`
+	Baseline      float64   `json:"baseline"`
+	Ratio         float64   `json:"ratio"`
+	Reason        string    `json:"reason"`
+	DetectedAt    time.Time `json:"detected_at"`
+}
@@ -0,0 +1,235 @@
+package insight
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/insight_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// minBaselineSamples is the fewest past expenses in a category before a
+	// single transaction can be judged against them.
+	minBaselineSamples = 5
+	// minBaselineMonths is the fewest past months with spending in a category
+	// before a monthly total can be judged against them.
+	minBaselineMonths = 3
+	// transactionRatioThreshold flags any expense this many times the median.
+	transactionRatioThreshold = 5.0
+	// transactionMinRatio is the smallest ratio a statistically unusual expense
+	// must also reach, so tightly clustered categories do not flag small bumps.
+	transactionMinRatio = 2.0
+	// monthSpikeRatio is the smallest month-over-median ratio treated as a spike.
+	monthSpikeRatio = 2.0
+	// robustZThreshold is the modified z-score cut-off from Iglewicz and Hoaglin.
+	robustZThreshold = 3.5
+)
+
+type IInsightService interface {
+	AnalyzeTransaction(txnId uint, txn entities.Transaction) error
+	GetAnomalies(spenderId uint) ([]GetAnomalyResponse, error)
+}
+
+type insightService struct {
+	transactionRepository transaction_repository.ITransactionRepository
+	insightRepository     insight_repository.IInsightRepository
+	logger                echo.Logger
+}
+
+func NewInsightService(transactionRepository transaction_repository.ITransactionRepository, insightRepository insight_repository.IInsightRepository, logger echo.Logger) IInsightService {
+	return &insightService{
+		transactionRepository: transactionRepository,
+		insightRepository:     insightRepository,
+		logger:                logger,
+	}
+}
+
+// AnalyzeTransaction compares a newly saved expense against the spender's
+// history in the same category using the median and median absolute deviation,
+// which a handful of earlier outliers cannot drag upwards the way a mean would.
+func (s *insightService) AnalyzeTransaction(txnId uint, txn entities.Transaction) error {
+	if strings.ToLower(txn.TransactionType) != "expense" {
+		return nil
+	}
+
+	history, err := s.transactionRepository.GetAllBySpenderId(uint(txn.SpenderId))
+	if err != nil {
+		return errors.New("failed to get transaction")
+	}
+
+	var amounts []float64
+	monthTotals := make(map[string]float64)
+	for _, value := range history {
+		if value.ID == txnId || value.Date == nil {
+			continue
+		}
+		if strings.ToLower(value.TransactionType) != "expense" || value.Category != txn.Category {
+			continue
+		}
+		amounts = append(amounts, value.Amount)
+		monthTotals[value.Date.Format("2006-01")] += value.Amount
+	}
+
+	if anomaly := detectTransactionAnomaly(txn.Amount, txn.Category, amounts); anomaly != nil {
+		anomaly.TransactionId = txnId
+		anomaly.SpenderId = txn.SpenderId
+		if _, err = s.insightRepository.SaveAnomaly(*anomaly); err != nil {
+			return errors.New("failed to save anomaly")
+		}
+		s.logger.Infof("flagged transaction id: %d as anomaly", txnId)
+	}
+
+	month := txn.Date.Format("2006-01")
+	monthTotals[month] += txn.Amount
+	anomaly := detectMonthlyAnomaly(month, txn.Category, monthTotals)
+	if anomaly == nil {
+		return nil
+	}
+
+	existing, err := s.insightRepository.GetMonthlyAnomaly(uint(txn.SpenderId), txn.Category, month)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("failed to get anomaly")
+	}
+	if existing != nil {
+		anomaly.Model = existing.Model
+	}
+	anomaly.TransactionId = txnId
+	anomaly.SpenderId = txn.SpenderId
+	if _, err = s.insightRepository.SaveAnomaly(*anomaly); err != nil {
+		return errors.New("failed to save anomaly")
+	}
+	s.logger.Infof("flagged %s spending of spender id: %d in %s as anomaly", txn.Category, txn.SpenderId, month)
+	return nil
+}
+
+func detectTransactionAnomaly(amount float64, category string, history []float64) *entities.TransactionAnomaly {
+	if len(history) < minBaselineSamples {
+		return nil
+	}
+
+	med := median(history)
+	if med <= 0 {
+		return nil
+	}
+	ratio := amount / med
+	z := robustZ(amount, med, medianAbsoluteDeviation(history, med))
+	if ratio < transactionRatioThreshold && !(ratio >= transactionMinRatio && z > robustZThreshold) {
+		return nil
+	}
+
+	return &entities.TransactionAnomaly{
+		Kind:     AnomalyKindTransaction,
+		Category: category,
+		Amount:   amount,
+		Baseline: roundAmount(med),
+		Ratio:    roundAmount(ratio),
+		Reason: fmt.Sprintf("amount %.2f is %.1fx the median %.2f of your last %d %s expenses",
+			amount, ratio, med, len(history), category),
+	}
+}
+
+func detectMonthlyAnomaly(month, category string, monthTotals map[string]float64) *entities.TransactionAnomaly {
+	var previous []float64
+	for key, total := range monthTotals {
+		if key < month {
+			previous = append(previous, total)
+		}
+	}
+	if len(previous) < minBaselineMonths {
+		return nil
+	}
+
+	med := median(previous)
+	if med <= 0 {
+		return nil
+	}
+	total := monthTotals[month]
+	ratio := total / med
+	if ratio < monthSpikeRatio {
+		return nil
+	}
+	if mad := medianAbsoluteDeviation(previous, med); mad > 0 && robustZ(total, med, mad) <= robustZThreshold {
+		return nil
+	}
+
+	return &entities.TransactionAnomaly{
+		Kind:     AnomalyKindCategoryMonth,
+		Category: category,
+		Month:    month,
+		Amount:   roundAmount(total),
+		Baseline: roundAmount(med),
+		Ratio:    roundAmount(ratio),
+		Reason: fmt.Sprintf("%s spending in %s is %.2f, %.1fx the median monthly total %.2f over %d previous months",
+			category, month, total, ratio, med, len(previous)),
+	}
+}
+
+func (s *insightService) GetAnomalies(spenderId uint) ([]GetAnomalyResponse, error) {
+	results, err := s.insightRepository.GetAnomaliesBySpenderId(spenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get anomalies")
+	}
+	s.logger.Infof("get anomalies of spender id: %d success", spenderId)
+
+	var newResults []GetAnomalyResponse
+	for _, value := range results {
+		result := GetAnomalyResponse{
+			ID:            value.ID,
+			TransactionId: value.TransactionId,
+			Kind:          value.Kind,
+			Category:      value.Category,
+			Month:         value.Month,
+			Amount:        value.Amount,
+			Baseline:      value.Baseline,
+			Ratio:         value.Ratio,
+			Reason:        value.Reason,
+			DetectedAt:    value.UpdatedAt,
+		}
+		newResults = append(newResults, result)
+	}
+	return newResults, nil
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func medianAbsoluteDeviation(values []float64, med float64) float64 {
+	deviations := make([]float64, 0, len(values))
+	for _, value := range values {
+		deviations = append(deviations, math.Abs(value-med))
+	}
+	return median(deviations)
+}
+
+func robustZ(value, med, mad float64) float64 {
+	if mad == 0 {
+		if value > med {
+			return math.Inf(1)
+		}
+		return 0
+	}
+	return 0.6745 * (value - med) / mad
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,145 @@
+package insight
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func foodHistory(date time.Time, amounts ...float64) []entities.GetAllResponse {
+	var results []entities.GetAllResponse
+	for i, amount := range amounts {
+		results = append(results, entities.GetAllResponse{
+			ID: uint(i + 1), Date: &date, Amount: amount, Category: "food", TransactionType: "expense",
+		})
+	}
+	return results
+}
+
+func TestInsightService_AnalyzeTransaction_FlagsLargeExpense(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockInsightRepo := new(mocks.InsightRepositoryMock)
+	logger := echo.New().Logger
+
+	date := time.Now()
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(foodHistory(date, 100, 120, 90, 110, 100), nil)
+	mockInsightRepo.On("SaveAnomaly", mock.MatchedBy(func(anomaly entities.TransactionAnomaly) bool {
+		return anomaly.Kind == AnomalyKindTransaction && anomaly.TransactionId == uint(10) && anomaly.Baseline == 100
+	})).Return(uint(1), nil)
+	service := NewInsightService(mockTxnRepo, mockInsightRepo, logger)
+
+	txn := entities.Transaction{Date: date, Amount: 600, Category: "food", TransactionType: "expense", SpenderId: 1}
+	err := service.AnalyzeTransaction(uint(10), txn)
+
+	assert.NoError(t, err)
+	mockInsightRepo.AssertNumberOfCalls(t, "SaveAnomaly", 1)
+}
+
+func TestInsightService_AnalyzeTransaction_NormalExpense(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockInsightRepo := new(mocks.InsightRepositoryMock)
+	logger := echo.New().Logger
+
+	date := time.Now()
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(foodHistory(date, 100, 120, 90, 110, 100), nil)
+	service := NewInsightService(mockTxnRepo, mockInsightRepo, logger)
+
+	txn := entities.Transaction{Date: date, Amount: 130, Category: "food", TransactionType: "expense", SpenderId: 1}
+	err := service.AnalyzeTransaction(uint(10), txn)
+
+	assert.NoError(t, err)
+	mockInsightRepo.AssertNotCalled(t, "SaveAnomaly", mock.Anything)
+}
+
+func TestInsightService_AnalyzeTransaction_IgnoresIncome(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockInsightRepo := new(mocks.InsightRepositoryMock)
+	logger := echo.New().Logger
+
+	service := NewInsightService(mockTxnRepo, mockInsightRepo, logger)
+
+	txn := entities.Transaction{Date: time.Now(), Amount: 50000, Category: "salary", TransactionType: "income", SpenderId: 1}
+	err := service.AnalyzeTransaction(uint(10), txn)
+
+	assert.NoError(t, err)
+	mockTxnRepo.AssertNotCalled(t, "GetAllBySpenderId", mock.Anything)
+}
+
+func TestInsightService_AnalyzeTransaction_FlagsMonthlySpike(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockInsightRepo := new(mocks.InsightRepositoryMock)
+	logger := echo.New().Logger
+
+	now := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	var history []entities.GetAllResponse
+	for i := 1; i <= 3; i++ {
+		date := now.AddDate(0, -i, 0)
+		history = append(history, entities.GetAllResponse{
+			ID: uint(i), Date: &date, Amount: 1000, Category: "shopping", TransactionType: "expense",
+		})
+	}
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(history, nil)
+	mockInsightRepo.On("GetMonthlyAnomaly", uint(1), "shopping", "2024-06").
+		Return((*entities.TransactionAnomaly)(nil), gorm.ErrRecordNotFound)
+	mockInsightRepo.On("SaveAnomaly", mock.MatchedBy(func(anomaly entities.TransactionAnomaly) bool {
+		return anomaly.Kind == AnomalyKindCategoryMonth && anomaly.Month == "2024-06" && anomaly.Ratio == 3
+	})).Return(uint(1), nil)
+	service := NewInsightService(mockTxnRepo, mockInsightRepo, logger)
+
+	txn := entities.Transaction{Date: now, Amount: 3000, Category: "shopping", TransactionType: "expense", SpenderId: 1}
+	err := service.AnalyzeTransaction(uint(10), txn)
+
+	assert.NoError(t, err)
+	mockInsightRepo.AssertNumberOfCalls(t, "SaveAnomaly", 1)
+}
+
+func TestInsightService_AnalyzeTransaction_Error(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockInsightRepo := new(mocks.InsightRepositoryMock)
+	logger := echo.New().Logger
+
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return([]entities.GetAllResponse{}, errors.New("some error"))
+	service := NewInsightService(mockTxnRepo, mockInsightRepo, logger)
+
+	txn := entities.Transaction{Date: time.Now(), Amount: 100, Category: "food", TransactionType: "expense", SpenderId: 1}
+	err := service.AnalyzeTransaction(uint(10), txn)
+
+	assert.EqualError(t, err, "failed to get transaction")
+}
+
+func TestInsightService_GetAnomalies_Success(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockInsightRepo := new(mocks.InsightRepositoryMock)
+	logger := echo.New().Logger
+
+	mockInsightRepo.On("GetAnomaliesBySpenderId", uint(1)).Return([]entities.TransactionAnomaly{
+		{TransactionId: 10, Kind: AnomalyKindTransaction, Category: "food", Amount: 600, Baseline: 100, Ratio: 6, Reason: "reason"},
+	}, nil)
+	service := NewInsightService(mockTxnRepo, mockInsightRepo, logger)
+
+	result, err := service.GetAnomalies(uint(1))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, uint(10), result[0].TransactionId)
+	assert.Equal(t, "reason", result[0].Reason)
+}
+
+func TestInsightService_GetAnomalies_Error(t *testing.T) {
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	mockInsightRepo := new(mocks.InsightRepositoryMock)
+	logger := echo.New().Logger
+
+	mockInsightRepo.On("GetAnomaliesBySpenderId", uint(1)).Return([]entities.TransactionAnomaly{}, errors.New("some error"))
+	service := NewInsightService(mockTxnRepo, mockInsightRepo, logger)
+
+	_, err := service.GetAnomalies(uint(1))
+
+	assert.EqualError(t, err, "failed to get anomalies")
+}
@@ -0,0 +1,43 @@
+package networth
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	ItemKindAsset     = "asset"
+	ItemKindLiability = "liability"
+)
+
+type NetWorthItem struct {
+	gorm.Model
+	Name      string     `json:"name" validate:"required"`
+	Kind      string     `json:"kind" validate:"required,oneof=asset liability"`
+	Value     float64    `json:"value" validate:"gte=0"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+	SpenderId int        `json:"spender_id" validate:"required"`
+}
+
+type PeriodFilter struct {
+	StartDate *time.Time `query:"start-date"`
+	EndDate   *time.Time `query:"end-date"`
+}
+
+type GetItemResponse struct {
+	ID        uint       `json:"id"`
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Value     float64    `json:"value"`
+	StartDate time.Time  `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+type SnapshotResponse struct {
+	Date        time.Time `json:"date"`
+	CashBalance float64   `json:"cash_balance"`
+	Assets      float64   `json:"assets"`
+	Liabilities float64   `json:"liabilities"`
+	NetWorth    float64   `json:"net_worth"`
+}
@@ -0,0 +1,238 @@
+package networth
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/networth_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxRecomputeDays bounds how far back a single recompute reaches so a
+// transaction dated years ago cannot turn one request into thousands of writes.
+const maxRecomputeDays = 730
+
+type INetWorthService interface {
+	GetItems(spenderId uint) ([]GetItemResponse, error)
+	CreateItem(req NetWorthItem) (uint, error)
+	DeleteItem(spenderId, itemId uint) error
+	GetHistory(spenderId uint, filter PeriodFilter) ([]SnapshotResponse, error)
+	RecordSnapshots(date time.Time) error
+	RecomputeSnapshots(spenderId uint, from, to time.Time) error
+}
+
+type netWorthService struct {
+	netWorthRepository    networth_repository.INetWorthRepository
+	transactionRepository transaction_repository.ITransactionRepository
+	logger                echo.Logger
+}
+
+func NewNetWorthService(netWorthRepository networth_repository.INetWorthRepository, transactionRepository transaction_repository.ITransactionRepository, logger echo.Logger) INetWorthService {
+	return &netWorthService{
+		netWorthRepository:    netWorthRepository,
+		transactionRepository: transactionRepository,
+		logger:                logger,
+	}
+}
+
+func (s *netWorthService) GetItems(spenderId uint) ([]GetItemResponse, error) {
+	results, err := s.netWorthRepository.GetItemsBySpenderId(spenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get net worth items")
+	}
+
+	var newResults []GetItemResponse
+	for _, value := range results {
+		result := GetItemResponse{
+			ID:        value.ID,
+			Name:      value.Name,
+			Kind:      value.Kind,
+			Value:     value.Value,
+			StartDate: value.StartDate,
+			EndDate:   value.EndDate,
+		}
+		newResults = append(newResults, result)
+	}
+	return newResults, nil
+}
+
+func (s *netWorthService) CreateItem(req NetWorthItem) (uint, error) {
+	startDate := req.StartDate
+	if startDate.IsZero() {
+		startDate = time.Now()
+	}
+	item := entities.NetWorthItem{
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Value:     req.Value,
+		StartDate: startDate,
+		EndDate:   req.EndDate,
+		SpenderId: req.SpenderId,
+	}
+	result, err := s.netWorthRepository.SaveItem(item)
+	if err != nil {
+		return 0, errors.New("failed to save net worth item")
+	}
+	s.logger.Infof("saved net worth item with ID: %d success", result)
+
+	if err = s.RecomputeSnapshots(uint(req.SpenderId), startDate, time.Now()); err != nil {
+		s.logger.Error(err)
+	}
+	return result, nil
+}
+
+func (s *netWorthService) DeleteItem(spenderId, itemId uint) error {
+	err := s.netWorthRepository.DeleteItem(spenderId, itemId)
+	if err != nil {
+		return errors.New("failed to delete net worth item")
+	}
+	s.logger.Infof("delete net worth item with item id: %d success", itemId)
+	return nil
+}
+
+func (s *netWorthService) GetHistory(spenderId uint, filter PeriodFilter) ([]SnapshotResponse, error) {
+	newFilter := entities.PeriodFilter{
+		StartDate: filter.StartDate,
+		EndDate:   filter.EndDate,
+	}
+	results, err := s.netWorthRepository.GetSnapshots(spenderId, newFilter)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get net worth history")
+	}
+	s.logger.Infof("get net worth history of spender id: %d success", spenderId)
+
+	var newResults []SnapshotResponse
+	for _, value := range results {
+		result := SnapshotResponse{
+			Date:        value.Date,
+			CashBalance: value.CashBalance,
+			Assets:      value.Assets,
+			Liabilities: value.Liabilities,
+			NetWorth:    value.NetWorth,
+		}
+		newResults = append(newResults, result)
+	}
+	return newResults, nil
+}
+
+// RecordSnapshots writes every spender's net worth as of the end of date. It
+// is run by the daily job and keeps going past individual failures so one bad
+// account does not block everyone else's history.
+func (s *netWorthService) RecordSnapshots(date time.Time) error {
+	spenderIds, err := s.netWorthRepository.GetSpenderIds()
+	if err != nil {
+		return errors.New("failed to get spenders")
+	}
+
+	var failed int
+	for _, spenderId := range spenderIds {
+		if err = s.RecomputeSnapshots(spenderId, date, date); err != nil {
+			s.logger.Error(err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.New("failed to record some net worth snapshots")
+	}
+	s.logger.Infof("recorded net worth snapshots of %d spenders for %s", len(spenderIds), date.Format("2006-01-02"))
+	return nil
+}
+
+// RecomputeSnapshots rebuilds the snapshots for each day in [from, to] from
+// the current transactions and tracked items, replacing any recorded before a
+// backdated change.
+func (s *netWorthService) RecomputeSnapshots(spenderId uint, from, to time.Time) error {
+	from, to = startOfDay(from), startOfDay(to)
+	if earliest := to.AddDate(0, 0, -maxRecomputeDays); from.Before(earliest) {
+		from = earliest
+	}
+	if from.After(to) {
+		return nil
+	}
+
+	txns, err := s.transactionRepository.GetAllBySpenderId(spenderId)
+	if err != nil {
+		return errors.New("failed to get transaction")
+	}
+
+	items, err := s.netWorthRepository.GetItemsBySpenderId(spenderId)
+	if err != nil {
+		return errors.New("failed to get net worth items")
+	}
+
+	snapshots := computeSnapshots(spenderId, txns, items, from, to)
+	if err = s.netWorthRepository.SaveSnapshots(snapshots); err != nil {
+		return errors.New("failed to save net worth snapshots")
+	}
+	s.logger.Infof("recomputed %d net worth snapshots of spender id: %d", len(snapshots), spenderId)
+	return nil
+}
+
+func computeSnapshots(spenderId uint, txns []entities.GetAllResponse, items []entities.NetWorthItem, from, to time.Time) []entities.NetWorthSnapshot {
+	sorted := make([]entities.GetAllResponse, 0, len(txns))
+	for _, txn := range txns {
+		if txn.Date != nil {
+			sorted = append(sorted, txn)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(*sorted[j].Date)
+	})
+
+	var snapshots []entities.NetWorthSnapshot
+	var cash float64
+	next := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		dayEnd := day.AddDate(0, 0, 1)
+		for next < len(sorted) && sorted[next].Date.Before(dayEnd) {
+			switch strings.ToLower(sorted[next].TransactionType) {
+			case "income":
+				cash += sorted[next].Amount
+			case "expense":
+				cash -= sorted[next].Amount
+			}
+			next++
+		}
+
+		var assets, liabilities float64
+		for _, item := range items {
+			if !item.StartDate.Before(dayEnd) || (item.EndDate != nil && item.EndDate.Before(day)) {
+				continue
+			}
+			if item.Kind == ItemKindLiability {
+				liabilities += item.Value
+			} else {
+				assets += item.Value
+			}
+		}
+
+		snapshots = append(snapshots, entities.NetWorthSnapshot{
+			Date:        day,
+			CashBalance: roundAmount(cash),
+			Assets:      roundAmount(assets),
+			Liabilities: roundAmount(liabilities),
+			NetWorth:    roundAmount(cash + assets - liabilities),
+			SpenderId:   int(spenderId),
+		})
+	}
+	return snapshots
+}
+
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
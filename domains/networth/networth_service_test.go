@@ -0,0 +1,108 @@
+package networth
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestNetWorthService_RecomputeSnapshots_Success(t *testing.T) {
+	mockRepo := new(mocks.NetWorthRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	day1 := time.Date(2024, time.June, 1, 10, 0, 0, 0, time.Local)
+	day3 := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.Local)
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return([]entities.GetAllResponse{
+		{Date: &day1, Amount: 5000, TransactionType: "income"},
+		{Date: &day3, Amount: 1000, TransactionType: "expense"},
+	}, nil)
+	mockRepo.On("GetItemsBySpenderId", uint(1)).Return([]entities.NetWorthItem{
+		{Kind: ItemKindAsset, Value: 20000, StartDate: day1},
+		{Kind: ItemKindLiability, Value: 8000, StartDate: day3},
+	}, nil)
+
+	var saved []entities.NetWorthSnapshot
+	mockRepo.On("SaveSnapshots", mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(0).([]entities.NetWorthSnapshot)
+	}).Return(nil)
+	service := NewNetWorthService(mockRepo, mockTxnRepo, logger)
+
+	err := service.RecomputeSnapshots(uint(1), day1, day3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(saved))
+	assert.Equal(t, float64(25000), saved[0].NetWorth)
+	assert.Equal(t, float64(25000), saved[1].NetWorth)
+	assert.Equal(t, float64(4000), saved[2].CashBalance)
+	assert.Equal(t, float64(8000), saved[2].Liabilities)
+	assert.Equal(t, float64(16000), saved[2].NetWorth)
+}
+
+func TestNetWorthService_RecomputeSnapshots_Error(t *testing.T) {
+	mockRepo := new(mocks.NetWorthRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return([]entities.GetAllResponse{}, errors.New("some error"))
+	service := NewNetWorthService(mockRepo, mockTxnRepo, logger)
+
+	err := service.RecomputeSnapshots(uint(1), time.Now().AddDate(0, 0, -3), time.Now())
+
+	assert.EqualError(t, err, "failed to get transaction")
+}
+
+func TestNetWorthService_RecordSnapshots_Success(t *testing.T) {
+	mockRepo := new(mocks.NetWorthRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetSpenderIds").Return([]uint{1, 2}, nil)
+	mockTxnRepo.On("GetAllBySpenderId", mock.Anything).Return([]entities.GetAllResponse{}, nil)
+	mockRepo.On("GetItemsBySpenderId", mock.Anything).Return([]entities.NetWorthItem{}, nil)
+	mockRepo.On("SaveSnapshots", mock.MatchedBy(func(snapshots []entities.NetWorthSnapshot) bool {
+		return len(snapshots) == 1
+	})).Return(nil)
+	service := NewNetWorthService(mockRepo, mockTxnRepo, logger)
+
+	err := service.RecordSnapshots(time.Now().AddDate(0, 0, -1))
+
+	assert.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "SaveSnapshots", 2)
+}
+
+func TestNetWorthService_GetHistory_Success(t *testing.T) {
+	mockRepo := new(mocks.NetWorthRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	date := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.Local)
+	mockRepo.On("GetSnapshots", uint(1), mock.Anything).Return([]entities.NetWorthSnapshot{
+		{Date: date, CashBalance: 100, Assets: 50, Liabilities: 20, NetWorth: 130},
+	}, nil)
+	service := NewNetWorthService(mockRepo, mockTxnRepo, logger)
+
+	result, err := service.GetHistory(uint(1), PeriodFilter{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, float64(130), result[0].NetWorth)
+}
+
+func TestNetWorthService_CreateItem_Error(t *testing.T) {
+	mockRepo := new(mocks.NetWorthRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("SaveItem", mock.Anything).Return(uint(0), errors.New("some error"))
+	service := NewNetWorthService(mockRepo, mockTxnRepo, logger)
+
+	_, err := service.CreateItem(NetWorthItem{Name: "car loan", Kind: ItemKindLiability, Value: 300000, SpenderId: 1})
+
+	assert.EqualError(t, err, "failed to save net worth item")
+}
@@ -0,0 +1,65 @@
+package recurring
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	FrequencyOnce    = "once"
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyYearly  = "yearly"
+)
+
+const (
+	CadenceWeekly    = "weekly"
+	CadenceMonthly   = "monthly"
+	CadenceQuarterly = "quarterly"
+	CadenceYearly    = "yearly"
+)
+
+const (
+	SubscriptionStatusDetected  = "detected"
+	SubscriptionStatusConfirmed = "confirmed"
+	SubscriptionStatusDismissed = "dismissed"
+)
+
+type RecurringRule struct {
+	gorm.Model
+	Name            string     `json:"name" validate:"required"`
+	Amount          float64    `json:"amount" validate:"required,gt=0"`
+	Category        string     `json:"category"`
+	TransactionType string     `json:"transaction_type" validate:"required,oneof=income expense"`
+	Frequency       string     `json:"frequency" validate:"required,oneof=once daily weekly monthly yearly"`
+	Interval        int        `json:"interval" validate:"gte=0"`
+	NextDate        time.Time  `json:"next_date" validate:"required"`
+	EndDate         *time.Time `json:"end_date"`
+	SpenderId       int        `json:"spender_id" validate:"required"`
+}
+
+type GetRecurringResponse struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Amount          float64    `json:"amount"`
+	Category        string     `json:"category"`
+	TransactionType string     `json:"transaction_type"`
+	Frequency       string     `json:"frequency"`
+	Interval        int        `json:"interval"`
+	NextDate        time.Time  `json:"next_date"`
+	EndDate         *time.Time `json:"end_date"`
+}
+
+type GetSubscriptionResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Category    string    `json:"category"`
+	Amount      float64   `json:"amount"`
+	Cadence     string    `json:"cadence"`
+	Occurrences int       `json:"occurrences"`
+	LastDate    time.Time `json:"last_date"`
+	NextDate    time.Time `json:"next_date"`
+	AnnualCost  float64   `json:"annual_cost"`
+	Status      string    `json:"status"`
+}
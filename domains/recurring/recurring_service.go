@@ -0,0 +1,456 @@
+package recurring
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/recurring_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// minSubscriptionOccurrences is the fewest charges needed before a payee
+	// is treated as a subscription rather than a coincidence.
+	minSubscriptionOccurrences = 3
+	// subscriptionAmountTolerance allows small price changes between charges.
+	subscriptionAmountTolerance = 0.2
+	// subscriptionIntervalMatch is the share of gaps that must fit the cadence,
+	// so one late or skipped charge does not hide a subscription.
+	subscriptionIntervalMatch = 0.75
+)
+
+type cadenceSpec struct {
+	name      string
+	minDays   float64
+	maxDays   float64
+	perYear   float64
+	frequency string
+	interval  int
+}
+
+var cadences = []cadenceSpec{
+	{name: CadenceWeekly, minDays: 6, maxDays: 8, perYear: 52, frequency: FrequencyWeekly, interval: 1},
+	{name: CadenceMonthly, minDays: 26, maxDays: 35, perYear: 12, frequency: FrequencyMonthly, interval: 1},
+	{name: CadenceQuarterly, minDays: 84, maxDays: 98, perYear: 4, frequency: FrequencyMonthly, interval: 3},
+	{name: CadenceYearly, minDays: 350, maxDays: 380, perYear: 1, frequency: FrequencyYearly, interval: 1},
+}
+
+type IRecurringService interface {
+	GetRules(spenderId uint) ([]GetRecurringResponse, error)
+	CreateRule(req RecurringRule) (uint, error)
+	DeleteRule(spenderId, ruleId uint) error
+	DetectSubscriptions(spenderId uint) ([]GetSubscriptionResponse, error)
+	ConfirmSubscription(spenderId, subscriptionId uint) (uint, error)
+	DismissSubscription(spenderId, subscriptionId uint) error
+}
+
+type recurringService struct {
+	recurringRepository   recurring_repository.IRecurringRepository
+	transactionRepository transaction_repository.ITransactionRepository
+	logger                echo.Logger
+}
+
+func NewRecurringService(recurringRepository recurring_repository.IRecurringRepository, transactionRepository transaction_repository.ITransactionRepository, logger echo.Logger) IRecurringService {
+	return &recurringService{
+		recurringRepository:   recurringRepository,
+		transactionRepository: transactionRepository,
+		logger:                logger,
+	}
+}
+
+func (s *recurringService) GetRules(spenderId uint) ([]GetRecurringResponse, error) {
+	results, err := s.recurringRepository.GetBySpenderId(spenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get recurring rules")
+	}
+
+	var newResults []GetRecurringResponse
+	for _, value := range results {
+		result := GetRecurringResponse{
+			ID:              value.ID,
+			Name:            value.Name,
+			Amount:          value.Amount,
+			Category:        value.Category,
+			TransactionType: value.TransactionType,
+			Frequency:       value.Frequency,
+			Interval:        value.Interval,
+			NextDate:        value.NextDate,
+			EndDate:         value.EndDate,
+		}
+		newResults = append(newResults, result)
+	}
+	return newResults, nil
+}
+
+func (s *recurringService) CreateRule(req RecurringRule) (uint, error) {
+	interval := req.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+	rule := entities.RecurringRule{
+		Name:            req.Name,
+		Amount:          req.Amount,
+		Category:        req.Category,
+		TransactionType: req.TransactionType,
+		Frequency:       req.Frequency,
+		Interval:        interval,
+		NextDate:        req.NextDate,
+		EndDate:         req.EndDate,
+		SpenderId:       req.SpenderId,
+	}
+	result, err := s.recurringRepository.SaveRule(rule)
+	if err != nil {
+		return 0, errors.New("failed to save recurring rule")
+	}
+	s.logger.Infof("saved recurring rule with ID: %d success", result)
+	return result, nil
+}
+
+func (s *recurringService) DeleteRule(spenderId, ruleId uint) error {
+	err := s.recurringRepository.DeleteRule(spenderId, ruleId)
+	if err != nil {
+		return errors.New("failed to delete recurring rule")
+	}
+	s.logger.Infof("delete recurring rule with rule id: %d success", ruleId)
+	return nil
+}
+
+// DetectSubscriptions scans the spender's expenses for payees charged a
+// similar amount at a regular interval and records each as a detected
+// subscription. Ones the user already confirmed or dismissed keep their status
+// and are not surfaced again.
+func (s *recurringService) DetectSubscriptions(spenderId uint) ([]GetSubscriptionResponse, error) {
+	txns, err := s.transactionRepository.GetAllBySpenderId(spenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get transaction")
+	}
+
+	rules, err := s.recurringRepository.GetBySpenderId(spenderId)
+	if err != nil {
+		return nil, errors.New("failed to get recurring rules")
+	}
+
+	existing, err := s.recurringRepository.GetSubscriptionsBySpenderId(spenderId)
+	if err != nil {
+		return nil, errors.New("failed to get subscriptions")
+	}
+	existingBySignature := make(map[string]entities.DetectedSubscription)
+	for _, value := range existing {
+		existingBySignature[value.Signature] = value
+	}
+
+	var newResults []GetSubscriptionResponse
+	for _, candidate := range detectSubscriptions(txns, time.Now()) {
+		if matchesRule(candidate, rules) {
+			continue
+		}
+
+		if previous, ok := existingBySignature[candidate.Signature]; ok {
+			if previous.Status != SubscriptionStatusDetected {
+				continue
+			}
+			candidate.Model = previous.Model
+		}
+		candidate.SpenderId = int(spenderId)
+		candidate.Status = SubscriptionStatusDetected
+
+		id, err := s.recurringRepository.SaveSubscription(candidate)
+		if err != nil {
+			return nil, errors.New("failed to save subscription")
+		}
+		candidate.ID = id
+		newResults = append(newResults, toSubscriptionResponse(candidate))
+	}
+	s.logger.Infof("detected %d subscriptions of spender id: %d", len(newResults), spenderId)
+	return newResults, nil
+}
+
+func (s *recurringService) ConfirmSubscription(spenderId, subscriptionId uint) (uint, error) {
+	subscription, err := s.recurringRepository.GetSubscription(spenderId, subscriptionId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+		return 0, errors.New("failed to get subscription")
+	}
+	if subscription.Status != SubscriptionStatusDetected {
+		return 0, fmt.Errorf("subscription is already %s", subscription.Status)
+	}
+
+	cadence, ok := findCadence(subscription.Cadence)
+	if !ok {
+		return 0, errors.New("subscription cadence is invalid")
+	}
+	rule := entities.RecurringRule{
+		Name:            subscription.Name,
+		Amount:          subscription.Amount,
+		Category:        subscription.Category,
+		TransactionType: "expense",
+		Frequency:       cadence.frequency,
+		Interval:        cadence.interval,
+		NextDate:        subscription.NextDate,
+		SpenderId:       subscription.SpenderId,
+	}
+	ruleId, err := s.recurringRepository.SaveRule(rule)
+	if err != nil {
+		return 0, errors.New("failed to save recurring rule")
+	}
+
+	subscription.Status = SubscriptionStatusConfirmed
+	subscription.RecurringRuleId = &ruleId
+	if _, err = s.recurringRepository.SaveSubscription(*subscription); err != nil {
+		return 0, errors.New("failed to save subscription")
+	}
+	s.logger.Infof("confirmed subscription id: %d as recurring rule id: %d", subscriptionId, ruleId)
+	return ruleId, nil
+}
+
+func (s *recurringService) DismissSubscription(spenderId, subscriptionId uint) error {
+	subscription, err := s.recurringRepository.GetSubscription(spenderId, subscriptionId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to get subscription")
+	}
+
+	subscription.Status = SubscriptionStatusDismissed
+	if _, err = s.recurringRepository.SaveSubscription(*subscription); err != nil {
+		return errors.New("failed to save subscription")
+	}
+	s.logger.Infof("dismissed subscription id: %d", subscriptionId)
+	return nil
+}
+
+func detectSubscriptions(txns []entities.GetAllResponse, now time.Time) []entities.DetectedSubscription {
+	groups := make(map[string][]entities.GetAllResponse)
+	for _, txn := range txns {
+		if txn.Date == nil || strings.ToLower(txn.TransactionType) != "expense" {
+			continue
+		}
+		signature := payeeSignature(txn.Note)
+		if signature == "" {
+			continue
+		}
+		groups[signature] = append(groups[signature], txn)
+	}
+
+	var results []entities.DetectedSubscription
+	for signature, group := range groups {
+		if len(group) < minSubscriptionOccurrences {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].Date.Before(*group[j].Date)
+		})
+
+		var amounts, gaps []float64
+		for i, txn := range group {
+			amounts = append(amounts, txn.Amount)
+			if i > 0 {
+				gaps = append(gaps, group[i].Date.Sub(*group[i-1].Date).Hours()/24)
+			}
+		}
+		amount := median(amounts)
+		if !amountsSimilar(amounts, amount) {
+			continue
+		}
+
+		cadence, ok := matchCadence(gaps)
+		if !ok {
+			continue
+		}
+
+		last := group[len(group)-1]
+		next, _ := nthOccurrence(*last.Date, cadence.frequency, cadence.interval)
+		// A charge that missed two cycles in a row has most likely been cancelled.
+		if now.Sub(next).Hours()/24 > cadence.maxDays {
+			continue
+		}
+
+		results = append(results, entities.DetectedSubscription{
+			Signature:   signature,
+			Name:        strings.TrimSpace(last.Note),
+			Category:    last.Category,
+			Amount:      math.Round(amount*100) / 100,
+			Cadence:     cadence.name,
+			Occurrences: len(group),
+			LastDate:    *last.Date,
+			NextDate:    next,
+			AnnualCost:  math.Round(amount*cadence.perYear*100) / 100,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].NextDate.Before(results[j].NextDate)
+	})
+	return results
+}
+
+// payeeSignature reduces a note to the words that stay the same from charge to
+// charge, dropping digits and punctuation such as billing months or invoice
+// numbers.
+func payeeSignature(note string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(note) {
+		switch {
+		case unicode.IsLetter(r) || unicode.Is(unicode.Mn, r):
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+func amountsSimilar(amounts []float64, med float64) bool {
+	if med <= 0 {
+		return false
+	}
+	for _, amount := range amounts {
+		if math.Abs(amount-med) > med*subscriptionAmountTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func matchCadence(gaps []float64) (cadenceSpec, bool) {
+	if len(gaps) == 0 {
+		return cadenceSpec{}, false
+	}
+	typical := median(gaps)
+	for _, cadence := range cadences {
+		if typical < cadence.minDays || typical > cadence.maxDays {
+			continue
+		}
+		var matched int
+		for _, gap := range gaps {
+			if gap >= cadence.minDays && gap <= cadence.maxDays {
+				matched++
+			}
+		}
+		if float64(matched)/float64(len(gaps)) >= subscriptionIntervalMatch {
+			return cadence, true
+		}
+	}
+	return cadenceSpec{}, false
+}
+
+func findCadence(name string) (cadenceSpec, bool) {
+	for _, cadence := range cadences {
+		if cadence.name == name {
+			return cadence, true
+		}
+	}
+	return cadenceSpec{}, false
+}
+
+func matchesRule(subscription entities.DetectedSubscription, rules []entities.RecurringRule) bool {
+	for _, rule := range rules {
+		if payeeSignature(rule.Name) == subscription.Signature {
+			return true
+		}
+		if rule.Category == subscription.Category &&
+			math.Abs(rule.Amount-subscription.Amount) <= rule.Amount*subscriptionAmountTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func toSubscriptionResponse(subscription entities.DetectedSubscription) GetSubscriptionResponse {
+	return GetSubscriptionResponse{
+		ID:          subscription.ID,
+		Name:        subscription.Name,
+		Category:    subscription.Category,
+		Amount:      subscription.Amount,
+		Cadence:     subscription.Cadence,
+		Occurrences: subscription.Occurrences,
+		LastDate:    subscription.LastDate,
+		NextDate:    subscription.NextDate,
+		AnnualCost:  subscription.AnnualCost,
+		Status:      subscription.Status,
+	}
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// Occurrences returns every date the rule fires within [from, to]. Monthly and
+// yearly rules are anchored to NextDate and clamped to the last day of shorter
+// months, so a rule on the 31st fires on the 30th in April rather than drifting.
+func Occurrences(rule entities.RecurringRule, from, to time.Time) []time.Time {
+	interval := rule.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	var dates []time.Time
+	for k := 0; ; k++ {
+		date, ok := nthOccurrence(rule.NextDate, rule.Frequency, k*interval)
+		if !ok || date.After(to) {
+			break
+		}
+		if rule.EndDate != nil && date.After(*rule.EndDate) {
+			break
+		}
+		if !date.Before(from) {
+			dates = append(dates, date)
+		}
+		if rule.Frequency == FrequencyOnce {
+			break
+		}
+	}
+	return dates
+}
+
+func nthOccurrence(anchor time.Time, frequency string, n int) (time.Time, bool) {
+	switch frequency {
+	case FrequencyOnce:
+		return anchor, n == 0
+	case FrequencyDaily:
+		return anchor.AddDate(0, 0, n), true
+	case FrequencyWeekly:
+		return anchor.AddDate(0, 0, 7*n), true
+	case FrequencyMonthly:
+		return addMonths(anchor, n), true
+	case FrequencyYearly:
+		return addMonths(anchor, 12*n), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func addMonths(date time.Time, months int) time.Time {
+	firstOfMonth := time.Date(date.Year(), date.Month()+time.Month(months), 1,
+		date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
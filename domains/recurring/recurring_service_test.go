@@ -0,0 +1,243 @@
+package recurring
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestRecurringService_GetRules_Success(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	nextDate := time.Now()
+	mockRepo.On("GetBySpenderId", uint(1)).Return([]entities.RecurringRule{
+		{Name: "rent", Amount: 8000, TransactionType: "expense", Frequency: "monthly", Interval: 1, NextDate: nextDate},
+	}, nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	result, err := service.GetRules(uint(1))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "rent", result[0].Name)
+	assert.Equal(t, float64(8000), result[0].Amount)
+}
+
+func TestRecurringService_GetRules_Error(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetBySpenderId", uint(1)).Return([]entities.RecurringRule{}, errors.New("some error"))
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	_, err := service.GetRules(uint(1))
+
+	assert.EqualError(t, err, "failed to get recurring rules")
+}
+
+func TestRecurringService_CreateRule_DefaultsInterval(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("SaveRule", mock.MatchedBy(func(rule entities.RecurringRule) bool {
+		return rule.Interval == 1
+	})).Return(uint(1), nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	req := RecurringRule{
+		Name:            "netflix",
+		Amount:          419,
+		TransactionType: "expense",
+		Frequency:       FrequencyMonthly,
+		NextDate:        time.Now(),
+		SpenderId:       1,
+	}
+	result, err := service.CreateRule(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), result)
+}
+
+func TestRecurringService_DeleteRule_Error(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("DeleteRule", uint(1), uint(2)).Return(errors.New("some error"))
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	err := service.DeleteRule(uint(1), uint(2))
+
+	assert.EqualError(t, err, "failed to delete recurring rule")
+}
+
+func monthlyCharges(note string, amount float64, count int, last time.Time) []entities.GetAllResponse {
+	var results []entities.GetAllResponse
+	for i := count - 1; i >= 0; i-- {
+		date := last.AddDate(0, -i, 0)
+		results = append(results, entities.GetAllResponse{
+			ID: uint(len(results) + 1), Date: &date, Amount: amount, Category: "entertainment",
+			Note: note, TransactionType: "expense",
+		})
+	}
+	return results
+}
+
+func TestRecurringService_DetectSubscriptions_Success(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	now := time.Now()
+	last := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).AddDate(0, 0, -5)
+	txns := monthlyCharges("Netflix 05/2024", 419, 4, last)
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(txns, nil)
+	mockRepo.On("GetBySpenderId", uint(1)).Return([]entities.RecurringRule{}, nil)
+	mockRepo.On("GetSubscriptionsBySpenderId", uint(1)).Return([]entities.DetectedSubscription{}, nil)
+	mockRepo.On("SaveSubscription", mock.Anything).Return(uint(7), nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	result, err := service.DetectSubscriptions(uint(1))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, uint(7), result[0].ID)
+	assert.Equal(t, CadenceMonthly, result[0].Cadence)
+	assert.Equal(t, float64(419*12), result[0].AnnualCost)
+	assert.Equal(t, last.AddDate(0, 1, 0).Format("2006-01-02"), result[0].NextDate.Format("2006-01-02"))
+}
+
+func TestRecurringService_DetectSubscriptions_SkipsDismissed(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	txns := monthlyCharges("Spotify", 149, 4, time.Now().AddDate(0, 0, -5))
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(txns, nil)
+	mockRepo.On("GetBySpenderId", uint(1)).Return([]entities.RecurringRule{}, nil)
+	mockRepo.On("GetSubscriptionsBySpenderId", uint(1)).Return([]entities.DetectedSubscription{
+		{Signature: "spotify", Status: SubscriptionStatusDismissed},
+	}, nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	result, err := service.DetectSubscriptions(uint(1))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(result))
+	mockRepo.AssertNotCalled(t, "SaveSubscription", mock.Anything)
+}
+
+func TestRecurringService_DetectSubscriptions_IgnoresIrregularAmounts(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	txns := monthlyCharges("7-eleven", 100, 4, time.Now().AddDate(0, 0, -5))
+	txns[1].Amount = 560
+	mockTxnRepo.On("GetAllBySpenderId", uint(1)).Return(txns, nil)
+	mockRepo.On("GetBySpenderId", uint(1)).Return([]entities.RecurringRule{}, nil)
+	mockRepo.On("GetSubscriptionsBySpenderId", uint(1)).Return([]entities.DetectedSubscription{}, nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	result, err := service.DetectSubscriptions(uint(1))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(result))
+}
+
+func TestRecurringService_ConfirmSubscription_Success(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	nextDate := time.Now().AddDate(0, 3, 0)
+	mockRepo.On("GetSubscription", uint(1), uint(7)).Return(&entities.DetectedSubscription{
+		Name: "Gym", Amount: 3000, Category: "health", Cadence: CadenceQuarterly,
+		NextDate: nextDate, Status: SubscriptionStatusDetected, SpenderId: 1,
+	}, nil)
+	mockRepo.On("SaveRule", mock.MatchedBy(func(rule entities.RecurringRule) bool {
+		return rule.Frequency == FrequencyMonthly && rule.Interval == 3 && rule.NextDate.Equal(nextDate)
+	})).Return(uint(3), nil)
+	mockRepo.On("SaveSubscription", mock.MatchedBy(func(subscription entities.DetectedSubscription) bool {
+		return subscription.Status == SubscriptionStatusConfirmed && *subscription.RecurringRuleId == uint(3)
+	})).Return(uint(7), nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	result, err := service.ConfirmSubscription(uint(1), uint(7))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), result)
+}
+
+func TestRecurringService_ConfirmSubscription_AlreadyDismissed(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetSubscription", uint(1), uint(7)).Return(&entities.DetectedSubscription{
+		Cadence: CadenceMonthly, Status: SubscriptionStatusDismissed,
+	}, nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	_, err := service.ConfirmSubscription(uint(1), uint(7))
+
+	assert.EqualError(t, err, "subscription is already dismissed")
+}
+
+func TestRecurringService_DismissSubscription_Success(t *testing.T) {
+	mockRepo := new(mocks.RecurringRepositoryMock)
+	mockTxnRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetSubscription", uint(1), uint(7)).Return(&entities.DetectedSubscription{
+		Status: SubscriptionStatusDetected,
+	}, nil)
+	mockRepo.On("SaveSubscription", mock.MatchedBy(func(subscription entities.DetectedSubscription) bool {
+		return subscription.Status == SubscriptionStatusDismissed
+	})).Return(uint(7), nil)
+	service := NewRecurringService(mockRepo, mockTxnRepo, logger)
+
+	err := service.DismissSubscription(uint(1), uint(7))
+
+	assert.NoError(t, err)
+}
+
+func TestOccurrences_MonthlyClampsToMonthEnd(t *testing.T) {
+	rule := entities.RecurringRule{
+		Frequency: FrequencyMonthly,
+		Interval:  1,
+		NextDate:  time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	dates := Occurrences(rule, rule.NextDate, time.Date(2024, time.April, 30, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, []time.Time{
+		time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.April, 30, 0, 0, 0, 0, time.UTC),
+	}, dates)
+}
+
+func TestOccurrences_StopsAtEndDate(t *testing.T) {
+	endDate := time.Date(2024, time.January, 20, 0, 0, 0, 0, time.UTC)
+	rule := entities.RecurringRule{
+		Frequency: FrequencyWeekly,
+		Interval:  1,
+		NextDate:  time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   &endDate,
+	}
+
+	dates := Occurrences(rule, rule.NextDate, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, 3, len(dates))
+}
@@ -0,0 +1,159 @@
+package slipjob
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	// MaxSlipSize caps one slip, which is held in the job queue until it has
+	// been processed.
+	MaxSlipSize = 10 << 20
+	// MaxBatchFiles and MaxBatchSize cap one batch upload, counting the files
+	// inside archives.
+	MaxBatchFiles = 50
+	MaxBatchSize  = 100 << 20
+)
+
+// Outcomes of a file in a batch report.
+const (
+	OutcomePending     = "pending"
+	OutcomeCreated     = "created"
+	OutcomeDuplicate   = "duplicate"
+	OutcomeNeedsReview = "needs_review"
+	OutcomeFailed      = "failed"
+)
+
+var (
+	ErrBatchEmpty       = errors.New("batch has no slips")
+	ErrBatchTooLarge    = errors.New("batch has too many or too large slips")
+	ErrInvalidArchive   = errors.New("zip archive is invalid")
+	ErrSlipTooLarge     = errors.New("slip image is too large")
+	ErrSlipNotAnImage   = errors.New("file is not an image")
+	errRejectedInReview = errors.New("rejected in review")
+)
+
+// BatchFile is one slip of a batch. A file that could not be read carries
+// the reason in Err and is reported as failed.
+type BatchFile struct {
+	Filename string
+	Data     []byte
+	Err      error
+}
+
+type GetBatchResponse struct {
+	ID      uint                `json:"batch_id"`
+	Total   int                 `json:"total"`
+	Done    bool                `json:"done"`
+	Summary map[string]int      `json:"summary"`
+	Files   []BatchFileResponse `json:"files"`
+}
+
+type BatchFileResponse struct {
+	JobId         uint   `json:"job_id"`
+	Filename      string `json:"filename"`
+	Outcome       string `json:"outcome"`
+	Status        string `json:"status"`
+	TransactionId *uint  `json:"transaction_id,omitempty"`
+	DuplicateOf   *uint  `json:"duplicate_of,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ExtractZip reads the slips in a ZIP archive, skipping folders and the
+// hidden files archivers add. Sizes in the archive's headers are not
+// trusted; every entry is read through a limit.
+func ExtractZip(data []byte) ([]BatchFile, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+
+	var files []BatchFile
+	var total int
+	for _, entry := range reader.File {
+		name := path.Base(entry.Name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(entry.Name, "__MACOSX/") {
+			continue
+		}
+		if len(files) == MaxBatchFiles {
+			return nil, ErrBatchTooLarge
+		}
+		if entry.UncompressedSize64 > MaxSlipSize {
+			files = append(files, BatchFile{Filename: name, Err: ErrSlipTooLarge})
+			continue
+		}
+
+		content, err := readEntry(entry)
+		if err != nil {
+			files = append(files, BatchFile{Filename: name, Err: err})
+			continue
+		}
+		total += len(content)
+		if total > MaxBatchSize {
+			return nil, ErrBatchTooLarge
+		}
+		files = append(files, BatchFile{Filename: name, Data: content})
+	}
+	return files, nil
+}
+
+func readEntry(entry *zip.File) ([]byte, error) {
+	src, err := entry.Open()
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(io.LimitReader(src, MaxSlipSize+1))
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	if len(content) > MaxSlipSize {
+		return nil, ErrSlipTooLarge
+	}
+	return content, nil
+}
+
+// IsZip reports whether data is a ZIP archive by its signature.
+func IsZip(data []byte) bool {
+	return http.DetectContentType(data) == "application/zip"
+}
+
+// checkSlipFile returns why a file cannot go through the slip pipeline.
+func checkSlipFile(file BatchFile) error {
+	if file.Err != nil {
+		return file.Err
+	}
+	if len(file.Data) == 0 {
+		return errors.New("slip image is empty")
+	}
+	if len(file.Data) > MaxSlipSize {
+		return ErrSlipTooLarge
+	}
+	if !strings.HasPrefix(http.DetectContentType(file.Data), "image/") {
+		return ErrSlipNotAnImage
+	}
+	return nil
+}
+
+// outcome sums up a job for the batch report.
+func outcome(status string, duplicateOf *uint) string {
+	switch status {
+	case StatusSaved:
+		return OutcomeCreated
+	case StatusNeedsReview:
+		if duplicateOf != nil {
+			return OutcomeDuplicate
+		}
+		return OutcomeNeedsReview
+	case StatusFailed, StatusRejected:
+		return OutcomeFailed
+	default:
+		return OutcomePending
+	}
+}
@@ -0,0 +1,64 @@
+package slipjob
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func zipOf(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		assert.NoError(t, err)
+		_, err = entry.Write(content)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestExtractZip_SkipsHiddenFiles(t *testing.T) {
+	data := zipOf(t, map[string][]byte{
+		"slips/a.png":            pngHeader,
+		"slips/.DS_Store":        []byte("junk"),
+		"__MACOSX/slips/._a.png": []byte("junk"),
+		"slips/":                 nil,
+	})
+
+	files, err := ExtractZip(data)
+
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "a.png", files[0].Filename)
+	assert.Equal(t, pngHeader, files[0].Data)
+	assert.True(t, IsZip(data))
+}
+
+func TestExtractZip_Invalid(t *testing.T) {
+	_, err := ExtractZip([]byte("not a zip"))
+
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
+
+func TestExtractZip_TooManyFiles(t *testing.T) {
+	files := map[string][]byte{}
+	for i := 0; i <= MaxBatchFiles; i++ {
+		files[string(rune('a'+i%26))+string(rune('a'+i/26))+".png"] = pngHeader
+	}
+
+	_, err := ExtractZip(zipOf(t, files))
+
+	assert.ErrorIs(t, err, ErrBatchTooLarge)
+}
+
+func TestOutcome(t *testing.T) {
+	id := uint(3)
+	assert.Equal(t, OutcomeCreated, outcome(StatusSaved, nil))
+	assert.Equal(t, OutcomeDuplicate, outcome(StatusNeedsReview, &id))
+	assert.Equal(t, OutcomeNeedsReview, outcome(StatusNeedsReview, nil))
+	assert.Equal(t, OutcomeFailed, outcome(StatusRejected, nil))
+	assert.Equal(t, OutcomePending, outcome(StatusOCR, nil))
+}
@@ -0,0 +1,29 @@
+package slipjob
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"time"
+)
+
+const (
+	StatusQueued      = "queued"
+	StatusOCR         = "ocr"
+	StatusParsed      = "parsed"
+	StatusNeedsReview = "needs_review"
+	StatusSaved       = "saved"
+	StatusFailed      = "failed"
+	StatusRejected    = "rejected"
+)
+
+type GetJobResponse struct {
+	ID            uint                   `json:"job_id"`
+	Status        string                 `json:"status"`
+	Attempts      int                    `json:"attempts"`
+	LastError     string                 `json:"last_error,omitempty"`
+	NextRunAt     *time.Time             `json:"next_run_at,omitempty"`
+	Draft         *transaction.SlipDraft `json:"draft,omitempty"`
+	TransactionId *uint                  `json:"transaction_id,omitempty"`
+	DuplicateOf   *uint                  `json:"duplicate_of,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
@@ -0,0 +1,543 @@
+package slipjob
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"github.com/Montheankul-K/jod-jod/repository/slipjob_repository"
+	"github.com/Montheankul-K/jod-jod/slip"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWorkers = 4
+	// maxAttempts is how many times a job is tried before it fails for good.
+	maxAttempts = 5
+	// Retries wait baseBackoff, doubling after every attempt up to maxBackoff.
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 30 * time.Minute
+	// jobLease is how long a worker may hold a job before another may take
+	// it over, which must outlast the slowest OCR call.
+	jobLease = 5 * time.Minute
+	// pollInterval is how often idle workers look for due retries.
+	pollInterval = 2 * time.Second
+	// batchPollInterval is how often a caller waiting on a batch checks it.
+	batchPollInterval = 500 * time.Millisecond
+	lastErrorSize     = 255
+)
+
+// ErrNotInReview is returned when a review action is taken on a job that is
+// not waiting for review.
+var ErrNotInReview = errors.New("slip job is not waiting for review")
+
+// runnableStates are the states a job can be claimed in. A job claimed in ocr
+// or parsed was abandoned by a worker that stopped mid-way.
+var runnableStates = []string{StatusQueued, StatusOCR, StatusParsed}
+
+type ISlipJobService interface {
+	Enqueue(spenderId uint, filename string, image []byte, force bool) (uint, error)
+	GetJob(spenderId, jobId uint) (*GetJobResponse, error)
+	EnqueueBatch(spenderId uint, files []BatchFile, force bool) (*GetBatchResponse, error)
+	GetBatch(spenderId, batchId uint) (*GetBatchResponse, error)
+	AwaitBatch(spenderId, batchId uint, timeout time.Duration) (*GetBatchResponse, error)
+	GetReviews(spenderId uint) ([]GetJobResponse, error)
+	GetReviewImage(spenderId, jobId uint) ([]byte, error)
+	CorrectReview(spenderId, jobId uint, correction transaction.DraftCorrection) (*GetJobResponse, error)
+	ApproveReview(spenderId, jobId uint, force bool) (uint, error)
+	RejectReview(spenderId, jobId uint) error
+	ProcessNext() (bool, error)
+	Start(workers int)
+	Stop()
+}
+
+type slipJobService struct {
+	slipJobRepository  slipjob_repository.ISlipJobRepository
+	transactionService transaction.ITransactionService
+	logger             echo.Logger
+	now                func() time.Time
+	wake               chan struct{}
+	stop               chan struct{}
+	wg                 sync.WaitGroup
+}
+
+func NewSlipJobService(slipJobRepository slipjob_repository.ISlipJobRepository, transactionService transaction.ITransactionService, logger echo.Logger) ISlipJobService {
+	return &slipJobService{
+		slipJobRepository:  slipJobRepository,
+		transactionService: transactionService,
+		logger:             logger,
+		now:                time.Now,
+		wake:               make(chan struct{}, 1),
+		stop:               make(chan struct{}),
+	}
+}
+
+func (s *slipJobService) Enqueue(spenderId uint, filename string, image []byte, force bool) (uint, error) {
+	if len(image) == 0 {
+		return 0, errors.New("slip image is empty")
+	}
+
+	job := entities.SlipJob{
+		Status:    StatusQueued,
+		Filename:  filename,
+		Image:     image,
+		Force:     force,
+		NextRunAt: s.now(),
+		SpenderId: int(spenderId),
+	}
+	result, err := s.slipJobRepository.CreateJob(job)
+	if err != nil {
+		return 0, errors.New("failed to queue slip")
+	}
+	s.logger.Infof("queued slip job with ID: %d success", result)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return result, nil
+}
+
+func (s *slipJobService) GetJob(spenderId, jobId uint) (*GetJobResponse, error) {
+	job, err := s.slipJobRepository.GetJob(jobId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get slip job")
+	}
+	// Someone else's job is reported as missing rather than forbidden so job
+	// IDs cannot be probed.
+	if uint(job.SpenderId) != spenderId {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return jobResponse(job), nil
+}
+
+// EnqueueBatch queues every readable slip of a batch as its own job. Files
+// that cannot be slips are recorded as failed jobs so the report covers every
+// file sent.
+func (s *slipJobService) EnqueueBatch(spenderId uint, files []BatchFile, force bool) (*GetBatchResponse, error) {
+	if len(files) == 0 {
+		return nil, ErrBatchEmpty
+	}
+	if len(files) > MaxBatchFiles {
+		return nil, ErrBatchTooLarge
+	}
+
+	now := s.now()
+	jobs := make([]entities.SlipJob, 0, len(files))
+	for _, file := range files {
+		job := entities.SlipJob{
+			Status:    StatusQueued,
+			Filename:  file.Filename,
+			Image:     file.Data,
+			Force:     force,
+			NextRunAt: now,
+			SpenderId: int(spenderId),
+		}
+		if err := checkSlipFile(file); err != nil {
+			job.Status = StatusFailed
+			job.Image = nil
+			job.LastError = truncate(err.Error(), lastErrorSize)
+		}
+		jobs = append(jobs, job)
+	}
+
+	batch := entities.SlipBatch{FileCount: len(files), SpenderId: int(spenderId)}
+	batchId, jobIds, err := s.slipJobRepository.CreateBatch(batch, jobs)
+	if err != nil {
+		return nil, errors.New("failed to queue slip batch")
+	}
+	s.logger.Infof("queued slip batch with ID: %d of %d files success", batchId, len(files))
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	for i := range jobs {
+		jobs[i].ID = jobIds[i]
+	}
+	return batchResponse(batchId, jobs), nil
+}
+
+func (s *slipJobService) GetBatch(spenderId, batchId uint) (*GetBatchResponse, error) {
+	batch, err := s.slipJobRepository.GetBatch(batchId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get slip batch")
+	}
+	if uint(batch.SpenderId) != spenderId {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	jobs, err := s.slipJobRepository.GetJobsByBatch(batchId)
+	if err != nil {
+		return nil, errors.New("failed to get slip batch")
+	}
+	return batchResponse(batchId, jobs), nil
+}
+
+// AwaitBatch polls a batch until every file has an outcome or timeout has
+// passed, then returns the report as it stands.
+func (s *slipJobService) AwaitBatch(spenderId, batchId uint, timeout time.Duration) (*GetBatchResponse, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		result, err := s.GetBatch(spenderId, batchId)
+		if err != nil || result.Done || time.Now().After(deadline) {
+			return result, err
+		}
+		time.Sleep(batchPollInterval)
+	}
+}
+
+// Start runs workers that process jobs until Stop. Enqueue wakes an idle
+// worker at once; retries are picked up by polling.
+func (s *slipJobService) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	s.logger.Infof("slip workers started: %d", workers)
+}
+
+// Stop waits for jobs in progress to finish.
+func (s *slipJobService) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+	s.logger.Info("slip workers stopped")
+}
+
+func (s *slipJobService) work() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		// Drain every due job before going back to sleep.
+		for {
+			processed, err := s.ProcessNext()
+			if err != nil {
+				s.logger.Error(err)
+			}
+			if !processed {
+				break
+			}
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// ProcessNext claims one due job and runs it through OCR and saving. It
+// reports whether there was a job to process.
+func (s *slipJobService) ProcessNext() (bool, error) {
+	job, err := s.slipJobRepository.ClaimJob(runnableStates, s.now(), jobLease)
+	if err != nil {
+		return false, errors.New("failed to claim slip job")
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Errorf("slip job %d panicked: %v", job.ID, r)
+			s.retry(job, errors.New("slip processing panicked"))
+		}
+	}()
+	s.process(job)
+	return true, nil
+}
+
+// process resumes a job from its last recorded state, so a job whose slip was
+// already read is not sent to OCR again.
+func (s *slipJobService) process(job *entities.SlipJob) {
+	var draft *transaction.SlipDraft
+	if job.Draft != "" {
+		draft = &transaction.SlipDraft{}
+		if err := json.Unmarshal([]byte(job.Draft), draft); err != nil {
+			draft = nil
+		}
+	}
+
+	if draft == nil {
+		job.Status = StatusOCR
+		if err := s.slipJobRepository.UpdateJob(*job); err != nil {
+			s.retry(job, err)
+			return
+		}
+
+		result, err := s.transactionService.ParseSlip(job.Image)
+		if err != nil {
+			s.retry(job, err)
+			return
+		}
+
+		encoded, _ := json.Marshal(result)
+		job.Draft = string(encoded)
+		if result.NeedsReview() {
+			s.finish(job, StatusNeedsReview, nil)
+			return
+		}
+		job.Status = StatusParsed
+		if err = s.slipJobRepository.UpdateJob(*job); err != nil {
+			s.retry(job, err)
+			return
+		}
+		draft = result
+	}
+
+	// A job retried after its transaction was saved but before it was marked
+	// saved finds that transaction as a duplicate and goes to review.
+	txnId, err := s.transactionService.SaveSlip(uint(job.SpenderId), *draft, job.Filename, job.Image, job.Force)
+	if err != nil {
+		var duplicate *transaction.DuplicateError
+		if errors.As(err, &duplicate) {
+			job.DuplicateOf = &duplicate.TransactionId
+			s.finish(job, StatusNeedsReview, err)
+			return
+		}
+		if errors.Is(err, transaction.ErrDraftIncomplete) {
+			s.finish(job, StatusNeedsReview, err)
+			return
+		}
+		s.retry(job, err)
+		return
+	}
+
+	job.TransactionId = &txnId
+	// The image now lives in the slip bucket.
+	job.Image = nil
+	s.finish(job, StatusSaved, nil)
+}
+
+func (s *slipJobService) GetReviews(spenderId uint) ([]GetJobResponse, error) {
+	jobs, err := s.slipJobRepository.GetJobsByStatus(spenderId, StatusNeedsReview)
+	if err != nil {
+		return nil, errors.New("failed to get slip reviews")
+	}
+
+	result := make([]GetJobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		result = append(result, *jobResponse(&job))
+	}
+	return result, nil
+}
+
+func (s *slipJobService) GetReviewImage(spenderId, jobId uint) ([]byte, error) {
+	job, err := s.getReview(spenderId, jobId)
+	if err != nil {
+		return nil, err
+	}
+	return job.Image, nil
+}
+
+// CorrectReview applies a reviewer's corrections to the draft. A job that was
+// never read gets a draft made of the corrections alone.
+func (s *slipJobService) CorrectReview(spenderId, jobId uint, correction transaction.DraftCorrection) (*GetJobResponse, error) {
+	job, err := s.getReview(spenderId, jobId)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := reviewDraft(job)
+	draft.Apply(correction)
+	encoded, _ := json.Marshal(draft)
+	job.Draft = string(encoded)
+	if err = s.slipJobRepository.UpdateJob(*job); err != nil {
+		return nil, errors.New("failed to correct slip review")
+	}
+	return jobResponse(job), nil
+}
+
+// ApproveReview saves the reviewed draft as a transaction. A draft still
+// flagged as a duplicate is saved only with force.
+func (s *slipJobService) ApproveReview(spenderId, jobId uint, force bool) (uint, error) {
+	job, err := s.getReview(spenderId, jobId)
+	if err != nil {
+		return 0, err
+	}
+
+	draft := reviewDraft(job)
+	if draft.Amount <= 0 {
+		return 0, transaction.ErrDraftIncomplete
+	}
+
+	txnId, err := s.transactionService.SaveSlip(spenderId, draft, job.Filename, job.Image, force)
+	if err != nil {
+		return 0, err
+	}
+
+	job.TransactionId = &txnId
+	job.Image = nil
+	s.finish(job, StatusSaved, nil)
+	return txnId, nil
+}
+
+func (s *slipJobService) RejectReview(spenderId, jobId uint) error {
+	job, err := s.getReview(spenderId, jobId)
+	if err != nil {
+		return err
+	}
+
+	job.Status = StatusRejected
+	job.Image = nil
+	if err = s.slipJobRepository.UpdateJob(*job); err != nil {
+		return errors.New("failed to reject slip review")
+	}
+	s.logger.Infof("slip job %d rejected", job.ID)
+	return nil
+}
+
+// getReview loads a job of the spender's that is waiting for review.
+func (s *slipJobService) getReview(spenderId, jobId uint) (*entities.SlipJob, error) {
+	job, err := s.slipJobRepository.GetJob(jobId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get slip review")
+	}
+	if uint(job.SpenderId) != spenderId {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if job.Status != StatusNeedsReview {
+		return nil, ErrNotInReview
+	}
+	return job, nil
+}
+
+func reviewDraft(job *entities.SlipJob) transaction.SlipDraft {
+	var draft transaction.SlipDraft
+	if job.Draft != "" {
+		_ = json.Unmarshal([]byte(job.Draft), &draft)
+	}
+	if draft.Category == "" {
+		draft.Category = slip.KindTransfer
+	}
+	return draft
+}
+
+func (s *slipJobService) finish(job *entities.SlipJob, status string, cause error) {
+	job.Status = status
+	job.LockedUntil = nil
+	job.LastError = ""
+	if cause != nil {
+		job.LastError = truncate(cause.Error(), lastErrorSize)
+	}
+	if err := s.slipJobRepository.UpdateJob(*job); err != nil {
+		s.logger.Errorf("failed to update slip job %d: %v", job.ID, err)
+		return
+	}
+	s.logger.Infof("slip job %d finished as %s", job.ID, status)
+}
+
+// retry puts a job back to wait out its backoff, or fails it once it has
+// used all its attempts.
+func (s *slipJobService) retry(job *entities.SlipJob, cause error) {
+	if job.Attempts >= maxAttempts {
+		s.finish(job, StatusFailed, cause)
+		return
+	}
+
+	job.Status = StatusQueued
+	if job.Draft != "" {
+		job.Status = StatusParsed
+	}
+	job.LockedUntil = nil
+	job.LastError = truncate(cause.Error(), lastErrorSize)
+	job.NextRunAt = s.now().Add(backoff(job.Attempts))
+	if err := s.slipJobRepository.UpdateJob(*job); err != nil {
+		s.logger.Errorf("failed to update slip job %d: %v", job.ID, err)
+		return
+	}
+	s.logger.Warnf("slip job %d attempt %d failed, retrying at %s: %v", job.ID, job.Attempts, job.NextRunAt.Format(time.RFC3339), cause)
+}
+
+func jobResponse(job *entities.SlipJob) *GetJobResponse {
+	result := &GetJobResponse{
+		ID:            job.ID,
+		Status:        job.Status,
+		Attempts:      job.Attempts,
+		LastError:     job.LastError,
+		TransactionId: job.TransactionId,
+		DuplicateOf:   job.DuplicateOf,
+		CreatedAt:     job.CreatedAt,
+		UpdatedAt:     job.UpdatedAt,
+	}
+	if job.Status == StatusQueued || job.Status == StatusParsed {
+		result.NextRunAt = &job.NextRunAt
+	}
+	if job.Draft != "" {
+		var draft transaction.SlipDraft
+		if err := json.Unmarshal([]byte(job.Draft), &draft); err == nil {
+			result.Draft = &draft
+		}
+	}
+	return result
+}
+
+func batchResponse(batchId uint, jobs []entities.SlipJob) *GetBatchResponse {
+	result := &GetBatchResponse{
+		ID:      batchId,
+		Total:   len(jobs),
+		Done:    true,
+		Summary: map[string]int{},
+		Files:   make([]BatchFileResponse, 0, len(jobs)),
+	}
+	for _, job := range jobs {
+		file := BatchFileResponse{
+			JobId:         job.ID,
+			Filename:      job.Filename,
+			Outcome:       outcome(job.Status, job.DuplicateOf),
+			Status:        job.Status,
+			TransactionId: job.TransactionId,
+			DuplicateOf:   job.DuplicateOf,
+			Error:         job.LastError,
+		}
+		if job.Status == StatusRejected {
+			file.Error = errRejectedInReview.Error()
+		}
+		if file.Outcome == OutcomePending {
+			result.Done = false
+		}
+		result.Summary[file.Outcome]++
+		result.Files = append(result.Files, file)
+	}
+	return result
+}
+
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
+
+func truncate(text string, size int) string {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text
+	}
+	return string(runes[:size])
+}
@@ -0,0 +1,396 @@
+package slipjob
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// transactionServiceStub stands in for the slip steps of the transaction
+// service; any other method panics.
+type transactionServiceStub struct {
+	transaction.ITransactionService
+	draft     *transaction.SlipDraft
+	parseErr  error
+	saveErr   error
+	parsed    int
+	savedWith *transaction.SlipDraft
+}
+
+func (s *transactionServiceStub) ParseSlip(image []byte) (*transaction.SlipDraft, error) {
+	s.parsed++
+	if s.parseErr != nil {
+		return nil, s.parseErr
+	}
+	if s.draft != nil {
+		return s.draft, nil
+	}
+	return &transaction.SlipDraft{Amount: 250, Category: "transfer", TransRef: "REF1"}, nil
+}
+
+func (s *transactionServiceStub) SaveSlip(spenderId uint, draft transaction.SlipDraft, filename string, image []byte, force bool) (uint, error) {
+	s.savedWith = &draft
+	if s.saveErr != nil {
+		return 0, s.saveErr
+	}
+	return 42, nil
+}
+
+var now = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+func newTestService(repo *mocks.SlipJobRepositoryMock, txn *transactionServiceStub) (*slipJobService, *[]entities.SlipJob) {
+	var updates []entities.SlipJob
+	repo.On("UpdateJob", mock.Anything).Run(func(args mock.Arguments) {
+		updates = append(updates, args.Get(0).(entities.SlipJob))
+	}).Return(nil)
+
+	service := NewSlipJobService(repo, txn, echo.New().Logger).(*slipJobService)
+	service.now = func() time.Time { return now }
+	return service, &updates
+}
+
+func claimed(job entities.SlipJob) *entities.SlipJob {
+	job.ID = 7
+	job.SpenderId = 1
+	job.Image = []byte("image")
+	job.Attempts++
+	return &job
+}
+
+func TestSlipJobService_Enqueue_Success(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("CreateJob", mock.MatchedBy(func(job entities.SlipJob) bool {
+		return job.Status == StatusQueued && job.SpenderId == 1 && job.NextRunAt.Equal(now)
+	})).Return(uint(7), nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	result, err := service.Enqueue(1, "slip.jpg", []byte("image"), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), result)
+}
+
+func TestSlipJobService_Enqueue_EmptyImage(t *testing.T) {
+	service, _ := newTestService(new(mocks.SlipJobRepositoryMock), &transactionServiceStub{})
+
+	_, err := service.Enqueue(1, "slip.jpg", nil, false)
+
+	assert.EqualError(t, err, "slip image is empty")
+}
+
+func TestSlipJobService_GetJob_OtherSpender(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetJob", uint(7)).Return(&entities.SlipJob{Status: StatusSaved, SpenderId: 2}, nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	_, err := service.GetJob(1, 7)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSlipJobService_GetJob_Draft(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetJob", uint(7)).Return(&entities.SlipJob{
+		Status:    StatusParsed,
+		Draft:     `{"amount":250,"trans_ref":"REF1"}`,
+		NextRunAt: now,
+		SpenderId: 1,
+	}, nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	result, err := service.GetJob(1, 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusParsed, result.Status)
+	assert.Equal(t, float64(250), result.Draft.Amount)
+	assert.NotNil(t, result.NextRunAt)
+}
+
+func TestSlipJobService_ProcessNext_NoJob(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return((*entities.SlipJob)(nil), nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	processed, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	assert.False(t, processed)
+}
+
+func TestSlipJobService_ProcessNext_Saved(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return(claimed(entities.SlipJob{Status: StatusQueued}), nil)
+	txn := &transactionServiceStub{}
+	service, updates := newTestService(mockRepo, txn)
+
+	processed, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	assert.True(t, processed)
+	statuses := []string{}
+	for _, update := range *updates {
+		statuses = append(statuses, update.Status)
+	}
+	assert.Equal(t, []string{StatusOCR, StatusParsed, StatusSaved}, statuses)
+	last := (*updates)[len(*updates)-1]
+	assert.Equal(t, uint(42), *last.TransactionId)
+	assert.Nil(t, last.Image)
+	assert.Nil(t, last.LockedUntil)
+}
+
+func TestSlipJobService_ProcessNext_ResumesParsed(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return(claimed(entities.SlipJob{
+		Status: StatusParsed,
+		Draft:  `{"amount":99,"trans_ref":"REF2"}`,
+	}), nil)
+	txn := &transactionServiceStub{}
+	service, updates := newTestService(mockRepo, txn)
+
+	_, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, txn.parsed)
+	assert.Equal(t, "REF2", txn.savedWith.TransRef)
+	assert.Equal(t, StatusSaved, (*updates)[len(*updates)-1].Status)
+}
+
+func TestSlipJobService_ProcessNext_NeedsReview(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return(claimed(entities.SlipJob{Status: StatusQueued}), nil)
+	txn := &transactionServiceStub{draft: &transaction.SlipDraft{Issues: []string{transaction.IssueAmountMissing}}}
+	service, updates := newTestService(mockRepo, txn)
+
+	_, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	assert.Nil(t, txn.savedWith)
+	last := (*updates)[len(*updates)-1]
+	assert.Equal(t, StatusNeedsReview, last.Status)
+	assert.Contains(t, last.Draft, transaction.IssueAmountMissing)
+	assert.NotNil(t, last.Image)
+}
+
+func TestSlipJobService_ProcessNext_Duplicate(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return(claimed(entities.SlipJob{Status: StatusQueued}), nil)
+	duplicate := &transaction.DuplicateError{TransactionId: 3, Reason: transaction.DuplicateReasonReference}
+	service, updates := newTestService(mockRepo, &transactionServiceStub{saveErr: duplicate})
+
+	_, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	last := (*updates)[len(*updates)-1]
+	assert.Equal(t, StatusNeedsReview, last.Status)
+	assert.Equal(t, uint(3), *last.DuplicateOf)
+}
+
+func TestSlipJobService_ProcessNext_RetryWithBackoff(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	job := claimed(entities.SlipJob{Status: StatusQueued, Attempts: 1})
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return(job, nil)
+	service, updates := newTestService(mockRepo, &transactionServiceStub{parseErr: errors.New("ocr timed out")})
+
+	_, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	last := (*updates)[len(*updates)-1]
+	assert.Equal(t, StatusQueued, last.Status)
+	assert.Equal(t, now.Add(time.Minute), last.NextRunAt)
+	assert.Equal(t, "ocr timed out", last.LastError)
+}
+
+func TestSlipJobService_ProcessNext_RetryKeepsDraft(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return(claimed(entities.SlipJob{Status: StatusQueued}), nil)
+	service, updates := newTestService(mockRepo, &transactionServiceStub{saveErr: errors.New("failed to save transaction")})
+
+	_, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	last := (*updates)[len(*updates)-1]
+	assert.Equal(t, StatusParsed, last.Status)
+	assert.NotEmpty(t, last.Draft)
+}
+
+func TestSlipJobService_ProcessNext_FailsAfterMaxAttempts(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("ClaimJob", runnableStates, now, jobLease).Return(claimed(entities.SlipJob{Status: StatusQueued, Attempts: maxAttempts - 1}), nil)
+	service, updates := newTestService(mockRepo, &transactionServiceStub{parseErr: errors.New("ocr timed out")})
+
+	_, err := service.ProcessNext()
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, (*updates)[len(*updates)-1].Status)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, 2*time.Minute, backoff(3))
+	assert.Equal(t, maxBackoff, backoff(20))
+}
+
+func reviewJob(draft string) *entities.SlipJob {
+	return &entities.SlipJob{
+		Model:     gorm.Model{ID: 7},
+		Status:    StatusNeedsReview,
+		Image:     []byte("image"),
+		Draft:     draft,
+		SpenderId: 1,
+	}
+}
+
+func TestSlipJobService_GetReviews_Success(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetJobsByStatus", uint(1), StatusNeedsReview).Return([]entities.SlipJob{
+		*reviewJob(`{"amount":0,"issues":["amount_missing"]}`),
+	}, nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	result, err := service.GetReviews(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, []string{transaction.IssueAmountMissing}, result[0].Draft.Issues)
+}
+
+func TestSlipJobService_CorrectReview_Success(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetJob", uint(7)).Return(reviewJob(`{"amount":0,"issues":["amount_missing","low_confidence"]}`), nil)
+	service, updates := newTestService(mockRepo, &transactionServiceStub{})
+	amount := 120.5
+
+	result, err := service.CorrectReview(1, 7, transaction.DraftCorrection{Amount: &amount})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 120.5, result.Draft.Amount)
+	assert.Empty(t, result.Draft.Issues)
+	assert.Equal(t, StatusNeedsReview, (*updates)[0].Status)
+}
+
+func TestSlipJobService_ApproveReview_Incomplete(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetJob", uint(7)).Return(reviewJob(`{"amount":0}`), nil)
+	txn := &transactionServiceStub{}
+	service, _ := newTestService(mockRepo, txn)
+
+	_, err := service.ApproveReview(1, 7, false)
+
+	assert.ErrorIs(t, err, transaction.ErrDraftIncomplete)
+	assert.Nil(t, txn.savedWith)
+}
+
+func TestSlipJobService_ApproveReview_Success(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetJob", uint(7)).Return(reviewJob(`{"amount":99}`), nil)
+	service, updates := newTestService(mockRepo, &transactionServiceStub{})
+
+	result, err := service.ApproveReview(1, 7, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), result)
+	last := (*updates)[len(*updates)-1]
+	assert.Equal(t, StatusSaved, last.Status)
+	assert.Nil(t, last.Image)
+}
+
+func TestSlipJobService_RejectReview_NotInReview(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	job := reviewJob("")
+	job.Status = StatusSaved
+	mockRepo.On("GetJob", uint(7)).Return(job, nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	err := service.RejectReview(1, 7)
+
+	assert.ErrorIs(t, err, ErrNotInReview)
+}
+
+func TestSlipJobService_RejectReview_Success(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetJob", uint(7)).Return(reviewJob(`{"amount":99}`), nil)
+	service, updates := newTestService(mockRepo, &transactionServiceStub{})
+
+	err := service.RejectReview(1, 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRejected, (*updates)[0].Status)
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestSlipJobService_EnqueueBatch_ReportsUnreadableFiles(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("CreateBatch", entities.SlipBatch{FileCount: 3, SpenderId: 1}, mock.MatchedBy(func(jobs []entities.SlipJob) bool {
+		return len(jobs) == 3 && jobs[0].Status == StatusQueued &&
+			jobs[1].Status == StatusFailed && jobs[1].Image == nil &&
+			jobs[2].Status == StatusFailed
+	})).Return(uint(5), []uint{10, 11, 12}, nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	result, err := service.EnqueueBatch(1, []BatchFile{
+		{Filename: "a.png", Data: pngHeader},
+		{Filename: "notes.txt", Data: []byte("hello")},
+		{Filename: "big.png", Err: ErrSlipTooLarge},
+	}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), result.ID)
+	assert.False(t, result.Done)
+	assert.Equal(t, map[string]int{OutcomePending: 1, OutcomeFailed: 2}, result.Summary)
+	assert.Equal(t, uint(11), result.Files[1].JobId)
+	assert.Equal(t, ErrSlipNotAnImage.Error(), result.Files[1].Error)
+	assert.Equal(t, ErrSlipTooLarge.Error(), result.Files[2].Error)
+}
+
+func TestSlipJobService_EnqueueBatch_Empty(t *testing.T) {
+	service, _ := newTestService(new(mocks.SlipJobRepositoryMock), &transactionServiceStub{})
+
+	_, err := service.EnqueueBatch(1, nil, false)
+
+	assert.ErrorIs(t, err, ErrBatchEmpty)
+}
+
+func TestSlipJobService_GetBatch_OtherSpender(t *testing.T) {
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetBatch", uint(5)).Return(&entities.SlipBatch{SpenderId: 2}, nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	_, err := service.GetBatch(1, 5)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSlipJobService_GetBatch_Outcomes(t *testing.T) {
+	txnId, duplicateOf := uint(42), uint(40)
+	mockRepo := new(mocks.SlipJobRepositoryMock)
+	mockRepo.On("GetBatch", uint(5)).Return(&entities.SlipBatch{SpenderId: 1}, nil)
+	mockRepo.On("GetJobsByBatch", uint(5)).Return([]entities.SlipJob{
+		{Filename: "a.png", Status: StatusSaved, TransactionId: &txnId},
+		{Filename: "b.png", Status: StatusNeedsReview, DuplicateOf: &duplicateOf},
+		{Filename: "c.png", Status: StatusNeedsReview},
+		{Filename: "d.png", Status: StatusRejected},
+	}, nil)
+	service, _ := newTestService(mockRepo, &transactionServiceStub{})
+
+	result, err := service.GetBatch(1, 5)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Done)
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, map[string]int{
+		OutcomeCreated:     1,
+		OutcomeDuplicate:   1,
+		OutcomeNeedsReview: 1,
+		OutcomeFailed:      1,
+	}, result.Summary)
+	assert.Equal(t, &txnId, result.Files[0].TransactionId)
+	assert.Equal(t, "rejected in review", result.Files[3].Error)
+}
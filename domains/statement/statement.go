@@ -0,0 +1,64 @@
+package statement
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	LanguageThai    = "th"
+	LanguageEnglish = "en"
+)
+
+var ErrFutureMonth = errors.New("month has not started")
+
+type GetStatementRequest struct {
+	SpenderId uint   `validate:"required"`
+	Month     string `validate:"required,datetime=2006-01"`
+	Language  string `validate:"omitempty,oneof=th en"`
+}
+
+// Line is a transaction on a statement with the balance after it.
+type Line struct {
+	ID              uint      `json:"id"`
+	Date            time.Time `json:"date"`
+	Description     string    `json:"description"`
+	Category        string    `json:"category"`
+	TransactionType string    `json:"transaction_type"`
+	Amount          float64   `json:"amount"`
+	Balance         float64   `json:"balance"`
+}
+
+type CategoryTotal struct {
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Share    float64 `json:"share"`
+}
+
+// Report is what a statement shows. Balances are income less expenses over
+// all of the spender's history, since jod-jod tracks no account balances:
+// the opening balance is everything saved before the month.
+type Report struct {
+	SpenderId      uint            `json:"spender_id"`
+	Name           string          `json:"name"`
+	Month          string          `json:"month"`
+	Language       string          `json:"language"`
+	Days           int             `json:"days"`
+	OpeningBalance float64         `json:"opening_balance"`
+	TotalIncome    float64         `json:"total_income"`
+	TotalExpense   float64         `json:"total_expense"`
+	ClosingBalance float64         `json:"closing_balance"`
+	Lines          []Line          `json:"lines"`
+	Categories     []CategoryTotal `json:"categories"`
+	// DailyBalance is the balance at the end of each day of the month, up to
+	// today for the current month.
+	DailyBalance []float64 `json:"daily_balance"`
+}
+
+// StatementFile is a rendered statement; the caller closes Body.
+type StatementFile struct {
+	Filename    string
+	ContentType string
+	Body        io.ReadCloser
+}
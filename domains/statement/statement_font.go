@@ -0,0 +1,84 @@
+package statement
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/pdfdoc"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"os"
+)
+
+// thaiFontPaths are where common Linux packages install a Thai TrueType
+// font, tried in order when none is configured.
+var thaiFontPaths = [][2]string{
+	{"/usr/share/fonts/truetype/thai/Sarabun-Regular.ttf", "/usr/share/fonts/truetype/thai/Sarabun-Bold.ttf"},
+	{"/usr/share/fonts/truetype/noto/NotoSansThai-Regular.ttf", "/usr/share/fonts/truetype/noto/NotoSansThai-Bold.ttf"},
+	{"/usr/share/fonts/truetype/tlwg/Loma.ttf", "/usr/share/fonts/truetype/tlwg/Loma-Bold.ttf"},
+	{"/usr/share/fonts/truetype/tlwg/Garuda.ttf", "/usr/share/fonts/truetype/tlwg/Garuda-Bold.ttf"},
+}
+
+// Fonts are the TrueType programs statements are set in, in the order a
+// character is looked for. Fonts are parsed again for every statement, since
+// a parsed font belongs to one document.
+type Fonts struct {
+	Regular [][]byte
+	Bold    [][]byte
+}
+
+// HasThai reports whether a font other than the Go fonts was found.
+func (f Fonts) HasThai() bool {
+	return len(f.Regular) > 1
+}
+
+// LoadFonts returns the Go fonts followed by the configured Thai font, or
+// the first Thai font found on the system. A configured font that cannot be
+// read is an error; finding none is not, though Thai text then shows as
+// boxes. The bold Thai font falls back to the regular one.
+func LoadFonts(cfg *config.Statement) (Fonts, error) {
+	fonts := Fonts{Regular: [][]byte{goregular.TTF}, Bold: [][]byte{gobold.TTF}}
+
+	var regular, bold []byte
+	if cfg != nil && cfg.FontPath != "" {
+		data, err := readFont(cfg.FontPath)
+		if err != nil {
+			return fonts, err
+		}
+		regular = data
+		if cfg.BoldFontPath != "" {
+			if bold, err = readFont(cfg.BoldFontPath); err != nil {
+				return fonts, err
+			}
+		}
+	} else {
+		for _, paths := range thaiFontPaths {
+			data, err := readFont(paths[0])
+			if err != nil {
+				continue
+			}
+			regular = data
+			bold, _ = readFont(paths[1])
+			break
+		}
+	}
+	if regular == nil {
+		return fonts, nil
+	}
+	if bold == nil {
+		bold = regular
+	}
+	fonts.Regular = append(fonts.Regular, regular)
+	fonts.Bold = append(fonts.Bold, bold)
+	return fonts, nil
+}
+
+func readFont(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("failed to read statement font " + path)
+	}
+	if _, err = pdfdoc.ParseFont(data); err != nil {
+		return nil, errors.New("statement font " + path + " is not a TrueType font")
+	}
+	return data, nil
+}
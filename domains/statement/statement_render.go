@@ -0,0 +1,345 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/chart"
+	"github.com/Montheankul-K/jod-jod/pdfdoc"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	margin     = 40.0
+	rowHeight  = 16.0
+	maxSlices  = 8
+	chartWidth = 245.0
+	// Charts sit between the section headings and the transactions.
+	chartTop    = 190.0
+	chartHeight = 140.0
+	chartScale  = 1.2
+)
+
+var (
+	textColor   = pdfdoc.RGB(33, 37, 41)
+	mutedColor  = pdfdoc.RGB(108, 117, 125)
+	ruleColor   = pdfdoc.RGB(206, 212, 218)
+	panelColor  = pdfdoc.RGB(241, 243, 245)
+	zebraColor  = pdfdoc.RGB(248, 249, 250)
+	incomeColor = pdfdoc.RGB(43, 138, 62)
+	spendColor  = pdfdoc.RGB(201, 42, 42)
+)
+
+var thaiMonths = [...]string{
+	"มกราคม", "กุมภาพันธ์", "มีนาคม", "เมษายน", "พฤษภาคม", "มิถุนายน",
+	"กรกฎาคม", "สิงหาคม", "กันยายน", "ตุลาคม", "พฤศจิกายน", "ธันวาคม",
+}
+
+var thaiShortMonths = [...]string{
+	"ม.ค.", "ก.พ.", "มี.ค.", "เม.ย.", "พ.ค.", "มิ.ย.",
+	"ก.ค.", "ส.ค.", "ก.ย.", "ต.ค.", "พ.ย.", "ธ.ค.",
+}
+
+type labels struct {
+	title, opening, income, expense, closing string
+	categories, balance, transactions        string
+	date, description, category, amountIn    string
+	amountOut, runningBalance, other, none   string
+	page                                     string
+}
+
+var statementLabels = map[string]labels{
+	LanguageEnglish: {
+		title: "Monthly statement", opening: "Opening balance", income: "Income", expense: "Expenses",
+		closing: "Closing balance", categories: "Spending by category", balance: "Daily balance",
+		transactions: "Transactions", date: "Date", description: "Description", category: "Category",
+		amountIn: "Income", amountOut: "Expense", runningBalance: "Balance", other: "Other",
+		none: "No transactions this month", page: "Page %d of %d",
+	},
+	LanguageThai: {
+		title: "รายการเดินบัญชีประจำเดือน", opening: "ยอดยกมา", income: "รายรับ", expense: "รายจ่าย",
+		closing: "ยอดคงเหลือ", categories: "รายจ่ายตามหมวดหมู่", balance: "ยอดคงเหลือรายวัน",
+		transactions: "รายการ", date: "วันที่", description: "รายละเอียด", category: "หมวดหมู่",
+		amountIn: "รายรับ", amountOut: "รายจ่าย", runningBalance: "คงเหลือ", other: "อื่น ๆ",
+		none: "ไม่มีรายการในเดือนนี้", page: "หน้า %d จาก %d",
+	},
+}
+
+// statementWriter draws a report onto A4 pages, keeping the faces and the
+// position of the next row.
+type statementWriter struct {
+	doc        *pdfdoc.Document
+	page       *pdfdoc.Page
+	labels     labels
+	report     Report
+	regular    func(size float64) pdfdoc.Face
+	bold       func(size float64) pdfdoc.Face
+	chartFonts [][]byte
+	y          float64
+}
+
+// render draws report as a PDF: a summary, spending by category, the daily
+// balance and every transaction with the balance after it.
+func render(report Report, fonts Fonts) ([]byte, error) {
+	doc := pdfdoc.New(monthName(report.Month, report.Language), pdfdoc.A4Width, pdfdoc.A4Height)
+	regular, err := parseFonts(doc, fonts.Regular)
+	if err != nil {
+		return nil, err
+	}
+	bold, err := parseFonts(doc, fonts.Bold)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &statementWriter{
+		doc:     doc,
+		labels:  statementLabels[report.Language],
+		report:  report,
+		regular: func(size float64) pdfdoc.Face { return pdfdoc.Face{Fonts: regular, Size: size} },
+		bold:    func(size float64) pdfdoc.Face { return pdfdoc.Face{Fonts: bold, Size: size} },
+		// Charts are set in the regular fonts, as the chart API sets them.
+		chartFonts: fonts.Regular,
+	}
+	w.page = doc.AddPage()
+	w.header()
+	w.summary()
+	if err = w.categories(); err != nil {
+		return nil, err
+	}
+	if err = w.balanceChart(); err != nil {
+		return nil, err
+	}
+	w.transactions()
+	w.footers()
+
+	var buf bytes.Buffer
+	if _, err = doc.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func parseFonts(doc *pdfdoc.Document, programs [][]byte) ([]*pdfdoc.Font, error) {
+	fonts := make([]*pdfdoc.Font, 0, len(programs))
+	for _, data := range programs {
+		f, err := pdfdoc.ParseFont(data)
+		if err != nil {
+			return nil, err
+		}
+		doc.AddFont(f)
+		fonts = append(fonts, f)
+	}
+	return fonts, nil
+}
+
+func (w *statementWriter) header() {
+	right := pdfdoc.A4Width - margin
+	w.page.Text(margin, 62, w.bold(18), textColor, w.labels.title)
+	w.page.TextRight(right, 62, w.bold(13), textColor, monthName(w.report.Month, w.report.Language))
+	w.page.Text(margin, 80, w.regular(10), mutedColor, w.report.Name)
+	w.page.Line(margin, 90, right, 90, 0.75, ruleColor)
+}
+
+// summary draws the opening balance, the month's income and expenses and the
+// closing balance in four boxes.
+func (w *statementWriter) summary() {
+	boxes := []struct {
+		label  string
+		amount float64
+		color  pdfdoc.Color
+	}{
+		{w.labels.opening, w.report.OpeningBalance, textColor},
+		{w.labels.income, w.report.TotalIncome, incomeColor},
+		{w.labels.expense, w.report.TotalExpense, spendColor},
+		{w.labels.closing, w.report.ClosingBalance, textColor},
+	}
+	gap := 8.0
+	width := (pdfdoc.A4Width - 2*margin - gap*float64(len(boxes)-1)) / float64(len(boxes))
+	for i, box := range boxes {
+		x := margin + float64(i)*(width+gap)
+		w.page.Rect(x, 102, width, 48, panelColor)
+		w.page.Text(x+10, 120, w.regular(9), mutedColor, w.regular(9).Fit(box.label, width-20))
+		w.page.Text(x+10, 140, w.bold(13), box.color, w.bold(13).Fit(formatMoney(box.amount), width-20))
+	}
+}
+
+// categories draws the month's spending by category as the same pie the
+// chart API serves. Beyond maxSlices the smallest are put together.
+func (w *statementWriter) categories() error {
+	w.page.Text(margin, 180, w.bold(11), textColor, w.labels.categories)
+	totals := w.report.Categories
+	if len(totals) > maxSlices {
+		rest := CategoryTotal{Category: w.labels.other}
+		for _, total := range totals[maxSlices-1:] {
+			rest.Amount += total.Amount
+			rest.Share += total.Share
+		}
+		rest.Amount, rest.Share = roundAmount(rest.Amount), roundAmount(rest.Share)
+		totals = append(totals[:maxSlices-1:maxSlices-1], rest)
+	}
+
+	c := chart.Chart{Kind: chart.KindPie, Empty: w.labels.none}
+	values := make([]float64, 0, len(totals))
+	for _, total := range totals {
+		c.Labels = append(c.Labels, total.Category)
+		values = append(values, total.Amount)
+	}
+	c.Series = []chart.Series{{Name: w.labels.categories, Values: values}}
+	return w.chart(margin, c)
+}
+
+// balanceChart plots the balance at the end of each day as the same line
+// the chart API serves. Days are placed across the whole month, so a month
+// in progress stops short of the right edge.
+func (w *statementWriter) balanceChart() error {
+	left := pdfdoc.A4Width - margin - chartWidth
+	w.page.Text(left, 180, w.bold(11), textColor, w.labels.balance)
+
+	c := chart.Chart{Kind: chart.KindLine, Empty: w.labels.none}
+	for day := 1; day <= w.report.Days; day++ {
+		c.Labels = append(c.Labels, strconv.Itoa(day))
+	}
+	c.Series = []chart.Series{{Name: w.labels.balance, Values: w.report.DailyBalance}}
+	return w.chart(left, c)
+}
+
+// chart draws c as a PNG into the chart box at x under a section heading.
+// It is rendered at chartScale pixels a point, which keeps chart labels
+// about the size of the table text.
+func (w *statementWriter) chart(x float64, c chart.Chart) error {
+	var buf bytes.Buffer
+	err := chart.Render(&buf, c, chart.Options{
+		Format: chart.FormatPNG,
+		Width:  int(chartWidth * chartScale),
+		Height: int(chartHeight * chartScale),
+		Fonts:  w.chartFonts,
+	})
+	if err != nil {
+		return err
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		return err
+	}
+	w.page.Image(x, chartTop, chartWidth, chartHeight, img)
+	return nil
+}
+
+// transactions lists every transaction between the opening and closing
+// balance, carrying the table onto new pages as needed.
+func (w *statementWriter) transactions() {
+	w.page.Text(margin, 350, w.bold(11), textColor, w.labels.transactions)
+	w.y = 362
+	w.tableHeader()
+
+	w.summaryRow(w.labels.opening, w.report.OpeningBalance)
+	for i, line := range w.report.Lines {
+		w.ensureRow()
+		if i%2 == 1 {
+			w.page.Rect(margin, w.y, pdfdoc.A4Width-2*margin, rowHeight, zebraColor)
+		}
+		face := w.regular(8.5)
+		baseline := w.y + 11
+		w.page.Text(margin+4, baseline, face, textColor, w.shortDate(line.Date))
+		w.page.Text(margin+52, baseline, face, textColor, face.Fit(line.Description, 158))
+		w.page.Text(margin+214, baseline, face, textColor, face.Fit(line.Category, 80))
+		switch line.TransactionType {
+		case "income":
+			w.page.TextRight(margin+372, baseline, face, incomeColor, formatMoney(line.Amount))
+		case "expense":
+			w.page.TextRight(margin+444, baseline, face, spendColor, formatMoney(line.Amount))
+		}
+		w.page.TextRight(pdfdoc.A4Width-margin-4, baseline, face, textColor, formatMoney(line.Balance))
+		w.y += rowHeight
+	}
+	if len(w.report.Lines) == 0 {
+		w.ensureRow()
+		w.page.Text(margin+52, w.y+11, w.regular(8.5), mutedColor, w.labels.none)
+		w.y += rowHeight
+	}
+	w.summaryRow(w.labels.closing, w.report.ClosingBalance)
+}
+
+func (w *statementWriter) tableHeader() {
+	face := w.bold(8.5)
+	w.page.Rect(margin, w.y, pdfdoc.A4Width-2*margin, rowHeight+2, panelColor)
+	baseline := w.y + 12
+	w.page.Text(margin+4, baseline, face, textColor, w.labels.date)
+	w.page.Text(margin+52, baseline, face, textColor, w.labels.description)
+	w.page.Text(margin+214, baseline, face, textColor, w.labels.category)
+	w.page.TextRight(margin+372, baseline, face, textColor, w.labels.amountIn)
+	w.page.TextRight(margin+444, baseline, face, textColor, w.labels.amountOut)
+	w.page.TextRight(pdfdoc.A4Width-margin-4, baseline, face, textColor, w.labels.runningBalance)
+	w.y += rowHeight + 2
+}
+
+func (w *statementWriter) summaryRow(label string, amount float64) {
+	w.ensureRow()
+	face := w.bold(8.5)
+	w.page.Line(margin, w.y, pdfdoc.A4Width-margin, w.y, 0.5, ruleColor)
+	w.page.Text(margin+52, w.y+11, face, textColor, label)
+	w.page.TextRight(pdfdoc.A4Width-margin-4, w.y+11, face, textColor, formatMoney(amount))
+	w.y += rowHeight
+}
+
+// ensureRow starts a new page, with the table header again, when another
+// row would run into the footer.
+func (w *statementWriter) ensureRow() {
+	if w.y+rowHeight <= pdfdoc.A4Height-margin-10 {
+		return
+	}
+	w.page = w.doc.AddPage()
+	w.y = margin
+	w.tableHeader()
+}
+
+func (w *statementWriter) footers() {
+	pages := w.doc.Pages()
+	face := w.regular(8)
+	y := pdfdoc.A4Height - margin + 12
+	for i, page := range pages {
+		page.Line(margin, y-12, pdfdoc.A4Width-margin, y-12, 0.5, ruleColor)
+		page.Text(margin, y, face, mutedColor, w.report.Name+" · "+monthName(w.report.Month, w.report.Language))
+		page.TextRight(pdfdoc.A4Width-margin, y, face, mutedColor, fmt.Sprintf(w.labels.page, i+1, len(pages)))
+	}
+}
+
+func (w *statementWriter) shortDate(date time.Time) string {
+	if w.report.Language == LanguageThai {
+		return fmt.Sprintf("%d %s", date.Day(), thaiShortMonths[date.Month()-1])
+	}
+	return date.Format("2 Jan")
+}
+
+func monthName(month, language string) string {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return month
+	}
+	if language == LanguageThai {
+		return fmt.Sprintf("%s %d", thaiMonths[t.Month()-1], t.Year()+543)
+	}
+	return t.Format("January 2006")
+}
+
+// formatMoney renders an amount with thousands separators and two decimals.
+func formatMoney(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	formatted := fmt.Sprintf("%.2f", amount)
+	whole, fraction := formatted[:len(formatted)-3], formatted[len(formatted)-3:]
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String() + fraction
+}
@@ -0,0 +1,265 @@
+package statement
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/digest_repository"
+	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimezone = "Asia/Bangkok"
+	contentType     = "application/pdf"
+	// layoutVersion is part of every cache key, so changing how statements
+	// look is a matter of bumping it.
+	layoutVersion = 2
+)
+
+type IStatementService interface {
+	GetStatement(req GetStatementRequest) (*StatementFile, error)
+}
+
+type statementService struct {
+	transactionRepository transaction_repository.ITransactionRepository
+	digestRepository      digest_repository.IDigestRepository
+	blobStore             blobstore.BlobStore
+	fonts                 Fonts
+	logger                echo.Logger
+	now                   func() time.Time
+}
+
+func NewStatementService(transactionRepository transaction_repository.ITransactionRepository, digestRepository digest_repository.IDigestRepository, blobStore blobstore.BlobStore, fonts Fonts, logger echo.Logger) IStatementService {
+	return &statementService{
+		transactionRepository: transactionRepository,
+		digestRepository:      digestRepository,
+		blobStore:             blobStore,
+		fonts:                 fonts,
+		logger:                logger,
+		now:                   time.Now,
+	}
+}
+
+// GetStatement returns the month's statement in the spender's timezone and,
+// by default, language. Rendered statements are kept in the blob store
+// under a key made from their content, so one is rendered again only when a
+// transaction it shows, or one before it, has changed.
+func (s *statementService) GetStatement(req GetStatementRequest) (*StatementFile, error) {
+	recipient, err := s.digestRepository.GetRecipient(req.SpenderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get user")
+	}
+
+	loc := loadLocation(recipient.Timezone)
+	month, err := time.ParseInLocation("2006-01", req.Month, loc)
+	if err != nil {
+		return nil, errors.New("month is invalid")
+	}
+	now := s.now().In(loc)
+	if month.After(now) {
+		return nil, ErrFutureMonth
+	}
+	language := req.Language
+	if language == "" {
+		language = recipient.Language
+	}
+
+	history, err := s.transactionRepository.GetAllBySpenderId(req.SpenderId)
+	if err != nil {
+		return nil, errors.New("failed to get transaction")
+	}
+	report := buildReport(history, month, now)
+	report.SpenderId = req.SpenderId
+	report.Name = recipient.Firstname
+	report.Language = normalizeLanguage(language)
+
+	key, err := cacheKey(report)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to render statement")
+	}
+	file := &StatementFile{
+		Filename:    fmt.Sprintf("statement-%s.pdf", report.Month),
+		ContentType: contentType,
+	}
+	body, err := s.blobStore.Get(key)
+	if err == nil {
+		file.Body = body
+		return file, nil
+	}
+	if !errors.Is(err, blobstore.ErrNotFound) {
+		s.logger.Error(err)
+	}
+
+	data, err := render(report, s.fonts)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to render statement")
+	}
+	// A statement that cannot be cached can still be sent.
+	if err = s.blobStore.Put(key, data, contentType); err != nil {
+		s.logger.Error(err)
+	}
+	file.Body = io.NopCloser(bytes.NewReader(data))
+	return file, nil
+}
+
+// buildReport works out the statement of the month starting at month from
+// the spender's whole history. Days after now are left off the balance
+// chart.
+func buildReport(history []entities.GetAllResponse, month time.Time, now time.Time) Report {
+	loc := month.Location()
+	end := month.AddDate(0, 1, 0)
+	report := Report{Month: month.Format("2006-01"), Days: end.AddDate(0, 0, -1).Day()}
+
+	var lines []Line
+	byCategory := make(map[string]float64)
+	for _, value := range history {
+		if value.Date == nil {
+			continue
+		}
+		signed := signedAmount(value)
+		date := value.Date.In(loc)
+		switch {
+		case date.Before(month):
+			report.OpeningBalance += signed
+		case date.Before(end):
+			lines = append(lines, Line{
+				ID:              value.ID,
+				Date:            date,
+				Description:     description(value),
+				Category:        value.Category,
+				TransactionType: strings.ToLower(value.TransactionType),
+				Amount:          value.Amount,
+			})
+			if signed > 0 {
+				report.TotalIncome += signed
+			} else if signed < 0 {
+				report.TotalExpense -= signed
+				byCategory[value.Category] -= signed
+			}
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if !lines[i].Date.Equal(lines[j].Date) {
+			return lines[i].Date.Before(lines[j].Date)
+		}
+		return lines[i].ID < lines[j].ID
+	})
+
+	balance := report.OpeningBalance
+	apply := func(line *Line) {
+		switch line.TransactionType {
+		case "income":
+			balance += line.Amount
+		case "expense":
+			balance -= line.Amount
+		}
+		line.Balance = roundAmount(balance)
+	}
+	days := report.Days
+	if now.Before(end) {
+		days = now.Day()
+	}
+	report.DailyBalance = make([]float64, days)
+	next := 0
+	for day := 0; day < days; day++ {
+		for ; next < len(lines) && lines[next].Date.Day() == day+1; next++ {
+			apply(&lines[next])
+		}
+		report.DailyBalance[day] = roundAmount(balance)
+	}
+	// Transactions dated after now, later today or ahead of time, still count.
+	for ; next < len(lines); next++ {
+		apply(&lines[next])
+	}
+	report.Lines = lines
+
+	for category, amount := range byCategory {
+		var share float64
+		if report.TotalExpense > 0 {
+			share = roundAmount(amount / report.TotalExpense * 100)
+		}
+		report.Categories = append(report.Categories, CategoryTotal{Category: category, Amount: roundAmount(amount), Share: share})
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		if report.Categories[i].Amount != report.Categories[j].Amount {
+			return report.Categories[i].Amount > report.Categories[j].Amount
+		}
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+
+	report.OpeningBalance = roundAmount(report.OpeningBalance)
+	report.TotalIncome = roundAmount(report.TotalIncome)
+	report.TotalExpense = roundAmount(report.TotalExpense)
+	report.ClosingBalance = roundAmount(balance)
+	return report
+}
+
+// cacheKey names a statement by what it shows.
+func cacheKey(report Report) (string, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append(data, byte(layoutVersion)))
+	return fmt.Sprintf("statements/%d/%s-%s-%s.pdf", report.SpenderId, report.Month, report.Language, hex.EncodeToString(sum[:8])), nil
+}
+
+func signedAmount(value entities.GetAllResponse) float64 {
+	switch strings.ToLower(value.TransactionType) {
+	case "income":
+		return value.Amount
+	case "expense":
+		return -value.Amount
+	}
+	return 0
+}
+
+func description(value entities.GetAllResponse) string {
+	switch {
+	case value.Merchant != "" && value.Note != "":
+		return value.Merchant + " - " + value.Note
+	case value.Merchant != "":
+		return value.Merchant
+	}
+	return value.Note
+}
+
+func loadLocation(name string) *time.Location {
+	if name == "" {
+		name = defaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// Bangkok has no DST, so a fixed offset is exact if tzdata is missing.
+		return time.FixedZone(defaultTimezone, 7*60*60)
+	}
+	return loc
+}
+
+func normalizeLanguage(language string) string {
+	if language == LanguageEnglish {
+		return LanguageEnglish
+	}
+	return LanguageThai
+}
+
+func roundAmount(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,181 @@
+package statement
+
+import (
+	"bytes"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"io"
+	"testing"
+	"time"
+)
+
+var bangkok = time.FixedZone("ICT", 7*60*60)
+
+func statementTxn(id uint, date time.Time, amount float64, category, txnType string) entities.GetAllResponse {
+	return entities.GetAllResponse{ID: id, Date: &date, Amount: amount, Category: category, TransactionType: txnType}
+}
+
+func statementHistory() []entities.GetAllResponse {
+	return []entities.GetAllResponse{
+		statementTxn(1, time.Date(2024, 2, 25, 12, 0, 0, 0, bangkok), 5000, "salary", "income"),
+		statementTxn(2, time.Date(2024, 2, 26, 12, 0, 0, 0, bangkok), 1000, "food", "expense"),
+		statementTxn(5, time.Date(2024, 3, 10, 9, 0, 0, 0, bangkok), 300, "transport", "expense"),
+		statementTxn(3, time.Date(2024, 3, 1, 8, 0, 0, 0, bangkok), 30000, "salary", "income"),
+		statementTxn(4, time.Date(2024, 3, 10, 9, 0, 0, 0, bangkok), 700, "food", "expense"),
+		{ID: 6, Date: timePtr(time.Date(2024, 3, 20, 19, 0, 0, 0, bangkok)), Amount: 900, Category: "food", Note: "ข้าวมันไก่", Merchant: "Jay Fai", TransactionType: "expense"},
+		// 23:30 on 31 March in Bangkok is still March even though it is
+		// already 1 April in UTC+9.
+		statementTxn(7, time.Date(2024, 4, 1, 1, 30, 0, 0, time.FixedZone("JST", 9*60*60)), 100, "food", "expense"),
+		statementTxn(8, time.Date(2024, 4, 2, 12, 0, 0, 0, bangkok), 9999, "food", "expense"),
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func TestBuildReport(t *testing.T) {
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, bangkok)
+
+	report := buildReport(statementHistory(), month, now)
+
+	assert.Equal(t, "2024-03", report.Month)
+	assert.Equal(t, 31, report.Days)
+	assert.Equal(t, 4000.0, report.OpeningBalance)
+	assert.Equal(t, 30000.0, report.TotalIncome)
+	assert.Equal(t, 2000.0, report.TotalExpense)
+	assert.Equal(t, 32000.0, report.ClosingBalance)
+
+	ids := make([]uint, len(report.Lines))
+	for i, line := range report.Lines {
+		ids[i] = line.ID
+	}
+	assert.Equal(t, []uint{3, 4, 5, 6, 7}, ids)
+	assert.Equal(t, 34000.0, report.Lines[0].Balance)
+	assert.Equal(t, 33000.0, report.Lines[2].Balance)
+	assert.Equal(t, "Jay Fai - ข้าวมันไก่", report.Lines[3].Description)
+
+	assert.Equal(t, 31, len(report.DailyBalance))
+	assert.Equal(t, 34000.0, report.DailyBalance[0])
+	assert.Equal(t, 33000.0, report.DailyBalance[9])
+	assert.Equal(t, 32000.0, report.DailyBalance[30])
+
+	assert.Equal(t, []CategoryTotal{
+		{Category: "food", Amount: 1700, Share: 85},
+		{Category: "transport", Amount: 300, Share: 15},
+	}, report.Categories)
+}
+
+func TestBuildReport_CurrentMonth(t *testing.T) {
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, bangkok)
+	now := time.Date(2024, 3, 10, 8, 0, 0, 0, bangkok)
+
+	report := buildReport(statementHistory(), month, now)
+
+	// The chart stops at today, but the table and closing balance still show
+	// transactions dated later.
+	assert.Equal(t, 10, len(report.DailyBalance))
+	assert.Equal(t, 33000.0, report.DailyBalance[9])
+	assert.Equal(t, 32000.0, report.ClosingBalance)
+	assert.Equal(t, 5, len(report.Lines))
+}
+
+func newTestService(t *testing.T, now time.Time) (*statementService, *mocks.TransactionRepositoryMock, *mocks.DigestRepositoryMock, blobstore.BlobStore) {
+	transactionRepository := new(mocks.TransactionRepositoryMock)
+	digestRepository := new(mocks.DigestRepositoryMock)
+	store, err := blobstore.NewLocalStore(t.TempDir())
+	assert.NoError(t, err)
+	fonts, err := LoadFonts(nil)
+	assert.NoError(t, err)
+	service := NewStatementService(transactionRepository, digestRepository, store, fonts, echo.New().Logger).(*statementService)
+	service.now = func() time.Time { return now }
+	return service, transactionRepository, digestRepository, store
+}
+
+func TestStatementService_GetStatement(t *testing.T) {
+	service, transactionRepository, digestRepository, store := newTestService(t, time.Date(2024, 5, 1, 0, 0, 0, 0, bangkok))
+	recipient := &entities.DigestRecipient{ID: 1, Firstname: "Somchai", Timezone: "Asia/Bangkok", Language: LanguageThai}
+	digestRepository.On("GetRecipient", uint(1)).Return(recipient, nil)
+	transactionRepository.On("GetAllBySpenderId", uint(1)).Return(statementHistory(), nil)
+
+	file, err := service.GetStatement(GetStatementRequest{SpenderId: 1, Month: "2024-03"})
+	assert.NoError(t, err)
+	assert.Equal(t, "statement-2024-03.pdf", file.Filename)
+	assert.Equal(t, "application/pdf", file.ContentType)
+	first, err := io.ReadAll(file.Body)
+	assert.NoError(t, err)
+	file.Body.Close()
+	assert.True(t, bytes.HasPrefix(first, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(first, []byte("%%EOF\n")))
+	// The category pie and balance line are embedded as images.
+	assert.Equal(t, 2, bytes.Count(first, []byte("/Subtype /Image")))
+
+	report := buildReport(statementHistory(), time.Date(2024, 3, 1, 0, 0, 0, 0, loadLocation("Asia/Bangkok")), service.now())
+	report.SpenderId, report.Name, report.Language = 1, "Somchai", LanguageThai
+	key, err := cacheKey(report)
+	assert.NoError(t, err)
+	cached, err := store.Get(key)
+	assert.NoError(t, err)
+	stored, _ := io.ReadAll(cached)
+	cached.Close()
+	assert.Equal(t, first, stored)
+
+	// A cached statement is served as it is, so replacing it shows whether
+	// it was rendered again.
+	assert.NoError(t, store.Put(key, []byte("cached"), contentType))
+	file, err = service.GetStatement(GetStatementRequest{SpenderId: 1, Month: "2024-03", Language: LanguageThai})
+	assert.NoError(t, err)
+	second, _ := io.ReadAll(file.Body)
+	file.Body.Close()
+	assert.Equal(t, "cached", string(second))
+
+	// Another language is another statement.
+	file, err = service.GetStatement(GetStatementRequest{SpenderId: 1, Month: "2024-03", Language: LanguageEnglish})
+	assert.NoError(t, err)
+	english, _ := io.ReadAll(file.Body)
+	file.Body.Close()
+	assert.True(t, bytes.HasPrefix(english, []byte("%PDF-1.4")))
+}
+
+func TestStatementService_GetStatement_Paginates(t *testing.T) {
+	service, transactionRepository, digestRepository, _ := newTestService(t, time.Date(2024, 5, 1, 0, 0, 0, 0, bangkok))
+	digestRepository.On("GetRecipient", uint(1)).Return(&entities.DigestRecipient{ID: 1, Language: LanguageEnglish}, nil)
+	var history []entities.GetAllResponse
+	for i := 0; i < 120; i++ {
+		history = append(history, statementTxn(uint(i+1), time.Date(2024, 3, 1+i%31, 12, 0, 0, 0, bangkok), 10, "food", "expense"))
+	}
+	transactionRepository.On("GetAllBySpenderId", uint(1)).Return(history, nil)
+
+	file, err := service.GetStatement(GetStatementRequest{SpenderId: 1, Month: "2024-03"})
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(file.Body)
+	file.Body.Close()
+	assert.Equal(t, 4, bytes.Count(data, []byte("/Type /Page /Parent")))
+}
+
+func TestStatementService_GetStatement_FutureMonth(t *testing.T) {
+	service, _, digestRepository, _ := newTestService(t, time.Date(2024, 3, 31, 23, 0, 0, 0, bangkok))
+	digestRepository.On("GetRecipient", uint(1)).Return(&entities.DigestRecipient{ID: 1}, nil)
+
+	_, err := service.GetStatement(GetStatementRequest{SpenderId: 1, Month: "2024-04"})
+	assert.ErrorIs(t, err, ErrFutureMonth)
+}
+
+func TestStatementService_GetStatement_RecordNotFound(t *testing.T) {
+	service, _, digestRepository, _ := newTestService(t, time.Date(2024, 5, 1, 0, 0, 0, 0, bangkok))
+	digestRepository.On("GetRecipient", uint(1)).Return((*entities.DigestRecipient)(nil), gorm.ErrRecordNotFound)
+
+	_, err := service.GetStatement(GetStatementRequest{SpenderId: 1, Month: "2024-03"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestLoadFonts_Invalid(t *testing.T) {
+	_, err := LoadFonts(&config.Statement{FontPath: "testdata/missing.ttf"})
+	assert.Error(t, err)
+}
@@ -1,7 +1,11 @@
 package transaction
 
 import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/slip"
 	"gorm.io/gorm"
+	"io"
 	"time"
 )
 
@@ -13,6 +17,16 @@ type Transaction struct {
 	TransactionType string    `gorm:"type:varchar(20); not null; column:transaction_type" json:"transaction_type"`
 	Note            string    `gorm:"type:varchar(255); column:note" json:"note"`
 	ImageUrl        string    `gorm:"type:varchar(255); column:image_url" json:"image_url"`
+	ThumbnailUrl    string    `gorm:"type:varchar(255); column:thumbnail_url" json:"thumbnail_url"`
+	BankCode        string    `gorm:"type:varchar(20); column:bank_code" json:"bank_code"`
+	TransRef        string    `gorm:"type:varchar(50); index:idx_transaction_trans_ref; column:trans_ref" json:"trans_ref"`
+	ImageHash       string    `gorm:"type:varchar(16); column:image_hash" json:"image_hash"`
+	Merchant        string    `gorm:"type:varchar(100); column:merchant" json:"merchant"`
+	TaxId           string    `gorm:"type:varchar(13); column:tax_id" json:"tax_id"`
+	VAT             float64   `gorm:"type:decimal(10,2); default:0.00; column:vat" json:"vat"`
+	ImportBatchId   *uint     `gorm:"type:int; index:idx_transaction_import_batch; column:import_batch_id" json:"import_batch_id"`
+	AccountId       *uint     `gorm:"type:int; index:idx_transaction_account_fit_id; column:account_id" json:"account_id"`
+	FitId           string    `gorm:"type:varchar(255); index:idx_transaction_account_fit_id; column:fit_id" json:"fit_id"`
 	SpenderId       int       `gorm:"type:int; not null; column:spender_id" json:"spender_id"`
 }
 
@@ -79,7 +93,204 @@ type GetByCategoryResponse struct {
 	ImageUrl string    `gorm:"column: image_url" json:"image_url"`
 }
 
-type TextractResult struct {
-	Category string
-	Amount   float64
+const (
+	DuplicateReasonReference = "reference"
+	DuplicateReasonImage     = "image"
+	DuplicateReasonSimilar   = "similar"
+)
+
+// DuplicateError reports the transaction a new one duplicates. Possible is
+// set for fuzzy matches, which may be genuine repeat purchases.
+type DuplicateError struct {
+	TransactionId uint
+	Reason        string
+	Possible      bool
+}
+
+func (e *DuplicateError) Error() string {
+	if e.Possible {
+		return fmt.Sprintf("possible duplicate of transaction %d", e.TransactionId)
+	}
+	return fmt.Sprintf("duplicate of transaction %d", e.TransactionId)
+}
+
+var ErrImageNotFound = errors.New("transaction has no image")
+
+// ExportOptions choose the file an export writes. Columns and Locale are
+// checked by exportfile; Timezone is the zone dates are written in, Bangkok
+// when empty.
+type ExportOptions struct {
+	Format   string   `validate:"required,oneof=csv xlsx ndjson beancount hledger"`
+	Columns  []string `validate:"max=20"`
+	Locale   string   `validate:"max=35"`
+	Timezone string   `validate:"omitempty,timezone"`
+}
+
+// TransactionImage is either a URL to redirect to or content to stream; the
+// caller closes Body.
+type TransactionImage struct {
+	URL         string
+	Body        io.ReadCloser
+	ContentType string
+}
+
+// ErrDraftIncomplete is returned when a slip or receipt draft without an
+// amount is saved.
+var ErrDraftIncomplete = errors.New("draft has no amount")
+
+// ErrDraftNeedsReview is returned when a receipt draft with issues is saved
+// without force.
+var ErrDraftNeedsReview = errors.New("draft needs review")
+
+// Reasons a slip or receipt draft needs a person to check it before it is
+// saved.
+const (
+	IssueAmountMissing = "amount_missing"
+	IssueDateMissing   = "date_missing"
+	IssueLowConfidence = "low_confidence"
+	IssueItemsMismatch = "items_mismatch"
+)
+
+// SlipDraft is a transaction read from a slip image before it is saved.
+type SlipDraft struct {
+	Date       time.Time     `json:"date"`
+	Amount     float64       `json:"amount"`
+	Category   string        `json:"category"`
+	Note       string        `json:"note"`
+	BankCode   string        `json:"bank_code"`
+	TransRef   string        `json:"trans_ref"`
+	ImageHash  string        `json:"image_hash"`
+	Confidence float64       `json:"confidence"`
+	Regions    []slip.Region `json:"regions"`
+	Issues     []string      `json:"issues"`
+}
+
+// NeedsReview reports whether the draft cannot be saved as read.
+func (d SlipDraft) NeedsReview() bool {
+	return len(d.Issues) > 0
+}
+
+// DraftCorrection holds the fields a reviewer changed; nil fields are kept.
+type DraftCorrection struct {
+	Date     *time.Time `json:"date"`
+	Amount   *float64   `json:"amount" validate:"omitempty,gt=0"`
+	Category *string    `json:"category" validate:"omitempty,max=50"`
+	Note     *string    `json:"note" validate:"omitempty,max=255"`
+}
+
+// Apply corrects the draft. Corrected fields were checked by a person, so
+// the issues about them are dropped, as is low confidence.
+func (d *SlipDraft) Apply(correction DraftCorrection) {
+	if correction.Date != nil {
+		d.Date = *correction.Date
+	}
+	if correction.Amount != nil {
+		d.Amount = *correction.Amount
+	}
+	if correction.Category != nil {
+		d.Category = *correction.Category
+	}
+	if correction.Note != nil {
+		d.Note = *correction.Note
+	}
+
+	var issues []string
+	for _, issue := range d.Issues {
+		switch {
+		case issue == IssueAmountMissing && d.Amount > 0:
+		case issue == IssueDateMissing && correction.Date != nil:
+		case issue == IssueLowConfidence:
+		default:
+			issues = append(issues, issue)
+		}
+	}
+	d.Issues = issues
+}
+
+// ReceiptDraft is a purchase read from a store receipt before it is saved.
+// Amount is what was paid, after discounts.
+type ReceiptDraft struct {
+	Date       time.Time     `json:"date"`
+	Merchant   string        `json:"merchant"`
+	TaxId      string        `json:"tax_id"`
+	Reference  string        `json:"reference"`
+	Subtotal   float64       `json:"subtotal"`
+	Discount   float64       `json:"discount"`
+	VAT        float64       `json:"vat"`
+	Amount     float64       `json:"amount"`
+	Category   string        `json:"category"`
+	ImageHash  string        `json:"image_hash"`
+	Confidence float64       `json:"confidence"`
+	Items      []ReceiptItem `json:"items"`
+	Issues     []string      `json:"issues"`
+}
+
+// ReceiptItem is one line of a receipt. Its category starts as the receipt's
+// and can be changed on its own.
+type ReceiptItem struct {
+	Name      string  `json:"name"`
+	Quantity  float64 `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Amount    float64 `json:"amount"`
+	Category  string  `json:"category"`
+}
+
+func (d ReceiptDraft) NeedsReview() bool {
+	return len(d.Issues) > 0
+}
+
+type GetItemResponse struct {
+	ID        uint    `json:"id"`
+	Name      string  `json:"name"`
+	Quantity  float64 `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Amount    float64 `json:"amount"`
+	Category  string  `json:"category"`
+}
+
+type UpdateItemRequest struct {
+	Category string `json:"category" validate:"required,max=50"`
+}
+
+// EmailDraft is a payment read from an emailed bank notification or
+// e-receipt before it is saved.
+type EmailDraft struct {
+	Date            time.Time `json:"date"`
+	Amount          float64   `json:"amount"`
+	TransactionType string    `json:"transaction_type"`
+	Category        string    `json:"category"`
+	Merchant        string    `json:"merchant"`
+	Note            string    `json:"note"`
+	BankCode        string    `json:"bank_code"`
+	TransRef        string    `json:"trans_ref"`
+	Issues          []string  `json:"issues"`
+}
+
+func (d EmailDraft) NeedsReview() bool {
+	return len(d.Issues) > 0
+}
+
+// Apply corrects the draft the way SlipDraft.Apply does.
+func (d *EmailDraft) Apply(correction DraftCorrection) {
+	if correction.Date != nil {
+		d.Date = *correction.Date
+	}
+	if correction.Amount != nil {
+		d.Amount = *correction.Amount
+	}
+	if correction.Category != nil {
+		d.Category = *correction.Category
+	}
+	if correction.Note != nil {
+		d.Note = *correction.Note
+	}
+
+	var issues []string
+	for _, issue := range d.Issues {
+		if issue == IssueAmountMissing && d.Amount > 0 {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	d.Issues = issues
 }
@@ -3,25 +3,61 @@ package transaction
 import (
 	"errors"
 	"fmt"
+	"github.com/Montheankul-K/jod-jod/blobstore"
 	"github.com/Montheankul-K/jod-jod/config"
 	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/insight"
+	"github.com/Montheankul-K/jod-jod/domains/networth"
+	"github.com/Montheankul-K/jod-jod/exportfile"
+	"github.com/Montheankul-K/jod-jod/imagehash"
+	"github.com/Montheankul-K/jod-jod/imageproc"
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"github.com/Montheankul-K/jod-jod/receipt"
+	"github.com/Montheankul-K/jod-jod/repository/attachment_repository"
 	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/textract"
+	"github.com/Montheankul-K/jod-jod/slip"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
-	"mime/multipart"
-	"strconv"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"path"
 	"strings"
 	"time"
 )
 
+const (
+	// duplicateHashDistance is the most image hash bits two copies of one
+	// slip differ by after re-encoding or resizing.
+	duplicateHashDistance = 4
+	// duplicateWindow is how far apart two manual entries may be and still be
+	// taken for the same purchase entered twice.
+	duplicateWindow = 24 * time.Hour
+	// duplicateAmountTolerance is the relative amount difference, or 1 baht if
+	// larger, within which two amounts count as the same.
+	duplicateAmountTolerance = 0.01
+	// reviewConfidence is the OCR confidence, out of 100, below which a slip
+	// is checked by a person before it is saved.
+	reviewConfidence = 80
+	// imageURLExpiry is how long a presigned image URL stays valid.
+	imageURLExpiry = 5 * time.Minute
+	// receiptCategory is the category a receipt and its items start with.
+	receiptCategory = "shopping"
+	// itemsTolerance is how far, in baht, a receipt's items may add up from
+	// its total before a misread line is suspected.
+	itemsTolerance = 1
+)
+
 type ITransactionService interface {
-	SaveByManual(req Transaction) (uint, error)
-	SaveFromSlip(spenderId uint, file *multipart.FileHeader) (uint, error)
+	SaveByManual(req Transaction, force bool) (uint, error)
+	ParseSlip(image []byte) (*SlipDraft, error)
+	SaveSlip(spenderId uint, draft SlipDraft, filename string, image []byte, force bool) (uint, error)
+	ParseReceipt(image []byte) (*ReceiptDraft, error)
+	SaveReceipt(spenderId uint, draft ReceiptDraft, filename string, image []byte, force bool) (uint, error)
+	SaveEmail(spenderId uint, draft EmailDraft, force bool) (uint, error)
+	GetItems(spenderId, txnId uint) ([]GetItemResponse, error)
+	UpdateItem(spenderId, txnId, itemId uint, req UpdateItemRequest) error
 	GetDetails(req GetByTxnTypeRequest) ([]GetAllByTxnTypeResponse, error)
 	GetSummary(req GetByTxnTypeRequest) (*GetSummaryResponse, error)
 	GetBalance(spenderId uint) (*GetBalanceResponse, error)
@@ -30,23 +66,39 @@ type ITransactionService interface {
 	Update(txnId uint, req Transaction) error
 	Delete(spenderId, txnId uint) error
 	GetAllTxn(filter GetAllTxnFilter, pagination Pagination) ([]GetAllResponse, error)
+	Export(spenderId uint, filter GetAllTxnFilter, opts ExportOptions, w io.Writer) error
+	GetImage(spenderId, txnId uint, thumbnail bool) (*TransactionImage, error)
 }
 
 type transactionService struct {
 	cfg                   *config.Config
 	transactionRepository transaction_repository.ITransactionRepository
+	attachmentRepository  attachment_repository.IAttachmentRepository
+	insightService        insight.IInsightService
+	netWorthService       networth.INetWorthService
+	ocrProvider           ocr.OCRProvider
+	blobStore             blobstore.BlobStore
+	slipRegistry          *slip.Registry
+	receiptRegistry       *receipt.Registry
 	logger                echo.Logger
 }
 
-func NewTransactionService(cfg *config.Config, transactionRepository transaction_repository.ITransactionRepository, logger echo.Logger) ITransactionService {
+func NewTransactionService(cfg *config.Config, transactionRepository transaction_repository.ITransactionRepository, attachmentRepository attachment_repository.IAttachmentRepository, insightService insight.IInsightService, netWorthService networth.INetWorthService, ocrProvider ocr.OCRProvider, blobStore blobstore.BlobStore, logger echo.Logger) ITransactionService {
 	return &transactionService{
 		cfg:                   cfg,
 		transactionRepository: transactionRepository,
+		attachmentRepository:  attachmentRepository,
+		insightService:        insightService,
+		netWorthService:       netWorthService,
+		ocrProvider:           ocrProvider,
+		blobStore:             blobStore,
+		slipRegistry:          slip.DefaultRegistry(),
+		receiptRegistry:       receipt.DefaultRegistry(),
 		logger:                logger,
 	}
 }
 
-func (s *transactionService) SaveByManual(req Transaction) (uint, error) {
+func (s *transactionService) SaveByManual(req Transaction, force bool) (uint, error) {
 	txn := entities.Transaction{
 		Date:            req.Date,
 		Amount:          req.Amount,
@@ -56,124 +108,568 @@ func (s *transactionService) SaveByManual(req Transaction) (uint, error) {
 		ImageUrl:        req.ImageUrl,
 		SpenderId:       req.SpenderId,
 	}
+	if !force {
+		if err := s.checkPossibleDuplicate(txn); err != nil {
+			return 0, err
+		}
+	}
+
 	result, err := s.transactionRepository.SaveTxn(txn)
 	if err != nil {
 		return 0, errors.New("failed to save transaction")
 	}
 	s.logger.Infof("saved transaction with ID: %d success", result)
+	s.analyzeTransaction(result, txn)
+	if !req.Date.IsZero() {
+		s.recomputeNetWorth(uint(req.SpenderId), req.Date)
+	}
 	return result, nil
 }
 
-func (s *transactionService) SaveFromSlip(spenderId uint, file *multipart.FileHeader) (uint, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(s.cfg.AWS.Region),
-		Credentials: credentials.NewStaticCredentials(s.cfg.AWS.AccessKeyID, s.cfg.AWS.SecretAccessKey, ""),
-	})
-	if err != nil {
+// analyzeTransaction flags unusual spending after a save. A failure here must
+// not undo a transaction the user already recorded, so it is only logged.
+func (s *transactionService) analyzeTransaction(txnId uint, txn entities.Transaction) {
+	if err := s.insightService.AnalyzeTransaction(txnId, txn); err != nil {
 		s.logger.Error(err)
-		return 0, errors.New("failed to create aws session")
+	}
+}
+
+// recomputeNetWorth refreshes the net-worth snapshots already recorded from
+// date onwards when a transaction lands on an earlier day. Today's snapshot is
+// written by the daily job, so changes dated today need no recompute.
+func (s *transactionService) recomputeNetWorth(spenderId uint, date time.Time) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !date.Before(today) {
+		return
+	}
+	if err := s.netWorthService.RecomputeSnapshots(spenderId, date, today.AddDate(0, 0, -1)); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+// ParseSlip reads a slip image into a transaction that is not saved yet. A
+// slip whose amount or date could not be read, or was read with low
+// confidence, comes back with issues for a person to resolve rather than an
+// error; an error is worth retrying.
+func (s *transactionService) ParseSlip(image []byte) (*SlipDraft, error) {
+	draft := &SlipDraft{Date: time.Now()}
+	if hash, err := imagehash.FromBytes(image); err == nil {
+		draft.ImageHash = imagehash.Format(hash)
 	}
 
-	src, err := file.Open()
+	slipResult, err := s.extractTextFromSlip(image)
 	if err != nil {
 		s.logger.Error(err)
-		return 0, errors.New("failed to open silp file")
+		if errors.Is(err, slip.ErrAmountNotFound) {
+			draft.Category = slip.KindTransfer
+			draft.Issues = []string{IssueAmountMissing, IssueDateMissing}
+			return draft, nil
+		}
+		return nil, errors.New("failed to extract text from slip image")
+	}
+	bankCode, transRef := s.verificationFromSlip(image, slipResult)
+
+	// The transfer fee leaves the account too, so it is part of the expense.
+	draft.Amount = slipResult.Amount + slipResult.Fee
+	draft.Category = slipResult.Kind
+	draft.Note = slipNote(slipResult)
+	draft.BankCode = bankCode
+	draft.TransRef = transRef
+	draft.Confidence = slipResult.Confidence
+	draft.Regions = slipResult.Regions
+	if !slipResult.Date.IsZero() {
+		draft.Date = slipResult.Date
+	}
+
+	if draft.Amount <= 0 {
+		draft.Issues = append(draft.Issues, IssueAmountMissing)
+	}
+	if slipResult.Date.IsZero() {
+		draft.Issues = append(draft.Issues, IssueDateMissing)
+	}
+	if slipResult.Confidence < reviewConfidence {
+		draft.Issues = append(draft.Issues, IssueLowConfidence)
+	}
+	return draft, nil
+}
+
+// SaveSlip uploads the slip image and saves its draft as an expense unless
+// it duplicates one already saved and force is not set.
+func (s *transactionService) SaveSlip(spenderId uint, draft SlipDraft, filename string, image []byte, force bool) (uint, error) {
+	if draft.Amount <= 0 {
+		return 0, ErrDraftIncomplete
+	}
+
+	txn := entities.Transaction{
+		Date:            draft.Date,
+		Amount:          draft.Amount,
+		Category:        draft.Category,
+		TransactionType: "expense",
+		Note:            draft.Note,
+		BankCode:        draft.BankCode,
+		TransRef:        draft.TransRef,
+		ImageHash:       draft.ImageHash,
+		SpenderId:       int(spenderId),
+	}
+	if !force {
+		if err := s.checkSlipDuplicate(txn); err != nil {
+			return 0, err
+		}
 	}
-	defer src.Close()
 
-	s3Service := s3.New(sess)
-	filename := file.Filename
-	s3Path := s.cfg.AWS.BucketSlipPath
-	objectKey := fmt.Sprintf("%s/%d_%s_%s", s3Path, spenderId, time.Now().Format("20060102150405"), filename)
-	input := &s3.PutObjectInput{
-		Bucket: aws.String(s.cfg.AWS.Bucket),
-		Key:    aws.String(objectKey),
-		Body:   src,
+	imageKey, thumbnailKey, err := s.uploadSlip(spenderId, filename, image)
+	if err != nil {
+		return 0, err
 	}
+	txn.ImageUrl = imageKey
+	txn.ThumbnailUrl = thumbnailKey
 
-	_, err = s3Service.PutObject(input)
+	result, err := s.transactionRepository.SaveTxn(txn)
 	if err != nil {
 		s.logger.Error(err)
-		return 0, errors.New("failed to upload silp file to S3")
+		return 0, errors.New("failed to save transaction")
+	}
+	s.logger.Infof("saved transaction with ID: %d success", result)
+	s.analyzeTransaction(result, txn)
+	s.recomputeNetWorth(spenderId, txn.Date)
+	return result, nil
+}
+
+// ParseReceipt reads a store receipt into a purchase that is not saved yet.
+// Like ParseSlip, what could not be read is reported as issues; items that do
+// not add up to the total are flagged too, as one line was likely misread.
+func (s *transactionService) ParseReceipt(image []byte) (*ReceiptDraft, error) {
+	draft := &ReceiptDraft{Date: time.Now(), Category: receiptCategory}
+	if hash, err := imagehash.FromBytes(image); err == nil {
+		draft.ImageHash = imagehash.Format(hash)
 	}
-	s.logger.Infof("uplaod slip image: %s to S3 success", objectKey)
 
-	extractTextResult, err := s.extractTextFromSlip(objectKey)
+	detected, err := s.detectText(image)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.receiptRegistry.Parse(detected)
 	if err != nil {
 		s.logger.Error(err)
-		return 0, errors.New("failed to extract text from slip image")
+		draft.Issues = []string{IssueAmountMissing, IssueDateMissing}
+		return draft, nil
+	}
+	s.logger.Infof("extract text from %s receipt success", result.Merchant)
+
+	draft.Merchant = result.Merchant
+	draft.TaxId = result.TaxId
+	draft.Reference = result.Reference
+	draft.Subtotal = result.Subtotal
+	draft.Discount = result.Discount
+	draft.VAT = result.VAT
+	draft.Amount = result.Total
+	draft.Confidence = result.Confidence
+	if !result.Date.IsZero() {
+		draft.Date = result.Date
+	}
+	for _, item := range result.Items {
+		draft.Items = append(draft.Items, ReceiptItem{
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Amount:    item.Amount,
+			Category:  draft.Category,
+		})
+	}
+
+	if draft.Amount <= 0 {
+		draft.Issues = append(draft.Issues, IssueAmountMissing)
+	}
+	if result.Date.IsZero() {
+		draft.Issues = append(draft.Issues, IssueDateMissing)
+	}
+	if len(result.Items) > 0 && math.Abs(result.ItemsTotal()-result.Total) > itemsTolerance {
+		draft.Issues = append(draft.Issues, IssueItemsMismatch)
+	}
+	if result.Confidence < reviewConfidence {
+		draft.Issues = append(draft.Issues, IssueLowConfidence)
+	}
+	return draft, nil
+}
+
+// SaveReceipt uploads the receipt image and saves its draft as one expense
+// with the items as its lines. Unless force is set, a draft with issues, such
+// as a date that was not read, or one that duplicates a receipt already
+// saved is not saved.
+func (s *transactionService) SaveReceipt(spenderId uint, draft ReceiptDraft, filename string, image []byte, force bool) (uint, error) {
+	if draft.Amount <= 0 {
+		return 0, ErrDraftIncomplete
+	}
+	if draft.NeedsReview() && !force {
+		return 0, ErrDraftNeedsReview
+	}
+	if draft.Category == "" {
+		draft.Category = receiptCategory
 	}
 
 	txn := entities.Transaction{
-		Date:            time.Now(),
-		Amount:          extractTextResult.Amount,
-		Category:        extractTextResult.Category,
+		Date:            draft.Date,
+		Amount:          draft.Amount,
+		Category:        draft.Category,
 		TransactionType: "expense",
-		ImageUrl:        objectKey,
+		Note:            truncate(draft.Merchant, 255),
+		TransRef:        receiptRef(draft),
+		ImageHash:       draft.ImageHash,
+		Merchant:        truncate(draft.Merchant, 100),
+		TaxId:           truncate(draft.TaxId, 13),
+		VAT:             draft.VAT,
 		SpenderId:       int(spenderId),
 	}
+	if !force {
+		if err := s.checkSlipDuplicate(txn); err != nil {
+			return 0, err
+		}
+	}
+
+	imageKey, thumbnailKey, err := s.uploadSlip(spenderId, filename, image)
+	if err != nil {
+		return 0, err
+	}
+	txn.ImageUrl = imageKey
+	txn.ThumbnailUrl = thumbnailKey
+
+	items := make([]entities.TransactionItem, 0, len(draft.Items))
+	for _, item := range draft.Items {
+		category := item.Category
+		if category == "" {
+			category = draft.Category
+		}
+		items = append(items, entities.TransactionItem{
+			Name:      truncate(item.Name, 255),
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Amount:    item.Amount,
+			Category:  category,
+		})
+	}
+
+	result, err := s.transactionRepository.SaveTxnWithItems(txn, items)
+	if err != nil {
+		return 0, errors.New("failed to save transaction")
+	}
+	s.logger.Infof("saved receipt transaction with ID: %d and %d items success", result, len(items))
+	s.analyzeTransaction(result, txn)
+	s.recomputeNetWorth(spenderId, txn.Date)
+	return result, nil
+}
+
+// SaveEmail saves a draft read from an email. One with a reference is
+// checked for duplicates like a slip, since the same payment often arrives as
+// both a slip and a notification; one without is checked like a manual entry.
+func (s *transactionService) SaveEmail(spenderId uint, draft EmailDraft, force bool) (uint, error) {
+	if draft.Amount <= 0 {
+		return 0, ErrDraftIncomplete
+	}
+
+	note := draft.Note
+	if note == "" {
+		note = draft.Merchant
+	}
+	txn := entities.Transaction{
+		Date:            draft.Date,
+		Amount:          draft.Amount,
+		Category:        draft.Category,
+		TransactionType: draft.TransactionType,
+		Note:            note,
+		BankCode:        draft.BankCode,
+		TransRef:        draft.TransRef,
+		Merchant:        draft.Merchant,
+		SpenderId:       int(spenderId),
+	}
+	if !force {
+		check := s.checkPossibleDuplicate
+		if txn.TransRef != "" {
+			check = s.checkSlipDuplicate
+		}
+		if err := check(txn); err != nil {
+			return 0, err
+		}
+	}
 
 	result, err := s.transactionRepository.SaveTxn(txn)
 	if err != nil {
-		s.logger.Error(err)
 		return 0, errors.New("failed to save transaction")
 	}
+	s.logger.Infof("saved email transaction with ID: %d success", result)
+	s.analyzeTransaction(result, txn)
+	s.recomputeNetWorth(spenderId, txn.Date)
 	return result, nil
 }
 
-func (s *transactionService) extractTextFromSlip(objectKey string) (*TextractResult, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(s.cfg.AWS.Region),
-		Credentials: credentials.NewStaticCredentials(s.cfg.AWS.AccessKeyID, s.cfg.AWS.SecretAccessKey, ""),
-	})
+// receiptRef identifies a receipt by the store's tax ID and the receipt
+// number, which together are unique, for duplicate detection.
+func receiptRef(draft ReceiptDraft) string {
+	if draft.TaxId == "" || draft.Reference == "" {
+		return ""
+	}
+	return truncate(draft.TaxId+":"+draft.Reference, 50)
+}
+
+// truncate cuts text read by OCR to size characters, the width of the column
+// it is saved in.
+func truncate(text string, size int) string {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text
+	}
+	return string(runes[:size])
+}
+
+func (s *transactionService) GetItems(spenderId, txnId uint) ([]GetItemResponse, error) {
+	if err := s.checkOwner(spenderId, txnId); err != nil {
+		return nil, err
+	}
+
+	results, err := s.transactionRepository.GetItems(txnId)
 	if err != nil {
+		return nil, errors.New("failed to get transaction items")
+	}
+	newResults := make([]GetItemResponse, 0, len(results))
+	for _, value := range results {
+		newResults = append(newResults, GetItemResponse{
+			ID:        value.ID,
+			Name:      value.Name,
+			Quantity:  value.Quantity,
+			UnitPrice: value.UnitPrice,
+			Amount:    value.Amount,
+			Category:  value.Category,
+		})
+	}
+	return newResults, nil
+}
+
+// UpdateItem recategorizes one line of a receipt. The transaction keeps its
+// own category.
+func (s *transactionService) UpdateItem(spenderId, txnId, itemId uint, req UpdateItemRequest) error {
+	if err := s.checkOwner(spenderId, txnId); err != nil {
+		return err
+	}
+
+	err := s.transactionRepository.UpdateItemCategory(txnId, itemId, req.Category)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to update transaction item")
+	}
+	s.logger.Infof("update item %d of transaction %d success", itemId, txnId)
+	return nil
+}
+
+// checkOwner reports another spender's transaction as not found.
+func (s *transactionService) checkOwner(spenderId, txnId uint) error {
+	txn, err := s.transactionRepository.GetTxn(txnId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to get transaction")
+	}
+	if uint(txn.SpenderId) != spenderId {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// uploadSlip stores a clean copy of the slip and its thumbnail. An image that
+// cannot be decoded is stored as it came, without a thumbnail.
+func (s *transactionService) uploadSlip(spenderId uint, filename string, image []byte) (string, string, error) {
+	name := fmt.Sprintf("%s/%d_%s_%s", s.cfg.SlipPath(), spenderId, time.Now().Format("20060102150405"), blobstore.SafeName(filename, "slip"))
+
+	processed, err := imageproc.Process(image)
+	if err != nil {
+		s.logger.Warnf("store slip image %s unprocessed: %v", name, err)
+		if err = s.blobStore.Put(name, image, http.DetectContentType(image)); err != nil {
+			s.logger.Error(err)
+			return "", "", errors.New("failed to upload slip image")
+		}
+		return name, "", nil
+	}
+
+	base := strings.TrimSuffix(name, path.Ext(name))
+	imageKey := base + processed.Ext
+	thumbnailKey := base + "_thumb.jpg"
+	if err = s.blobStore.Put(imageKey, processed.Image, processed.ContentType); err != nil {
+		s.logger.Error(err)
+		return "", "", errors.New("failed to upload slip image")
+	}
+	if err = s.blobStore.Put(thumbnailKey, processed.Thumbnail, "image/jpeg"); err != nil {
 		s.logger.Error(err)
-		return nil, errors.New("failed to create aws session")
+		return "", "", errors.New("failed to upload slip thumbnail")
+	}
+	s.logger.Infof("upload slip image: %s success", imageKey)
+	return imageKey, thumbnailKey, nil
+}
+
+// GetImage opens a transaction's image, or its thumbnail when asked and one
+// exists. Stores that can presign return a short-lived URL instead of the
+// content. Another spender's transaction is reported as not found.
+func (s *transactionService) GetImage(spenderId, txnId uint, thumbnail bool) (*TransactionImage, error) {
+	txn, err := s.transactionRepository.GetTxn(txnId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		return nil, errors.New("failed to get transaction")
+	}
+	if uint(txn.SpenderId) != spenderId {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	key := txn.ImageUrl
+	if thumbnail && txn.ThumbnailUrl != "" {
+		key = txn.ThumbnailUrl
+	}
+	if key == "" {
+		return nil, ErrImageNotFound
 	}
 
-	textractService := textract.New(sess)
-	input := &textract.DetectDocumentTextInput{
-		Document: &textract.Document{
-			S3Object: &textract.S3Object{
-				Bucket: aws.String(s.cfg.AWS.Bucket),
-				Name:   aws.String(objectKey),
-			},
-		},
+	url, err := s.blobStore.Presign(key, imageURLExpiry)
+	if err == nil {
+		return &TransactionImage{URL: url}, nil
+	}
+	if !errors.Is(err, blobstore.ErrPresignNotSupported) {
+		s.logger.Error(err)
+		return nil, errors.New("failed to presign transaction image")
 	}
 
-	result, err := textractService.DetectDocumentText(input)
+	body, err := s.blobStore.Get(key)
 	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, ErrImageNotFound
+		}
 		s.logger.Error(err)
-		return nil, errors.New("failed to detect document text")
+		return nil, errors.New("failed to get transaction image")
 	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &TransactionImage{Body: body, ContentType: contentType}, nil
+}
 
-	var lines []string
-	for _, block := range result.Blocks {
-		if *block.BlockType == "LINE" {
-			text := *block.Text
-			replaceText := s.FixOCRExtractText(text)
-			lines = append(lines, replaceText)
+// checkSlipDuplicate rejects a slip already saved by the same spender, found
+// by its transaction reference or by a near-identical image. Slips from one
+// bank share a template and can hash alike, so an image match must also agree
+// on the amount.
+func (s *transactionService) checkSlipDuplicate(txn entities.Transaction) error {
+	spenderId := uint(txn.SpenderId)
+	if txn.TransRef != "" {
+		existing, err := s.transactionRepository.GetByTransRef(spenderId, txn.TransRef)
+		if err == nil {
+			return &DuplicateError{TransactionId: existing.ID, Reason: DuplicateReasonReference}
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Error(err)
+			return errors.New("failed to check duplicate transaction")
 		}
 	}
 
-	var textractResult TextractResult
-	if len(lines) == 13 {
-		parts := strings.Split(lines[9], " ")
-		amount, _ := strconv.ParseFloat(parts[0], 64)
-		textractResult = TextractResult{
-			Category: "transfer",
-			Amount:   amount,
+	if txn.ImageHash == "" {
+		return nil
+	}
+	hash, err := imagehash.Parse(txn.ImageHash)
+	if err != nil {
+		return nil
+	}
+	hashes, err := s.transactionRepository.GetSlipHashes(spenderId)
+	if err != nil {
+		return errors.New("failed to check duplicate transaction")
+	}
+	for _, value := range hashes {
+		existing, err := imagehash.Parse(value.ImageHash)
+		if err != nil {
+			continue
 		}
-	} else {
-		parts := strings.Split(lines[11], " ")
-		amount, _ := strconv.ParseFloat(parts[0], 64)
-		textractResult = TextractResult{
-			Category: "bill payment",
-			Amount:   amount,
+		if imagehash.Distance(hash, existing) <= duplicateHashDistance && amountsMatch(value.Amount, txn.Amount) {
+			return &DuplicateError{TransactionId: value.ID, Reason: DuplicateReasonImage}
 		}
 	}
-	s.logger.Info("extract text from slip success")
-	return &textractResult, nil
+	return nil
+}
+
+// checkPossibleDuplicate warns about a manual entry that looks like one made
+// within duplicateWindow of it: same category and type and about the same
+// amount. It may be a genuine second purchase, so the client can force-save.
+func (s *transactionService) checkPossibleDuplicate(txn entities.Transaction) error {
+	date := txn.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	similar, err := s.transactionRepository.GetSimilar(uint(txn.SpenderId), txn.Category, txn.TransactionType, date.Add(-duplicateWindow), date.Add(duplicateWindow))
+	if err != nil {
+		return errors.New("failed to check duplicate transaction")
+	}
+	for _, value := range similar {
+		if amountsMatch(value.Amount, txn.Amount) {
+			return &DuplicateError{TransactionId: value.ID, Reason: DuplicateReasonSimilar, Possible: true}
+		}
+	}
+	return nil
+}
+
+func amountsMatch(a, b float64) bool {
+	return math.Abs(a-b) <= math.Max(duplicateAmountTolerance*math.Max(a, b), 1)
+}
+
+// verificationFromSlip reads the sending bank and transaction reference from
+// the slip's verification QR. Slips without a readable QR, such as e-wallet
+// slips or screenshots cropped above it, fall back to what OCR found. The
+// bank code is then the Bank of Thailand code when known, else the slip bank.
+func (s *transactionService) verificationFromSlip(image []byte, slipResult *slip.Slip) (string, string) {
+	verification, err := slip.DecodeQR(image)
+	if err == nil {
+		return verification.BankCode, verification.TransRef
+	}
+	s.logger.Infof("slip qr not used, falling back to ocr: %v", err)
+
+	bankCode := slip.BankCode(slipResult.Bank)
+	if bankCode == "" && slipResult.Bank != slip.BankUnknown {
+		bankCode = slipResult.Bank
+	}
+	return bankCode, slipResult.Reference
+}
+
+// slipNote prefers the memo the spender typed, then who was paid.
+func slipNote(result *slip.Slip) string {
+	if result.Memo != "" {
+		return result.Memo
+	}
+	return result.Receiver.Name
+}
+
+func (s *transactionService) extractTextFromSlip(image []byte) (*slip.Slip, error) {
+	detected, err := s.detectText(image)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.slipRegistry.Parse(detected)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Infof("extract text from %s slip success", result.Bank)
+	return result, nil
+}
+
+func (s *transactionService) detectText(image []byte) ([]ocr.Line, error) {
+	detected, err := s.ocrProvider.DetectText(image)
+	if err != nil {
+		s.logger.Error(err)
+		return nil, errors.New("failed to detect document text")
+	}
+
+	for i := range detected {
+		detected[i].Text = s.FixOCRExtractText(detected[i].Text)
+	}
+	return detected, nil
 }
 
 func (s *transactionService) FixOCRExtractText(text string) string {
@@ -399,29 +895,87 @@ func (s *transactionService) GetByPeriod(req GetByTxnTypeRequest, filter PeriodF
 }
 
 func (s *transactionService) Update(txnId uint, req Transaction) error {
+	existing, err := s.transactionRepository.GetTxn(txnId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to get transaction")
+	}
+
 	txn := entities.Transaction{
+		Date:            req.Date,
 		Amount:          req.Amount,
 		Category:        req.Category,
 		TransactionType: req.TransactionType,
 		Note:            req.Note,
+		SpenderId:       existing.SpenderId,
 	}
-	err := s.transactionRepository.UpdateTxn(txnId, txn)
+	err = s.transactionRepository.UpdateTxn(txnId, txn)
 	if err != nil {
 		return errors.New("failed to update transaction")
 	}
-	s.logger.Infof("update transaction with transaction id: %d success", req.ID)
+	s.logger.Infof("update transaction with transaction id: %d success", txnId)
+
+	from := existing.Date
+	if !req.Date.IsZero() && req.Date.Before(from) {
+		from = req.Date
+	}
+	s.recomputeNetWorth(uint(existing.SpenderId), from)
 	return nil
 }
 
+// Delete removes a transaction and then the files kept for it: its slip
+// image, thumbnail and attachments.
 func (s *transactionService) Delete(spenderId, txnId uint) error {
-	err := s.transactionRepository.DeleteTxn(spenderId, txnId)
+	txn, err := s.transactionRepository.GetTxn(txnId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to get transaction")
+	}
+	if uint(txn.SpenderId) != spenderId {
+		return gorm.ErrRecordNotFound
+	}
+
+	attachments, err := s.attachmentRepository.GetByTransactionId(txnId)
+	if err != nil {
+		return errors.New("failed to get attachments")
+	}
+
+	err = s.transactionRepository.DeleteTxn(spenderId, txnId)
 	if err != nil {
 		return errors.New("failed to delete transaction")
 	}
 	s.logger.Infof("delete transaction with transaction id: %d success", txnId)
+	s.deleteFiles(txn, attachments)
 	return nil
 }
 
+// deleteFiles runs after the transaction is gone, so a failure is only
+// logged: it leaves an orphaned blob, not a broken transaction.
+func (s *transactionService) deleteFiles(txn *entities.Transaction, attachments []entities.Attachment) {
+	keys := []string{txn.ImageUrl, txn.ThumbnailUrl}
+	for _, value := range attachments {
+		keys = append(keys, value.BlobKey)
+	}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.blobStore.Delete(key); err != nil {
+			s.logger.Errorf("failed to delete blob %s: %v", key, err)
+		}
+	}
+
+	if len(attachments) > 0 {
+		if err := s.attachmentRepository.DeleteByTransactionId(txn.ID); err != nil {
+			s.logger.Error(err)
+		}
+	}
+}
+
 func (s *transactionService) GetAllTxn(filter GetAllTxnFilter, pagination Pagination) ([]GetAllResponse, error) {
 	newFilter := entities.GetAllTxnFilter{
 		Date:     filter.Date,
@@ -454,3 +1008,80 @@ func (s *transactionService) GetAllTxn(filter GetAllTxnFilter, pagination Pagina
 	}
 	return newResults, nil
 }
+
+// Export writes the spender's transactions that match filter to w as they
+// are read from the database. The options are checked before anything is
+// written, so an error from them can still be sent as an error response.
+func (s *transactionService) Export(spenderId uint, filter GetAllTxnFilter, opts ExportOptions, w io.Writer) error {
+	exportOpts := exportfile.Options{
+		Format:  opts.Format,
+		Columns: opts.Columns,
+		Locale:  opts.Locale,
+	}
+	if opts.Timezone != "" {
+		loc, err := time.LoadLocation(opts.Timezone)
+		if err != nil {
+			return err
+		}
+		exportOpts.Location = loc
+	}
+	writer, err := exportfile.NewWriter(w, exportOpts)
+	if err != nil {
+		return err
+	}
+
+	newFilter := entities.GetAllTxnFilter{
+		Date:     filter.Date,
+		Category: filter.Category,
+		TxnType:  filter.TxnType,
+	}
+	var count int
+	err = s.transactionRepository.StreamTxns(spenderId, newFilter, func(txn entities.ExportTxn) error {
+		count++
+		return writer.Write(exportRow(txn))
+	})
+	if err != nil {
+		s.logger.Error(err)
+		return errors.New("failed to export transactions")
+	}
+	if err = writer.Close(); err != nil {
+		s.logger.Error(err)
+		return errors.New("failed to export transactions")
+	}
+	s.logger.Infof("exported %d transactions of spender id: %d as %s", count, spenderId, opts.Format)
+	return nil
+}
+
+// exportRow tags a transaction with where it came from, for journals.
+func exportRow(txn entities.ExportTxn) exportfile.Row {
+	row := exportfile.Row{
+		ID:              txn.ID,
+		Date:            txn.Date,
+		Amount:          txn.Amount,
+		TransactionType: txn.TransactionType,
+		Category:        txn.Category,
+		Note:            txn.Note,
+		Merchant:        txn.Merchant,
+		BankCode:        txn.BankCode,
+		TransRef:        txn.TransRef,
+		TaxId:           txn.TaxId,
+		VAT:             txn.VAT,
+		AccountId:       txn.AccountId,
+		FitId:           txn.FitId,
+	}
+	if txn.AccountId != nil && txn.AccountName != "" {
+		row.Account = &exportfile.Account{
+			ID:       *txn.AccountId,
+			Name:     txn.AccountName,
+			Kind:     txn.AccountKind,
+			Currency: txn.AccountCurrency,
+		}
+	}
+	if txn.ImportBatchId != nil {
+		row.Tags = append(row.Tags, "imported")
+	}
+	if txn.ImageHash != "" {
+		row.Tags = append(row.Tags, "slip")
+	}
+	return row
+}
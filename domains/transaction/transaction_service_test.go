@@ -1,23 +1,61 @@
 package transaction
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/config"
 	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/Montheankul-K/jod-jod/domains/insight"
+	"github.com/Montheankul-K/jod-jod/domains/networth"
+	"github.com/Montheankul-K/jod-jod/exportfile"
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"github.com/Montheankul-K/jod-jod/receipt"
 	"github.com/Montheankul-K/jod-jod/repository/mocks"
+	"github.com/Montheankul-K/jod-jod/slip"
 	"github.com/labstack/echo/v4"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gorm.io/gorm"
+	imagepkg "image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 )
 
+type insightServiceStub struct{}
+
+func (insightServiceStub) AnalyzeTransaction(txnId uint, txn entities.Transaction) error {
+	return nil
+}
+
+func (insightServiceStub) GetAnomalies(spenderId uint) ([]insight.GetAnomalyResponse, error) {
+	return nil, nil
+}
+
+type netWorthServiceStub struct {
+	networth.INetWorthService
+}
+
+func (netWorthServiceStub) RecomputeSnapshots(spenderId uint, from, to time.Time) error {
+	return nil
+}
+
 func TestTransactionService_SaveByManual_Success(t *testing.T) {
 	mockRepo := new(mocks.TransactionRepositoryMock)
 	logger := echo.New().Logger
 
+	mockRepo.On("GetSimilar", uint(1), "food", "", mock.Anything, mock.Anything).Return([]entities.Transaction{}, nil)
 	mockRepo.On("SaveTxn", mock.Anything).Return(uint(1), nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := Transaction{
 		Date:      time.Now(),
@@ -27,7 +65,7 @@ func TestTransactionService_SaveByManual_Success(t *testing.T) {
 		ImageUrl:  "https://image.jpg",
 		SpenderId: 1,
 	}
-	result, err := service.SaveByManual(req)
+	result, err := service.SaveByManual(req, false)
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint(1), result)
@@ -37,8 +75,9 @@ func TestTransactionService_SaveByManual_Error(t *testing.T) {
 	mockRepo := new(mocks.TransactionRepositoryMock)
 	logger := echo.New().Logger
 
+	mockRepo.On("GetSimilar", uint(1), "food", "", mock.Anything, mock.Anything).Return([]entities.Transaction{}, nil)
 	mockRepo.On("SaveTxn", mock.Anything).Return(uint(0), errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := Transaction{
 		Date:      time.Now(),
@@ -48,11 +87,101 @@ func TestTransactionService_SaveByManual_Error(t *testing.T) {
 		ImageUrl:  "https://image.jpg",
 		SpenderId: 1,
 	}
-	_, err := service.SaveByManual(req)
+	_, err := service.SaveByManual(req, false)
 
 	assert.EqualError(t, err, "failed to save transaction")
 }
 
+func TestTransactionService_SaveByManual_PossibleDuplicate(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	existing := entities.Transaction{Amount: 1005, Category: "food"}
+	existing.ID = 7
+	mockRepo.On("GetSimilar", uint(1), "food", "expense", mock.Anything, mock.Anything).Return([]entities.Transaction{existing}, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	req := Transaction{
+		Date:            time.Now(),
+		Amount:          1000,
+		Category:        "food",
+		TransactionType: "expense",
+		SpenderId:       1,
+	}
+	_, err := service.SaveByManual(req, false)
+
+	var duplicate *DuplicateError
+	assert.ErrorAs(t, err, &duplicate)
+	assert.Equal(t, uint(7), duplicate.TransactionId)
+	assert.True(t, duplicate.Possible)
+	mockRepo.AssertNotCalled(t, "SaveTxn", mock.Anything)
+}
+
+func TestTransactionService_SaveByManual_Force(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("SaveTxn", mock.Anything).Return(uint(8), nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	req := Transaction{
+		Date:            time.Now(),
+		Amount:          1000,
+		Category:        "food",
+		TransactionType: "expense",
+		SpenderId:       1,
+	}
+	result, err := service.SaveByManual(req, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(8), result)
+	mockRepo.AssertNotCalled(t, "GetSimilar", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransactionService_CheckSlipDuplicate_Reference(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	existing := &entities.Transaction{}
+	existing.ID = 3
+	mockRepo.On("GetByTransRef", uint(1), "015071101522BPM05423").Return(existing, nil)
+	service := &transactionService{transactionRepository: mockRepo, logger: logger}
+
+	err := service.checkSlipDuplicate(entities.Transaction{TransRef: "015071101522BPM05423", SpenderId: 1})
+
+	assert.Equal(t, &DuplicateError{TransactionId: 3, Reason: DuplicateReasonReference}, err)
+}
+
+func TestTransactionService_CheckSlipDuplicate_Image(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetByTransRef", uint(1), "REF").Return((*entities.Transaction)(nil), gorm.ErrRecordNotFound)
+	mockRepo.On("GetSlipHashes", uint(1)).Return([]entities.SlipHash{
+		{ID: 4, Amount: 99, ImageHash: "f0f0f0f0f0f0f0f1"},
+		{ID: 5, Amount: 1250, ImageHash: "f0f0f0f0f0f0f0f3"},
+	}, nil)
+	service := &transactionService{transactionRepository: mockRepo, logger: logger}
+
+	err := service.checkSlipDuplicate(entities.Transaction{TransRef: "REF", ImageHash: "f0f0f0f0f0f0f0f0", Amount: 1250, SpenderId: 1})
+
+	assert.Equal(t, &DuplicateError{TransactionId: 5, Reason: DuplicateReasonImage}, err)
+}
+
+func TestTransactionService_CheckSlipDuplicate_None(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetSlipHashes", uint(1)).Return([]entities.SlipHash{
+		{ID: 6, Amount: 1250, ImageHash: "0f0f0f0f0f0f0f0f"},
+	}, nil)
+	service := &transactionService{transactionRepository: mockRepo, logger: logger}
+
+	err := service.checkSlipDuplicate(entities.Transaction{ImageHash: "f0f0f0f0f0f0f0f0", Amount: 1250, SpenderId: 1})
+
+	assert.NoError(t, err)
+}
+
 func TestTransactionService_GetDetails_Success(t *testing.T) {
 	mockRepo := new(mocks.TransactionRepositoryMock)
 	logger := echo.New().Logger
@@ -61,7 +190,7 @@ func TestTransactionService_GetDetails_Success(t *testing.T) {
 	mockRepo.On("GetByTxnType", mock.Anything).Return([]entities.GetAllByTxnTypeResponse{
 		{ID: uint(1), Date: &date, Amount: 1000, Category: "food", ImageUrl: ""},
 	}, nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -81,7 +210,7 @@ func TestTransactionService_GetDetails_RecordNotFound(t *testing.T) {
 
 	mockRepo.On("GetByTxnType", mock.Anything).Return([]entities.GetAllByTxnTypeResponse{},
 		gorm.ErrRecordNotFound)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -98,7 +227,7 @@ func TestTransactionService_GetDetails_OtherError(t *testing.T) {
 
 	mockRepo.On("GetByTxnType", mock.Anything).Return([]entities.GetAllByTxnTypeResponse{},
 		errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -119,7 +248,7 @@ func TestTransactionService_GetSummary_Success(t *testing.T) {
 		{ID: uint(1), Date: &date1, Amount: 1000, Category: "food", ImageUrl: ""},
 		{ID: uint(2), Date: &date2, Amount: 2000, Category: "food", ImageUrl: ""},
 	}, nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -138,7 +267,7 @@ func TestTransactionService_GetSummary_RecordNotFound(t *testing.T) {
 	logger := echo.New().Logger
 
 	mockRepo.On("GetByTxnType", mock.Anything).Return([]entities.GetAllByTxnTypeResponse{}, gorm.ErrRecordNotFound)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -154,7 +283,7 @@ func TestTransactionService_GetSummary_OtherError(t *testing.T) {
 	logger := echo.New().Logger
 
 	mockRepo.On("GetByTxnType", mock.Anything).Return([]entities.GetAllByTxnTypeResponse{}, errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -175,7 +304,7 @@ func TestTransactionService_GetBalance_Success(t *testing.T) {
 		{ID: uint(1), Date: &date1, Amount: 1000, Category: "food", ImageUrl: "", TransactionType: "expense"},
 		{ID: uint(2), Date: &date2, Amount: 2000, Category: "food", ImageUrl: "", TransactionType: "expense"},
 	}, nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	result, err := service.GetBalance(spenderId)
 
@@ -191,7 +320,7 @@ func TestTransactionService_GetBalance_RecordNotFound(t *testing.T) {
 
 	spenderId := uint(1)
 	mockRepo.On("GetAllBySpenderId", mock.Anything).Return([]entities.GetAllResponse{}, gorm.ErrRecordNotFound)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	_, err := service.GetBalance(spenderId)
 
@@ -204,7 +333,7 @@ func TestTransactionService_GetBalance_OtherError(t *testing.T) {
 
 	spenderId := uint(1)
 	mockRepo.On("GetAllBySpenderId", mock.Anything).Return([]entities.GetAllResponse{}, errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	_, err := service.GetBalance(spenderId)
 
@@ -221,7 +350,7 @@ func TestTransactionService_GetByCategory_Success(t *testing.T) {
 		{ID: uint(1), Date: date1, Amount: 1000, ImageUrl: ""},
 		{ID: uint(2), Date: date2, Amount: 2000, ImageUrl: ""},
 	}, nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByCategoryRequest{
 		SpenderId: uint(1),
@@ -243,7 +372,7 @@ func TestTransactionService_GetByCategory_RecordNotFound(t *testing.T) {
 	logger := echo.New().Logger
 
 	mockRepo.On("GetByCategory", mock.Anything).Return([]entities.GetByCategoryResponse{}, gorm.ErrRecordNotFound)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByCategoryRequest{
 		SpenderId: uint(1),
@@ -260,7 +389,7 @@ func TestTransactionService_GetByCategory_OtherError(t *testing.T) {
 	logger := echo.New().Logger
 
 	mockRepo.On("GetByCategory", mock.Anything).Return([]entities.GetByCategoryResponse{}, errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByCategoryRequest{
 		SpenderId: uint(1),
@@ -282,7 +411,7 @@ func TestTransactionService_GetByPeriod_Success(t *testing.T) {
 		{ID: uint(1), Date: &date1, Amount: 1000, Category: "food", ImageUrl: ""},
 		{ID: uint(2), Date: &date2, Amount: 2000, Category: "food", ImageUrl: ""},
 	}, nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -310,7 +439,7 @@ func TestTransactionService_GetByPeriod_RecordNotFound(t *testing.T) {
 	date2 := time.Now()
 	mockRepo.On("GetByPeriod", mock.Anything, mock.Anything).Return([]entities.GetAllByTxnTypeResponse{},
 		gorm.ErrRecordNotFound)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -332,7 +461,7 @@ func TestTransactionService_GetByPeriod_OtherError(t *testing.T) {
 	date1 := time.Now().AddDate(0, 0, -2)
 	date2 := time.Now()
 	mockRepo.On("GetByPeriod", mock.Anything, mock.Anything).Return([]entities.GetAllByTxnTypeResponse{}, errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := GetByTxnTypeRequest{
 		SpenderId: uint(1),
@@ -352,8 +481,9 @@ func TestTransactionService_Update_Success(t *testing.T) {
 	logger := echo.New().Logger
 
 	txnId := uint(1)
+	mockRepo.On("GetTxn", txnId).Return(&entities.Transaction{Date: time.Now(), SpenderId: 1}, nil)
 	mockRepo.On("UpdateTxn", mock.Anything, mock.Anything).Return(nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := Transaction{
 		Date:      time.Now(),
@@ -373,8 +503,9 @@ func TestTransactionService_Update_Error(t *testing.T) {
 	logger := echo.New().Logger
 
 	txnId := uint(1)
+	mockRepo.On("GetTxn", txnId).Return(&entities.Transaction{Date: time.Now(), SpenderId: 1}, nil)
 	mockRepo.On("UpdateTxn", mock.Anything, mock.Anything).Return(errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	req := Transaction{
 		Date:      time.Now(),
@@ -389,14 +520,31 @@ func TestTransactionService_Update_Error(t *testing.T) {
 	assert.EqualError(t, err, "failed to update transaction")
 }
 
+func TestTransactionService_Update_RecordNotFound(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	txnId := uint(1)
+	mockRepo.On("GetTxn", txnId).Return((*entities.Transaction)(nil), gorm.ErrRecordNotFound)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	err := service.Update(txnId, Transaction{Amount: 1000})
+
+	assert.EqualError(t, err, gorm.ErrRecordNotFound.Error())
+	mockRepo.AssertNotCalled(t, "UpdateTxn", mock.Anything, mock.Anything)
+}
+
 func TestTransactionService_Delete_Success(t *testing.T) {
 	mockRepo := new(mocks.TransactionRepositoryMock)
 	logger := echo.New().Logger
 	spenderId := uint(1)
 	txnId := uint(1)
 
+	mockAttachmentRepo := new(mocks.AttachmentRepositoryMock)
+	mockRepo.On("GetTxn", txnId).Return(&entities.Transaction{SpenderId: 1}, nil)
+	mockAttachmentRepo.On("GetByTransactionId", txnId).Return([]entities.Attachment{}, nil)
 	mockRepo.On("DeleteTxn", mock.Anything, mock.Anything).Return(nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, mockAttachmentRepo, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	err := service.Delete(spenderId, txnId)
 
@@ -409,14 +557,56 @@ func TestTransactionService_Delete_Error(t *testing.T) {
 	spenderId := uint(1)
 	txnId := uint(1)
 
+	mockAttachmentRepo := new(mocks.AttachmentRepositoryMock)
+	mockRepo.On("GetTxn", txnId).Return(&entities.Transaction{SpenderId: 1}, nil)
+	mockAttachmentRepo.On("GetByTransactionId", txnId).Return([]entities.Attachment{}, nil)
 	mockRepo.On("DeleteTxn", mock.Anything, mock.Anything).Return(errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, mockAttachmentRepo, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	err := service.Delete(spenderId, txnId)
 
 	assert.EqualError(t, err, "failed to delete transaction")
 }
 
+func TestTransactionService_Delete_RemovesFiles(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	mockAttachmentRepo := new(mocks.AttachmentRepositoryMock)
+	logger := echo.New().Logger
+	store, _ := blobstore.NewLocalStore(t.TempDir())
+	keys := []string{"slips/a.png", "slips/a_thumb.jpg", "attachments/1/1/b.pdf"}
+	for _, key := range keys {
+		assert.NoError(t, store.Put(key, []byte("x"), ""))
+	}
+
+	mockRepo.On("GetTxn", uint(1)).Return(&entities.Transaction{Model: gorm.Model{ID: 1}, ImageUrl: keys[0], ThumbnailUrl: keys[1], SpenderId: 1}, nil)
+	mockAttachmentRepo.On("GetByTransactionId", uint(1)).Return([]entities.Attachment{{BlobKey: keys[2]}}, nil)
+	mockRepo.On("DeleteTxn", uint(1), uint(1)).Return(nil)
+	mockAttachmentRepo.On("DeleteByTransactionId", uint(1)).Return(nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, mockAttachmentRepo, insightServiceStub{}, netWorthServiceStub{}, nil, store, logger)
+
+	err := service.Delete(1, 1)
+
+	assert.NoError(t, err)
+	for _, key := range keys {
+		_, err = store.Get(key)
+		assert.ErrorIs(t, err, blobstore.ErrNotFound, key)
+	}
+	mockAttachmentRepo.AssertExpectations(t)
+}
+
+func TestTransactionService_Delete_OtherSpender(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetTxn", uint(1)).Return(&entities.Transaction{SpenderId: 2}, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	err := service.Delete(1, 1)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	mockRepo.AssertNotCalled(t, "DeleteTxn", mock.Anything, mock.Anything)
+}
+
 func TestTransactionService_GetAllTxn_Success(t *testing.T) {
 	mockRepo := new(mocks.TransactionRepositoryMock)
 	logger := echo.New().Logger
@@ -426,7 +616,7 @@ func TestTransactionService_GetAllTxn_Success(t *testing.T) {
 		{ID: uint(1), Date: &date1, Amount: 1000, Category: "food", ImageUrl: "", TransactionType: "expense"},
 		{ID: uint(2), Date: &date1, Amount: 2000, Category: "food", ImageUrl: "", TransactionType: "expense"},
 	}, nil)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	filter := GetAllTxnFilter{
 		Date:     &date1,
@@ -456,7 +646,7 @@ func TestTransactionService_GetAllTxn_RecordNotFound(t *testing.T) {
 	date1 := time.Now().AddDate(0, 0, -2)
 	mockRepo.On("GetAllTxn", mock.Anything, mock.Anything).Return([]entities.GetAllResponse{},
 		gorm.ErrRecordNotFound)
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	filter := GetAllTxnFilter{
 		Date:     &date1,
@@ -479,7 +669,7 @@ func TestTransactionService_GetAllTxn_OtherError(t *testing.T) {
 	date1 := time.Now().AddDate(0, 0, -2)
 	mockRepo.On("GetAllTxn", mock.Anything, mock.Anything).Return([]entities.GetAllResponse{},
 		errors.New("some error"))
-	service := NewTransactionService(mockRepo, logger)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
 
 	filter := GetAllTxnFilter{
 		Date:     &date1,
@@ -494,3 +684,394 @@ func TestTransactionService_GetAllTxn_OtherError(t *testing.T) {
 
 	assert.EqualError(t, err, "failed to get transaction")
 }
+
+func writeOCRFixture(t *testing.T, image []byte, texts ...string) string {
+	dir := t.TempDir()
+	var lines []ocr.Line
+	for _, text := range texts {
+		lines = append(lines, ocr.Line{Text: text, Confidence: 99})
+	}
+	data, _ := json.Marshal(lines)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ocr.FixtureName(image)), data, 0o644))
+	return dir
+}
+
+func TestTransactionService_ExtractTextFromSlip_Transfer(t *testing.T) {
+	logger := echo.New().Logger
+	image := []byte("transfer slip")
+	dir := writeOCRFixture(t, image,
+		"โอนเงินสำเร็จ", "12 มี.ค. 67 10:15 น.", "นาย ก", "ธ.กสิกรไทย", "xxx-x-x1234-x", "นาย ข",
+		"ธ.ไทยพาณิชย์", "xxx-x-x5678-x", "จำนวน:", "1250.00 unn", "ค่าธรรมเนียม:", "0.00 unn", "เลขที่รายการ:")
+	service := &transactionService{ocrProvider: ocr.NewFixtureProvider(dir), slipRegistry: slip.DefaultRegistry(), logger: logger}
+
+	result, err := service.extractTextFromSlip(image)
+
+	assert.NoError(t, err)
+	assert.Equal(t, slip.BankKBank, result.Bank)
+	assert.Equal(t, slip.KindTransfer, result.Kind)
+	assert.Equal(t, 1250.0, result.Amount)
+	assert.Equal(t, "นาย ข", result.Receiver.Name)
+}
+
+func TestTransactionService_ExtractTextFromSlip_AmountNotFound(t *testing.T) {
+	logger := echo.New().Logger
+	image := []byte("cropped slip")
+	dir := writeOCRFixture(t, image, "โอนเงินสำเร็จ", "นาย ก")
+	service := &transactionService{ocrProvider: ocr.NewFixtureProvider(dir), slipRegistry: slip.DefaultRegistry(), logger: logger}
+
+	_, err := service.extractTextFromSlip(image)
+
+	assert.ErrorIs(t, err, slip.ErrAmountNotFound)
+}
+
+func TestTransactionService_VerificationFromSlip_QR(t *testing.T) {
+	logger := echo.New().Logger
+	matrix, err := qrcode.NewQRCodeWriter().Encode("0041000600000101030040220015071101522BPM054235102TH910455E0", gozxing.BarcodeFormat_QR_CODE, 200, 200, nil)
+	assert.NoError(t, err)
+	var image bytes.Buffer
+	assert.NoError(t, png.Encode(&image, matrix))
+	service := &transactionService{logger: logger}
+
+	bankCode, transRef := service.verificationFromSlip(image.Bytes(), &slip.Slip{Bank: slip.BankSCB, Reference: "OCR-REF"})
+
+	assert.Equal(t, "004", bankCode)
+	assert.Equal(t, "015071101522BPM05423", transRef)
+}
+
+func TestTransactionService_VerificationFromSlip_FallbackToOCR(t *testing.T) {
+	logger := echo.New().Logger
+	service := &transactionService{logger: logger}
+
+	bankCode, transRef := service.verificationFromSlip([]byte("not an image"), &slip.Slip{Bank: slip.BankSCB, Reference: "202402282147339876"})
+	assert.Equal(t, "014", bankCode)
+	assert.Equal(t, "202402282147339876", transRef)
+
+	bankCode, _ = service.verificationFromSlip([]byte("not an image"), &slip.Slip{Bank: slip.BankTrueMoney})
+	assert.Equal(t, slip.BankTrueMoney, bankCode)
+}
+
+func TestTransactionService_ParseSlip_Transfer(t *testing.T) {
+	logger := echo.New().Logger
+	image := []byte("transfer slip")
+	dir := writeOCRFixture(t, image,
+		"โอนเงินสำเร็จ", "12 มี.ค. 67 10:15 น.", "นาย ก", "ธ.กสิกรไทย", "xxx-x-x1234-x", "นาย ข",
+		"ธ.ไทยพาณิชย์", "xxx-x-x5678-x", "จำนวน:", "1250.00 unn", "ค่าธรรมเนียม:", "0.00 unn")
+	service := &transactionService{ocrProvider: ocr.NewFixtureProvider(dir), slipRegistry: slip.DefaultRegistry(), logger: logger}
+
+	result, err := service.ParseSlip(image)
+
+	assert.NoError(t, err)
+	assert.False(t, result.NeedsReview())
+	assert.Equal(t, 1250.0, result.Amount)
+	assert.Equal(t, slip.FieldAmount, result.Regions[0].Field)
+	assert.Equal(t, "1250.00 bath", result.Regions[0].Text)
+}
+
+func TestTransactionService_ParseSlip_AmountNotFound(t *testing.T) {
+	logger := echo.New().Logger
+	image := []byte("cropped slip")
+	dir := writeOCRFixture(t, image, "โอนเงินสำเร็จ", "นาย ก")
+	service := &transactionService{ocrProvider: ocr.NewFixtureProvider(dir), slipRegistry: slip.DefaultRegistry(), logger: logger}
+
+	result, err := service.ParseSlip(image)
+
+	assert.NoError(t, err)
+	assert.True(t, result.NeedsReview())
+	assert.Equal(t, float64(0), result.Amount)
+	assert.Equal(t, []string{IssueAmountMissing, IssueDateMissing}, result.Issues)
+}
+
+func TestTransactionService_ParseSlip_LowConfidence(t *testing.T) {
+	logger := echo.New().Logger
+	image := []byte("blurry slip")
+	dir := t.TempDir()
+	data, _ := json.Marshal([]ocr.Line{
+		{Text: "12 มี.ค. 67 10:15 น.", Confidence: 95},
+		{Text: "จำนวน: 1,250.00 บาท", Confidence: 41},
+	})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ocr.FixtureName(image)), data, 0o644))
+	service := &transactionService{ocrProvider: ocr.NewFixtureProvider(dir), slipRegistry: slip.DefaultRegistry(), logger: logger}
+
+	result, err := service.ParseSlip(image)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{IssueLowConfidence}, result.Issues)
+}
+
+func TestTransactionService_SaveSlip_NoAmount(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	_, err := service.SaveSlip(1, SlipDraft{Date: time.Now()}, "slip.jpg", []byte("image"), false)
+
+	assert.ErrorIs(t, err, ErrDraftIncomplete)
+	mockRepo.AssertNotCalled(t, "SaveTxn", mock.Anything)
+}
+
+func TestSlipDraft_Apply(t *testing.T) {
+	draft := SlipDraft{Amount: 0, Issues: []string{IssueAmountMissing, IssueDateMissing, IssueLowConfidence}}
+	amount := 80.0
+
+	draft.Apply(DraftCorrection{Amount: &amount})
+
+	assert.Equal(t, 80.0, draft.Amount)
+	assert.Equal(t, []string{IssueDateMissing}, draft.Issues)
+}
+
+func TestTransactionService_SaveSlip_UploadsToBlobStore(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	dir := t.TempDir()
+	store, _ := blobstore.NewLocalStore(dir)
+	cfg := &config.Config{Storage: &config.Storage{Provider: blobstore.ProviderLocal, LocalDir: dir}}
+
+	mockRepo.On("SaveTxn", mock.MatchedBy(func(txn entities.Transaction) bool {
+		return strings.HasPrefix(txn.ImageUrl, "slips/1_") && strings.HasSuffix(txn.ImageUrl, "_my_slip.jpg")
+	})).Return(uint(5), nil)
+	service := NewTransactionService(cfg, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, store, logger)
+
+	result, err := service.SaveSlip(1, SlipDraft{Date: time.Now(), Amount: 100}, "../my slip.jpg", []byte("image"), true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), result)
+	matches, _ := filepath.Glob(filepath.Join(dir, "slips", "1_*_my_slip.jpg"))
+	assert.Equal(t, 1, len(matches))
+}
+
+func TestTransactionService_SaveSlip_StoresCleanCopyAndThumbnail(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	dir := t.TempDir()
+	store, _ := blobstore.NewLocalStore(dir)
+	var saved entities.Transaction
+	mockRepo.On("SaveTxn", mock.Anything).Run(func(args mock.Arguments) {
+		saved = args.Get(0).(entities.Transaction)
+	}).Return(uint(5), nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, store, logger)
+	var image bytes.Buffer
+	assert.NoError(t, png.Encode(&image, blankImage(640, 480)))
+
+	_, err := service.SaveSlip(1, SlipDraft{Date: time.Now(), Amount: 100}, "slip.jpeg", image.Bytes(), true)
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(saved.ImageUrl, "_slip.png"))
+	assert.True(t, strings.HasSuffix(saved.ThumbnailUrl, "_slip_thumb.jpg"))
+	_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(saved.ThumbnailUrl)))
+	assert.NoError(t, err)
+}
+
+// blankImage is a plain white image.
+func blankImage(width, height int) *imagepkg.RGBA {
+	img := imagepkg.NewRGBA(imagepkg.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	return img
+}
+
+type presignStore struct {
+	blobstore.BlobStore
+}
+
+func (presignStore) Presign(key string, expiry time.Duration) (string, error) {
+	return "https://blobs.example.com/" + key + "?signature=abc", nil
+}
+
+func TestTransactionService_GetImage_Presigned(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	mockRepo.On("GetTxn", uint(5)).Return(&entities.Transaction{ImageUrl: "slips/a.png", ThumbnailUrl: "slips/a_thumb.jpg", SpenderId: 1}, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, presignStore{}, logger)
+
+	result, err := service.GetImage(1, 5, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://blobs.example.com/slips/a_thumb.jpg?signature=abc", result.URL)
+}
+
+func TestTransactionService_GetImage_Streamed(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	store, _ := blobstore.NewLocalStore(t.TempDir())
+	assert.NoError(t, store.Put("slips/a.png", []byte("png"), "image/png"))
+	mockRepo.On("GetTxn", uint(5)).Return(&entities.Transaction{ImageUrl: "slips/a.png", SpenderId: 1}, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, store, logger)
+
+	result, err := service.GetImage(1, 5, true)
+
+	assert.NoError(t, err)
+	defer result.Body.Close()
+	assert.Equal(t, "image/png", result.ContentType)
+	data, _ := io.ReadAll(result.Body)
+	assert.Equal(t, "png", string(data))
+}
+
+func TestTransactionService_GetImage_OtherSpender(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	mockRepo.On("GetTxn", uint(5)).Return(&entities.Transaction{ImageUrl: "slips/a.png", SpenderId: 2}, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, presignStore{}, logger)
+
+	_, err := service.GetImage(1, 5, false)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestTransactionService_GetImage_NoImage(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	mockRepo.On("GetTxn", uint(5)).Return(&entities.Transaction{SpenderId: 1}, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, presignStore{}, logger)
+
+	_, err := service.GetImage(1, 5, false)
+
+	assert.ErrorIs(t, err, ErrImageNotFound)
+}
+
+func TestTransactionService_ParseReceipt_Items(t *testing.T) {
+	logger := echo.New().Logger
+	image := []byte("receipt")
+	dir := writeOCRFixture(t, image,
+		"7-ELEVEN",
+		"TAX ID:0107542000011",
+		"POS#0301 R#0012345",
+		"2 ลาเต้เย็น @45.00 90.00",
+		"1 แซนวิชแฮมชีส 35.00",
+		"ยอดสุทธิ 3 ชิ้น 125.00",
+		"VAT 7% 8.18",
+		"12/03/2024 10:15",
+	)
+	service := &transactionService{ocrProvider: ocr.NewFixtureProvider(dir), receiptRegistry: receipt.DefaultRegistry(), logger: logger}
+
+	result, err := service.ParseReceipt(image)
+
+	assert.NoError(t, err)
+	assert.False(t, result.NeedsReview())
+	assert.Equal(t, receipt.MerchantSevenEleven, result.Merchant)
+	assert.Equal(t, 125.0, result.Amount)
+	assert.Equal(t, 8.18, result.VAT)
+	assert.Len(t, result.Items, 2)
+	assert.Equal(t, ReceiptItem{Name: "ลาเต้เย็น", Quantity: 2, UnitPrice: 45, Amount: 90, Category: "shopping"}, result.Items[0])
+}
+
+func TestTransactionService_ParseReceipt_ItemsMismatch(t *testing.T) {
+	logger := echo.New().Logger
+	image := []byte("receipt with a misread line")
+	dir := writeOCRFixture(t, image, "makro", "SUGAR 1KG 35.00", "TOTAL 95.00", "13 Mar 2024 18:02")
+	service := &transactionService{ocrProvider: ocr.NewFixtureProvider(dir), receiptRegistry: receipt.DefaultRegistry(), logger: logger}
+
+	result, err := service.ParseReceipt(image)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{IssueItemsMismatch}, result.Issues)
+}
+
+func TestTransactionService_SaveReceipt_SavesItems(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	dir := t.TempDir()
+	store, _ := blobstore.NewLocalStore(dir)
+	cfg := &config.Config{Storage: &config.Storage{Provider: blobstore.ProviderLocal, LocalDir: dir}}
+
+	mockRepo.On("GetByTransRef", uint(1), "0107542000011:0012345").Return((*entities.Transaction)(nil), gorm.ErrRecordNotFound)
+	mockRepo.On("SaveTxnWithItems", mock.MatchedBy(func(txn entities.Transaction) bool {
+		return txn.Merchant == receipt.MerchantSevenEleven && txn.TaxId == "0107542000011" && txn.Amount == 125 && txn.Category == "shopping"
+	}), []entities.TransactionItem{
+		{Name: "ลาเต้เย็น", Quantity: 2, UnitPrice: 45, Amount: 90, Category: "food"},
+		{Name: "ถุงผ้า", Quantity: 1, UnitPrice: 35, Amount: 35, Category: "shopping"},
+	}).Return(uint(9), nil)
+	service := NewTransactionService(cfg, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, store, logger)
+
+	draft := ReceiptDraft{
+		Date:      time.Now(),
+		Merchant:  receipt.MerchantSevenEleven,
+		TaxId:     "0107542000011",
+		Reference: "0012345",
+		Amount:    125,
+		Items: []ReceiptItem{
+			{Name: "ลาเต้เย็น", Quantity: 2, UnitPrice: 45, Amount: 90, Category: "food"},
+			{Name: "ถุงผ้า", Quantity: 1, UnitPrice: 35, Amount: 35},
+		},
+	}
+	result, err := service.SaveReceipt(1, draft, "receipt.jpg", []byte("image"), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTransactionService_SaveReceipt_NeedsReview(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	draft := ReceiptDraft{Date: time.Now(), Amount: 125, Issues: []string{IssueDateMissing}}
+	_, err := service.SaveReceipt(1, draft, "receipt.jpg", []byte("image"), false)
+
+	assert.ErrorIs(t, err, ErrDraftNeedsReview)
+	mockRepo.AssertNotCalled(t, "SaveTxnWithItems", mock.Anything, mock.Anything)
+}
+
+func TestReceiptRef_TruncatesByRune(t *testing.T) {
+	ref := receiptRef(ReceiptDraft{TaxId: "0107542000011", Reference: strings.Repeat("ก", 60)})
+
+	assert.Equal(t, 50, utf8.RuneCountInString(ref))
+	assert.True(t, utf8.ValidString(ref))
+}
+
+func TestTransactionService_UpdateItem_OtherSpender(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetTxn", uint(9)).Return(&entities.Transaction{SpenderId: 2}, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	err := service.UpdateItem(1, 9, 3, UpdateItemRequest{Category: "food"})
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	mockRepo.AssertNotCalled(t, "UpdateItemCategory", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTransactionService_UpdateItem_Success(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+
+	mockRepo.On("GetTxn", uint(9)).Return(&entities.Transaction{SpenderId: 1}, nil)
+	mockRepo.On("UpdateItemCategory", uint(9), uint(3), "food").Return(nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	err := service.UpdateItem(1, 9, 3, UpdateItemRequest{Category: "food"})
+
+	assert.NoError(t, err)
+}
+
+func TestTransactionService_Export_CSV(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	logger := echo.New().Logger
+	date := time.Date(2024, time.March, 12, 10, 15, 0, 0, time.UTC)
+	txns := []entities.ExportTxn{
+		{Transaction: entities.Transaction{Model: gorm.Model{ID: 1}, Date: date, Amount: 1250.5, Category: "food", TransactionType: "expense", SpenderId: 1}},
+		{Transaction: entities.Transaction{Model: gorm.Model{ID: 2}, Date: date.AddDate(0, 0, 1), Amount: 30000, Category: "salary", TransactionType: "income", SpenderId: 1}},
+	}
+	mockRepo.On("StreamTxns", uint(1), entities.GetAllTxnFilter{Category: "food"}, mock.Anything).Return(txns, nil)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, logger)
+
+	var out bytes.Buffer
+	err := service.Export(1, GetAllTxnFilter{Category: "food"}, ExportOptions{Format: "csv", Columns: []string{"id", "date", "amount"}, Timezone: "UTC"}, &out)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "\xEF\xBB\xBFID,Date,Amount\n1,2024-03-12T10:15:00Z,1250.50\n2,2024-03-13T10:15:00Z,30000.00\n", out.String())
+}
+
+func TestTransactionService_Export_UnknownColumn(t *testing.T) {
+	mockRepo := new(mocks.TransactionRepositoryMock)
+	service := NewTransactionService(&config.Config{}, mockRepo, nil, insightServiceStub{}, netWorthServiceStub{}, nil, nil, echo.New().Logger)
+
+	var out bytes.Buffer
+	err := service.Export(1, GetAllTxnFilter{}, ExportOptions{Format: "csv", Columns: []string{"password"}}, &out)
+
+	assert.ErrorIs(t, err, exportfile.ErrUnknownColumn)
+	assert.Zero(t, out.Len())
+	mockRepo.AssertNotCalled(t, "StreamTxns", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -12,6 +12,8 @@ type Users struct {
 	Email     string `gorm:"type:varchar; not null; unique; column:email" validate:"required" json:"email"`
 	Username  string `gorm:"type:varchar; not null; unique; column:username" validate:"required" json:"username"`
 	Password  string `gorm:"type:varchar; not null; column:password" validate:"required" json:"password"`
+	Timezone  string `gorm:"type:varchar(50); default:'Asia/Bangkok'; column:timezone" validate:"omitempty,timezone" json:"timezone"`
+	Language  string `gorm:"type:varchar(5); default:'th'; column:language" validate:"omitempty,oneof=th en" json:"language"`
 }
 
 type Claims struct {
@@ -28,6 +30,8 @@ type UpdateInfoRequest struct {
 	Firstname string `json:"firstname"`
 	Lastname  string `json:"lastname"`
 	Email     string `json:"email"`
+	Timezone  string `json:"timezone" validate:"omitempty,timezone"`
+	Language  string `json:"language" validate:"omitempty,oneof=th en"`
 }
 
 type UpdatePasswordRequest struct {
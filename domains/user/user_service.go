@@ -22,6 +22,7 @@ type IUserService interface {
 	RegenToken(req RegenTokenRequest) (string, error)
 	UpdateInfo(userId uint, req Users) error
 	UpdatePassword(req UpdatePasswordRequest) error
+	ResetPassword(username, password string) error
 	DeleteUser(userId uint) error
 }
 
@@ -92,6 +93,8 @@ func (s *userService) CreateUser(req Users) (uint, error) {
 		Email:     req.Email,
 		Username:  req.Username,
 		Password:  string(hashPassword),
+		Timezone:  req.Timezone,
+		Language:  req.Language,
 	}
 	result, err := s.userRepository.CreateUser(user)
 	if err != nil {
@@ -234,6 +237,8 @@ func (s *userService) UpdateInfo(userId uint, req Users) error {
 		Firstname: req.Firstname,
 		Lastname:  req.Lastname,
 		Email:     req.Email,
+		Timezone:  req.Timezone,
+		Language:  req.Language,
 	}
 	err := s.userRepository.UpdateUser(userId, user)
 	if err != nil {
@@ -256,6 +261,19 @@ func (s *userService) UpdatePassword(req UpdatePasswordRequest) error {
 	return nil
 }
 
+// ResetPassword sets the password of the user with username, for when they
+// can no longer log in to change it themselves.
+func (s *userService) ResetPassword(username, password string) error {
+	user, err := s.userRepository.GetUserForLogin(username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return errors.New("failed to get user")
+	}
+	return s.UpdatePassword(UpdatePasswordRequest{ID: user.ID, Password: password})
+}
+
 func (s *userService) DeleteUser(userId uint) error {
 	err := s.userRepository.DeleteUser(userId)
 	if err != nil {
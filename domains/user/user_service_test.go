@@ -242,6 +242,30 @@ func TestUserService_UpdatePassword_Error(t *testing.T) {
 	assert.EqualError(t, err, "failed to update user")
 }
 
+func TestUserService_ResetPassword_Success(t *testing.T) {
+	mockRepo := new(mocks.UserRepositoryMock)
+	var logger echo.Logger
+
+	mockRepo.On("GetUserForLogin", "john.d").Return(&entities.GetUserForLoginResponse{ID: 1, Username: "john.d"}, nil)
+	mockRepo.On("UpdatePassword", uint(1), mock.AnythingOfType("string")).Return(nil)
+	service := NewUserService(mockRepo, logger)
+	err := service.ResetPassword("john.d", "newPassword")
+
+	assert.Nil(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_ResetPassword_RecordNotFound(t *testing.T) {
+	mockRepo := new(mocks.UserRepositoryMock)
+	var logger echo.Logger
+
+	mockRepo.On("GetUserForLogin", "john.d").Return((*entities.GetUserForLoginResponse)(nil), gorm.ErrRecordNotFound)
+	service := NewUserService(mockRepo, logger)
+	err := service.ResetPassword("john.d", "newPassword")
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
 func TestUserService_DeleteUser_Success(t *testing.T) {
 	mockRepo := new(mocks.UserRepositoryMock)
 	var logger echo.Logger
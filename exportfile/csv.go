@@ -0,0 +1,65 @@
+package exportfile
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// utf8BOM lets Excel open the file as UTF-8 instead of the system code page,
+// which would garble Thai.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+type csvWriter struct {
+	out     io.Writer
+	writer  *csv.Writer
+	columns []string
+	format  *format
+	started bool
+	rows    int
+}
+
+func newCSVWriter(w io.Writer, columns []string, format *format) *csvWriter {
+	return &csvWriter{out: w, writer: csv.NewWriter(w), columns: columns, format: format}
+}
+
+func (w *csvWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if _, err := w.out.Write(utf8BOM); err != nil {
+		return err
+	}
+	header := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		header[i] = w.format.header(column)
+	}
+	return w.writer.Write(header)
+}
+
+func (w *csvWriter) Write(row Row) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	record := make([]string, len(w.columns))
+	for i, column := range w.columns {
+		record[i] = w.format.text(row, column)
+	}
+	if err := w.writer.Write(record); err != nil {
+		return err
+	}
+	w.rows++
+	if w.rows%flushEvery == 0 {
+		w.writer.Flush()
+		flush(w.out)
+	}
+	return w.writer.Error()
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.writer.Flush()
+	return w.writer.Error()
+}
@@ -0,0 +1,281 @@
+package exportfile
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatXLSX   = "xlsx"
+	FormatNDJSON = "ndjson"
+	// Journal formats for plain-text accounting tools. They always hold
+	// every transaction field and ignore the columns and locale.
+	FormatBeancount = "beancount"
+	FormatHledger   = "hledger"
+
+	// flushEvery is how many rows are buffered before they are sent on, so a
+	// long export reaches the client as it is read.
+	flushEvery = 200
+)
+
+// Columns that can be exported, in their default order.
+const (
+	ColumnId              = "id"
+	ColumnDate            = "date"
+	ColumnAmount          = "amount"
+	ColumnTransactionType = "transaction_type"
+	ColumnCategory        = "category"
+	ColumnNote            = "note"
+	ColumnMerchant        = "merchant"
+	ColumnBankCode        = "bank_code"
+	ColumnTransRef        = "trans_ref"
+	ColumnTaxId           = "tax_id"
+	ColumnVAT             = "vat"
+	ColumnAccountId       = "account_id"
+)
+
+var (
+	ErrUnknownFormat = errors.New("export format is unknown")
+	ErrUnknownColumn = errors.New("export column is unknown")
+	ErrUnknownLocale = errors.New("export locale is unknown")
+)
+
+// DefaultColumns are exported when none are asked for.
+var DefaultColumns = []string{ColumnId, ColumnDate, ColumnAmount, ColumnTransactionType, ColumnCategory, ColumnNote, ColumnMerchant}
+
+// headers are the column titles by language; English is the fallback.
+var headers = map[string]map[string]string{
+	"en": {
+		ColumnId: "ID", ColumnDate: "Date", ColumnAmount: "Amount", ColumnTransactionType: "Type",
+		ColumnCategory: "Category", ColumnNote: "Note", ColumnMerchant: "Merchant", ColumnBankCode: "Bank code",
+		ColumnTransRef: "Reference", ColumnTaxId: "Tax ID", ColumnVAT: "VAT", ColumnAccountId: "Account ID",
+	},
+	"th": {
+		ColumnId: "รหัส", ColumnDate: "วันที่", ColumnAmount: "จำนวนเงิน", ColumnTransactionType: "ประเภท",
+		ColumnCategory: "หมวดหมู่", ColumnNote: "หมายเหตุ", ColumnMerchant: "ร้านค้า", ColumnBankCode: "รหัสธนาคาร",
+		ColumnTransRef: "เลขที่อ้างอิง", ColumnTaxId: "เลขประจำตัวผู้เสียภาษี", ColumnVAT: "ภาษีมูลค่าเพิ่ม", ColumnAccountId: "รหัสบัญชี",
+	},
+}
+
+// Row is one exported transaction.
+type Row struct {
+	ID              uint
+	Date            time.Time
+	Amount          float64
+	TransactionType string
+	Category        string
+	Note            string
+	Merchant        string
+	BankCode        string
+	TransRef        string
+	TaxId           string
+	VAT             float64
+	AccountId       *uint
+	FitId           string
+	// Account is the account AccountId points at, for journals to post to.
+	Account *Account
+	// Tags mark where the transaction came from, such as "imported".
+	Tags []string
+}
+
+// Options say how rows are written. Without a locale, CSV gets ISO dates and
+// plain numbers for other programs to read; with one, it gets the dates,
+// digit grouping and headers a person in that locale expects. XLSX always
+// stores real numbers and dates and only takes the display format from the
+// locale, and NDJSON is never localized.
+type Options struct {
+	Format   string
+	Columns  []string
+	Locale   string
+	Location *time.Location
+}
+
+// Writer writes rows as they come and finishes the file on Close, which
+// must be called even when there are no rows.
+type Writer interface {
+	Write(row Row) error
+	Close() error
+}
+
+// NewWriter checks opts and returns a writer for its format. Nothing is
+// written to w until the first row or Close, so a bad option can still be
+// reported as an error response.
+func NewWriter(w io.Writer, opts Options) (Writer, error) {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+	for _, column := range columns {
+		if _, ok := headers["en"][column]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+	}
+	format, err := newFormat(opts.Locale)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Location != nil {
+		format.location = opts.Location
+	}
+
+	switch opts.Format {
+	case FormatCSV:
+		return newCSVWriter(w, columns, format), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns, format), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns, format), nil
+	case FormatBeancount, FormatHledger:
+		return newJournalWriter(w, opts.Format, format), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType is the media type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatBeancount, FormatHledger:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// Extension is the file name extension of a format.
+func Extension(format string) string {
+	if format == FormatHledger {
+		return "journal"
+	}
+	return format
+}
+
+// format holds how one locale writes values.
+type format struct {
+	localized bool
+	language  string
+	printer   *message.Printer
+	// dateLayout is a Go layout; buddhist adds 543 to the year as Thai
+	// documents count it.
+	dateLayout string
+	buddhist   bool
+	// excelDate is the XLSX number format for dates.
+	excelDate string
+	location  *time.Location
+}
+
+func newFormat(locale string) (*format, error) {
+	f := &format{
+		language:   "en",
+		dateLayout: time.RFC3339,
+		excelDate:  "yyyy-mm-dd hh:mm",
+		location:   bangkok,
+	}
+	if locale == "" {
+		return f, nil
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, ErrUnknownLocale
+	}
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+	f.localized = true
+	f.printer = message.NewPrinter(tag)
+	switch {
+	case base.String() == "th":
+		f.language = "th"
+		f.dateLayout, f.buddhist = "02/01/2006 15:04", true
+		f.excelDate = "dd/mm/yyyy hh:mm"
+	case region.String() == "US":
+		f.dateLayout = "01/02/2006 15:04"
+		f.excelDate = "mm/dd/yyyy hh:mm"
+	default:
+		f.dateLayout = "02/01/2006 15:04"
+		f.excelDate = "dd/mm/yyyy hh:mm"
+	}
+	return f, nil
+}
+
+func (f *format) header(column string) string {
+	if title, ok := headers[f.language][column]; ok {
+		return title
+	}
+	return headers["en"][column]
+}
+
+func (f *format) date(date time.Time) string {
+	date = date.In(f.location)
+	if !f.buddhist {
+		return date.Format(f.dateLayout)
+	}
+	// Format with a placeholder year so the layout's other fields are left
+	// alone, then put the Buddhist-era year in.
+	text := date.Format(strings.Replace(f.dateLayout, "2006", "YYYY", 1))
+	return strings.Replace(text, "YYYY", strconv.Itoa(date.Year()+543), 1)
+}
+
+func (f *format) number(value float64) string {
+	if !f.localized {
+		return strconv.FormatFloat(value, 'f', 2, 64)
+	}
+	return f.printer.Sprintf("%.2f", value)
+}
+
+// text is the value of a column as CSV writes it.
+func (f *format) text(row Row, column string) string {
+	switch column {
+	case ColumnId:
+		return strconv.FormatUint(uint64(row.ID), 10)
+	case ColumnDate:
+		return f.date(row.Date)
+	case ColumnAmount:
+		return f.number(row.Amount)
+	case ColumnTransactionType:
+		return row.TransactionType
+	case ColumnCategory:
+		return row.Category
+	case ColumnNote:
+		return row.Note
+	case ColumnMerchant:
+		return row.Merchant
+	case ColumnBankCode:
+		return row.BankCode
+	case ColumnTransRef:
+		return row.TransRef
+	case ColumnTaxId:
+		return row.TaxId
+	case ColumnVAT:
+		return f.number(row.VAT)
+	case ColumnAccountId:
+		if row.AccountId == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*row.AccountId), 10)
+	}
+	return ""
+}
+
+// flush sends buffered output on to the client when w is an HTTP response.
+func flush(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// bangkok is the default zone of exported dates. It has no DST, so a fixed
+// offset is exact.
+var bangkok = time.FixedZone("Asia/Bangkok", 7*60*60)
@@ -0,0 +1,174 @@
+package exportfile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var accountId = uint(3)
+
+var rows = []Row{
+	{ID: 1, Date: time.Date(2024, time.March, 12, 10, 15, 0, 0, bangkok), Amount: 1250.5, TransactionType: "expense", Category: "food", Note: `rent, "march"`, Merchant: "ร้านกาแฟ"},
+	{ID: 2, Date: time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC), Amount: 30000, TransactionType: "income", Category: "salary", AccountId: &accountId},
+}
+
+func writeAll(t *testing.T, opts Options) []byte {
+	var out bytes.Buffer
+	writer, err := NewWriter(&out, opts)
+	assert.NoError(t, err)
+	for _, row := range rows {
+		assert.NoError(t, writer.Write(row))
+	}
+	assert.NoError(t, writer.Close())
+	return out.Bytes()
+}
+
+func TestCSV_Plain(t *testing.T) {
+	got := writeAll(t, Options{Format: FormatCSV, Columns: []string{ColumnDate, ColumnAmount, ColumnNote}})
+
+	assert.Equal(t, "\xEF\xBB\xBFDate,Amount,Note\n"+
+		"2024-03-12T10:15:00+07:00,1250.50,\"rent, \"\"march\"\"\"\n"+
+		"2024-03-13T07:00:00+07:00,30000.00,\n", string(got))
+}
+
+func TestCSV_Thai(t *testing.T) {
+	got := writeAll(t, Options{Format: FormatCSV, Columns: []string{ColumnDate, ColumnAmount, ColumnMerchant}, Locale: "th-TH"})
+
+	lines := strings.Split(strings.TrimPrefix(string(got), "\xEF\xBB\xBF"), "\n")
+	assert.Equal(t, "วันที่,จำนวนเงิน,ร้านค้า", lines[0])
+	assert.Equal(t, `12/03/2567 10:15,"1,250.50",ร้านกาแฟ`, lines[1])
+}
+
+func TestCSV_German(t *testing.T) {
+	got := writeAll(t, Options{Format: FormatCSV, Columns: []string{ColumnDate, ColumnAmount}, Locale: "de"})
+
+	lines := strings.Split(string(got), "\n")
+	assert.Equal(t, "12/03/2024 10:15,\"1.250,50\"", lines[1])
+}
+
+func TestCSV_EmptyHasHeader(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewWriter(&out, Options{Format: FormatCSV, Columns: []string{ColumnId}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, "\xEF\xBB\xBFID\n", out.String())
+}
+
+func TestNDJSON(t *testing.T) {
+	got := writeAll(t, Options{Format: FormatNDJSON, Columns: []string{ColumnId, ColumnDate, ColumnAmount, ColumnAccountId}, Locale: "th"})
+
+	lines := strings.Split(strings.TrimSpace(string(got)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Equal(t, `{"id":1,"date":"2024-03-12T10:15:00+07:00","amount":1250.5,"account_id":null}`, lines[0])
+	var second map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, float64(3), second["account_id"])
+}
+
+func TestXLSX(t *testing.T) {
+	got := writeAll(t, Options{Format: FormatXLSX, Columns: []string{ColumnDate, ColumnAmount, ColumnNote}, Locale: "th"})
+
+	archive, err := zip.NewReader(bytes.NewReader(got), int64(len(got)))
+	assert.NoError(t, err)
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		assert.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		files[file.Name] = string(content)
+	}
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files["xl/styles.xml"], `formatCode="dd/mm/yyyy hh:mm"`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row r="1"><c s="3" t="inlineStr"><is><t xml:space="preserve">วันที่</t></is></c>`)
+	assert.Contains(t, sheet, `<row r="2"><c s="1"><v>45363.427083333336</v></c><c s="2"><v>1250.50</v></c>`)
+	assert.Contains(t, sheet, `rent, &#34;march&#34;`)
+	assert.True(t, strings.HasSuffix(sheet, "</sheetData></worksheet>"))
+}
+
+func TestNewWriter_Errors(t *testing.T) {
+	_, err := NewWriter(io.Discard, Options{Format: "pdf"})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = NewWriter(io.Discard, Options{Format: FormatCSV, Columns: []string{"password"}})
+	assert.ErrorIs(t, err, ErrUnknownColumn)
+
+	_, err = NewWriter(io.Discard, Options{Format: FormatCSV, Locale: "not a locale!"})
+	assert.ErrorIs(t, err, ErrUnknownLocale)
+}
+
+func TestJournal(t *testing.T) {
+	journalRows := []Row{
+		rows[0],
+		{ID: 2, Date: time.Date(2024, time.March, 13, 0, 0, 0, 0, time.UTC), Amount: 30000, TransactionType: "income", Category: "salary",
+			AccountId: &accountId, Account: &Account{ID: accountId, Name: "KBank savings", Kind: "bank", Currency: "THB"}, FitId: "F1", Tags: []string{"imported"}},
+	}
+	write := func(format string) string {
+		var out bytes.Buffer
+		writer, err := NewWriter(&out, Options{Format: format})
+		assert.NoError(t, err)
+		for _, row := range journalRows {
+			assert.NoError(t, writer.Write(row))
+		}
+		assert.NoError(t, writer.Close())
+		return out.String()
+	}
+
+	assert.Equal(t, `option "title" "jod-jod"
+option "operating_currency" "THB"
+
+2024-03-12 * "ร้านกาแฟ" "rent, \"march\""
+  jodjod-id: "1"
+  category: "food"
+  time: "10:15:00"
+  Expenses:Food  1250.50 THB
+  Assets:Cash  -1250.50 THB
+
+2024-03-13 * "" #imported
+  jodjod-id: "2"
+  category: "salary"
+  time: "07:00:00"
+  fit-id: "F1"
+  Income:Salary  -30000.00 THB
+  Assets:Bank:KBank-Savings  30000.00 THB
+
+2024-03-13 open Assets:Bank:KBank-Savings THB
+2024-03-12 open Assets:Cash THB
+2024-03-12 open Expenses:Food THB
+2024-03-13 open Income:Salary THB
+`, write(FormatBeancount))
+
+	assert.Equal(t, `; jod-jod
+commodity 1,000.00 THB
+
+2024-03-12 * ร้านกาแฟ | rent, "march"  ; jodjod-id:1, category:food, time:10:15:00
+  Expenses:Food  1250.50 THB
+  Assets:Cash  -1250.50 THB
+
+2024-03-13 *   ; imported:, jodjod-id:2, category:salary, time:07:00:00, fit-id:F1
+  Income:Salary  -30000.00 THB
+  Assets:Bank:KBank-Savings  30000.00 THB
+
+account Assets:Bank:KBank-Savings
+account Assets:Cash
+account Expenses:Food
+account Income:Salary
+`, write(FormatHledger))
+}
+
+func TestLedgerAccount(t *testing.T) {
+	assert.Equal(t, "Liabilities:CreditCard:KTC-Visa", LedgerAccount(Account{Name: "KTC / visa", Kind: "credit_card"}))
+	assert.Equal(t, "Assets:Cash:กระเป๋าเงิน", LedgerAccount(Account{Name: "กระเป๋าเงิน", Kind: "cash"}))
+	assert.Equal(t, "Assets:Bank:Account9", LedgerAccount(Account{ID: 9, Name: "***", Kind: "bank"}))
+	assert.Equal(t, "Expenses:Eating-Out", CategoryAccount("expense", "eating out"))
+	assert.Equal(t, "Expenses:Other", CategoryAccount("expense", ""))
+}
@@ -0,0 +1,257 @@
+package exportfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// DefaultCurrency is the commodity of transactions on no account.
+	DefaultCurrency = "THB"
+	// CashAccount is the journal account of transactions on no account.
+	CashAccount = "Assets:Cash"
+
+	// Metadata keys a journal entry carries, so that reading it back gives
+	// the same transaction.
+	MetaId       = "jodjod-id"
+	MetaCategory = "category"
+	MetaTime     = "time"
+	MetaFitId    = "fit-id"
+	MetaTransRef = "trans-ref"
+	MetaBankCode = "bank-code"
+	MetaTaxId    = "tax-id"
+)
+
+// Account is what a journal needs to know of a jod-jod account.
+type Account struct {
+	ID       uint
+	Name     string
+	Kind     string
+	Currency string
+}
+
+// LedgerAccount is the journal name of an account: banks and wallets are
+// assets and credit cards are liabilities. An account whose name has no
+// letters or digits is named by its ID.
+func LedgerAccount(account Account) string {
+	name := accountComponent(account.Name)
+	if name == "" {
+		name = fmt.Sprintf("Account%d", account.ID)
+	}
+	switch account.Kind {
+	case "bank":
+		return "Assets:Bank:" + name
+	case "credit_card":
+		return "Liabilities:CreditCard:" + name
+	case "cash":
+		return "Assets:Cash:" + name
+	}
+	return "Assets:" + name
+}
+
+// CategoryAccount is the journal account a category's money goes to or
+// comes from.
+func CategoryAccount(txnType, category string) string {
+	name := accountComponent(category)
+	if name == "" {
+		name = "Other"
+	}
+	if strings.EqualFold(txnType, "income") {
+		return "Income:" + name
+	}
+	return "Expenses:" + name
+}
+
+// accountComponent turns text into one part of an account name: words of
+// letters and digits, each capitalised, joined by dashes. Thai is kept as it
+// is, since both tools take UTF-8 names.
+func accountComponent(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.Is(unicode.Mn, r)
+	})
+	for i, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, "-")
+}
+
+// journalWriter writes beancount or hledger entries as rows come. Both tools
+// take account declarations anywhere in the file, so the accounts seen are
+// declared on Close, dated by their first use for beancount.
+type journalWriter struct {
+	out     io.Writer
+	w       *bufio.Writer
+	ledger  string
+	format  *format
+	started bool
+	rows    int
+	opened  map[string]time.Time
+	// currencies are the commodity each account was posted in, or empty
+	// when it was posted in more than one.
+	currencies map[string]string
+}
+
+func newJournalWriter(w io.Writer, ledger string, f *format) *journalWriter {
+	return &journalWriter{
+		out:        w,
+		w:          bufio.NewWriter(w),
+		ledger:     ledger,
+		format:     f,
+		opened:     make(map[string]time.Time),
+		currencies: make(map[string]string),
+	}
+}
+
+func (j *journalWriter) Write(row Row) error {
+	if !j.started {
+		j.writeHeader()
+	}
+
+	date := row.Date.In(j.format.location)
+	currency := DefaultCurrency
+	source := CashAccount
+	if row.Account != nil {
+		source = LedgerAccount(*row.Account)
+		if row.Account.Currency != "" {
+			currency = row.Account.Currency
+		}
+	}
+	target := CategoryAccount(row.TransactionType, row.Category)
+	amount := row.Amount
+	if strings.EqualFold(row.TransactionType, "income") {
+		amount = -amount
+	}
+	j.use(source, currency, date)
+	j.use(target, currency, date)
+
+	meta := [][2]string{
+		{MetaId, strconv.FormatUint(uint64(row.ID), 10)},
+		{MetaCategory, row.Category},
+		{MetaTime, date.Format("15:04:05")},
+		{MetaFitId, row.FitId},
+		{MetaTransRef, row.TransRef},
+		{MetaBankCode, row.BankCode},
+		{MetaTaxId, row.TaxId},
+	}
+	if j.ledger == FormatBeancount {
+		j.writeBeancount(row, date, meta)
+	} else {
+		j.writeHledger(row, date, meta)
+	}
+	fmt.Fprintf(j.w, "  %s  %s %s\n", target, formatAmount(amount), currency)
+	fmt.Fprintf(j.w, "  %s  %s %s\n\n", source, formatAmount(-amount), currency)
+
+	j.rows++
+	if j.rows%flushEvery == 0 {
+		if err := j.w.Flush(); err != nil {
+			return err
+		}
+		flush(j.out)
+	}
+	return nil
+}
+
+// writeBeancount writes the entry's header and metadata. A transaction
+// without a merchant gets only a narration, which beancount reads as having
+// no payee.
+func (j *journalWriter) writeBeancount(row Row, date time.Time, meta [][2]string) {
+	fmt.Fprintf(j.w, "%s *", date.Format("2006-01-02"))
+	if row.Merchant != "" {
+		fmt.Fprintf(j.w, " %s", beancountString(row.Merchant))
+	}
+	fmt.Fprintf(j.w, " %s", beancountString(row.Note))
+	for _, tag := range row.Tags {
+		fmt.Fprintf(j.w, " #%s", tag)
+	}
+	j.w.WriteString("\n")
+	for _, pair := range meta {
+		if pair[1] != "" {
+			fmt.Fprintf(j.w, "  %s: %s\n", pair[0], beancountString(pair[1]))
+		}
+	}
+}
+
+// writeHledger writes the entry's header with its tags and metadata as
+// hledger tags in a comment. hledger splits "payee | note" descriptions.
+func (j *journalWriter) writeHledger(row Row, date time.Time, meta [][2]string) {
+	description := hledgerText(row.Note)
+	if row.Merchant != "" {
+		description = hledgerText(row.Merchant) + " | " + description
+	}
+	var tags []string
+	for _, tag := range row.Tags {
+		tags = append(tags, tag+":")
+	}
+	for _, pair := range meta {
+		if pair[1] != "" {
+			tags = append(tags, pair[0]+":"+strings.ReplaceAll(hledgerText(pair[1]), ",", " "))
+		}
+	}
+	fmt.Fprintf(j.w, "%s * %s  ; %s\n", date.Format("2006-01-02"), description, strings.Join(tags, ", "))
+}
+
+func (j *journalWriter) writeHeader() {
+	j.started = true
+	if j.ledger == FormatBeancount {
+		fmt.Fprintf(j.w, "option \"title\" \"jod-jod\"\noption \"operating_currency\" \"%s\"\n\n", DefaultCurrency)
+		return
+	}
+	fmt.Fprintf(j.w, "; jod-jod\ncommodity 1,000.00 %s\n\n", DefaultCurrency)
+}
+
+func (j *journalWriter) use(account, currency string, date time.Time) {
+	if opened, ok := j.opened[account]; !ok || date.Before(opened) {
+		j.opened[account] = date
+	}
+	if seen, ok := j.currencies[account]; !ok {
+		j.currencies[account] = currency
+	} else if seen != currency {
+		j.currencies[account] = ""
+	}
+}
+
+// Close declares the accounts used, sorted by name.
+func (j *journalWriter) Close() error {
+	if !j.started {
+		j.writeHeader()
+	}
+	accounts := make([]string, 0, len(j.opened))
+	for account := range j.opened {
+		accounts = append(accounts, account)
+	}
+	sort.Strings(accounts)
+	for _, account := range accounts {
+		if j.ledger == FormatBeancount {
+			open := strings.TrimSpace(fmt.Sprintf("%s open %s %s", j.opened[account].Format("2006-01-02"), account, j.currencies[account]))
+			fmt.Fprintln(j.w, open)
+		} else {
+			fmt.Fprintf(j.w, "account %s\n", account)
+		}
+	}
+	return j.w.Flush()
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func beancountString(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+	return `"` + text + `"`
+}
+
+// hledgerText keeps a value on one line and out of the comment, which a
+// semicolon would start.
+func hledgerText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.NewReplacer(";", ",", "|", "/").Replace(text)
+}
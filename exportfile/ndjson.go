@@ -0,0 +1,70 @@
+package exportfile
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type ndjsonWriter struct {
+	out     io.Writer
+	buffer  *bufio.Writer
+	columns []string
+	format  *format
+	rows    int
+}
+
+func newNDJSONWriter(w io.Writer, columns []string, format *format) *ndjsonWriter {
+	return &ndjsonWriter{out: w, buffer: bufio.NewWriter(w), columns: columns, format: format}
+}
+
+// Write puts the row on one line as an object whose keys are the columns in
+// the order asked for. Dates are RFC 3339 and numbers are numbers.
+func (w *ndjsonWriter) Write(row Row) error {
+	w.buffer.WriteByte('{')
+	for i, column := range w.columns {
+		if i > 0 {
+			w.buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(w.value(row, column))
+		if err != nil {
+			return err
+		}
+		w.buffer.Write(key)
+		w.buffer.WriteByte(':')
+		w.buffer.Write(value)
+	}
+	if _, err := w.buffer.WriteString("}\n"); err != nil {
+		return err
+	}
+	w.rows++
+	if w.rows%flushEvery == 0 {
+		if err := w.buffer.Flush(); err != nil {
+			return err
+		}
+		flush(w.out)
+	}
+	return nil
+}
+
+func (w *ndjsonWriter) value(row Row, column string) interface{} {
+	switch column {
+	case ColumnId:
+		return row.ID
+	case ColumnDate:
+		return row.Date.In(w.format.location).Format(time.RFC3339)
+	case ColumnAmount:
+		return row.Amount
+	case ColumnVAT:
+		return row.VAT
+	case ColumnAccountId:
+		return row.AccountId
+	}
+	return w.format.text(row, column)
+}
+
+func (w *ndjsonWriter) Close() error {
+	return w.buffer.Flush()
+}
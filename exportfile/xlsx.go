@@ -0,0 +1,187 @@
+package exportfile
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// MaxXLSXRows is the most rows a worksheet holds, the header included.
+const MaxXLSXRows = 1048576
+
+var ErrTooManyRows = errors.New("export has more rows than a worksheet holds")
+
+// Cell styles, as indexes into cellXfs in styles.xml.
+const (
+	styleDefault = 0
+	styleDate    = 1
+	styleAmount  = 2
+	styleHeader  = 3
+)
+
+// excelEpoch is day zero of spreadsheet serial dates, which count from
+// 1900 as though it were a leap year.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Transactions" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+
+// xlsxStyles has the date format filled in; amounts use built-in format 4,
+// "#,##0.00".
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="1"><numFmt numFmtId="164" formatCode="%s"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+
+const xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+
+const xlsxSheetEnd = `</sheetData></worksheet>`
+
+// xlsxWriter streams a one-sheet workbook. The worksheet is the last part of
+// the archive so its rows can be written as they come, with inline strings
+// rather than a shared string table that would need every row up front.
+type xlsxWriter struct {
+	out     io.Writer
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	columns []string
+	format  *format
+	started bool
+	rows    int
+}
+
+func newXLSXWriter(w io.Writer, columns []string, format *format) *xlsxWriter {
+	return &xlsxWriter{out: w, columns: columns, format: format}
+}
+
+func (w *xlsxWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	w.archive = zip.NewWriter(w.out)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", fmt.Sprintf(xlsxStyles, w.format.excelDate)},
+	}
+	for _, part := range parts {
+		file, err := w.archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(file, part.content); err != nil {
+			return err
+		}
+	}
+
+	file, err := w.archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	w.sheet = bufio.NewWriter(file)
+	w.sheet.WriteString(xlsxSheetStart)
+
+	w.startRow()
+	for _, column := range w.columns {
+		w.stringCell(w.format.header(column), styleHeader)
+	}
+	w.sheet.WriteString("</row>")
+	return nil
+}
+
+func (w *xlsxWriter) Write(row Row) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if w.rows+1 >= MaxXLSXRows {
+		return ErrTooManyRows
+	}
+
+	w.startRow()
+	for _, column := range w.columns {
+		switch column {
+		case ColumnId:
+			w.numberCell(strconv.FormatUint(uint64(row.ID), 10), styleDefault)
+		case ColumnDate:
+			w.numberCell(strconv.FormatFloat(serialDate(row.Date.In(w.format.location)), 'f', -1, 64), styleDate)
+		case ColumnAmount:
+			w.numberCell(strconv.FormatFloat(row.Amount, 'f', 2, 64), styleAmount)
+		case ColumnVAT:
+			w.numberCell(strconv.FormatFloat(row.VAT, 'f', 2, 64), styleAmount)
+		case ColumnAccountId:
+			if row.AccountId == nil {
+				w.sheet.WriteString("<c/>")
+				continue
+			}
+			w.numberCell(strconv.FormatUint(uint64(*row.AccountId), 10), styleDefault)
+		default:
+			w.stringCell(w.format.text(row, column), styleDefault)
+		}
+	}
+	if _, err := w.sheet.WriteString("</row>"); err != nil {
+		return err
+	}
+	if w.rows%flushEvery == 0 {
+		if err := w.sheet.Flush(); err != nil {
+			return err
+		}
+		if err := w.archive.Flush(); err != nil {
+			return err
+		}
+		flush(w.out)
+	}
+	return nil
+}
+
+func (w *xlsxWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.sheet.WriteString(xlsxSheetEnd)
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
+
+// startRow opens the next row; rows is counted from the header as 1.
+func (w *xlsxWriter) startRow() {
+	w.rows++
+	w.sheet.WriteString(`<row r="`)
+	w.sheet.WriteString(strconv.Itoa(w.rows))
+	w.sheet.WriteString(`">`)
+}
+
+func (w *xlsxWriter) numberCell(value string, style int) {
+	fmt.Fprintf(w.sheet, `<c s="%d"><v>%s</v></c>`, style, value)
+}
+
+func (w *xlsxWriter) stringCell(value string, style int) {
+	fmt.Fprintf(w.sheet, `<c s="%d" t="inlineStr"><is><t xml:space="preserve">`, style)
+	xml.EscapeText(w.sheet, []byte(value))
+	w.sheet.WriteString(`</t></is></c>`)
+}
+
+// serialDate is the spreadsheet serial number of the wall-clock time of
+// date, which is how spreadsheets store dates.
+func serialDate(date time.Time) float64 {
+	wall := time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), 0, time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
@@ -0,0 +1,81 @@
+package imagehash
+
+import (
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/imageproc"
+	"image"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	hashWidth  = 9
+	hashHeight = 8
+)
+
+// DHash is a 64 bit difference hash: the image is shrunk to 9x8 grey cells
+// and each bit records whether a cell is brighter than its right neighbour.
+// Re-encoding, resizing or recompressing an image barely changes it.
+func DHash(img image.Image) uint64 {
+	cells := shrink(img)
+	var hash uint64
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth-1; x++ {
+			hash <<= 1
+			if cells[y][x] > cells[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// FromBytes decodes an image within imageproc.MaxPixels and hashes it.
+func FromBytes(data []byte) (uint64, error) {
+	img, _, err := imageproc.Decode(data)
+	if err != nil {
+		return 0, err
+	}
+	return DHash(img), nil
+}
+
+// Distance is the number of differing bits; 0 is the same picture.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Format renders a hash as 16 hex digits for storage.
+func Format(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+func Parse(value string) (uint64, error) {
+	return strconv.ParseUint(value, 16, 64)
+}
+
+// shrink averages the luminance of every pixel in each cell, which is less
+// sensitive to noise than sampling one pixel per cell.
+func shrink(img image.Image) [hashHeight][hashWidth]float64 {
+	var sums [hashHeight][hashWidth]float64
+	var counts [hashHeight][hashWidth]float64
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		cy := (y - bounds.Min.Y) * hashHeight / height
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cx := (x - bounds.Min.X) * hashWidth / width
+			r, g, b, _ := img.At(x, y).RGBA()
+			sums[cy][cx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			counts[cy][cx]++
+		}
+	}
+
+	for y := range sums {
+		for x := range sums[y] {
+			if counts[y][x] > 0 {
+				sums[y][x] /= counts[y][x]
+			}
+		}
+	}
+	return sums
+}
@@ -0,0 +1,59 @@
+package imagehash
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+func gradient(width, height int, invert bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x*255/width + y*64/height) % 256)
+			if invert {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{R: v, G: v / 2, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestDHash_SurvivesReencoding(t *testing.T) {
+	original := gradient(360, 640, false)
+	var encoded bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&encoded, original, &jpeg.Options{Quality: 40}))
+
+	reencoded, err := FromBytes(encoded.Bytes())
+
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, Distance(DHash(original), reencoded), 4)
+}
+
+func TestDHash_SurvivesResize(t *testing.T) {
+	assert.LessOrEqual(t, Distance(DHash(gradient(360, 640, false)), DHash(gradient(720, 1280, false))), 4)
+}
+
+func TestDHash_DifferentImages(t *testing.T) {
+	assert.Greater(t, Distance(DHash(gradient(360, 640, false)), DHash(gradient(360, 640, true))), 20)
+}
+
+func TestFromBytes_NotAnImage(t *testing.T) {
+	_, err := FromBytes([]byte("not an image"))
+
+	assert.Error(t, err)
+}
+
+func TestFormatParse(t *testing.T) {
+	hash := uint64(0x00f0_0000_dead_beef)
+
+	parsed, err := Parse(Format(hash))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "00f00000deadbeef", Format(hash))
+	assert.Equal(t, hash, parsed)
+}
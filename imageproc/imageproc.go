@@ -0,0 +1,158 @@
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+const (
+	// ThumbnailSize is the longest side of a thumbnail in pixels.
+	ThumbnailSize    = 320
+	jpegQuality      = 85
+	thumbnailQuality = 80
+	// MaxPixels is the largest image decoded, about 160 MB once decoded. It
+	// is well above any phone camera's default.
+	MaxPixels = 40_000_000
+)
+
+var (
+	ErrUnsupported = errors.New("image format is not supported")
+	ErrTooLarge    = errors.New("image is too large")
+)
+
+// Result is what is stored for an uploaded image: a clean copy without EXIF
+// or other metadata, and a small preview.
+type Result struct {
+	Image       []byte
+	ContentType string
+	Ext         string
+	Thumbnail   []byte
+}
+
+// Process decodes an image, turns it upright by its EXIF orientation and
+// re-encodes it, which drops the metadata with the camera and location.
+// Lossless sources stay PNG; photos become JPEG.
+func Process(data []byte) (*Result, error) {
+	img, format, err := Decode(data)
+	if err != nil {
+		if errors.Is(err, ErrTooLarge) {
+			return nil, err
+		}
+		return nil, ErrUnsupported
+	}
+	if format == "jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+
+	result := &Result{}
+	var out bytes.Buffer
+	switch format {
+	case "png", "gif":
+		err = png.Encode(&out, img)
+		result.ContentType, result.Ext = "image/png", ".png"
+	default:
+		err = jpeg.Encode(&out, flatten(img), &jpeg.Options{Quality: jpegQuality})
+		result.ContentType, result.Ext = "image/jpeg", ".jpg"
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Image = out.Bytes()
+
+	var thumbnail bytes.Buffer
+	if err = jpeg.Encode(&thumbnail, flatten(resize(img, ThumbnailSize)), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	result.Thumbnail = thumbnail.Bytes()
+	return result, nil
+}
+
+// Decode decodes an image once its header shows it is within MaxPixels, so
+// a small file claiming huge dimensions is refused before the decoder
+// allocates for them.
+func Decode(data []byte) (image.Image, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, "", ErrTooLarge
+	}
+	return image.Decode(bytes.NewReader(data))
+}
+
+// resize scales img down so its longest side is at most size.
+func resize(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	if width >= height {
+		height = max(1, height*size/width)
+		width = size
+	} else {
+		width = max(1, width*size/height)
+		height = size
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// flatten puts transparent images on white, since JPEG has no alpha and
+// would turn transparency black.
+func flatten(img image.Image) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// orient applies an EXIF orientation (1 to 8) so the image displays upright
+// without its metadata.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dstW, dstH := w, h
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return dst
+}
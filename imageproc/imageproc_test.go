@@ -0,0 +1,122 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF segment holding only the orientation tag
+// right after the JPEG start marker.
+func withOrientation(jpegData []byte, orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0,
+		0, 0, 0, 0,
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	size := len(payload) + 2
+	segment := append([]byte{0xFF, 0xE1, byte(size >> 8), byte(size)}, payload...)
+
+	out := append([]byte{}, jpegData[:2]...)
+	out = append(out, segment...)
+	return append(out, jpegData[2:]...)
+}
+
+func TestProcess_PNG(t *testing.T) {
+	var data bytes.Buffer
+	assert.NoError(t, png.Encode(&data, testImage(800, 400)))
+
+	result, err := Process(data.Bytes())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", result.ContentType)
+	assert.Equal(t, ".png", result.Ext)
+	thumbnail, err := jpeg.Decode(bytes.NewReader(result.Thumbnail))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Pt(ThumbnailSize, 160), thumbnail.Bounds().Size())
+}
+
+func TestProcess_JPEGStripsExifAndRotates(t *testing.T) {
+	var data bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&data, testImage(40, 20), nil))
+	withExif := withOrientation(data.Bytes(), 6)
+	assert.Equal(t, 6, exifOrientation(withExif))
+
+	result, err := Process(withExif)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", result.ContentType)
+	assert.False(t, bytes.Contains(result.Image, []byte("Exif")))
+	assert.Equal(t, 1, exifOrientation(result.Image))
+	img, err := jpeg.Decode(bytes.NewReader(result.Image))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Pt(20, 40), img.Bounds().Size())
+}
+
+func TestProcess_Unsupported(t *testing.T) {
+	_, err := Process([]byte("%PDF-1.4"))
+
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+// hugePNG is only a PNG signature and a header claiming width by height
+// pixels, which is all DecodeConfig reads.
+func hugePNG(width, height uint32) []byte {
+	chunk := []byte("IHDR")
+	chunk = binary.BigEndian.AppendUint32(chunk, width)
+	chunk = binary.BigEndian.AppendUint32(chunk, height)
+	chunk = append(chunk, 8, 2, 0, 0, 0)
+
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = binary.BigEndian.AppendUint32(out, uint32(len(chunk)-4))
+	out = append(out, chunk...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(chunk))
+}
+
+func TestDecode_TooLarge(t *testing.T) {
+	_, _, err := Decode(hugePNG(100000, 100000))
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	_, err = Process(hugePNG(100000, 100000))
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	// Within the budget the header alone is not enough to decode.
+	_, _, err = Decode(hugePNG(100, 100))
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrTooLarge)
+}
+
+func TestOrient(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{R: 255, A: 255})
+	src.Set(1, 0, color.RGBA{B: 255, A: 255})
+
+	// Rotating 90 degrees clockwise puts the left pixel on top.
+	rotated := orient(src, 6)
+	assert.Equal(t, image.Pt(1, 2), rotated.Bounds().Size())
+	r, _, _, _ := rotated.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0xFFFF), r)
+
+	// Rotating 270 degrees clockwise puts the right pixel on top.
+	rotated = orient(src, 8)
+	_, _, b, _ := rotated.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0xFFFF), b)
+}
@@ -0,0 +1,293 @@
+package importfile
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const FormatBeancount = "beancount"
+
+var ErrInvalidBeancount = errors.New("beancount file has no transactions")
+
+// Metadata keys that jod-jod's journal export writes, kept in step with
+// exportfile.
+const (
+	beancountMetaId       = "jodjod-id"
+	beancountMetaCategory = "category"
+	beancountMetaTime     = "time"
+	beancountMetaFitId    = "fit-id"
+	beancountMetaTransRef = "trans-ref"
+	beancountMetaBankCode = "bank-code"
+	beancountMetaTaxId    = "tax-id"
+)
+
+var (
+	beancountTxnLine     = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})\s+(?:txn|\*|!)(?:\s+(.*))?$`)
+	beancountOptionLine  = regexp.MustCompile(`^option\s+"([^"]*)"\s+"([^"]*)"`)
+	beancountMetaLine    = regexp.MustCompile(`^([a-z][A-Za-z0-9_-]*):\s*(.*)$`)
+	beancountPostingLine = regexp.MustCompile(`^(?:[*!]\s+)?([A-Z][^\s;]*)(?:\s+(\S+)\s+([A-Z][A-Z0-9'._-]*))?`)
+	beancountAccounts    = map[string]bool{"Assets": true, "Liabilities": true}
+)
+
+type beancountEntry struct {
+	line      int
+	date      string
+	payee     string
+	narration string
+	meta      map[string]string
+	postings  []beancountPosting
+	errors    []string
+}
+
+type beancountPosting struct {
+	account  string
+	amount   float64
+	elided   bool
+	currency string
+}
+
+// ReadBeancount reads the transactions of a beancount file. Each becomes an
+// income or an expense by the net of its Income and Expenses postings, paid
+// from or into its first Assets or Liabilities account. Other directives are
+// skipped. Metadata written by jod-jod's export, such as the category and
+// time, is read back so an exported file imports as it left.
+func ReadBeancount(data []byte) (*Statement, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptyFile
+	}
+
+	statement := &Statement{Format: FormatBeancount, Encoding: "UTF-8"}
+	var entries []*beancountEntry
+	var entry *beancountEntry
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, ";") {
+			if line == "" {
+				entry = nil
+			}
+			continue
+		}
+		if raw[0] == ' ' || raw[0] == '\t' {
+			if entry != nil {
+				entry.add(line)
+			}
+			continue
+		}
+
+		entry = nil
+		if match := beancountOptionLine.FindStringSubmatch(line); match != nil {
+			if match[1] == "operating_currency" && statement.Currency == "" {
+				statement.Currency = match[2]
+			}
+			continue
+		}
+		if match := beancountTxnLine.FindStringSubmatch(line); match != nil {
+			entry = &beancountEntry{line: i + 1, date: match[1], meta: make(map[string]string)}
+			entry.readHeader(match[2])
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, ErrInvalidBeancount
+	}
+	if len(entries) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+
+	for _, entry := range entries {
+		statement.Records = append(statement.Records, entry.record(statement))
+	}
+	statement.fillDates()
+	statement.fillFitIds()
+	return statement, nil
+}
+
+// readHeader reads the payee, narration and tags after the flag. With one
+// string it is the narration.
+func (e *beancountEntry) readHeader(text string) {
+	var texts []string
+header:
+	for len(text) > 0 {
+		switch text[0] {
+		case ' ', '\t':
+			text = text[1:]
+		case '"':
+			value, rest, ok := readBeancountString(text)
+			if !ok {
+				e.errors = append(e.errors, "description is invalid")
+				return
+			}
+			texts = append(texts, value)
+			text = rest
+		case ';':
+			break header
+		default:
+			// Tags and links are not kept.
+			end := strings.IndexAny(text, " \t")
+			if end < 0 {
+				break header
+			}
+			text = text[end:]
+		}
+	}
+	switch len(texts) {
+	case 1:
+		e.narration = texts[0]
+	case 2:
+		e.payee, e.narration = texts[0], texts[1]
+	}
+}
+
+func (e *beancountEntry) add(line string) {
+	if match := beancountMetaLine.FindStringSubmatch(line); match != nil {
+		value := strings.TrimSpace(match[2])
+		if unquoted, _, ok := readBeancountString(value); ok {
+			value = unquoted
+		}
+		// Posting metadata comes after the first posting and is not the
+		// transaction's.
+		if len(e.postings) == 0 {
+			e.meta[match[1]] = value
+		}
+		return
+	}
+	match := beancountPostingLine.FindStringSubmatch(line)
+	if match == nil {
+		e.errors = append(e.errors, "posting is invalid")
+		return
+	}
+	posting := beancountPosting{account: match[1], currency: match[3], elided: match[2] == ""}
+	if !posting.elided {
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(match[2], ",", ""), 64)
+		if err != nil {
+			e.errors = append(e.errors, "amount is invalid")
+			return
+		}
+		posting.amount = amount
+	}
+	e.postings = append(e.postings, posting)
+}
+
+func (e *beancountEntry) record(statement *Statement) Record {
+	record := Record{
+		Line:     e.line,
+		Merchant: truncate(e.payee, merchantSize),
+		Note:     truncate(e.narration, noteSize),
+		TransRef: e.meta[beancountMetaTransRef],
+		BankCode: e.meta[beancountMetaBankCode],
+		TaxId:    e.meta[beancountMetaTaxId],
+		FitId:    e.meta[beancountMetaFitId],
+		Errors:   e.errors,
+	}
+	if id, err := strconv.ParseUint(e.meta[beancountMetaId], 10, 32); err == nil {
+		record.SourceId = uint(id)
+	}
+
+	layout, value := "2006-01-02", e.date
+	if clock := e.meta[beancountMetaTime]; clock != "" {
+		layout, value = "2006-01-02 15:04:05", e.date+" "+clock
+	}
+	date, err := time.ParseInLocation(layout, value, bangkok)
+	if err != nil {
+		date, err = time.ParseInLocation("2006-01-02", e.date, bangkok)
+	}
+	if err != nil {
+		record.Errors = append(record.Errors, "date is invalid")
+	}
+	record.Date = date
+
+	// At most one posting may leave its amount out; it takes what balances
+	// the others.
+	var sum float64
+	elided := -1
+	for i, posting := range e.postings {
+		if posting.elided {
+			if elided >= 0 {
+				record.Errors = append(record.Errors, "more than one posting has no amount")
+				return record
+			}
+			elided = i
+			continue
+		}
+		if statement.Currency == "" {
+			statement.Currency = posting.currency
+		}
+		if posting.currency != statement.Currency {
+			record.Errors = append(record.Errors, "currency "+posting.currency+" is not "+statement.Currency)
+			return record
+		}
+		sum += posting.amount
+	}
+	if elided >= 0 {
+		e.postings[elided].amount = -sum
+	}
+
+	var net, largest float64
+	var category string
+	var flows int
+	for _, posting := range e.postings {
+		root, rest, _ := strings.Cut(posting.account, ":")
+		switch {
+		case root == "Expenses" || root == "Income":
+			flows++
+			net += posting.amount
+			if math.Abs(posting.amount) > largest {
+				largest = math.Abs(posting.amount)
+				category, _, _ = strings.Cut(rest, ":")
+			}
+		case beancountAccounts[root] && record.Account == "":
+			record.Account = posting.account
+		}
+	}
+	net = math.Round(net*100) / 100
+	switch {
+	case flows == 0:
+		record.Errors = append(record.Errors, "entry only moves money between accounts")
+		return record
+	case net > 0:
+		record.TransactionType, record.Amount = TypeExpense, net
+	case net < 0:
+		record.TransactionType, record.Amount = TypeIncome, -net
+	default:
+		record.Errors = append(record.Errors, "entry's income and expenses cancel out")
+		return record
+	}
+
+	record.Category = e.meta[beancountMetaCategory]
+	if record.Category == "" {
+		record.Category = strings.ToLower(category)
+	}
+	if record.Category == "" {
+		record.Category = DefaultCategory
+	}
+	record.Category = truncate(record.Category, categorySize)
+	return record
+}
+
+// readBeancountString reads the quoted string text starts with and returns
+// it with the text after it.
+func readBeancountString(text string) (string, string, bool) {
+	if !strings.HasPrefix(text, `"`) {
+		return "", text, false
+	}
+	var value strings.Builder
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 < len(text) {
+				i++
+				value.WriteByte(text[i])
+			}
+		case '"':
+			return value.String(), text[i+1:], true
+		default:
+			value.WriteByte(text[i])
+		}
+	}
+	return "", text, false
+}
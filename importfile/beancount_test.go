@@ -0,0 +1,80 @@
+package importfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/Montheankul-K/jod-jod/exportfile"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestReadBeancount_Golden reads a hand-written journal with elided
+// postings, splits, a transfer, a refund and a foreign currency. Run with
+// -update to regenerate the golden file after a deliberate change.
+func TestReadBeancount_Golden(t *testing.T) {
+	data, err := os.ReadFile("testdata/personal.beancount")
+	assert.NoError(t, err)
+
+	statement, err := ReadBeancount(data)
+	assert.NoError(t, err)
+	got, err := json.MarshalIndent(statement, "", "  ")
+	assert.NoError(t, err)
+
+	golden := filepath.Join("testdata", "personal.golden.json")
+	if *update {
+		assert.NoError(t, os.WriteFile(golden, append(got, '\n'), 0o644))
+		return
+	}
+	want, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(want), string(got))
+}
+
+func TestReadBeancount_RoundTrip(t *testing.T) {
+	accountId := uint(4)
+	rows := []exportfile.Row{
+		{ID: 7, Date: time.Date(2024, 3, 12, 10, 15, 0, 0, bangkok), Amount: 1250.5, TransactionType: TypeExpense, Category: "อาหาร", Note: `dinner "set" for 2`, Merchant: "ร้านกาแฟ", TransRef: "REF1", Tags: []string{"slip"}},
+		{ID: 8, Date: time.Date(2024, 3, 13, 8, 0, 0, 0, bangkok), Amount: 30000, TransactionType: TypeIncome, Category: "salary", FitId: "kbank:1",
+			AccountId: &accountId, Account: &exportfile.Account{ID: accountId, Name: "kbank savings", Kind: "bank", Currency: "THB"}, Tags: []string{"imported"}},
+	}
+	var out bytes.Buffer
+	writer, err := exportfile.NewWriter(&out, exportfile.Options{Format: exportfile.FormatBeancount})
+	assert.NoError(t, err)
+	for _, row := range rows {
+		assert.NoError(t, writer.Write(row))
+	}
+	assert.NoError(t, writer.Close())
+
+	statement, err := ReadBeancount(out.Bytes())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "THB", statement.Currency)
+	assert.Len(t, statement.Records, 2)
+	for i, row := range rows {
+		record := statement.Records[i]
+		assert.True(t, record.Valid(), record.Errors)
+		assert.Equal(t, row.ID, record.SourceId)
+		assert.True(t, row.Date.Equal(record.Date))
+		assert.Equal(t, row.Amount, record.Amount)
+		assert.Equal(t, row.TransactionType, record.TransactionType)
+		assert.Equal(t, row.Category, record.Category)
+		assert.Equal(t, row.Note, record.Note)
+		assert.Equal(t, row.Merchant, record.Merchant)
+		assert.Equal(t, row.TransRef, record.TransRef)
+	}
+	assert.Equal(t, exportfile.CashAccount, statement.Records[0].Account)
+	assert.Equal(t, "Assets:Bank:Kbank-Savings", statement.Records[1].Account)
+	assert.Equal(t, "kbank:1", statement.Records[1].FitId)
+	assert.Contains(t, statement.Records[0].FitId, FormatBeancount+":")
+}
+
+func TestReadBeancount_Errors(t *testing.T) {
+	_, err := ReadBeancount([]byte("  \n"))
+	assert.ErrorIs(t, err, ErrEmptyFile)
+
+	_, err = ReadBeancount([]byte("2024-01-01 open Assets:Cash THB\n"))
+	assert.ErrorIs(t, err, ErrInvalidBeancount)
+}
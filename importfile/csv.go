@@ -0,0 +1,152 @@
+package importfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	EncodingUTF8  = "utf-8"
+	EncodingUTF16 = "utf-16"
+	// EncodingTIS620 is read as Windows-874, the superset Excel writes
+	// Thai CSV files in.
+	EncodingTIS620 = "tis-620"
+
+	// sniffRows is how many rows the delimiter is judged on.
+	sniffRows = 20
+)
+
+var ErrInvalidCSV = errors.New("csv file is invalid")
+
+// delimiters are tried in order; the first wins a tie.
+var delimiters = []rune{',', ';', '\t', '|'}
+
+// CSV is a decoded CSV file. The first row is taken to be the header.
+type CSV struct {
+	Encoding  string     `json:"encoding"`
+	Delimiter string     `json:"delimiter"`
+	Header    []string   `json:"header"`
+	Rows      [][]string `json:"-"`
+}
+
+// ReadCSV decodes a CSV file of any delimiter in UTF-8, UTF-16 with a byte
+// order mark, or TIS-620.
+func ReadCSV(data []byte) (*CSV, error) {
+	text, encoding, err := decode(data)
+	if err != nil {
+		return nil, ErrInvalidCSV
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptyFile
+	}
+
+	delimiter := detectDelimiter(text)
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidCSV
+		}
+		if isBlank(row) {
+			continue
+		}
+		if len(rows) > MaxRows {
+			return nil, ErrTooManyRows
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) < 2 {
+		return nil, ErrEmptyFile
+	}
+
+	header := make([]string, len(rows[0]))
+	for i, name := range rows[0] {
+		header[i] = strings.TrimSpace(name)
+	}
+	return &CSV{
+		Encoding:  encoding,
+		Delimiter: string(delimiter),
+		Header:    header,
+		Rows:      rows[1:],
+	}, nil
+}
+
+// decode converts the file to UTF-8. A byte order mark decides the encoding;
+// without one, text that is not valid UTF-8 is taken to be Thai.
+func decode(data []byte) (string, string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), EncodingUTF8, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoded, err := unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		return string(decoded), EncodingUTF16, err
+	case utf8.Valid(data):
+		return string(data), EncodingUTF8, nil
+	default:
+		decoded, err := charmap.Windows874.NewDecoder().Bytes(data)
+		return string(decoded), EncodingTIS620, err
+	}
+}
+
+// detectDelimiter picks the delimiter that splits the first rows into the
+// same number of fields, preferring more fields. A comma inside a Thai
+// amount such as "1,250.00" splits rows unevenly unless it is quoted, which
+// is what tells it apart from a real delimiter.
+func detectDelimiter(text string) rune {
+	best, bestFields := delimiters[0], 1
+	for _, delimiter := range delimiters {
+		reader := csv.NewReader(strings.NewReader(text))
+		reader.Comma = delimiter
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+
+		fields, consistent := 0, true
+		for i := 0; i < sniffRows; i++ {
+			row, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				consistent = false
+				break
+			}
+			if isBlank(row) {
+				continue
+			}
+			if fields == 0 {
+				fields = len(row)
+			} else if len(row) != fields {
+				consistent = false
+				break
+			}
+		}
+		if consistent && fields > bestFields {
+			best, bestFields = delimiter, fields
+		}
+	}
+	return best
+}
+
+func isBlank(row []string) bool {
+	for _, field := range row {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package importfile
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+	"testing"
+	"time"
+)
+
+func TestReadCSV_Semicolon(t *testing.T) {
+	data := []byte("\xEF\xBB\xBFDate;Amount;Note\n12/03/2024;1,250.00;rent\n13/03/2024;45;coffee\n")
+
+	result, err := ReadCSV(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, EncodingUTF8, result.Encoding)
+	assert.Equal(t, ";", result.Delimiter)
+	assert.Equal(t, []string{"Date", "Amount", "Note"}, result.Header)
+	assert.Equal(t, [][]string{{"12/03/2024", "1,250.00", "rent"}, {"13/03/2024", "45", "coffee"}}, result.Rows)
+}
+
+func TestReadCSV_QuotedCommas(t *testing.T) {
+	data := []byte("date,amount,note\n2024-03-12,\"1,250.00\",\"rent, march\"\n\n2024-03-13,45,coffee\n")
+
+	result, err := ReadCSV(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ",", result.Delimiter)
+	assert.Len(t, result.Rows, 2)
+	assert.Equal(t, "rent, march", result.Rows[0][2])
+}
+
+func TestReadCSV_TIS620(t *testing.T) {
+	data, err := charmap.Windows874.NewEncoder().Bytes([]byte("วันที่\tจำนวนเงิน\tหมวดหมู่\n12/03/2567\t120\tอาหาร\n"))
+	assert.NoError(t, err)
+
+	result, err := ReadCSV(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, EncodingTIS620, result.Encoding)
+	assert.Equal(t, "\t", result.Delimiter)
+	assert.Equal(t, []string{"วันที่", "จำนวนเงิน", "หมวดหมู่"}, result.Header)
+	assert.Equal(t, "อาหาร", result.Rows[0][2])
+}
+
+func TestReadCSV_UTF16(t *testing.T) {
+	data := []byte{0xFF, 0xFE}
+	for _, r := range "date,amount\n2024-03-12,10\n" {
+		data = append(data, byte(r), 0)
+	}
+
+	result, err := ReadCSV(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, EncodingUTF16, result.Encoding)
+	assert.Equal(t, [][]string{{"2024-03-12", "10"}}, result.Rows)
+}
+
+func TestReadCSV_Empty(t *testing.T) {
+	_, err := ReadCSV([]byte("date,amount\n"))
+
+	assert.ErrorIs(t, err, ErrEmptyFile)
+}
+
+func TestProposeMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   Mapping
+	}{
+		{
+			name:   "english",
+			header: []string{"Transaction Date", "Description", "Category", "Amount (THB)", "Type"},
+			want:   Mapping{FieldDate: 0, FieldNote: 1, FieldCategory: 2, FieldAmount: 3, FieldType: 4},
+		},
+		{
+			name:   "thai bank statement",
+			header: []string{"วันที่", "รายละเอียด", "ถอนเงิน", "ฝากเงิน", "ยอดเงินคงเหลือ"},
+			want:   Mapping{FieldDate: 0, FieldNote: 1, FieldDebit: 2, FieldCredit: 3},
+		},
+		{
+			name:   "unknown",
+			header: []string{"a", "b"},
+			want:   Mapping{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ProposeMapping(tt.header))
+		})
+	}
+}
+
+func TestMapping_Validate(t *testing.T) {
+	assert.NoError(t, Mapping{FieldDate: 0, FieldAmount: 1}.Validate(2))
+	assert.EqualError(t, Mapping{FieldAmount: 1}.Validate(2), "mapping has no date column")
+	assert.EqualError(t, Mapping{FieldDate: 0}.Validate(2), "mapping has no amount, debit or credit column")
+	assert.EqualError(t, Mapping{FieldDate: 0, FieldAmount: 2}.Validate(2), "mapping column 2 of amount is out of range")
+	assert.EqualError(t, Mapping{FieldDate: 0, FieldAmount: 1, "balance": 1}.Validate(2), "mapping field balance is unknown")
+}
+
+func TestMapping_Record(t *testing.T) {
+	mapping := Mapping{FieldDate: 0, FieldAmount: 1, FieldCategory: 2, FieldNote: 3}
+	opts := Options{DefaultType: TypeIncome}
+
+	tests := []struct {
+		name string
+		row  []string
+		want Record
+	}{
+		{
+			name: "positive amount takes the default type",
+			row:  []string{"12/03/2567", "฿1,250.50", "Salary", "march"},
+			want: Record{Line: 2, Date: time.Date(2024, 3, 12, 0, 0, 0, 0, bangkok), Amount: 1250.5, TransactionType: TypeIncome, Category: "salary", Note: "march"},
+		},
+		{
+			name: "negative amount is an expense",
+			row:  []string{"2024-03-13 08:30", "(45.00)", "", "coffee"},
+			want: Record{Line: 2, Date: time.Date(2024, 3, 13, 8, 30, 0, 0, bangkok), Amount: 45, TransactionType: TypeExpense, Category: DefaultCategory, Note: "coffee"},
+		},
+		{
+			name: "every problem is reported",
+			row:  []string{"31/02/2024", "abc", "", ""},
+			want: Record{Line: 2, Category: DefaultCategory, Errors: []string{`date "31/02/2024" is invalid`, `amount "abc" is invalid`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mapping.Record(tt.row, 2, opts))
+		})
+	}
+}
+
+func TestMapping_Record_DebitCredit(t *testing.T) {
+	mapping := Mapping{FieldDate: 0, FieldDebit: 1, FieldCredit: 2}
+
+	debit := mapping.Record([]string{"01/04/2024", "120.00", ""}, 2, Options{})
+	credit := mapping.Record([]string{"01/04/2024", "", "5,000.00"}, 3, Options{})
+	both := mapping.Record([]string{"01/04/2024", "1", "2"}, 4, Options{})
+
+	assert.Equal(t, TypeExpense, debit.TransactionType)
+	assert.Equal(t, float64(120), debit.Amount)
+	assert.Equal(t, TypeIncome, credit.TransactionType)
+	assert.Equal(t, float64(5000), credit.Amount)
+	assert.Equal(t, []string{"row has both a debit and a credit"}, both.Errors)
+}
+
+func TestParseDate_Order(t *testing.T) {
+	dmy, ok := ParseDate("03/04/2024", DateOrderDMY)
+	assert.True(t, ok)
+	mdy, ok := ParseDate("03/04/2024", DateOrderMDY)
+	assert.True(t, ok)
+	thai, ok := ParseDate("12 มี.ค. 67", "")
+	assert.True(t, ok)
+
+	assert.Equal(t, time.April, dmy.Month())
+	assert.Equal(t, time.March, mdy.Month())
+	assert.Equal(t, time.Date(2024, 3, 12, 0, 0, 0, 0, bangkok), thai.In(bangkok))
+}
@@ -0,0 +1,63 @@
+package importfile
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	TypeIncome  = "income"
+	TypeExpense = "expense"
+
+	// DefaultCategory is the category of rows that do not name one, as for
+	// manual entries.
+	DefaultCategory = "other"
+
+	// MaxRows caps how many rows one file may hold.
+	MaxRows = 5000
+
+	categorySize = 50
+	noteSize     = 255
+	merchantSize = 100
+)
+
+var (
+	ErrEmptyFile   = errors.New("import file is empty")
+	ErrTooManyRows = errors.New("import file has too many rows")
+)
+
+// Record is one transaction read from an import file. Line is its row in the
+// file, counting the header but not blank lines, so errors can be traced
+// back to the spreadsheet; in a statement it is the entry's position, and in
+// a journal the line its entry starts on. FitId identifies a statement entry
+// across downloads. Account, SourceId and the fields after it are only read
+// from journals: the journal account paid from or into, and the jod-jod ID
+// of an entry that was exported.
+type Record struct {
+	Line            int       `json:"line"`
+	Date            time.Time `json:"date"`
+	Amount          float64   `json:"amount"`
+	TransactionType string    `json:"transaction_type"`
+	Category        string    `json:"category"`
+	Note            string    `json:"note"`
+	Merchant        string    `json:"merchant"`
+	FitId           string    `json:"fit_id,omitempty"`
+	Account         string    `json:"account,omitempty"`
+	SourceId        uint      `json:"source_id,omitempty"`
+	TransRef        string    `json:"trans_ref,omitempty"`
+	BankCode        string    `json:"bank_code,omitempty"`
+	TaxId           string    `json:"tax_id,omitempty"`
+	Errors          []string  `json:"errors,omitempty"`
+}
+
+func (r Record) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+func truncate(text string, size int) string {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text
+	}
+	return string(runes[:size])
+}
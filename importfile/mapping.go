@@ -0,0 +1,358 @@
+package importfile
+
+import (
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/slip"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields a column can be mapped to. An amount comes from FieldAmount, or
+// from FieldDebit and FieldCredit when the file has a column for each way.
+const (
+	FieldDate     = "date"
+	FieldAmount   = "amount"
+	FieldDebit    = "debit"
+	FieldCredit   = "credit"
+	FieldType     = "transaction_type"
+	FieldCategory = "category"
+	FieldNote     = "note"
+	FieldMerchant = "merchant"
+)
+
+// Orders of the day, month and year in numeric dates.
+const (
+	DateOrderDMY = "dmy"
+	DateOrderMDY = "mdy"
+	DateOrderYMD = "ymd"
+)
+
+// Mapping maps fields to column indexes.
+type Mapping map[string]int
+
+// Options say how values are read.
+type Options struct {
+	// DateOrder is how numeric dates are written; Thai files use dmy, the
+	// default.
+	DateOrder string
+	// DefaultType is the type of a positive amount without a type column.
+	// Negative amounts are always expenses.
+	DefaultType string
+}
+
+// fieldNames are the header names each field is recognized by, compared
+// without case. More specific fields come first so "transaction date" is not
+// taken for a type.
+var fieldNames = []struct {
+	field string
+	names []string
+}{
+	{FieldDate, []string{"date", "transaction date", "posting date", "วันที่", "วันที่ทำรายการ", "วันเดือนปี"}},
+	{FieldDebit, []string{"debit", "withdrawal", "withdrawals", "expense", "expenses", "ถอน", "ถอนเงิน", "รายจ่าย", "จ่าย"}},
+	{FieldCredit, []string{"credit", "deposit", "deposits", "income", "ฝาก", "ฝากเงิน", "รายรับ", "รับ"}},
+	{FieldAmount, []string{"amount", "value", "total", "จำนวนเงิน", "จำนวน", "ยอดเงิน", "ยอด"}},
+	{FieldType, []string{"type", "transaction type", "ประเภท", "ประเภทรายการ"}},
+	{FieldCategory, []string{"category", "หมวดหมู่", "หมวด"}},
+	{FieldMerchant, []string{"merchant", "payee", "shop", "store", "ร้านค้า", "ร้าน", "ผู้รับเงิน"}},
+	{FieldNote, []string{"note", "notes", "description", "memo", "details", "รายละเอียด", "รายการ", "หมายเหตุ", "บันทึก"}},
+}
+
+var (
+	numericDateRe = regexp.MustCompile(`^(\d{1,4})[/\-.](\d{1,2})[/\-.](\d{1,4})(?:[ T](\d{1,2}):(\d{2})(?::(\d{2}))?)?`)
+	amountJunk    = strings.NewReplacer(",", "", " ", "", "฿", "", "บาท", "", "THB", "", "thb", "", "\u00a0", "")
+)
+
+var incomeTypes = map[string]bool{
+	"income": true, "in": true, "credit": true, "cr": true, "deposit": true, "รายรับ": true, "รับ": true, "เงินเข้า": true,
+}
+
+var expenseTypes = map[string]bool{
+	"expense": true, "out": true, "debit": true, "dr": true, "withdrawal": true, "รายจ่าย": true, "จ่าย": true, "เงินออก": true,
+}
+
+var bangkok = loadBangkok()
+
+func loadBangkok() *time.Location {
+	loc, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		return time.FixedZone("Asia/Bangkok", 7*60*60)
+	}
+	return loc
+}
+
+// ProposeMapping maps the columns whose header names a field. A header
+// matched exactly wins over one that only contains the name, and a column is
+// mapped once.
+func ProposeMapping(header []string) Mapping {
+	mapping := Mapping{}
+	used := map[int]bool{}
+	for _, exact := range []bool{true, false} {
+		for _, entry := range fieldNames {
+			if _, ok := mapping[entry.field]; ok {
+				continue
+			}
+			for i, column := range header {
+				if used[i] {
+					continue
+				}
+				if matchesName(strings.ToLower(strings.TrimSpace(column)), entry.names, exact) {
+					mapping[entry.field] = i
+					used[i] = true
+					break
+				}
+			}
+		}
+	}
+	// A single amount column says nothing of its direction; debit and
+	// credit columns do, so they are kept over it.
+	if _, ok := mapping[FieldDebit]; ok {
+		if _, ok = mapping[FieldCredit]; ok {
+			delete(mapping, FieldAmount)
+		}
+	}
+	return mapping
+}
+
+func matchesName(column string, names []string, exact bool) bool {
+	for _, name := range names {
+		if column == name || (!exact && strings.Contains(column, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the mapping gives a date and an amount and points at
+// columns the file has.
+func (m Mapping) Validate(columns int) error {
+	if _, ok := m[FieldDate]; !ok {
+		return fmt.Errorf("mapping has no %s column", FieldDate)
+	}
+	_, amount := m[FieldAmount]
+	_, debit := m[FieldDebit]
+	_, credit := m[FieldCredit]
+	if !amount && !debit && !credit {
+		return fmt.Errorf("mapping has no %s, %s or %s column", FieldAmount, FieldDebit, FieldCredit)
+	}
+
+	fields := make([]string, 0, len(m))
+	for field := range m {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if !knownField(field) {
+			return fmt.Errorf("mapping field %s is unknown", field)
+		}
+		if m[field] < 0 || m[field] >= columns {
+			return fmt.Errorf("mapping column %d of %s is out of range", m[field], field)
+		}
+	}
+	return nil
+}
+
+func knownField(field string) bool {
+	for _, entry := range fieldNames {
+		if entry.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Records reads every row through the mapping. line is the file line of the
+// first row.
+func (m Mapping) Records(rows [][]string, firstLine int, opts Options) []Record {
+	records := make([]Record, 0, len(rows))
+	for i, row := range rows {
+		records = append(records, m.Record(row, firstLine+i, opts))
+	}
+	return records
+}
+
+// Record reads one row. Problems are collected on the record rather than
+// stopping at the first, so a preview can show all of them.
+func (m Mapping) Record(row []string, line int, opts Options) Record {
+	record := Record{Line: line, Category: DefaultCategory}
+	value := func(field string) string {
+		i, ok := m[field]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	if text := value(FieldDate); text == "" {
+		record.Errors = append(record.Errors, "date is missing")
+	} else if date, ok := ParseDate(text, opts.DateOrder); ok {
+		record.Date = date
+	} else {
+		record.Errors = append(record.Errors, fmt.Sprintf("date %q is invalid", text))
+	}
+
+	m.readAmount(&record, value, opts)
+
+	if category := strings.ToLower(value(FieldCategory)); category != "" {
+		if len([]rune(category)) > categorySize {
+			record.Errors = append(record.Errors, fmt.Sprintf("category is longer than %d characters", categorySize))
+		}
+		record.Category = category
+	}
+	record.Note = truncate(value(FieldNote), noteSize)
+	record.Merchant = truncate(value(FieldMerchant), merchantSize)
+	return record
+}
+
+func (m Mapping) readAmount(record *Record, value func(string) string, opts Options) {
+	defaultType := opts.DefaultType
+	if defaultType == "" {
+		defaultType = TypeExpense
+	}
+
+	_, hasAmount := m[FieldAmount]
+	if hasAmount && value(FieldAmount) != "" {
+		text := value(FieldAmount)
+		amount, ok := ParseAmount(text)
+		if !ok {
+			record.Errors = append(record.Errors, fmt.Sprintf("amount %q is invalid", text))
+			return
+		}
+		record.Amount = math.Abs(amount)
+		record.TransactionType = defaultType
+		if amount < 0 {
+			record.TransactionType = TypeExpense
+		}
+		if text := value(FieldType); text != "" {
+			txnType, ok := ParseType(text)
+			if !ok {
+				record.Errors = append(record.Errors, fmt.Sprintf("transaction type %q is invalid", text))
+			}
+			record.TransactionType = txnType
+		}
+	} else {
+		debit, credit := value(FieldDebit), value(FieldCredit)
+		debitAmount, debitOk := ParseAmount(debit)
+		creditAmount, creditOk := ParseAmount(credit)
+		switch {
+		case debit != "" && !debitOk:
+			record.Errors = append(record.Errors, fmt.Sprintf("debit %q is invalid", debit))
+			return
+		case credit != "" && !creditOk:
+			record.Errors = append(record.Errors, fmt.Sprintf("credit %q is invalid", credit))
+			return
+		case debitAmount != 0 && creditAmount != 0:
+			record.Errors = append(record.Errors, "row has both a debit and a credit")
+			return
+		case debitAmount != 0:
+			record.Amount, record.TransactionType = math.Abs(debitAmount), TypeExpense
+		case creditAmount != 0:
+			record.Amount, record.TransactionType = math.Abs(creditAmount), TypeIncome
+		default:
+			record.Errors = append(record.Errors, "amount is missing")
+			return
+		}
+	}
+
+	if record.Amount == 0 {
+		record.Errors = append(record.Errors, "amount is zero")
+	}
+}
+
+// ParseAmount reads amounts as spreadsheets write them: "1,250.00",
+// "฿1,250", "-45" or "(45.00)" for a negative.
+func ParseAmount(text string) (float64, bool) {
+	text = amountJunk.Replace(strings.TrimSpace(text))
+	negative := false
+	if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+		text, negative = text[1:len(text)-1], true
+	}
+	if strings.HasSuffix(text, "-") {
+		text, negative = strings.TrimSuffix(text, "-"), true
+	}
+	if text == "" {
+		return 0, false
+	}
+	amount, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return 0, false
+	}
+	if negative {
+		amount = -amount
+	}
+	return math.Round(amount*100) / 100, true
+}
+
+// ParseType reads a transaction type in English or Thai.
+func ParseType(text string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(text))
+	switch {
+	case incomeTypes[key]:
+		return TypeIncome, true
+	case expenseTypes[key]:
+		return TypeExpense, true
+	default:
+		return "", false
+	}
+}
+
+// ParseDate reads a date in Bangkok time: ISO 8601, numeric in the given
+// order with Gregorian or Buddhist-era years, or with a Thai or English month
+// name as on slips.
+func ParseDate(text, order string) (time.Time, bool) {
+	if date, err := time.Parse(time.RFC3339, text); err == nil {
+		return date, true
+	}
+
+	match := numericDateRe.FindStringSubmatch(text)
+	if match == nil {
+		return slip.ParseDate(text)
+	}
+	parts := []int{atoi(match[1]), atoi(match[2]), atoi(match[3])}
+	var day, month, year int
+	switch {
+	case len(match[1]) == 4 || order == DateOrderYMD:
+		year, month, day = parts[0], parts[1], parts[2]
+	case order == DateOrderMDY:
+		month, day, year = parts[0], parts[1], parts[2]
+	default:
+		day, month, year = parts[0], parts[1], parts[2]
+	}
+	year = fullYear(year)
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	hour, minute, second := atoi(match[4]), atoi(match[5]), atoi(match[6])
+	if hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+	date := time.Date(year, time.Month(month), day, hour, minute, second, 0, bangkok)
+	if date.Day() != day {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// fullYear converts Buddhist-era and two digit years. As on slips, a two
+// digit year of 50 or more is Buddhist era.
+func fullYear(year int) int {
+	switch {
+	case year >= 2400:
+		return year - 543
+	case year >= 100:
+		return year
+	case year >= 50:
+		return 2500 + year - 543
+	default:
+		return 2000 + year
+	}
+}
+
+func atoi(text string) int {
+	n, _ := strconv.Atoi(text)
+	return n
+}
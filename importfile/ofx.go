@@ -0,0 +1,188 @@
+package importfile
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidOFX = errors.New("ofx file is invalid")
+
+var ofxDateRe = regexp.MustCompile(`^(\d{4})(\d{2})(\d{2})(?:(\d{2})(\d{2})(?:(\d{2}))?)?(?:\.\d+)?(?:\[([+-]?\d+(?:\.\d+)?)(?::[^\]]*)?\])?`)
+
+// ofxElement is one tag of an OFX file and the text after it. Version 1 is
+// SGML whose leaf elements are not closed, so the text up to the next tag is
+// all there is to go on.
+type ofxElement struct {
+	name    string
+	closing bool
+	value   string
+}
+
+// readOFX reads the first statement of an OFX or QFX file, bank or credit
+// card, in version 1 (SGML) or 2 (XML).
+func readOFX(text string) (*Statement, error) {
+	start := strings.Index(strings.ToUpper(text), "<OFX")
+	elements := tokenizeOFX(text[start:])
+
+	statement := &Statement{Format: FormatOFX}
+	var entry map[string]string
+	var line int
+	for _, element := range elements {
+		switch {
+		case element.name == "STMTTRN" && !element.closing:
+			entry = make(map[string]string)
+		case element.name == "STMTTRN" && element.closing:
+			if entry != nil {
+				line++
+				statement.Records = append(statement.Records, ofxRecord(entry, line))
+			}
+			entry = nil
+		case element.closing || element.value == "":
+		case entry != nil:
+			if _, ok := entry[element.name]; !ok {
+				entry[element.name] = element.value
+			}
+		default:
+			statement.setField(element.name, element.value)
+		}
+	}
+	// An SGML file may leave the last entry open when it is cut short.
+	if entry != nil {
+		statement.Records = append(statement.Records, ofxRecord(entry, line+1))
+	}
+	if statement.Records == nil && statement.Account == "" {
+		return nil, ErrInvalidOFX
+	}
+	return statement, nil
+}
+
+// setField keeps the first value of each statement field, which is the one
+// of the first account in the file.
+func (s *Statement) setField(name, value string) {
+	switch name {
+	case "ACCTID":
+		if s.Account == "" {
+			s.Account = value
+		}
+	case "BANKID":
+		if s.BankId == "" {
+			s.BankId = value
+		}
+	case "CURDEF":
+		if s.Currency == "" {
+			s.Currency = strings.ToUpper(value)
+		}
+	case "DTSTART":
+		if date, ok := parseOFXDate(value); ok && s.StartDate.IsZero() {
+			s.StartDate = date
+		}
+	case "DTEND":
+		if date, ok := parseOFXDate(value); ok && s.EndDate.IsZero() {
+			s.EndDate = date
+		}
+	}
+}
+
+func tokenizeOFX(text string) []ofxElement {
+	var elements []ofxElement
+	for {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			return elements
+		}
+		end := strings.IndexByte(text[open:], '>')
+		if end < 0 {
+			return elements
+		}
+		tag := strings.TrimSpace(text[open+1 : open+end])
+		text = text[open+end+1:]
+		name := strings.Fields(strings.TrimPrefix(tag, "/"))
+		if len(name) == 0 || strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		next := strings.IndexByte(text, '<')
+		if next < 0 {
+			next = len(text)
+		}
+		element := ofxElement{
+			name:  strings.ToUpper(name[0]),
+			value: strings.TrimSpace(html.UnescapeString(text[:next])),
+		}
+		element.closing = strings.HasPrefix(tag, "/")
+		elements = append(elements, element)
+	}
+}
+
+// ofxRecord makes a record from the fields of one STMTTRN. The payee is
+// NAME, or the NAME of a PAYEE aggregate, which the flat fields already hold.
+// Transfers between accounts are filed as such.
+func ofxRecord(entry map[string]string, line int) Record {
+	record := Record{
+		Line:     line,
+		Category: DefaultCategory,
+		Merchant: truncate(entry["NAME"], merchantSize),
+		Note:     truncate(entry["MEMO"], noteSize),
+		FitId:    entry["FITID"],
+	}
+
+	dateText := entry["DTPOSTED"]
+	if dateText == "" {
+		dateText = entry["DTUSER"]
+	}
+	if date, ok := parseOFXDate(dateText); ok {
+		record.Date = date
+	} else {
+		record.Errors = append(record.Errors, "date is invalid")
+	}
+
+	amount, ok := ParseAmount(entry["TRNAMT"])
+	switch {
+	case !ok:
+		record.Errors = append(record.Errors, "amount is invalid")
+	case amount == 0:
+		record.Errors = append(record.Errors, "amount is zero")
+	case amount < 0:
+		record.Amount, record.TransactionType = -amount, TypeExpense
+	default:
+		record.Amount, record.TransactionType = amount, TypeIncome
+	}
+
+	if strings.EqualFold(entry["TRNTYPE"], "XFER") {
+		record.Category = "transfer"
+	}
+	return record
+}
+
+// parseOFXDate reads "20240312", "20240312101500.000" or
+// "20240312101500[+7:ICT]". Without a zone the bank's local time is meant,
+// which for the banks this app sees is Bangkok.
+func parseOFXDate(text string) (time.Time, bool) {
+	match := ofxDateRe.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return time.Time{}, false
+	}
+	year, month, day := atoi(match[1]), atoi(match[2]), atoi(match[3])
+	hour, minute, second := atoi(match[4]), atoi(match[5]), atoi(match[6])
+	if month < 1 || month > 12 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+
+	loc := bangkok
+	if match[7] != "" {
+		offset, err := strconv.ParseFloat(match[7], 64)
+		if err != nil || offset < -12 || offset > 14 {
+			return time.Time{}, false
+		}
+		loc = time.FixedZone("", int(offset*3600))
+	}
+	date := time.Date(year, time.Month(month), day, hour, minute, second, 0, loc)
+	if date.Day() != day {
+		return time.Time{}, false
+	}
+	return date.In(bangkok), true
+}
@@ -0,0 +1,108 @@
+package importfile
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidQIF = errors.New("qif file is invalid")
+
+// qifAccountTypes are the !Type sections that hold an account's entries;
+// lists of categories, classes and investments are skipped.
+var qifAccountTypes = map[string]bool{
+	"bank": true, "cash": true, "ccard": true, "oth a": true, "oth l": true,
+}
+
+// readQIF reads the entries of the account sections of a QIF file. QIF has
+// no FITID, no account number and no currency, and its date order depends on
+// the program that wrote it.
+func readQIF(text, order string) (*Statement, error) {
+	statement := &Statement{Format: FormatQIF}
+	inAccount := false
+	sawType := false
+	entry := make(map[byte]string)
+	var line int
+
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if strings.HasPrefix(raw, "!") {
+			header := strings.ToLower(raw)
+			if kind, ok := strings.CutPrefix(header, "!type:"); ok {
+				inAccount = qifAccountTypes[strings.TrimSpace(kind)]
+				sawType = true
+			}
+			entry = make(map[byte]string)
+			continue
+		}
+		if !inAccount {
+			continue
+		}
+		if raw == "^" {
+			if len(entry) > 0 {
+				line++
+				statement.Records = append(statement.Records, qifRecord(entry, line, order))
+			}
+			entry = make(map[byte]string)
+			continue
+		}
+		// Split entries repeat S, E and $; only the first of each code is
+		// the entry's own.
+		if _, ok := entry[raw[0]]; !ok {
+			entry[raw[0]] = strings.TrimSpace(raw[1:])
+		}
+	}
+	if !sawType {
+		return nil, ErrInvalidQIF
+	}
+	return statement, nil
+}
+
+func qifRecord(entry map[byte]string, line int, order string) Record {
+	record := Record{
+		Line:     line,
+		Category: DefaultCategory,
+		Merchant: truncate(entry['P'], merchantSize),
+		Note:     truncate(entry['M'], noteSize),
+	}
+
+	// Quicken writes "3/12'24" for 2024 and "3/12/24" before 2000.
+	if date, ok := ParseDate(strings.ReplaceAll(strings.ReplaceAll(entry['D'], "'", "/"), " ", ""), order); ok {
+		record.Date = date
+	} else {
+		record.Errors = append(record.Errors, "date is invalid")
+	}
+
+	amountText := entry['T']
+	if amountText == "" {
+		amountText = entry['U']
+	}
+	amount, ok := ParseAmount(amountText)
+	switch {
+	case !ok:
+		record.Errors = append(record.Errors, "amount is invalid")
+	case amount == 0:
+		record.Errors = append(record.Errors, "amount is zero")
+	case amount < 0:
+		record.Amount, record.TransactionType = -amount, TypeExpense
+	default:
+		record.Amount, record.TransactionType = amount, TypeIncome
+	}
+
+	// "[Savings]" is a transfer to another account; "Food:Dining" is a
+	// category and its subcategory, of which the app keeps the first.
+	if category := entry['L']; category != "" {
+		if strings.HasPrefix(category, "[") {
+			record.Category = "transfer"
+		} else {
+			top, _, _ := strings.Cut(category, ":")
+			top, _, _ = strings.Cut(top, "/")
+			if top = strings.ToLower(strings.TrimSpace(top)); top != "" {
+				record.Category = truncate(top, categorySize)
+			}
+		}
+	}
+	return record
+}
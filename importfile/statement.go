@@ -0,0 +1,101 @@
+package importfile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatOFX = "ofx"
+	FormatQIF = "qif"
+)
+
+var ErrUnknownFormat = errors.New("statement format is not OFX, QFX or QIF")
+
+// Statement is the entries of one account read from a bank's OFX, QFX or QIF
+// download. Account, BankId and Currency are empty when the format does not
+// carry them.
+type Statement struct {
+	Format    string    `json:"format"`
+	Encoding  string    `json:"encoding"`
+	Account   string    `json:"account"`
+	BankId    string    `json:"bank_id"`
+	Currency  string    `json:"currency"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Records   []Record  `json:"records"`
+}
+
+// ReadStatement reads an OFX, QFX or QIF file. Dates in QIF follow order,
+// since the format does not say; OFX dates are unambiguous.
+func ReadStatement(data []byte, order string) (*Statement, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	text, encoding, err := decode(data)
+	if err != nil {
+		return nil, ErrUnknownFormat
+	}
+
+	var statement *Statement
+	trimmed := bytes.TrimSpace([]byte(text))
+	switch {
+	case bytes.Contains(bytes.ToUpper(trimmed), []byte("<OFX")):
+		statement, err = readOFX(text)
+	case bytes.HasPrefix(trimmed, []byte("!")):
+		statement, err = readQIF(text, order)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(statement.Records) > MaxRows {
+		return nil, ErrTooManyRows
+	}
+	statement.Encoding = encoding
+	statement.fillDates()
+	statement.fillFitIds()
+	return statement, nil
+}
+
+// fillDates takes the statement period from its entries when the file does
+// not give one.
+func (s *Statement) fillDates() {
+	for _, record := range s.Records {
+		if !record.Valid() {
+			continue
+		}
+		if s.StartDate.IsZero() || record.Date.Before(s.StartDate) {
+			s.StartDate = record.Date
+		}
+		if record.Date.After(s.EndDate) {
+			s.EndDate = record.Date
+		}
+	}
+}
+
+// fillFitIds gives entries without a FITID, which is every QIF entry, one
+// made from the entry itself. Identical entries are told apart by how many
+// came before them, so the same statement downloaded again gets the same IDs.
+func (s *Statement) fillFitIds() {
+	seen := make(map[string]int)
+	for i, record := range s.Records {
+		if record.FitId != "" || !record.Valid() {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%s|%s|%s",
+			record.Date.Format("2006-01-02"),
+			strconv.FormatFloat(record.Amount, 'f', 2, 64),
+			record.TransactionType, record.Merchant, record.Note)
+		sum := sha256.Sum256([]byte(key + "|" + strconv.Itoa(seen[key])))
+		seen[key]++
+		s.Records[i].FitId = s.Format + ":" + hex.EncodeToString(sum[:12])
+	}
+}
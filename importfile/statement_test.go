@@ -0,0 +1,101 @@
+package importfile
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestReadStatement_Golden reads every statement in testdata and compares it
+// with the matching .golden.json. QIF files are taken to be in month-first
+// order, as Quicken writes them. Run with -update to regenerate the golden
+// files after a deliberate change.
+func TestReadStatement_Golden(t *testing.T) {
+	var inputs []string
+	for _, pattern := range []string{"testdata/*.ofx", "testdata/*.qfx", "testdata/*.qif"} {
+		matches, err := filepath.Glob(pattern)
+		assert.NoError(t, err)
+		inputs = append(inputs, matches...)
+	}
+	assert.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			assert.NoError(t, err)
+
+			order := ""
+			if filepath.Ext(input) == ".qif" {
+				order = DateOrderMDY
+			}
+			statement, err := ReadStatement(data, order)
+			assert.NoError(t, err)
+			got, err := json.MarshalIndent(statement, "", "  ")
+			assert.NoError(t, err)
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, append(got, '\n'), 0o644))
+				return
+			}
+			want, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestReadStatement_QIFFitIdsAreStable(t *testing.T) {
+	data, err := os.ReadFile("testdata/checking.qif")
+	assert.NoError(t, err)
+
+	first, err := ReadStatement(data, DateOrderMDY)
+	assert.NoError(t, err)
+	second, err := ReadStatement(data, DateOrderMDY)
+	assert.NoError(t, err)
+
+	assert.Equal(t, first.Records[0].FitId, second.Records[0].FitId)
+	assert.NotEqual(t, first.Records[0].FitId, first.Records[1].FitId)
+	assert.True(t, strings.HasPrefix(first.Records[0].FitId, "qif:"))
+}
+
+func TestReadStatement_UnknownFormat(t *testing.T) {
+	_, err := ReadStatement([]byte("date,amount\n2024-03-01,10\n"), "")
+
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestReadStatement_Empty(t *testing.T) {
+	_, err := ReadStatement([]byte("  \n"), "")
+
+	assert.ErrorIs(t, err, ErrEmptyFile)
+}
+
+func TestParseOFXDate(t *testing.T) {
+	cases := []struct {
+		text string
+		want time.Time
+	}{
+		{"20240312", time.Date(2024, time.March, 12, 0, 0, 0, 0, bangkok)},
+		{"20240312101500.000", time.Date(2024, time.March, 12, 10, 15, 0, 0, bangkok)},
+		{"20240312101500[+7:ICT]", time.Date(2024, time.March, 12, 10, 15, 0, 0, bangkok)},
+		{"20240312000000[-5:EST]", time.Date(2024, time.March, 12, 12, 0, 0, 0, bangkok)},
+	}
+	for _, c := range cases {
+		got, ok := parseOFXDate(c.text)
+
+		assert.True(t, ok, c.text)
+		assert.True(t, c.want.Equal(got), c.text)
+	}
+
+	_, ok := parseOFXDate("20240231")
+	assert.False(t, ok)
+}
@@ -0,0 +1,94 @@
+package mailparse
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestExtract_Golden parses every testdata/*.eml and compares the notice read
+// from it with the matching .golden.json. Run with -update to regenerate the
+// golden files after a deliberate change.
+func TestExtract_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.eml")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inputs)
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".eml")
+		t.Run(name, func(t *testing.T) {
+			raw, err := os.ReadFile(input)
+			assert.NoError(t, err)
+
+			msg, err := Parse(raw)
+			assert.NoError(t, err)
+			notice, err := Extract(msg)
+			assert.NoError(t, err)
+			got, err := json.MarshalIndent(notice, "", "  ")
+			assert.NoError(t, err)
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, append(got, '\n'), 0o644))
+				return
+			}
+			want, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestParse_Headers(t *testing.T) {
+	raw, err := os.ReadFile("testdata/kbank_transfer.eml")
+	assert.NoError(t, err)
+
+	msg, err := Parse(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "kbank-0001@kasikornbank.com", msg.MessageId)
+	assert.Equal(t, "kplus@kasikornbank.com", msg.From)
+	assert.Equal(t, "ผลการโอนเงิน", msg.Subject)
+	assert.Equal(t, []string{"a1b2c3d4@in.jodjod.app", "somchai@example.com"}, msg.Recipients)
+}
+
+func TestParse_TIS620(t *testing.T) {
+	raw, err := os.ReadFile("testdata/cafe_tis620.eml")
+	assert.NoError(t, err)
+
+	msg, err := Parse(raw)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "บ้านสวน", msg.FromName)
+	assert.Contains(t, msg.Text, "ร้านกาแฟบ้านสวน")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := Parse([]byte("not an email"))
+
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestExtract_AmountNotFound(t *testing.T) {
+	msg := &Message{From: "kplus@kasikornbank.com", Text: "ขอบคุณที่ใช้บริการ"}
+
+	notice, err := Extract(msg)
+
+	assert.ErrorIs(t, err, ErrAmountNotFound)
+	assert.Equal(t, "kbank", notice.Template)
+}
+
+func TestHTMLLines(t *testing.T) {
+	body := `<html><head><style>p { color: red; }</style></head><body>
+<p>Receipt</p><table><tr><td>Total</td><td>&#3647;120.00</td></tr></table></body></html>`
+
+	lines := htmlLines(body)
+
+	assert.Equal(t, []string{"Receipt", "Total ฿120.00"}, lines)
+}
@@ -0,0 +1,194 @@
+package mailparse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// maxDepth bounds how deeply multiparts and attached messages are followed.
+const maxDepth = 5
+
+var ErrInvalidMessage = errors.New("email message is invalid")
+
+// Message is the part of an RFC 5322 message receipts are read from. Text
+// and HTML hold the first body of each type; Forwarded is the first message
+// attached to it, as mail clients do when forwarding as an attachment.
+type Message struct {
+	MessageId  string
+	From       string
+	FromName   string
+	Recipients []string
+	Subject    string
+	Date       time.Time
+	Text       string
+	HTML       string
+	Forwarded  *Message
+}
+
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+// Parse reads a raw message. Headers may be RFC 2047 encoded and bodies may
+// be in any transfer encoding, in UTF-8 or in the Thai TIS-620 charset.
+func Parse(raw []byte) (*Message, error) {
+	return parse(raw, 0)
+}
+
+func parse(raw []byte, depth int) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrInvalidMessage
+	}
+
+	msg := &Message{
+		MessageId: strings.Trim(m.Header.Get("Message-Id"), "<> "),
+	}
+	if subject, err := wordDecoder.DecodeHeader(m.Header.Get("Subject")); err == nil {
+		msg.Subject = subject
+	} else {
+		msg.Subject = m.Header.Get("Subject")
+	}
+	if date, err := m.Header.Date(); err == nil {
+		msg.Date = date
+	}
+
+	parser := &mail.AddressParser{WordDecoder: wordDecoder}
+	if from, err := parser.Parse(m.Header.Get("From")); err == nil {
+		msg.From = strings.ToLower(from.Address)
+		msg.FromName = from.Name
+	}
+	// The envelope recipient is often only in the headers the receiving
+	// server adds, not in To.
+	for _, name := range []string{"Delivered-To", "X-Original-To", "To", "Cc"} {
+		for _, value := range m.Header[name] {
+			addresses, err := parser.ParseList(value)
+			if err != nil {
+				continue
+			}
+			for _, address := range addresses {
+				msg.Recipients = append(msg.Recipients, strings.ToLower(address.Address))
+			}
+		}
+	}
+
+	err = msg.readPart(m.Header.Get("Content-Type"), m.Header.Get("Content-Transfer-Encoding"), "", m.Body, depth)
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (msg *Message) readPart(contentType, encoding, disposition string, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return ErrInvalidMessage
+			}
+			err = msg.readPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(encoding, body))
+	if err != nil {
+		return ErrInvalidMessage
+	}
+
+	switch {
+	case mediaType == "message/rfc822":
+		if msg.Forwarded == nil {
+			if forwarded, err := parse(data, depth+1); err == nil {
+				msg.Forwarded = forwarded
+			}
+		}
+	case strings.HasPrefix(disposition, "attachment"):
+	case mediaType == "text/plain" && msg.Text == "":
+		msg.Text = decodeCharset(params["charset"], data)
+	case mediaType == "text/html" && msg.HTML == "":
+		msg.HTML = decodeCharset(params["charset"], data)
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &newlineSkipper{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// newlineSkipper drops the line breaks base64 bodies are wrapped with, which
+// the standard decoder does not accept.
+type newlineSkipper struct {
+	r io.Reader
+}
+
+func (s *newlineSkipper) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	kept := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[kept] = b
+			kept++
+		}
+	}
+	if kept == 0 && n > 0 && err == nil {
+		return s.Read(p)
+	}
+	return kept, err
+}
+
+// decodeCharset converts a body to UTF-8. A charset that is not known is
+// read as UTF-8, which keeps at least the ASCII in it.
+func decodeCharset(charset string, data []byte) string {
+	reader, err := charsetReader(charset, bytes.NewReader(data))
+	if err != nil {
+		return string(data)
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii":
+		return input, nil
+	case "tis-620", "tis620", "windows-874", "x-windows-874", "cp874", "iso-8859-11":
+		return charmap.Windows874.NewDecoder().Reader(input), nil
+	case "iso-8859-1", "latin1":
+		return charmap.ISO8859_1.NewDecoder().Reader(input), nil
+	default:
+		return nil, fmt.Errorf("unsupported charset: %s", charset)
+	}
+}
@@ -0,0 +1,299 @@
+package mailparse
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/slip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	KindTransfer = "transfer"
+	KindReceipt  = "receipt"
+
+	TypeExpense = "expense"
+	TypeIncome  = "income"
+)
+
+var ErrAmountNotFound = errors.New("email amount not found")
+
+var (
+	decimalMoneyRe  = regexp.MustCompile(`\d{1,3}(?:,\d{3})+(?:\.\d{1,2})?|\d+\.\d{1,2}`)
+	currencyMoneyRe = regexp.MustCompile(`(?i)(?:฿|thb)\s*(\d+)|(\d+)\s*(?:บาท|thb|baht)`)
+	incomeKeywords  = []string{"เงินเข้า", "รับโอน", "ได้รับเงิน", "money received", "incoming transfer", "deposit"}
+)
+
+// Notice is a payment read from a bank notification or an e-receipt.
+type Notice struct {
+	Template        string    `json:"template"`
+	Kind            string    `json:"kind"`
+	TransactionType string    `json:"transaction_type"`
+	Merchant        string    `json:"merchant"`
+	Amount          float64   `json:"amount"`
+	Date            time.Time `json:"date"`
+	Reference       string    `json:"reference"`
+	BankCode        string    `json:"bank_code"`
+	Category        string    `json:"category"`
+}
+
+// template is how one sender writes its emails. Labels are tried in order,
+// so the most specific comes first.
+type template struct {
+	name      string
+	domains   []string
+	kind      string
+	bankCode  string
+	merchant  string
+	category  string
+	amount    []string
+	payee     []string
+	date      []string
+	reference []string
+}
+
+var (
+	transferAmountLabels    = []string{"จำนวนเงิน (บาท)", "จำนวนเงิน", "amount (thb)", "amount"}
+	transferPayeeLabels     = []string{"ชื่อบัญชีผู้รับ", "ชื่อผู้รับ", "โอนไปยัง", "ชำระให้", "จาก", "to account name", "paid to", "to"}
+	transferDateLabels      = []string{"วันที่ทำรายการ", "วันที่และเวลา", "transaction date", "date/time", "วันที่", "date"}
+	transferReferenceLabels = []string{"เลขที่อ้างอิง", "เลขที่รายการ", "รหัสอ้างอิง", "reference no", "ref no", "reference"}
+	receiptAmountLabels     = []string{"ยอดชำระทั้งหมด", "ยอดรวมทั้งหมด", "ยอดชำระ", "ยอดรวม", "total paid", "amount paid", "grand total", "total", "รวม"}
+	receiptPayeeLabels      = []string{"ร้านค้า", "ร้าน", "merchant", "restaurant", "store"}
+	receiptDateLabels       = []string{"วันที่สั่งซื้อ", "order date", "วันที่", "date"}
+	receiptReferenceLabels  = []string{"หมายเลขคำสั่งซื้อ", "เลขที่คำสั่งซื้อ", "order id", "order no", "booking id", "เลขที่ใบเสร็จ", "receipt no"}
+)
+
+// Bank codes are the Bank of Thailand's, as slips record them.
+var templates = []template{
+	bankTemplate("kbank", "004", "kasikornbank.com", "kbank.com"),
+	bankTemplate("scb", "014", "scb.co.th"),
+	bankTemplate("krungthai", "006", "krungthai.com"),
+	bankTemplate("bangkokbank", "002", "bangkokbank.com"),
+	bankTemplate("krungsri", "025", "krungsri.com"),
+	receiptTemplate("grab", "Grab", "transport", "grab.com"),
+	receiptTemplate("lineman", "LINE MAN", "food", "lineman.line.me", "linecorp.com"),
+	receiptTemplate("lazada", "Lazada", "shopping", "lazada.co.th"),
+	receiptTemplate("shopee", "Shopee", "shopping", "shopee.co.th"),
+}
+
+var genericTemplate = template{
+	name:      "generic",
+	kind:      KindReceipt,
+	category:  "shopping",
+	amount:    append(append([]string(nil), receiptAmountLabels...), transferAmountLabels...),
+	payee:     append(append([]string(nil), receiptPayeeLabels...), transferPayeeLabels...),
+	date:      append(append([]string(nil), receiptDateLabels...), transferDateLabels...),
+	reference: append(append([]string(nil), receiptReferenceLabels...), transferReferenceLabels...),
+}
+
+func bankTemplate(name, bankCode string, domains ...string) template {
+	return template{
+		name:      name,
+		domains:   domains,
+		kind:      KindTransfer,
+		bankCode:  bankCode,
+		category:  KindTransfer,
+		amount:    transferAmountLabels,
+		payee:     transferPayeeLabels,
+		date:      transferDateLabels,
+		reference: transferReferenceLabels,
+	}
+}
+
+func receiptTemplate(name, merchant, category string, domains ...string) template {
+	return template{
+		name:      name,
+		domains:   domains,
+		kind:      KindReceipt,
+		merchant:  merchant,
+		category:  category,
+		amount:    receiptAmountLabels,
+		payee:     receiptPayeeLabels,
+		date:      receiptDateLabels,
+		reference: receiptReferenceLabels,
+	}
+}
+
+// Extract reads the payment in a message. A message forwarded as an
+// attachment is read instead of the forward itself. The template is picked by
+// the sender's domain or, for an inline forward, by the first known sender
+// quoted in the body.
+func Extract(msg *Message) (*Notice, error) {
+	if msg.Forwarded != nil {
+		return Extract(msg.Forwarded)
+	}
+
+	lines := msg.Lines()
+	tmpl := detect(msg.From, lines)
+	notice := &Notice{
+		Template:        tmpl.name,
+		Kind:            tmpl.kind,
+		TransactionType: TypeExpense,
+		Merchant:        tmpl.merchant,
+		BankCode:        tmpl.bankCode,
+		Category:        tmpl.category,
+		Date:            msg.Date,
+	}
+	if notice.Merchant == "" && tmpl.kind == KindReceipt {
+		notice.Merchant = msg.FromName
+	}
+
+	text := strings.ToLower(msg.Subject + "\n" + strings.Join(lines, "\n"))
+	for _, keyword := range incomeKeywords {
+		if strings.Contains(text, keyword) {
+			notice.TransactionType = TypeIncome
+			break
+		}
+	}
+
+	if value, ok := findLabel(lines, tmpl.payee); ok {
+		notice.Merchant = value
+	}
+	if value, ok := findLabel(lines, tmpl.reference); ok {
+		notice.Reference = strings.ReplaceAll(value, " ", "")
+	}
+	if value, ok := findLabel(lines, tmpl.date); ok {
+		if date, ok := slip.ParseDate(value); ok {
+			notice.Date = date
+		}
+	}
+
+	amount, ok := findAmount(lines, tmpl.amount)
+	if !ok {
+		return notice, ErrAmountNotFound
+	}
+	notice.Amount = amount
+	return notice, nil
+}
+
+func detect(from string, lines []string) template {
+	if tmpl, ok := templateFor(from); ok {
+		return tmpl
+	}
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		if !strings.Contains(lower, "@") {
+			continue
+		}
+		for _, field := range strings.FieldsFunc(lower, func(r rune) bool {
+			return r == ' ' || r == '<' || r == '>' || r == '"' || r == ':'
+		}) {
+			if tmpl, ok := templateFor(field); ok {
+				return tmpl
+			}
+		}
+	}
+	return genericTemplate
+}
+
+func templateFor(address string) (template, bool) {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return template{}, false
+	}
+	domain := address[at+1:]
+	for _, tmpl := range templates {
+		for _, d := range tmpl.domains {
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				return tmpl, true
+			}
+		}
+	}
+	return template{}, false
+}
+
+// findLabel returns the value after the first label found at the start of a
+// line, or the next line when the label stands alone.
+func findLabel(lines []string, labels []string) (string, bool) {
+	sorted := append([]string(nil), labels...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	for _, label := range labels {
+		for i, line := range lines {
+			rest, ok := cutLabel(line, label, sorted)
+			if !ok {
+				continue
+			}
+			if rest != "" {
+				return rest, true
+			}
+			if i+1 < len(lines) {
+				return lines[i+1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// findAmount takes the first labelled line with a money value on it; a label
+// such as "Total items" without one is passed over.
+func findAmount(lines []string, labels []string) (float64, bool) {
+	sorted := append([]string(nil), labels...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	for _, label := range labels {
+		for i, line := range lines {
+			rest, ok := cutLabel(line, label, sorted)
+			if !ok {
+				continue
+			}
+			if amount, ok := parseMoney(rest); ok {
+				return amount, true
+			}
+			if rest == "" && i+1 < len(lines) {
+				if amount, ok := parseMoney(lines[i+1]); ok {
+					return amount, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// cutLabel matches line against label as a whole word, unless a longer label
+// also matches, so "จำนวนเงิน" does not take the line of "จำนวนเงิน (บาท)".
+func cutLabel(line, label string, sorted []string) (string, bool) {
+	lower := strings.ToLower(line)
+	for _, candidate := range sorted {
+		if !strings.HasPrefix(lower, candidate) {
+			continue
+		}
+		if next, _ := utf8.DecodeRuneInString(lower[len(candidate):]); isWordRune(next) {
+			continue
+		}
+		if candidate != label {
+			return "", false
+		}
+		return strings.Trim(line[len(candidate):], " :：-"), true
+	}
+	return "", false
+}
+
+func parseMoney(text string) (float64, bool) {
+	match := decimalMoneyRe.FindString(text)
+	if match == "" {
+		groups := currencyMoneyRe.FindStringSubmatch(text)
+		if groups == nil {
+			return 0, false
+		}
+		match = groups[1] + groups[2]
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", ""), 64)
+	if err != nil || amount <= 0 {
+		return 0, false
+	}
+	return amount, true
+}
+
+// isWordRune reports whether r continues a word, so that "ร้าน" does not
+// match "ร้านกาแฟ" and "to" does not match "total". Thai vowels and tone
+// marks are combining marks.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
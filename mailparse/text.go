@@ -0,0 +1,77 @@
+package mailparse
+
+import (
+	"golang.org/x/net/html"
+	"regexp"
+	"strings"
+)
+
+var spaceRe = regexp.MustCompile(`[\s\x{00a0}]+`)
+
+// blockTags end a line of text. Table cells are joined with a space instead,
+// so a label and its value in neighbouring cells read as one line.
+var blockTags = map[string]bool{
+	"br": true, "p": true, "div": true, "tr": true, "li": true, "table": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "section": true, "header": true, "footer": true, "blockquote": true,
+}
+
+var skippedTags = map[string]bool{"head": true, "style": true, "script": true, "title": true}
+
+// Lines returns the message body as trimmed, non-empty lines, preferring the
+// HTML body, which is where e-receipts put their tables. Quote markers of
+// inline forwards are dropped.
+func (msg *Message) Lines() []string {
+	if msg.HTML != "" {
+		return htmlLines(msg.HTML)
+	}
+	return textLines(msg.Text)
+}
+
+func htmlLines(body string) []string {
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	skipping := ""
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return textLines(b.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case skipping != "":
+			case skippedTags[tag]:
+				skipping = tag
+			case blockTags[tag]:
+				b.WriteString("\n")
+			case tag == "td" || tag == "th":
+				b.WriteString(" ")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == skipping {
+				skipping = ""
+			} else if skipping == "" && blockTags[tag] {
+				b.WriteString("\n")
+			}
+		case html.TextToken:
+			if skipping == "" {
+				b.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+func textLines(body string) []string {
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimLeft(strings.TrimSpace(line), "> ")
+		line = strings.TrimSpace(spaceRe.ReplaceAllString(line, " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
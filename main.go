@@ -1,21 +1,10 @@
 package main
 
 import (
-	"github.com/Montheankul-K/jod-jod/config"
-	"github.com/Montheankul-K/jod-jod/db"
-	"github.com/Montheankul-K/jod-jod/db/migration"
-	"github.com/Montheankul-K/jod-jod/server"
+	"github.com/Montheankul-K/jod-jod/cli"
+	"os"
 )
 
 func main() {
-	cfg := config.GetConfig()
-	database := db.InitDatabase(cfg.Database)
-	if err := migration.Migrate(database); err != nil {
-		panic(err)
-	}
-
-	srv := server.InitServer(cfg, database)
-	if err := srv.Start(); err != nil {
-		panic(err)
-	}
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
 }
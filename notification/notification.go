@@ -0,0 +1,173 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/labstack/echo/v4"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+const (
+	ChannelLog     = "log"
+	ChannelWebhook = "webhook"
+	ChannelEmail   = "email"
+)
+
+type Message struct {
+	SpenderId   uint         `json:"spender_id"`
+	To          string       `json:"to"`
+	Subject     string       `json:"subject"`
+	Body        string       `json:"body"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// Attachment is a file sent with a message, such as a chart. Webhooks
+// receive Data base64 encoded.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+type INotifier interface {
+	Channel() string
+	Send(msg Message) error
+}
+
+// NewNotifier picks the channel from config, falling back to logging when the
+// notification section is missing.
+func NewNotifier(cfg *config.Notification, logger echo.Logger) INotifier {
+	if cfg == nil {
+		return &logNotifier{logger: logger}
+	}
+
+	switch cfg.Channel {
+	case ChannelWebhook:
+		return &webhookNotifier{
+			url:    cfg.WebhookURL,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}
+	case ChannelEmail:
+		return &emailNotifier{cfg: cfg}
+	default:
+		return &logNotifier{logger: logger}
+	}
+}
+
+type logNotifier struct {
+	logger echo.Logger
+}
+
+func (n *logNotifier) Channel() string {
+	return ChannelLog
+}
+
+func (n *logNotifier) Send(msg Message) error {
+	n.logger.Infof("notification to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	for _, attachment := range msg.Attachments {
+		n.logger.Infof("attached %s (%s, %d bytes)", attachment.Filename, attachment.ContentType, len(attachment.Data))
+	}
+	return nil
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *webhookNotifier) Channel() string {
+	return ChannelWebhook
+}
+
+func (n *webhookNotifier) Send(msg Message) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type emailNotifier struct {
+	cfg *config.Notification
+}
+
+func (n *emailNotifier) Channel() string {
+	return ChannelEmail
+}
+
+func (n *emailNotifier) Send(msg Message) error {
+	if msg.To == "" {
+		return errors.New("recipient email is empty")
+	}
+
+	var auth smtp.Auth
+	if n.cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", n.cfg.SMTPUsername, n.cfg.SMTPPassword, n.cfg.SMTPHost)
+	}
+
+	addr := fmt.Sprintf("%s:%d", n.cfg.SMTPHost, n.cfg.SMTPPort)
+	return smtp.SendMail(addr, auth, n.cfg.From, []string{msg.To}, buildMail(n.cfg.From, msg))
+}
+
+// buildMail writes msg as plain text, or as a multipart message when it has
+// attachments.
+func buildMail(from string, msg Message) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	// Subjects may be Thai, so they are sent as RFC 2047 encoded words.
+	b.WriteString("Subject: =?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte(msg.Subject)) + "?=\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	body := strings.ReplaceAll(msg.Body, "\n", "\r\n")
+	if len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+		b.WriteString("\r\n")
+		b.WriteString(body)
+		return b.Bytes()
+	}
+
+	mw := multipart.NewWriter(&b)
+	b.WriteString("Content-Type: multipart/mixed; boundary=" + mw.Boundary() + "\r\n")
+	b.WriteString("\r\n")
+	part, _ := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	part.Write([]byte(body))
+	for _, attachment := range msg.Attachments {
+		part, _ = mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.Filename)},
+		})
+		encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+		// Lines in a message are limited to 76 characters.
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	mw.Close()
+	return b.Bytes()
+}
@@ -0,0 +1,41 @@
+package ocr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type fixtureProvider struct {
+	dir string
+}
+
+// NewFixtureProvider replays recorded OCR output so slip ingestion can run
+// offline. Each fixture is <dir>/<sha256 of the image>.json holding a JSON
+// array of lines.
+func NewFixtureProvider(dir string) OCRProvider {
+	return &fixtureProvider{dir: dir}
+}
+
+func (p *fixtureProvider) DetectText(image []byte) ([]Line, error) {
+	path := filepath.Join(p.dir, FixtureName(image))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no ocr fixture for image: %w", err)
+	}
+
+	var lines []Line
+	if err = json.Unmarshal(data, &lines); err != nil {
+		return nil, fmt.Errorf("invalid ocr fixture %s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// FixtureName is the file name the fixture provider looks up for an image.
+func FixtureName(image []byte) string {
+	sum := sha256.Sum256(image)
+	return hex.EncodeToString(sum[:]) + ".json"
+}
@@ -0,0 +1,31 @@
+package ocr
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixtureProvider_DetectText(t *testing.T) {
+	dir := t.TempDir()
+	image := []byte("slip image")
+	want := []Line{{Text: "1,250.00 บาท", Confidence: 99.1, Box: BoundingBox{Left: 0.1, Top: 0.5, Width: 0.3, Height: 0.02}}}
+	data, _ := json.Marshal(want)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, FixtureName(image)), data, 0o644))
+	provider := NewFixtureProvider(dir)
+
+	lines, err := provider.DetectText(image)
+
+	assert.NoError(t, err)
+	assert.Equal(t, want, lines)
+}
+
+func TestFixtureProvider_DetectText_Missing(t *testing.T) {
+	provider := NewFixtureProvider(t.TempDir())
+
+	_, err := provider.DetectText([]byte("unknown image"))
+
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
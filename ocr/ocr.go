@@ -0,0 +1,52 @@
+package ocr
+
+import (
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+)
+
+const (
+	ProviderTextract  = "textract"
+	ProviderTesseract = "tesseract"
+	ProviderFixture   = "fixture"
+)
+
+// BoundingBox is relative to the page, so every value is between 0 and 1
+// whatever the image resolution.
+type BoundingBox struct {
+	Left   float64 `json:"left"`
+	Top    float64 `json:"top"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Line is one line of detected text. Confidence is a percentage.
+type Line struct {
+	Text       string      `json:"text"`
+	Confidence float64     `json:"confidence"`
+	Box        BoundingBox `json:"box"`
+}
+
+type OCRProvider interface {
+	DetectText(image []byte) ([]Line, error)
+}
+
+// NewOCRProvider builds the provider named in the OCR config, defaulting to
+// Textract with the AWS credentials.
+func NewOCRProvider(cfg *config.OCR, awsCfg *config.AWS) (OCRProvider, error) {
+	provider := ProviderTextract
+	if cfg != nil && cfg.Provider != "" {
+		provider = cfg.Provider
+	}
+
+	switch provider {
+	case ProviderTextract:
+		return NewTextractProvider(awsCfg)
+	case ProviderTesseract:
+		return NewTesseractProvider(cfg.TesseractPath, cfg.Languages), nil
+	case ProviderFixture:
+		return NewFixtureProvider(cfg.FixtureDir), nil
+	default:
+		return nil, fmt.Errorf("unknown ocr provider: %s", provider)
+	}
+}
@@ -0,0 +1,164 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTesseractPath      = "tesseract"
+	defaultTesseractLanguages = "tha+eng"
+	tesseractTimeout          = 30 * time.Second
+)
+
+type tesseractProvider struct {
+	path      string
+	languages string
+}
+
+// NewTesseractProvider runs a local tesseract binary, which needs the tha and
+// eng traineddata installed.
+func NewTesseractProvider(path, languages string) OCRProvider {
+	if path == "" {
+		path = defaultTesseractPath
+	}
+	if languages == "" {
+		languages = defaultTesseractLanguages
+	}
+	return &tesseractProvider{
+		path:      path,
+		languages: languages,
+	}
+}
+
+func (p *tesseractProvider) DetectText(image []byte) ([]Line, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tesseractTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.path, "stdin", "stdout", "-l", p.languages, "tsv")
+	cmd.Stdin = bytes.NewReader(image)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseTesseractTSV(stdout.Bytes())
+}
+
+type tsvLine struct {
+	words                    []string
+	confidence               float64
+	left, top, right, bottom float64
+}
+
+// parseTesseractTSV folds tesseract's word rows into lines. Tesseract reports
+// pixels, so boxes are scaled by the page size from the level 1 row.
+func parseTesseractTSV(data []byte) ([]Line, error) {
+	var pageWidth, pageHeight float64
+	var order []string
+	lines := make(map[string]*tsvLine)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 || fields[0] == "level" {
+			continue
+		}
+
+		values := make([]float64, 11)
+		for i := range values {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tesseract tsv row: %q", scanner.Text())
+			}
+			values[i] = value
+		}
+
+		level := int(values[0])
+		left, top, width, height, conf := values[6], values[7], values[8], values[9], values[10]
+		if level == 1 {
+			pageWidth, pageHeight = width, height
+			continue
+		}
+
+		text := strings.TrimSpace(strings.Join(fields[11:], "\t"))
+		if level != 5 || text == "" || conf < 0 {
+			continue
+		}
+
+		key := strings.Join(fields[1:5], "-")
+		line, ok := lines[key]
+		if !ok {
+			line = &tsvLine{left: left, top: top, right: left + width, bottom: top + height}
+			lines[key] = line
+			order = append(order, key)
+		}
+		line.words = append(line.words, text)
+		line.confidence += conf
+		line.left = min(line.left, left)
+		line.top = min(line.top, top)
+		line.right = max(line.right, left+width)
+		line.bottom = max(line.bottom, top+height)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if pageWidth == 0 || pageHeight == 0 {
+		pageWidth, pageHeight = 1, 1
+	}
+
+	var result []Line
+	for _, key := range order {
+		line := lines[key]
+		result = append(result, Line{
+			Text:       joinWords(line.words),
+			Confidence: line.confidence / float64(len(line.words)),
+			Box: BoundingBox{
+				Left:   line.left / pageWidth,
+				Top:    line.top / pageHeight,
+				Width:  (line.right - line.left) / pageWidth,
+				Height: (line.bottom - line.top) / pageHeight,
+			},
+		})
+	}
+	return result, nil
+}
+
+// joinWords puts spaces between words, except between two Thai words, since
+// Thai is written without spaces and tesseract splits it at word boundaries.
+func joinWords(words []string) string {
+	var b strings.Builder
+	for i, word := range words {
+		if i > 0 && !(isThai(lastRune(words[i-1])) && isThai(firstRune(word))) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
+
+func isThai(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+	return 0
+}
+
+func lastRune(s string) rune {
+	runes := []rune(s)
+	if len(runes) == 0 {
+		return 0
+	}
+	return runes[len(runes)-1]
+}
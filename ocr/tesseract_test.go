@@ -0,0 +1,42 @@
+package ocr
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func tsvRows(rows ...string) []byte {
+	header := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext"
+	return []byte(header + "\n" + strings.Join(rows, "\n") + "\n")
+}
+
+func TestParseTesseractTSV(t *testing.T) {
+	data := tsvRows(
+		"1\t1\t0\t0\t0\t0\t0\t0\t1000\t2000\t-1\t",
+		"4\t1\t1\t1\t1\t0\t100\t200\t600\t50\t-1\t",
+		"5\t1\t1\t1\t1\t1\t100\t200\t200\t50\t90\tโอนเงิน",
+		"5\t1\t1\t1\t1\t2\t310\t205\t150\t40\t80\tสำเร็จ",
+		"5\t1\t1\t1\t1\t3\t470\t200\t230\t50\t70\tSuccess",
+		"4\t1\t1\t1\t2\t0\t100\t400\t300\t50\t-1\t",
+		"5\t1\t1\t1\t2\t1\t100\t400\t200\t50\t95\t1,250.00",
+		"5\t1\t1\t1\t2\t2\t310\t400\t90\t50\t85\tบาท",
+		"5\t1\t1\t1\t2\t3\t410\t400\t10\t50\t-1\t ",
+	)
+
+	lines, err := parseTesseractTSV(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "โอนเงินสำเร็จ Success", lines[0].Text)
+	assert.Equal(t, 80.0, lines[0].Confidence)
+	assert.Equal(t, BoundingBox{Left: 0.1, Top: 0.1, Width: 0.6, Height: 0.025}, lines[0].Box)
+	assert.Equal(t, "1,250.00 บาท", lines[1].Text)
+	assert.Equal(t, 90.0, lines[1].Confidence)
+}
+
+func TestParseTesseractTSV_InvalidRow(t *testing.T) {
+	_, err := parseTesseractTSV(tsvRows("5\t1\t1\t1\tx\t1\t0\t0\t0\t0\t90\ttext"))
+
+	assert.Error(t, err)
+}
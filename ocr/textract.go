@@ -0,0 +1,70 @@
+package ocr
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/textract"
+)
+
+type textractProvider struct {
+	client *textract.Textract
+}
+
+// NewTextractProvider opens one AWS session up front and reuses it for every
+// slip.
+func NewTextractProvider(awsCfg *config.AWS) (OCRProvider, error) {
+	if awsCfg == nil {
+		return nil, errors.New("aws config is required for textract")
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(awsCfg.Region),
+		Credentials: credentials.NewStaticCredentials(awsCfg.AccessKeyID, awsCfg.SecretAccessKey, ""),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &textractProvider{client: textract.New(sess)}, nil
+}
+
+func (p *textractProvider) DetectText(image []byte) ([]Line, error) {
+	input := &textract.DetectDocumentTextInput{
+		Document: &textract.Document{
+			Bytes: image,
+		},
+	}
+
+	result, err := p.client.DetectDocumentText(input)
+	if err != nil {
+		return nil, err
+	}
+	return textractLines(result.Blocks), nil
+}
+
+func textractLines(blocks []*textract.Block) []Line {
+	var lines []Line
+	for _, block := range blocks {
+		if aws.StringValue(block.BlockType) != textract.BlockTypeLine {
+			continue
+		}
+
+		line := Line{
+			Text:       aws.StringValue(block.Text),
+			Confidence: aws.Float64Value(block.Confidence),
+		}
+		if block.Geometry != nil && block.Geometry.BoundingBox != nil {
+			box := block.Geometry.BoundingBox
+			line.Box = BoundingBox{
+				Left:   aws.Float64Value(box.Left),
+				Top:    aws.Float64Value(box.Top),
+				Width:  aws.Float64Value(box.Width),
+				Height: aws.Float64Value(box.Height),
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,216 @@
+package pdfdoc
+
+import (
+	"errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidFont = errors.New("font is not a TrueType font")
+
+// Font is a TrueType font to embed. Text is written by glyph ID, so any
+// script the font covers can be drawn, Thai included. Thai fonts give their
+// vowel and tone marks no advance and draw them over the consonant before,
+// which places them well enough without a shaping engine. A Font records the
+// glyphs drawn with it, so it belongs to one document.
+type Font struct {
+	name       string
+	data       []byte
+	font       *sfnt.Font
+	buffer     sfnt.Buffer
+	unitsPerEm float64
+	ascent     float64
+	descent    float64
+	capHeight  float64
+	bbox       [4]float64
+	// used holds the glyphs drawn, with the character each stands for, so
+	// only their widths and mappings are written.
+	used   map[sfnt.GlyphIndex]rune
+	widths map[sfnt.GlyphIndex]float64
+	// resource is the font's name in page resources, set when added to a
+	// document.
+	resource string
+}
+
+// ParseFont reads a TrueType font. OpenType fonts with CFF outlines cannot be
+// embedded as TrueType and are refused.
+func ParseFont(data []byte) (*Font, error) {
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, ErrInvalidFont
+	}
+	if len(data) < 4 || string(data[:4]) == "OTTO" {
+		return nil, ErrInvalidFont
+	}
+
+	f := &Font{
+		data:       data,
+		font:       parsed,
+		unitsPerEm: float64(parsed.UnitsPerEm()),
+		used:       make(map[sfnt.GlyphIndex]rune),
+		widths:     make(map[sfnt.GlyphIndex]float64),
+	}
+	ppem := fixed.Int26_6(parsed.UnitsPerEm()) << 6
+	name, err := parsed.Name(&f.buffer, sfnt.NameIDPostScript)
+	if err != nil || name == "" {
+		name = "Embedded"
+	}
+	f.name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	metrics, err := parsed.Metrics(&f.buffer, ppem, font.HintingNone)
+	if err != nil {
+		return nil, ErrInvalidFont
+	}
+	f.ascent = f.scale(metrics.Ascent)
+	f.descent = -f.scale(metrics.Descent)
+	f.capHeight = f.scale(metrics.CapHeight)
+	if f.capHeight == 0 {
+		f.capHeight = f.ascent
+	}
+	bounds, err := parsed.Bounds(&f.buffer, ppem, font.HintingNone)
+	if err != nil {
+		return nil, ErrInvalidFont
+	}
+	// sfnt measures y downwards; PDF measures it upwards.
+	f.bbox = [4]float64{f.scale(bounds.Min.X), -f.scale(bounds.Max.Y), f.scale(bounds.Max.X), -f.scale(bounds.Min.Y)}
+	return f, nil
+}
+
+// scale turns a length in font units into thousandths of an em, which is
+// how PDF measures glyphs.
+func (f *Font) scale(value fixed.Int26_6) float64 {
+	return float64(value) / 64 * 1000 / f.unitsPerEm
+}
+
+// Has reports whether the font draws r.
+func (f *Font) Has(r rune) bool {
+	glyph, err := f.font.GlyphIndex(&f.buffer, r)
+	return err == nil && glyph != 0
+}
+
+// glyph returns r's glyph and its advance in thousandths of an em; a rune
+// the font lacks is drawn as the missing-glyph box.
+func (f *Font) glyph(r rune) (sfnt.GlyphIndex, float64) {
+	glyph, err := f.font.GlyphIndex(&f.buffer, r)
+	if err != nil {
+		glyph = 0
+	}
+	width, ok := f.widths[glyph]
+	if !ok {
+		advance, err := f.font.GlyphAdvance(&f.buffer, glyph, fixed.Int26_6(f.unitsPerEm)<<6, font.HintingNone)
+		if err == nil {
+			width = f.scale(advance)
+		}
+		f.widths[glyph] = width
+	}
+	return glyph, width
+}
+
+func (f *Font) use(glyph sfnt.GlyphIndex, r rune) {
+	if _, ok := f.used[glyph]; !ok {
+		f.used[glyph] = r
+	}
+}
+
+// usedGlyphs returns the glyphs drawn in order.
+func (f *Font) usedGlyphs() []sfnt.GlyphIndex {
+	glyphs := make([]sfnt.GlyphIndex, 0, len(f.used))
+	for glyph := range f.used {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// Face draws text in the first of its fonts that has each character, so a
+// Latin font can be paired with a Thai one.
+type Face struct {
+	Fonts []*Font
+	Size  float64
+}
+
+// run is a stretch of text drawn in one font.
+type run struct {
+	font   *Font
+	glyphs []sfnt.GlyphIndex
+	runes  []rune
+	width  float64
+}
+
+func (face Face) runs(text string) []run {
+	var runs []run
+	for _, r := range text {
+		f := face.Fonts[0]
+		for _, candidate := range face.Fonts {
+			if candidate.Has(r) {
+				f = candidate
+				break
+			}
+		}
+		glyph, width := f.glyph(r)
+		if len(runs) == 0 || runs[len(runs)-1].font != f {
+			runs = append(runs, run{font: f})
+		}
+		last := &runs[len(runs)-1]
+		last.glyphs = append(last.glyphs, glyph)
+		last.runes = append(last.runes, r)
+		last.width += width * face.Size / 1000
+	}
+	return runs
+}
+
+// Width is how wide text is drawn, in points.
+func (face Face) Width(text string) float64 {
+	var width float64
+	for _, run := range face.runs(text) {
+		width += run.width
+	}
+	return width
+}
+
+// Fit shortens text with an ellipsis until it is at most width points wide.
+func (face Face) Fit(text string, width float64) string {
+	if face.Width(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "…"
+		if face.Width(candidate) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Missing returns the characters of text none of the face's fonts draw.
+func (face Face) Missing(text string) []rune {
+	var missing []rune
+	seen := make(map[rune]bool)
+	for _, r := range text {
+		if r <= ' ' || seen[r] {
+			continue
+		}
+		found := false
+		for _, f := range face.Fonts {
+			if f.Has(r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			seen[r] = true
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,305 @@
+// Package pdfdoc writes simple PDF documents: text in embedded TrueType
+// fonts, lines, filled rectangles and images, which is all a statement or
+// report needs.
+package pdfdoc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 page size in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Color is an RGB color with components from 0 to 1.
+type Color struct {
+	R, G, B float64
+}
+
+// RGB makes a color from 0-255 components.
+func RGB(r, g, b uint8) Color {
+	return Color{float64(r) / 255, float64(g) / 255, float64(b) / 255}
+}
+
+var Black = Color{}
+
+// Document is a PDF being built. Pages are kept in memory until WriteTo.
+type Document struct {
+	title  string
+	width  float64
+	height float64
+	fonts  []*Font
+	images []image.Image
+	pages  []*Page
+}
+
+// New starts a document of pages width by height points.
+func New(title string, width, height float64) *Document {
+	return &Document{title: title, width: width, height: height}
+}
+
+// AddFont embeds f in the document; faces drawn on its pages may only use
+// fonts added here.
+func (d *Document) AddFont(f *Font) {
+	f.resource = "F" + strconv.Itoa(len(d.fonts)+1)
+	d.fonts = append(d.fonts, f)
+}
+
+// AddPage appends a blank page.
+func (d *Document) AddPage() *Page {
+	page := &Page{doc: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages added so far, for footers that need the count.
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Page is one page. Positions are in points from the top-left corner; a
+// text position is where its baseline starts.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// Text draws text with its baseline starting at x, y.
+func (p *Page) Text(x, y float64, face Face, color Color, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(&p.content, "BT %s rg\n%s %s Td\n", colorOps(color), num(x), num(p.doc.height-y))
+	for _, run := range face.runs(text) {
+		fmt.Fprintf(&p.content, "/%s %s Tf <", run.font.resource, num(face.Size))
+		for i, glyph := range run.glyphs {
+			run.font.use(glyph, run.runes[i])
+			fmt.Fprintf(&p.content, "%04X", uint16(glyph))
+		}
+		p.content.WriteString("> Tj\n")
+	}
+	p.content.WriteString("ET\n")
+}
+
+// TextRight draws text ending at x.
+func (p *Page) TextRight(x, y float64, face Face, color Color, text string) {
+	p.Text(x-face.Width(text), y, face, color, text)
+}
+
+// Rect fills a rectangle whose top-left corner is at x, y.
+func (p *Page) Rect(x, y, width, height float64, fill Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n", colorOps(fill),
+		num(x), num(p.doc.height-y-height), num(width), num(height))
+}
+
+// Line strokes a straight line.
+func (p *Page) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n", colorOps(color), num(width),
+		num(x1), num(p.doc.height-y1), num(x2), num(p.doc.height-y2))
+}
+
+// Polyline strokes a line through points given as x, y pairs.
+func (p *Page) Polyline(points []float64, width float64, color Color) {
+	if len(points) < 4 {
+		return
+	}
+	fmt.Fprintf(&p.content, "%s RG %s w 1 j ", colorOps(color), num(width))
+	for i := 0; i+1 < len(points); i += 2 {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&p.content, "%s %s %s ", num(points[i]), num(p.doc.height-points[i+1]), op)
+	}
+	p.content.WriteString("S\n")
+}
+
+// Image draws img scaled into a width by height box whose top-left corner
+// is at x, y. Transparency is dropped, so img should be opaque.
+func (p *Page) Image(x, y, width, height float64, img image.Image) {
+	p.doc.images = append(p.doc.images, img)
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		num(width), num(height), num(x), num(p.doc.height-y-height), len(p.doc.images))
+}
+
+// WriteTo writes the document. The output depends only on what was drawn,
+// so the same document always gives the same bytes.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &writer{offsets: make(map[int]int)}
+	out.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	// Objects 1 and 2 are the catalog and page tree; the rest are numbered
+	// as they are written.
+	catalog, pageTree := 1, 2
+	out.next = 3
+	info := out.object(fmt.Sprintf("<< /Title %s /Producer (jod-jod) >>", textString(d.title)))
+
+	// Fonts no page drew with are left out.
+	var fontRefs []string
+	for _, f := range d.fonts {
+		if len(f.used) > 0 {
+			fontRefs = append(fontRefs, fmt.Sprintf("/%s %d 0 R", f.resource, out.font(f)))
+		}
+	}
+	var imageRefs []string
+	for i, img := range d.images {
+		imageRefs = append(imageRefs, fmt.Sprintf("/Im%d %d 0 R", i+1, out.image(img)))
+	}
+	var xObjects string
+	if len(imageRefs) > 0 {
+		xObjects = fmt.Sprintf(" /XObject << %s >>", strings.Join(imageRefs, " "))
+	}
+	resources := out.object(fmt.Sprintf("<< /Font << %s >>%s >>", strings.Join(fontRefs, " "), xObjects))
+
+	kids := make([]string, len(d.pages))
+	for i, page := range d.pages {
+		content := out.stream("", page.content.Bytes(), true)
+		pageId := out.object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R >>",
+			pageTree, num(d.width), num(d.height), resources, content))
+		kids[i] = fmt.Sprintf("%d 0 R", pageId)
+	}
+	out.objectAt(pageTree, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	out.objectAt(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pageTree))
+
+	xref := out.buf.Len()
+	fmt.Fprintf(&out.buf, "xref\n0 %d\n0000000000 65535 f \n", out.next)
+	for id := 1; id < out.next; id++ {
+		fmt.Fprintf(&out.buf, "%010d 00000 n \n", out.offsets[id])
+	}
+	fmt.Fprintf(&out.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", out.next, catalog, info, xref)
+	return out.buf.WriteTo(w)
+}
+
+// writer numbers objects and remembers where each starts for the xref.
+type writer struct {
+	buf     bytes.Buffer
+	next    int
+	offsets map[int]int
+}
+
+func (w *writer) object(body string) int {
+	id := w.next
+	w.next++
+	w.objectAt(id, body)
+	return id
+}
+
+func (w *writer) objectAt(id int, body string) {
+	w.offsets[id] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream writes a stream object, deflated when compress is set. extra goes
+// into its dictionary.
+func (w *writer) stream(extra string, data []byte, compress bool) int {
+	filter := ""
+	if compress {
+		var deflated bytes.Buffer
+		zw := zlib.NewWriter(&deflated)
+		zw.Write(data)
+		zw.Close()
+		data = deflated.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	id := w.next
+	w.next++
+	w.offsets[id] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< /Length %d%s%s >>\nstream\n", id, len(data), filter, extra)
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\nendobj\n")
+	return id
+}
+
+// font writes f as a Type0 font over its whole TrueType program, with
+// widths and a ToUnicode map for the glyphs used so text can be copied and
+// searched.
+func (w *writer) font(f *Font) int {
+	file := w.stream(fmt.Sprintf(" /Length1 %d", len(f.data)), f.data, true)
+	descriptor := w.object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] "+
+		"/ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, num(f.bbox[0]), num(f.bbox[1]), num(f.bbox[2]), num(f.bbox[3]),
+		num(f.ascent), num(f.descent), num(f.capHeight), file))
+
+	glyphs := f.usedGlyphs()
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%s] ", glyph, num(f.widths[glyph]))
+	}
+	cid := w.object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>",
+		f.name, descriptor, strings.TrimSpace(widths.String())))
+
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, glyph := range glyphs[start:end] {
+			fmt.Fprintf(&cmap, "<%04X> <", uint16(glyph))
+			for _, unit := range utf16.Encode([]rune{f.used[glyph]}) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	toUnicode := w.stream("", []byte(cmap.String()), true)
+
+	return w.object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+		"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", f.name, cid, toUnicode))
+}
+
+// image writes img as 8-bit RGB samples.
+func (w *writer) image(img image.Image) int {
+	bounds := img.Bounds()
+	samples := make([]byte, 0, 3*bounds.Dx()*bounds.Dy())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			samples = append(samples, byte(r>>8), byte(g>>8), byte(b>>8))
+		}
+	}
+	return w.stream(fmt.Sprintf(" /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8",
+		bounds.Dx(), bounds.Dy()), samples, true)
+}
+
+func colorOps(c Color) string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B)
+}
+
+// num writes a number as PDF expects, without exponents and with no more
+// precision than a printer can use.
+func num(value float64) string {
+	text := strconv.FormatFloat(value, 'f', 2, 64)
+	text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	if text == "" || text == "-0" {
+		return "0"
+	}
+	return text
+}
+
+// textString writes text as a UTF-16 string, which PDF readers show in
+// document properties whatever the script.
+func textString(text string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package pdfdoc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"image"
+	"image/color"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func newFace(t *testing.T, data ...[]byte) Face {
+	var fonts []*Font
+	for _, ttf := range data {
+		f, err := ParseFont(ttf)
+		assert.NoError(t, err)
+		fonts = append(fonts, f)
+	}
+	return Face{Fonts: fonts, Size: 10}
+}
+
+func TestDocument_WriteTo(t *testing.T) {
+	face := newFace(t, goregular.TTF, gobold.TTF)
+	doc := New("Statement มีนาคม", A4Width, A4Height)
+	for _, f := range face.Fonts {
+		doc.AddFont(f)
+	}
+	page := doc.AddPage()
+	page.Text(40, 60, face, Black, "Hello")
+	page.Rect(40, 80, 100, 10, RGB(0x2e, 0x7d, 0x32))
+	page.Line(40, 100, 200, 100, 0.5, Black)
+	doc.AddPage().TextRight(500, 60, face, Black, "1,250.50")
+
+	var out bytes.Buffer
+	_, err := doc.WriteTo(&out)
+	assert.NoError(t, err)
+	data := out.Bytes()
+
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+	assert.Contains(t, string(data), "/Count 2")
+	// The bold font drew nothing, so only the regular one is embedded.
+	assert.Equal(t, 1, bytes.Count(data, []byte("/Subtype /Type0")))
+
+	// Every xref entry points at its object.
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	xrefAt, _ := strconv.Atoi(string(startxref[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xrefAt:], -1)
+	assert.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		assert.True(t, bytes.HasPrefix(data[offset:], []byte(strconv.Itoa(i+1)+" 0 obj")), "object %d", i+1)
+	}
+
+	// The text is written as glyph IDs of the embedded font.
+	first := regexp.MustCompile(`(?s)/Filter /FlateDecode >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(data, -1)
+	var contents []string
+	for _, stream := range first {
+		r, err := zlib.NewReader(bytes.NewReader(stream[1]))
+		assert.NoError(t, err)
+		text, _ := io.ReadAll(r)
+		contents = append(contents, string(text))
+	}
+	assert.Contains(t, contents, "BT 0 0 0 rg\n40 781.89 Td\n/F1 10 Tf <002B0048004F004F0052> Tj\nET\n"+
+		"0.18 0.49 0.2 rg 40 751.89 100 10 re f\n0 0 0 RG 0.5 w 40 741.89 m 200 741.89 l S\n")
+}
+
+func TestPage_Image(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.Set(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+	img.Set(1, 0, color.RGBA{0, 0, 0xff, 0xff})
+	doc := New("Chart", A4Width, A4Height)
+	doc.AddPage().Image(40, 100, 200, 100, img)
+
+	var out bytes.Buffer
+	_, err := doc.WriteTo(&out)
+	assert.NoError(t, err)
+	data := out.String()
+
+	assert.Contains(t, data, "/XObject << /Im1 4 0 R >>")
+	assert.Contains(t, data, "/Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8")
+
+	streams := regexp.MustCompile(`(?s)/Filter /FlateDecode[^>]*>>\nstream\n(.*?)\nendstream`).FindAllStringSubmatch(data, -1)
+	var contents []string
+	for _, stream := range streams {
+		r, err := zlib.NewReader(bytes.NewReader([]byte(stream[1])))
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(r)
+		contents = append(contents, string(content))
+	}
+	assert.Contains(t, contents, "\xff\x00\x00\x00\x00\xff")
+	assert.Contains(t, contents, "q 200 0 0 100 40 641.89 cm /Im1 Do Q\n")
+}
+
+func TestFace_Fallback(t *testing.T) {
+	face := newFace(t, goregular.TTF)
+
+	assert.Equal(t, []rune{'ก', 'า'}, face.Missing("กา a"))
+	assert.Empty(t, face.Missing("abc"))
+	assert.InDelta(t, face.Width("ab"), face.Width("a")+face.Width("b"), 0.001)
+	assert.Equal(t, "Long n…", face.Fit("Long note here", face.Width("Long n…")))
+}
+
+func TestParseFont_Invalid(t *testing.T) {
+	_, err := ParseFont([]byte("not a font"))
+	assert.ErrorIs(t, err, ErrInvalidFont)
+}
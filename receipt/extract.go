@@ -0,0 +1,369 @@
+package receipt
+
+import (
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"github.com/Montheankul-K/jod-jod/slip"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	spaceRe   = regexp.MustCompile(`\s+`)
+	moneyRe   = regexp.MustCompile(`-?(?:\d{1,3}(?:,\d{3})+|\d+)\.\d{2}`)
+	percentRe = regexp.MustCompile(`\d+(?:\.\d+)?\s*%`)
+	// itemRe splits an item line into its name and amount; Lotus's and Makro
+	// flag the amount with a VAT code.
+	itemRe = regexp.MustCompile(`^(.*?)\s*(-?(?:\d{1,3}(?:,\d{3})+|\d+)\.\d{2})\s*[A-Z*#]?$`)
+	// quantityRe reads "2 X 55.00", "0.456 KG @ 89.00" or "@45.00" at the end
+	// of an item name or on its own line.
+	quantityRe    = regexp.MustCompile(`(?i)(?:^|\s)(?:(\d+(?:\.\d+)?)\s*(?:kg|กก\.?)?\s*)?[@x×]\s*((?:\d{1,3}(?:,\d{3})+|\d+)\.\d{2})$`)
+	leadingQtyRe  = regexp.MustCompile(`^(\d{1,3})\s+(\S.*)$`)
+	itemCodeRe    = regexp.MustCompile(`^\d{6,14}\s+`)
+	taxIdDigitsRe = regexp.MustCompile(`\d[\d -]{11,19}\d`)
+	referenceRe   = regexp.MustCompile(`(?i)(?:\br#|receipt\s*no\.?|เลขที่ใบเสร็จ|bill\s*no\.?|inv\.?\s*no\.?|trans\s*no\.?)\s*[:#]?\s*([A-Za-z0-9][A-Za-z0-9/-]*)`)
+	nonDigitRe    = regexp.MustCompile(`\D`)
+)
+
+// Labels are in order of preference: a receipt that prints both "TOTAL" and
+// "NET TOTAL" is paid by the net total.
+var (
+	totalLabels    = []string{"ยอดสุทธิ", "ยอดรวมสุทธิ", "รวมทั้งสิ้น", "net total", "grand total", "amount due", "total", "ยอดรวม"}
+	subtotalLabels = []string{"subtotal", "sub total", "sub-total", "รวมเป็นเงิน", "รวม "}
+	discountLabels = []string{"ส่วนลด", "discount", "คูปอง", "coupon"}
+	vatLabels      = []string{"ภาษีมูลค่าเพิ่ม", "vat"}
+	taxIdLabels    = []string{"เลขประจำตัวผู้เสียภาษี", "เลขผู้เสียภาษี", "tax id", "tax i.d.", "taxid"}
+)
+
+// extract reads a receipt in three parts: the header with the store, tax ID
+// and receipt number; the items; and the summary, which starts at the first
+// subtotal or total line. Anything after the total, such as the payment and
+// change, is only read for VAT and the date, which some stores print last.
+func extract(merchant string, layout layout, lines []ocr.Line) (*Receipt, error) {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = strings.TrimSpace(spaceRe.ReplaceAllString(line.Text, " "))
+	}
+
+	result := &Receipt{Merchant: merchant}
+	end := len(texts)
+	for i, text := range texts {
+		if hasLabel(text, totalLabels) || hasLabel(text, subtotalLabels) {
+			end = i
+			break
+		}
+	}
+
+	start := 0
+	header := func(i int) {
+		if i < end && i+1 > start {
+			start = i + 1
+		}
+	}
+	if result.Merchant == "" {
+		for i := 0; i < end; i++ {
+			if strings.ContainsFunc(texts[i], isLetter) && !itemRe.MatchString(texts[i]) {
+				result.Merchant = texts[i]
+				header(i)
+				break
+			}
+		}
+	}
+	if taxId, i, ok := findTaxId(texts); ok {
+		result.TaxId = taxId
+		header(i)
+	}
+	for i, text := range texts {
+		if match := referenceRe.FindStringSubmatch(text); match != nil {
+			result.Reference = match[1]
+			header(i)
+			break
+		}
+	}
+	for i, text := range texts {
+		if itemRe.MatchString(text) {
+			continue
+		}
+		if date, ok := slip.ParseDate(text); ok {
+			result.Date = date
+			header(i)
+			break
+		}
+	}
+
+	sources := readItems(result, layout, texts[start:end], start)
+
+	totalIdx := -1
+	for _, label := range totalLabels {
+		for i := end; i < len(texts); i++ {
+			if !hasLabel(texts[i], []string{label}) {
+				continue
+			}
+			if amount, ok := amountNear(texts, i); ok {
+				result.Total, totalIdx = amount, i
+			}
+			break
+		}
+		if totalIdx >= 0 {
+			break
+		}
+	}
+	for i := end; i < len(texts); i++ {
+		switch {
+		case i == totalIdx:
+		case hasLabel(texts[i], subtotalLabels) && result.Subtotal == 0:
+			result.Subtotal, _ = amountNear(texts, i)
+		case hasLabel(texts[i], discountLabels):
+			if amount, ok := amountNear(texts, i); ok {
+				result.Discount += math.Abs(amount)
+			}
+		case hasLabel(texts[i], vatLabels) && result.VAT == 0:
+			result.VAT, _ = amountNear(texts, i)
+		}
+	}
+
+	if totalIdx < 0 {
+		// Small shops may print no total for a short receipt; the items then
+		// are the total.
+		if len(result.Items) == 0 {
+			return nil, ErrTotalNotFound
+		}
+		result.Total = round(result.ItemsTotal())
+	} else {
+		sources = append(sources, totalIdx)
+	}
+	if result.Subtotal == 0 && len(result.Items) > 0 {
+		var subtotal float64
+		for _, item := range result.Items {
+			subtotal += item.Amount
+		}
+		result.Subtotal = round(subtotal)
+	}
+
+	result.Confidence = 100
+	for _, i := range sources {
+		if lines[i].Confidence > 0 {
+			result.Confidence = math.Min(result.Confidence, lines[i].Confidence)
+		}
+	}
+	return result, nil
+}
+
+// readItems reads the item lines between the header and the summary and
+// returns the indexes of the lines it used. A line without an amount is held
+// as the name of the item the next quantity line prices, as Makro prints
+// them; a discount line under an item counts towards the receipt's discount.
+func readItems(result *Receipt, layout layout, texts []string, offset int) []int {
+	var sources []int
+	var pending string
+	for i, text := range texts {
+		if match := quantityRe.FindStringSubmatch(text); match != nil && strings.TrimSpace(text[:len(text)-len(match[0])]) == "" {
+			quantity := parseQuantity(match[1])
+			unitPrice, _ := parseMoney(match[2])
+			if pending != "" {
+				result.Items = append(result.Items, Item{Name: pending, Quantity: quantity, UnitPrice: unitPrice, Amount: round(quantity * unitPrice)})
+				pending = ""
+			} else if n := len(result.Items); n > 0 {
+				result.Items[n-1].Quantity = quantity
+				result.Items[n-1].UnitPrice = unitPrice
+			}
+			sources = append(sources, offset+i)
+			continue
+		}
+
+		match := itemRe.FindStringSubmatch(text)
+		if match == nil {
+			if name := itemName(text, layout); name != "" {
+				pending = name
+			}
+			continue
+		}
+		amount, _ := parseMoney(match[2])
+		// "2 X 55.00 110.00" prints the line amount after the unit price.
+		if qty := quantityRe.FindStringSubmatch(match[1]); qty != nil && strings.TrimSpace(match[1][:len(match[1])-len(qty[0])]) == "" {
+			unitPrice, _ := parseMoney(qty[2])
+			if pending != "" {
+				result.Items = append(result.Items, Item{Name: pending, Quantity: parseQuantity(qty[1]), UnitPrice: unitPrice, Amount: amount})
+				pending = ""
+			} else if n := len(result.Items); n > 0 {
+				result.Items[n-1].Quantity = parseQuantity(qty[1])
+				result.Items[n-1].UnitPrice = unitPrice
+			}
+			sources = append(sources, offset+i)
+			continue
+		}
+		if amount < 0 || hasLabel(text, discountLabels) {
+			result.Discount += math.Abs(amount)
+			sources = append(sources, offset+i)
+			continue
+		}
+
+		item := Item{Name: match[1], Quantity: 1, Amount: amount}
+		if layout.leadingQuantity {
+			if lead := leadingQtyRe.FindStringSubmatch(item.Name); lead != nil {
+				item.Quantity, _ = strconv.ParseFloat(lead[1], 64)
+				item.Name = lead[2]
+			}
+		}
+		if qty := quantityRe.FindStringSubmatch(item.Name); qty != nil {
+			if qty[1] != "" {
+				item.Quantity = parseQuantity(qty[1])
+			}
+			item.UnitPrice, _ = parseMoney(qty[2])
+			item.Name = item.Name[:len(item.Name)-len(qty[0])]
+		}
+		item.Name = itemName(item.Name, layout)
+		if item.Name == "" {
+			item.Name = pending
+		}
+		if item.UnitPrice == 0 && item.Quantity > 0 {
+			item.UnitPrice = round(item.Amount / item.Quantity)
+		}
+		pending = ""
+		result.Items = append(result.Items, item)
+		sources = append(sources, offset+i)
+	}
+	return sources
+}
+
+func itemName(text string, layout layout) string {
+	if layout.itemCodes {
+		text = itemCodeRe.ReplaceAllString(text, "")
+	}
+	return strings.Trim(text, " :-@")
+}
+
+// findTaxId reads the 13-digit tax ID after its label, on the same line or
+// the next, written with or without dashes.
+func findTaxId(texts []string) (string, int, bool) {
+	for i, text := range texts {
+		rest, ok := cutLabel(text, taxIdLabels)
+		if !ok {
+			continue
+		}
+		candidates := []string{rest}
+		if i+1 < len(texts) {
+			candidates = append(candidates, texts[i+1])
+		}
+		for _, candidate := range candidates {
+			for _, match := range taxIdDigitsRe.FindAllString(candidate, -1) {
+				if digits := nonDigitRe.ReplaceAllString(match, ""); len(digits) == 13 {
+					return digits, i, true
+				}
+			}
+		}
+	}
+	return "", -1, false
+}
+
+// amountNear returns the last amount on a summary line, ignoring rates such
+// as "7%", or the amount on the next line when the label stands alone.
+func amountNear(texts []string, i int) (float64, bool) {
+	if amount, ok := lastMoney(texts[i]); ok {
+		return amount, true
+	}
+	if i+1 < len(texts) && itemRe.MatchString(texts[i+1]) && !strings.ContainsFunc(itemRe.FindStringSubmatch(texts[i+1])[1], isLetter) {
+		return lastMoney(texts[i+1])
+	}
+	return 0, false
+}
+
+func lastMoney(text string) (float64, bool) {
+	matches := moneyRe.FindAllString(percentRe.ReplaceAllString(text, ""), -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	return parseMoney(matches[len(matches)-1])
+}
+
+func parseMoney(text string) (float64, bool) {
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return amount, true
+}
+
+func parseQuantity(text string) float64 {
+	quantity, err := strconv.ParseFloat(text, 64)
+	if err != nil || quantity <= 0 {
+		return 1
+	}
+	return quantity
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// cutLabel returns the rest of a line that starts with one of the labels,
+// trying longer labels first. Latin labels must end on a word boundary so
+// "vat" does not match "vatable"; Thai runs straight into its values.
+func cutLabel(text string, labels []string) (string, bool) {
+	sorted := append([]string(nil), labels...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	lower := strings.ToLower(text)
+	for _, label := range sorted {
+		if !strings.HasPrefix(lower, label) {
+			continue
+		}
+		if isLatin(label) && latinAfter(lower, len(label)) {
+			continue
+		}
+		return strings.Trim(text[len(label):], " :：.-"), true
+	}
+	return "", false
+}
+
+func hasLabel(text string, labels []string) bool {
+	_, ok := cutLabel(text, labels)
+	return ok
+}
+
+// containsWord matches Latin markers on word boundaries so "lotus" is not
+// found inside a longer word. Thai markers match anywhere.
+func containsWord(text, word string) bool {
+	from := 0
+	for {
+		i := strings.Index(text[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		if !isLatin(word) || (!latinBefore(text, start) && !latinAfter(text, end)) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+func isLatin(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func latinBefore(text string, i int) bool {
+	return i > 0 && isAlnum(rune(text[i-1]))
+}
+
+func latinAfter(text string, i int) bool {
+	return i < len(text) && isAlnum(rune(text[i]))
+}
+
+func isAlnum(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= 0x0E01 && r <= 0x0E2E)
+}
@@ -0,0 +1,100 @@
+package receipt
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"github.com/Montheankul-K/jod-jod/slip"
+	"time"
+)
+
+const (
+	MerchantSevenEleven = "7-Eleven"
+	MerchantLotuss      = "Lotus's"
+	MerchantMakro       = "Makro"
+)
+
+var ErrTotalNotFound = errors.New("receipt total not found")
+
+// Item is one purchased line. Quantity is 1 and UnitPrice the amount when the
+// receipt prints neither.
+type Item struct {
+	Name      string  `json:"name"`
+	Quantity  float64 `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Amount    float64 `json:"amount"`
+}
+
+// Receipt is what could be read off a store receipt. Fields that were not
+// found are left empty; only a missing total is an error.
+type Receipt struct {
+	Merchant  string    `json:"merchant"`
+	TaxId     string    `json:"tax_id"`
+	Reference string    `json:"reference"`
+	Date      time.Time `json:"date"`
+	Items     []Item    `json:"items"`
+	Subtotal  float64   `json:"subtotal"`
+	Discount  float64   `json:"discount"`
+	VAT       float64   `json:"vat"`
+	Total     float64   `json:"total"`
+	// Confidence is the lowest OCR confidence among the lines the total and
+	// items were read from.
+	Confidence float64 `json:"confidence"`
+}
+
+// ItemsTotal is what the items add up to after discounts.
+func (r *Receipt) ItemsTotal() float64 {
+	var total float64
+	for _, item := range r.Items {
+		total += item.Amount
+	}
+	return total - r.Discount
+}
+
+type Parser interface {
+	Merchant() string
+	// Detect reports whether a line names this parser's store.
+	Detect(text string) bool
+	Parse(lines []ocr.Line) (*Receipt, error)
+}
+
+type Registry struct {
+	parsers  []Parser
+	fallback Parser
+}
+
+// NewRegistry tries parsers in order and falls back to a parser that reads
+// the merchant from the receipt's first line when none of them recognises it.
+func NewRegistry(parsers ...Parser) *Registry {
+	return &Registry{
+		parsers:  parsers,
+		fallback: newGenericParser(),
+	}
+}
+
+// DefaultRegistry holds a parser for every supported store.
+func DefaultRegistry() *Registry {
+	return NewRegistry(
+		newSevenElevenParser(),
+		newLotussParser(),
+		newMakroParser(),
+	)
+}
+
+// Parse puts lines in reading order and picks the parser for the store named
+// on the receipt.
+func (r *Registry) Parse(lines []ocr.Line) (*Receipt, error) {
+	ordered := slip.ReadingOrder(lines)
+	return r.Detect(ordered).Parse(ordered)
+}
+
+// Detect returns the parser for the first store named on the receipt.
+func (r *Registry) Detect(lines []ocr.Line) Parser {
+	for _, line := range lines {
+		for _, parser := range r.parsers {
+			if parser.Detect(line.Text) {
+				return parser
+			}
+		}
+	}
+	return r.fallback
+}
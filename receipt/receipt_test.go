@@ -0,0 +1,85 @@
+package receipt
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestRegistry_Parse_Golden parses every testdata/*.txt, one OCR line per
+// text line, and compares the result with the matching .golden.json. Run with
+// -update to regenerate the golden files after a deliberate change.
+func TestRegistry_Parse_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.txt")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inputs)
+
+	registry := DefaultRegistry()
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			assert.NoError(t, err)
+
+			var lines []ocr.Line
+			for _, text := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				lines = append(lines, ocr.Line{Text: text, Confidence: 99})
+			}
+
+			result, err := registry.Parse(lines)
+			assert.NoError(t, err)
+			assert.InDelta(t, result.Total, result.ItemsTotal(), 0.01, "items do not add up to the total")
+			got, err := json.MarshalIndent(result, "", "  ")
+			assert.NoError(t, err)
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, append(got, '\n'), 0o644))
+				return
+			}
+			want, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestRegistry_Parse_TotalNotFound(t *testing.T) {
+	lines := []ocr.Line{{Text: "ร้านค้า"}, {Text: "ขอบคุณที่ใช้บริการ"}}
+
+	_, err := DefaultRegistry().Parse(lines)
+
+	assert.ErrorIs(t, err, ErrTotalNotFound)
+}
+
+func TestRegistry_Parse_NoTotalLine(t *testing.T) {
+	lines := []ocr.Line{{Text: "ร้านป้าแดง"}, {Text: "ข้าวมันไก่ 50.00"}, {Text: "น้ำเปล่า 10.00"}}
+
+	result, err := DefaultRegistry().Parse(lines)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ร้านป้าแดง", result.Merchant)
+	assert.Equal(t, 60.0, result.Total)
+	assert.Len(t, result.Items, 2)
+}
+
+func TestRegistry_Detect(t *testing.T) {
+	cases := map[string]string{
+		"7-ELEVEN": MerchantSevenEleven,
+		"บริษัท ซีพี ออลล์ จำกัด": MerchantSevenEleven,
+		"Lotus's": MerchantLotuss,
+		"สยามแม็คโคร จำกัด (มหาชน)": MerchantMakro,
+		"Lotusland Cafe": "",
+	}
+	for text, want := range cases {
+		parser := DefaultRegistry().Detect([]ocr.Line{{Text: text}})
+		assert.Equal(t, want, parser.Merchant(), text)
+	}
+}
@@ -0,0 +1,88 @@
+package receipt
+
+import (
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"strings"
+)
+
+// layout is how a store prints its item lines.
+type layout struct {
+	// leadingQuantity is set when items start with the quantity, e.g.
+	// "2 ลาเต้เย็น @45.00 90.00".
+	leadingQuantity bool
+	// itemCodes is set when items start with a barcode or article number.
+	itemCodes bool
+}
+
+type storeParser struct {
+	merchant string
+	markers  []string
+	layout   layout
+}
+
+func (p *storeParser) Merchant() string {
+	return p.merchant
+}
+
+func (p *storeParser) Detect(text string) bool {
+	lower := strings.ToLower(text)
+	for _, marker := range p.markers {
+		if containsWord(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *storeParser) Parse(lines []ocr.Line) (*Receipt, error) {
+	return extract(p.merchant, p.layout, lines)
+}
+
+// 7-Eleven prints the quantity first and the unit price after "@" when more
+// than one was bought, and the date at the bottom.
+func newSevenElevenParser() Parser {
+	return &storeParser{
+		merchant: MerchantSevenEleven,
+		markers:  []string{"7-eleven", "7-11", "เซเว่น", "cp all", "ซีพี ออลล์"},
+		layout:   layout{leadingQuantity: true},
+	}
+}
+
+// Lotus's prints the quantity and unit price inside the item line and flags
+// each amount V or N for whether VAT applies.
+func newLotussParser() Parser {
+	return &storeParser{
+		merchant: MerchantLotuss,
+		markers:  []string{"lotus's", "lotuss", "lotus", "โลตัส", "เอก-ชัย"},
+		layout:   layout{},
+	}
+}
+
+// Makro starts every item with its barcode and prints "2 X 55.00 110.00"
+// on the line below when more than one was bought.
+func newMakroParser() Parser {
+	return &storeParser{
+		merchant: MerchantMakro,
+		markers:  []string{"makro", "แม็คโคร", "แมคโคร", "cp axtra", "ซีพี แอ็กซ์ตร้า"},
+		layout:   layout{itemCodes: true},
+	}
+}
+
+type genericParser struct{}
+
+func newGenericParser() Parser {
+	return &genericParser{}
+}
+
+func (p *genericParser) Merchant() string {
+	return ""
+}
+
+func (p *genericParser) Detect(text string) bool {
+	return false
+}
+
+// Parse takes the merchant to be the first line of the receipt.
+func (p *genericParser) Parse(lines []ocr.Line) (*Receipt, error) {
+	return extract("", layout{}, lines)
+}
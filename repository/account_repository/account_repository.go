@@ -0,0 +1,71 @@
+package account_repository
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type IAccountRepository interface {
+	GetAccounts(spenderId uint) ([]entities.Account, error)
+	GetAccount(accountId uint) (*entities.Account, error)
+	SaveAccount(req entities.Account) (uint, error)
+	DeleteAccount(accountId uint) error
+}
+
+type accountRepository struct {
+	db     *gorm.DB
+	logger echo.Logger
+}
+
+func NewAccountRepository(db *gorm.DB, logger echo.Logger) IAccountRepository {
+	return &accountRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *accountRepository) GetAccounts(spenderId uint) ([]entities.Account, error) {
+	var res []entities.Account
+	query := r.db.Model(&entities.Account{}).Where("spender_id = ?", spenderId).Order("name")
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *accountRepository) GetAccount(accountId uint) (*entities.Account, error) {
+	var res entities.Account
+	query := r.db.Model(&entities.Account{}).Where("id = ?", accountId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *accountRepository) SaveAccount(req entities.Account) (uint, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+	return req.ID, nil
+}
+
+// DeleteAccount removes the account and takes its transactions off it; the
+// transactions themselves are kept.
+func (r *accountRepository) DeleteAccount(accountId uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&entities.Transaction{}).Where("account_id = ?", accountId)
+		if err := query.Update("account_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entities.Account{}, accountId).Error
+	})
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package attachment_repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"time"
+)
+
+type IAttachmentRepository interface {
+	GetByTransactionId(txnId uint) ([]entities.Attachment, error)
+	GetAttachment(attachmentId uint) (*entities.Attachment, error)
+	SaveAttachment(req entities.Attachment) (uint, error)
+	DeleteAttachment(txnId uint, attachmentId uint) error
+	DeleteByTransactionId(txnId uint) error
+}
+
+type attachmentRepository struct {
+	db          *gorm.DB
+	logger      echo.Logger
+	redisClient *redis.Client
+}
+
+func NewAttachmentRepository(db *gorm.DB, logger echo.Logger, redisClient *redis.Client) IAttachmentRepository {
+	return &attachmentRepository{
+		db:          db,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+func (r *attachmentRepository) GetByTransactionId(txnId uint) ([]entities.Attachment, error) {
+	var res []entities.Attachment
+	key := fmt.Sprintf("get-attachments:%v", txnId)
+	attachmentCache, err := r.redisClient.Get(context.Background(), key).Result()
+	if err == nil && attachmentCache != "" {
+		err = json.Unmarshal([]byte(attachmentCache), &res)
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	query := r.db.Model(&entities.Attachment{}).Where("transaction_id = ?", txnId).Order("created_at")
+	err = query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	cache, err := json.Marshal(res)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	err = r.redisClient.Set(context.Background(), key, string(cache), time.Hour*1).Err()
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *attachmentRepository) GetAttachment(attachmentId uint) (*entities.Attachment, error) {
+	var res entities.Attachment
+	query := r.db.Model(&entities.Attachment{}).Where("id = ?", attachmentId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *attachmentRepository) SaveAttachment(req entities.Attachment) (uint, error) {
+	tx := r.db.Begin()
+	if err := tx.Create(&req).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	key := fmt.Sprintf("get-attachments:%v", req.TransactionId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+	return req.ID, tx.Commit().Error
+}
+
+func (r *attachmentRepository) DeleteAttachment(txnId uint, attachmentId uint) error {
+	tx := r.db.Begin()
+	query := tx.Unscoped().Model(&entities.Attachment{}).Where("id = ? AND transaction_id = ?", attachmentId, txnId)
+	if err := query.Delete(&entities.Attachment{}).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+
+	key := fmt.Sprintf("get-attachments:%v", txnId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (r *attachmentRepository) DeleteByTransactionId(txnId uint) error {
+	tx := r.db.Begin()
+	query := tx.Unscoped().Model(&entities.Attachment{}).Where("transaction_id = ?", txnId)
+	if err := query.Delete(&entities.Attachment{}).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+
+	key := fmt.Sprintf("get-attachments:%v", txnId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
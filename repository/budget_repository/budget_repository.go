@@ -0,0 +1,107 @@
+package budget_repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type IBudgetRepository interface {
+	GetBySpenderId(spenderId uint) ([]entities.Budget, error)
+	SaveBudget(req entities.Budget) (uint, error)
+	DeleteBudget(spenderId uint, budgetId uint) error
+}
+
+type budgetRepository struct {
+	db          *gorm.DB
+	logger      echo.Logger
+	redisClient *redis.Client
+}
+
+func NewBudgetRepository(db *gorm.DB, logger echo.Logger, redisClient *redis.Client) IBudgetRepository {
+	return &budgetRepository{
+		db:          db,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+func (r *budgetRepository) GetBySpenderId(spenderId uint) ([]entities.Budget, error) {
+	var res []entities.Budget
+	key := fmt.Sprintf("get-budgets:%v", spenderId)
+	budgetCache, err := r.redisClient.Get(context.Background(), key).Result()
+	if err == nil && budgetCache != "" {
+		err = json.Unmarshal([]byte(budgetCache), &res)
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	query := r.db.Model(&entities.Budget{}).Where("spender_id = ?", spenderId).Order("category")
+	err = query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	cache, err := json.Marshal(res)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	err = r.redisClient.Set(context.Background(), key, string(cache), time.Hour*1).Err()
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// SaveBudget keeps one budget per category, so setting a category again
+// replaces its limit.
+func (r *budgetRepository) SaveBudget(req entities.Budget) (uint, error) {
+	tx := r.db.Begin()
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "spender_id"}, {Name: "category"}},
+		DoUpdates: clause.AssignmentColumns([]string{"monthly_limit", "updated_at", "deleted_at"}),
+	}).Create(&req).Error
+	if err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	budgetId := req.ID
+	key := fmt.Sprintf("get-budgets:%v", req.SpenderId)
+	if err = r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+	return budgetId, tx.Commit().Error
+}
+
+func (r *budgetRepository) DeleteBudget(spenderId uint, budgetId uint) error {
+	tx := r.db.Begin()
+	query := tx.Unscoped().Model(&entities.Budget{}).Where("id = ? AND spender_id = ?", budgetId, spenderId)
+	if err := query.Delete(&entities.Budget{}).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+
+	key := fmt.Sprintf("get-budgets:%v", spenderId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
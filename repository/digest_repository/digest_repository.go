@@ -0,0 +1,66 @@
+package digest_repository
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type IDigestRepository interface {
+	GetRecipients() ([]entities.DigestRecipient, error)
+	GetRecipient(spenderId uint) (*entities.DigestRecipient, error)
+	IsDelivered(spenderId uint, month string, channel string) (bool, error)
+	SaveDelivery(req entities.DigestDelivery) error
+}
+
+type digestRepository struct {
+	db     *gorm.DB
+	logger echo.Logger
+}
+
+func NewDigestRepository(db *gorm.DB, logger echo.Logger) IDigestRepository {
+	return &digestRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *digestRepository) GetRecipients() ([]entities.DigestRecipient, error) {
+	var res []entities.DigestRecipient
+	query := r.db.Model(&entities.Users{}).Select("id, firstname, email, timezone, language")
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *digestRepository) GetRecipient(spenderId uint) (*entities.DigestRecipient, error) {
+	var res entities.DigestRecipient
+	query := r.db.Model(&entities.Users{}).Select("id, firstname, email, timezone, language").Where("id = ?", spenderId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *digestRepository) IsDelivered(spenderId uint, month string, channel string) (bool, error) {
+	var count int64
+	query := r.db.Model(&entities.DigestDelivery{}).Where("spender_id = ? AND month = ? AND channel = ?", spenderId, month, channel)
+	if err := query.Count(&count).Error; err != nil {
+		r.logger.Error(err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *digestRepository) SaveDelivery(req entities.DigestDelivery) error {
+	tx := r.db.Begin()
+	if err := tx.Create(&req).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
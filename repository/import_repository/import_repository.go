@@ -0,0 +1,249 @@
+package import_repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"time"
+)
+
+// insertBatchSize keeps each INSERT of an import well under Postgres's
+// limit of 65535 parameters.
+const insertBatchSize = 500
+
+var ErrAlreadyReconciled = errors.New("transaction is already reconciled")
+
+type IImportRepository interface {
+	CreateBatch(req entities.ImportBatch, txns []entities.Transaction, matches []entities.ImportMatch) (uint, error)
+	GetBatch(batchId uint) (*entities.ImportBatch, error)
+	GetBatches(spenderId uint) ([]entities.ImportBatch, error)
+	RollbackBatch(req entities.ImportBatch) (int64, []string, error)
+	GetFitIds(accountId uint, fitIds []string) ([]string, error)
+	GetUnreconciled(spenderId, accountId uint, from, to time.Time) ([]entities.Transaction, error)
+	GetExisting(spenderId uint, ids []uint, fitIds []string) ([]entities.Transaction, error)
+}
+
+type importRepository struct {
+	db          *gorm.DB
+	logger      echo.Logger
+	redisClient *redis.Client
+}
+
+func NewImportRepository(db *gorm.DB, logger echo.Logger, redisClient *redis.Client) IImportRepository {
+	return &importRepository{
+		db:          db,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+// CreateBatch saves a batch, all its transactions and the manual entries it
+// reconciled in one database transaction, so a failed import leaves nothing
+// behind. A manual entry reconciled by another import in the meantime fails
+// the whole batch with ErrAlreadyReconciled.
+func (r *importRepository) CreateBatch(req entities.ImportBatch, txns []entities.Transaction, matches []entities.ImportMatch) (uint, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&req).Error; err != nil {
+			return err
+		}
+		for _, match := range matches {
+			updates := map[string]interface{}{"fit_id": match.FitId}
+			if match.AccountSet {
+				updates["account_id"] = req.AccountId
+			}
+			result := tx.Model(&entities.Transaction{}).Where("id = ? AND fit_id = ''", match.TransactionId).Updates(updates)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrAlreadyReconciled
+			}
+		}
+		if len(matches) > 0 {
+			for i := range matches {
+				matches[i].ImportBatchId = req.ID
+			}
+			if err := tx.CreateInBatches(&matches, insertBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		for i := range txns {
+			txns[i].ImportBatchId = &req.ID
+		}
+		if len(txns) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&txns, insertBatchSize).Error
+	})
+	if err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	r.deleteTxnCache(req.SpenderId)
+	return req.ID, nil
+}
+
+func (r *importRepository) GetBatch(batchId uint) (*entities.ImportBatch, error) {
+	var res entities.ImportBatch
+	query := r.db.Model(&entities.ImportBatch{}).Where("id = ?", batchId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetBatches lists a spender's imports, newest first.
+func (r *importRepository) GetBatches(spenderId uint) ([]entities.ImportBatch, error) {
+	var res []entities.ImportBatch
+	query := r.db.Model(&entities.ImportBatch{}).Where("spender_id = ?", spenderId).Order("created_at DESC")
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// RollbackBatch deletes the transactions of a batch, with their items and
+// attachments, unlinks the manual entries it reconciled and saves the batch
+// as the caller marked it, all or nothing. It returns how many transactions
+// were deleted and the blob keys of their images and attachments, which the
+// caller deletes once the rows are gone.
+func (r *importRepository) RollbackBatch(req entities.ImportBatch) (int64, []string, error) {
+	var deleted int64
+	var txnIds []uint
+	var blobKeys []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var matches []entities.ImportMatch
+		if err := tx.Where("import_batch_id = ?", req.ID).Find(&matches).Error; err != nil {
+			return err
+		}
+		for _, match := range matches {
+			updates := map[string]interface{}{"fit_id": ""}
+			if match.AccountSet {
+				updates["account_id"] = nil
+			}
+			if err := tx.Model(&entities.Transaction{}).Where("id = ?", match.TransactionId).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("import_batch_id = ?", req.ID).Delete(&entities.ImportMatch{}).Error; err != nil {
+			return err
+		}
+
+		var txns []entities.Transaction
+		if err := tx.Select("id", "image_url", "thumbnail_url").Where("import_batch_id = ?", req.ID).Find(&txns).Error; err != nil {
+			return err
+		}
+		for _, txn := range txns {
+			txnIds = append(txnIds, txn.ID)
+			blobKeys = append(blobKeys, txn.ImageUrl, txn.ThumbnailUrl)
+		}
+		if len(txnIds) > 0 {
+			var attachmentKeys []string
+			attachments := tx.Unscoped().Model(&entities.Attachment{}).Where("transaction_id IN ?", txnIds)
+			if err := attachments.Pluck("blob_key", &attachmentKeys).Error; err != nil {
+				return err
+			}
+			blobKeys = append(blobKeys, attachmentKeys...)
+			if err := tx.Unscoped().Where("transaction_id IN ?", txnIds).Delete(&entities.Attachment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("transaction_id IN ?", txnIds).Delete(&entities.TransactionItem{}).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Where("import_batch_id = ?", req.ID).Delete(&entities.Transaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return tx.Save(&req).Error
+	})
+	if err != nil {
+		r.logger.Error(err)
+		return 0, nil, err
+	}
+
+	r.deleteTxnCache(req.SpenderId)
+	for _, txnId := range txnIds {
+		if err = r.redisClient.Del(context.Background(), fmt.Sprintf("get-attachments:%v", txnId)).Err(); err != nil {
+			r.logger.Error(err)
+		}
+	}
+	return deleted, blobKeys, nil
+}
+
+// GetFitIds returns which of fitIds the account already has.
+func (r *importRepository) GetFitIds(accountId uint, fitIds []string) ([]string, error) {
+	var res []string
+	if len(fitIds) == 0 {
+		return res, nil
+	}
+	query := r.db.Model(&entities.Transaction{}).Where("account_id = ? AND fit_id IN ?", accountId, fitIds)
+	if err := query.Pluck("fit_id", &res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetUnreconciled returns the spender's entries between from and to that no
+// statement has claimed: not imported, without a FITID, and on no account or
+// on the given one.
+func (r *importRepository) GetUnreconciled(spenderId, accountId uint, from, to time.Time) ([]entities.Transaction, error) {
+	var res []entities.Transaction
+	query := r.db.Model(&entities.Transaction{}).
+		Where("spender_id = ? AND import_batch_id IS NULL AND fit_id = ''", spenderId).
+		Where("account_id IS NULL OR account_id = ?", accountId).
+		Where("date BETWEEN ? AND ?", from, to).
+		Order("date")
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// deleteTxnCache drops the cached transaction lists of the spender. The
+// database is already committed, so a failure is only logged.
+func (r *importRepository) deleteTxnCache(spenderId int) {
+	var keys []string
+	keys = append(keys, "get-all-txn")
+	keys = append(keys, fmt.Sprintf("get-all-spender:%v", spenderId))
+	keys = append(keys, fmt.Sprintf("get-by-txn-type:%v", spenderId))
+
+	for _, key := range keys {
+		if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+			r.logger.Error(err)
+		}
+	}
+}
+
+// GetExisting returns the spender's transactions whose ID is one of ids or
+// whose FITID is one of fitIds, on any account.
+func (r *importRepository) GetExisting(spenderId uint, ids []uint, fitIds []string) ([]entities.Transaction, error) {
+	var res []entities.Transaction
+	if len(ids) == 0 && len(fitIds) == 0 {
+		return res, nil
+	}
+	query := r.db.Model(&entities.Transaction{}).Where("spender_id = ?", spenderId)
+	switch {
+	case len(ids) == 0:
+		query = query.Where("fit_id IN ?", fitIds)
+	case len(fitIds) == 0:
+		query = query.Where("id IN ?", ids)
+	default:
+		query = query.Where("id IN ? OR fit_id IN ?", ids, fitIds)
+	}
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
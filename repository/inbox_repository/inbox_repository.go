@@ -0,0 +1,114 @@
+package inbox_repository
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IInboxRepository interface {
+	GetToken(spenderId uint) (*entities.InboxToken, error)
+	GetTokenByValue(token string) (*entities.InboxToken, error)
+	SaveToken(req entities.InboxToken) error
+	CreateEmail(req entities.InboundEmail) (uint, error)
+	GetEmail(emailId uint) (*entities.InboundEmail, error)
+	GetEmailByMessageId(spenderId uint, messageId string) (*entities.InboundEmail, error)
+	GetEmailsByStatus(spenderId uint, status string) ([]entities.InboundEmail, error)
+	UpdateEmail(req entities.InboundEmail) error
+}
+
+type inboxRepository struct {
+	db     *gorm.DB
+	logger echo.Logger
+}
+
+func NewInboxRepository(db *gorm.DB, logger echo.Logger) IInboxRepository {
+	return &inboxRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *inboxRepository) GetToken(spenderId uint) (*entities.InboxToken, error) {
+	var res entities.InboxToken
+	query := r.db.Model(&entities.InboxToken{}).Where("spender_id = ?", spenderId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *inboxRepository) GetTokenByValue(token string) (*entities.InboxToken, error) {
+	var res entities.InboxToken
+	query := r.db.Model(&entities.InboxToken{}).Where("token = ?", token)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+// SaveToken sets the spender's token, replacing the one they had so the old
+// address stops working.
+func (r *inboxRepository) SaveToken(req entities.InboxToken) error {
+	query := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "spender_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token", "updated_at"}),
+	})
+	if err := query.Create(&req).Error; err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (r *inboxRepository) CreateEmail(req entities.InboundEmail) (uint, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+	return req.ID, nil
+}
+
+func (r *inboxRepository) GetEmail(emailId uint) (*entities.InboundEmail, error) {
+	var res entities.InboundEmail
+	query := r.db.Model(&entities.InboundEmail{}).Where("id = ?", emailId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *inboxRepository) GetEmailByMessageId(spenderId uint, messageId string) (*entities.InboundEmail, error) {
+	var res entities.InboundEmail
+	query := r.db.Model(&entities.InboundEmail{}).Where("spender_id = ? AND message_id = ?", spenderId, messageId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetEmailsByStatus lists a spender's emails in status, newest first.
+func (r *inboxRepository) GetEmailsByStatus(spenderId uint, status string) ([]entities.InboundEmail, error) {
+	var res []entities.InboundEmail
+	query := r.db.Model(&entities.InboundEmail{}).
+		Where("spender_id = ? AND status = ?", spenderId, status).
+		Order("received_at DESC")
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *inboxRepository) UpdateEmail(req entities.InboundEmail) error {
+	if err := r.db.Save(&req).Error; err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	return nil
+}
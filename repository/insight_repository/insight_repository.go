@@ -0,0 +1,97 @@
+package insight_repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"time"
+)
+
+type IInsightRepository interface {
+	GetAnomaliesBySpenderId(spenderId uint) ([]entities.TransactionAnomaly, error)
+	GetMonthlyAnomaly(spenderId uint, category, month string) (*entities.TransactionAnomaly, error)
+	SaveAnomaly(req entities.TransactionAnomaly) (uint, error)
+}
+
+type insightRepository struct {
+	db          *gorm.DB
+	logger      echo.Logger
+	redisClient *redis.Client
+}
+
+func NewInsightRepository(db *gorm.DB, logger echo.Logger, redisClient *redis.Client) IInsightRepository {
+	return &insightRepository{
+		db:          db,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+func (r *insightRepository) GetAnomaliesBySpenderId(spenderId uint) ([]entities.TransactionAnomaly, error) {
+	var res []entities.TransactionAnomaly
+	key := fmt.Sprintf("get-anomalies:%v", spenderId)
+	anomalyCache, err := r.redisClient.Get(context.Background(), key).Result()
+	if err == nil && anomalyCache != "" {
+		err = json.Unmarshal([]byte(anomalyCache), &res)
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	query := r.db.Model(&entities.TransactionAnomaly{}).Where("spender_id = ?", spenderId).Order("created_at DESC")
+	err = query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	cache, err := json.Marshal(res)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	err = r.redisClient.Set(context.Background(), key, string(cache), time.Hour*1).Err()
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *insightRepository) GetMonthlyAnomaly(spenderId uint, category, month string) (*entities.TransactionAnomaly, error) {
+	var res entities.TransactionAnomaly
+	query := r.db.Model(&entities.TransactionAnomaly{}).
+		Where("spender_id = ? AND kind = ? AND category = ? AND month = ?", spenderId, "category_month", category, month)
+	err := query.First(&res).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			r.logger.Error(err)
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *insightRepository) SaveAnomaly(req entities.TransactionAnomaly) (uint, error) {
+	tx := r.db.Begin()
+	if err := tx.Save(&req).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	anomalyId := req.ID
+	key := fmt.Sprintf("get-anomalies:%v", req.SpenderId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+	return anomalyId, tx.Commit().Error
+}
@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type AccountRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *AccountRepositoryMock) GetAccounts(spenderId uint) ([]entities.Account, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.Account), args.Error(1)
+}
+
+func (m *AccountRepositoryMock) GetAccount(accountId uint) (*entities.Account, error) {
+	args := m.Called(accountId)
+	return args.Get(0).(*entities.Account), args.Error(1)
+}
+
+func (m *AccountRepositoryMock) SaveAccount(req entities.Account) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *AccountRepositoryMock) DeleteAccount(accountId uint) error {
+	args := m.Called(accountId)
+	return args.Error(0)
+}
@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type AttachmentRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *AttachmentRepositoryMock) GetByTransactionId(txnId uint) ([]entities.Attachment, error) {
+	args := m.Called(txnId)
+	return args.Get(0).([]entities.Attachment), args.Error(1)
+}
+
+func (m *AttachmentRepositoryMock) GetAttachment(attachmentId uint) (*entities.Attachment, error) {
+	args := m.Called(attachmentId)
+	return args.Get(0).(*entities.Attachment), args.Error(1)
+}
+
+func (m *AttachmentRepositoryMock) SaveAttachment(req entities.Attachment) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *AttachmentRepositoryMock) DeleteAttachment(txnId uint, attachmentId uint) error {
+	args := m.Called(txnId, attachmentId)
+	return args.Error(0)
+}
+
+func (m *AttachmentRepositoryMock) DeleteByTransactionId(txnId uint) error {
+	args := m.Called(txnId)
+	return args.Error(0)
+}
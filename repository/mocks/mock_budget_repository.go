@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type BudgetRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *BudgetRepositoryMock) GetBySpenderId(spenderId uint) ([]entities.Budget, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.Budget), args.Error(1)
+}
+
+func (m *BudgetRepositoryMock) SaveBudget(req entities.Budget) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *BudgetRepositoryMock) DeleteBudget(spenderId uint, budgetId uint) error {
+	args := m.Called(spenderId, budgetId)
+	return args.Error(0)
+}
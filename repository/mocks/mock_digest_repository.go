@@ -0,0 +1,30 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type DigestRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *DigestRepositoryMock) GetRecipients() ([]entities.DigestRecipient, error) {
+	args := m.Called()
+	return args.Get(0).([]entities.DigestRecipient), args.Error(1)
+}
+
+func (m *DigestRepositoryMock) GetRecipient(spenderId uint) (*entities.DigestRecipient, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).(*entities.DigestRecipient), args.Error(1)
+}
+
+func (m *DigestRepositoryMock) IsDelivered(spenderId uint, month string, channel string) (bool, error) {
+	args := m.Called(spenderId, month, channel)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *DigestRepositoryMock) SaveDelivery(req entities.DigestDelivery) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
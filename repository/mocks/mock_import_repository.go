@@ -0,0 +1,46 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type ImportRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ImportRepositoryMock) CreateBatch(req entities.ImportBatch, txns []entities.Transaction, matches []entities.ImportMatch) (uint, error) {
+	args := m.Called(req, txns, matches)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *ImportRepositoryMock) GetBatch(batchId uint) (*entities.ImportBatch, error) {
+	args := m.Called(batchId)
+	return args.Get(0).(*entities.ImportBatch), args.Error(1)
+}
+
+func (m *ImportRepositoryMock) GetBatches(spenderId uint) ([]entities.ImportBatch, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.ImportBatch), args.Error(1)
+}
+
+func (m *ImportRepositoryMock) RollbackBatch(req entities.ImportBatch) (int64, []string, error) {
+	args := m.Called(req)
+	return args.Get(0).(int64), args.Get(1).([]string), args.Error(2)
+}
+
+func (m *ImportRepositoryMock) GetFitIds(accountId uint, fitIds []string) ([]string, error) {
+	args := m.Called(accountId, fitIds)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *ImportRepositoryMock) GetUnreconciled(spenderId, accountId uint, from, to time.Time) ([]entities.Transaction, error) {
+	args := m.Called(spenderId, accountId, from, to)
+	return args.Get(0).([]entities.Transaction), args.Error(1)
+}
+
+func (m *ImportRepositoryMock) GetExisting(spenderId uint, ids []uint, fitIds []string) ([]entities.Transaction, error) {
+	args := m.Called(spenderId, ids, fitIds)
+	return args.Get(0).([]entities.Transaction), args.Error(1)
+}
@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type InboxRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *InboxRepositoryMock) GetToken(spenderId uint) (*entities.InboxToken, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).(*entities.InboxToken), args.Error(1)
+}
+
+func (m *InboxRepositoryMock) GetTokenByValue(token string) (*entities.InboxToken, error) {
+	args := m.Called(token)
+	return args.Get(0).(*entities.InboxToken), args.Error(1)
+}
+
+func (m *InboxRepositoryMock) SaveToken(req entities.InboxToken) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func (m *InboxRepositoryMock) CreateEmail(req entities.InboundEmail) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *InboxRepositoryMock) GetEmail(emailId uint) (*entities.InboundEmail, error) {
+	args := m.Called(emailId)
+	return args.Get(0).(*entities.InboundEmail), args.Error(1)
+}
+
+func (m *InboxRepositoryMock) GetEmailByMessageId(spenderId uint, messageId string) (*entities.InboundEmail, error) {
+	args := m.Called(spenderId, messageId)
+	return args.Get(0).(*entities.InboundEmail), args.Error(1)
+}
+
+func (m *InboxRepositoryMock) GetEmailsByStatus(spenderId uint, status string) ([]entities.InboundEmail, error) {
+	args := m.Called(spenderId, status)
+	return args.Get(0).([]entities.InboundEmail), args.Error(1)
+}
+
+func (m *InboxRepositoryMock) UpdateEmail(req entities.InboundEmail) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
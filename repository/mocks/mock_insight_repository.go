@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type InsightRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *InsightRepositoryMock) GetAnomaliesBySpenderId(spenderId uint) ([]entities.TransactionAnomaly, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.TransactionAnomaly), args.Error(1)
+}
+
+func (m *InsightRepositoryMock) GetMonthlyAnomaly(spenderId uint, category, month string) (*entities.TransactionAnomaly, error) {
+	args := m.Called(spenderId, category, month)
+	return args.Get(0).(*entities.TransactionAnomaly), args.Error(1)
+}
+
+func (m *InsightRepositoryMock) SaveAnomaly(req entities.TransactionAnomaly) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
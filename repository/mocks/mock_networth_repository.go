@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type NetWorthRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *NetWorthRepositoryMock) GetSpenderIds() ([]uint, error) {
+	args := m.Called()
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *NetWorthRepositoryMock) GetItemsBySpenderId(spenderId uint) ([]entities.NetWorthItem, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.NetWorthItem), args.Error(1)
+}
+
+func (m *NetWorthRepositoryMock) SaveItem(req entities.NetWorthItem) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *NetWorthRepositoryMock) DeleteItem(spenderId uint, itemId uint) error {
+	args := m.Called(spenderId, itemId)
+	return args.Error(0)
+}
+
+func (m *NetWorthRepositoryMock) GetSnapshots(spenderId uint, filter entities.PeriodFilter) ([]entities.NetWorthSnapshot, error) {
+	args := m.Called(spenderId, filter)
+	return args.Get(0).([]entities.NetWorthSnapshot), args.Error(1)
+}
+
+func (m *NetWorthRepositoryMock) SaveSnapshots(req []entities.NetWorthSnapshot) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
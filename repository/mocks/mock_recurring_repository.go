@@ -0,0 +1,40 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+)
+
+type RecurringRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *RecurringRepositoryMock) GetBySpenderId(spenderId uint) ([]entities.RecurringRule, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.RecurringRule), args.Error(1)
+}
+
+func (m *RecurringRepositoryMock) SaveRule(req entities.RecurringRule) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *RecurringRepositoryMock) DeleteRule(spenderId uint, ruleId uint) error {
+	args := m.Called(spenderId, ruleId)
+	return args.Error(0)
+}
+
+func (m *RecurringRepositoryMock) GetSubscriptionsBySpenderId(spenderId uint) ([]entities.DetectedSubscription, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.DetectedSubscription), args.Error(1)
+}
+
+func (m *RecurringRepositoryMock) GetSubscription(spenderId uint, subscriptionId uint) (*entities.DetectedSubscription, error) {
+	args := m.Called(spenderId, subscriptionId)
+	return args.Get(0).(*entities.DetectedSubscription), args.Error(1)
+}
+
+func (m *RecurringRepositoryMock) SaveSubscription(req entities.DetectedSubscription) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
@@ -0,0 +1,51 @@
+package mocks
+
+import (
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+type SlipJobRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *SlipJobRepositoryMock) CreateJob(req entities.SlipJob) (uint, error) {
+	args := m.Called(req)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *SlipJobRepositoryMock) GetJob(jobId uint) (*entities.SlipJob, error) {
+	args := m.Called(jobId)
+	return args.Get(0).(*entities.SlipJob), args.Error(1)
+}
+
+func (m *SlipJobRepositoryMock) GetJobsByStatus(spenderId uint, status string) ([]entities.SlipJob, error) {
+	args := m.Called(spenderId, status)
+	return args.Get(0).([]entities.SlipJob), args.Error(1)
+}
+
+func (m *SlipJobRepositoryMock) ClaimJob(states []string, now time.Time, lease time.Duration) (*entities.SlipJob, error) {
+	args := m.Called(states, now, lease)
+	return args.Get(0).(*entities.SlipJob), args.Error(1)
+}
+
+func (m *SlipJobRepositoryMock) UpdateJob(req entities.SlipJob) error {
+	args := m.Called(req)
+	return args.Error(0)
+}
+
+func (m *SlipJobRepositoryMock) CreateBatch(req entities.SlipBatch, jobs []entities.SlipJob) (uint, []uint, error) {
+	args := m.Called(req, jobs)
+	return args.Get(0).(uint), args.Get(1).([]uint), args.Error(2)
+}
+
+func (m *SlipJobRepositoryMock) GetBatch(batchId uint) (*entities.SlipBatch, error) {
+	args := m.Called(batchId)
+	return args.Get(0).(*entities.SlipBatch), args.Error(1)
+}
+
+func (m *SlipJobRepositoryMock) GetJobsByBatch(batchId uint) ([]entities.SlipJob, error) {
+	args := m.Called(batchId)
+	return args.Get(0).([]entities.SlipJob), args.Error(1)
+}
@@ -3,6 +3,7 @@ package mocks
 import (
 	"github.com/Montheankul-K/jod-jod/domains/entities"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
 type TransactionRepositoryMock struct {
@@ -34,6 +35,26 @@ func (m *TransactionRepositoryMock) GetByPeriod(req entities.GetByTxnTypeRequest
 	return args.Get(0).([]entities.GetAllByTxnTypeResponse), args.Error(1)
 }
 
+func (m *TransactionRepositoryMock) GetTxn(txnId uint) (*entities.Transaction, error) {
+	args := m.Called(txnId)
+	return args.Get(0).(*entities.Transaction), args.Error(1)
+}
+
+func (m *TransactionRepositoryMock) GetByTransRef(spenderId uint, transRef string) (*entities.Transaction, error) {
+	args := m.Called(spenderId, transRef)
+	return args.Get(0).(*entities.Transaction), args.Error(1)
+}
+
+func (m *TransactionRepositoryMock) GetSlipHashes(spenderId uint) ([]entities.SlipHash, error) {
+	args := m.Called(spenderId)
+	return args.Get(0).([]entities.SlipHash), args.Error(1)
+}
+
+func (m *TransactionRepositoryMock) GetSimilar(spenderId uint, category, txnType string, from, to time.Time) ([]entities.Transaction, error) {
+	args := m.Called(spenderId, category, txnType, from, to)
+	return args.Get(0).([]entities.Transaction), args.Error(1)
+}
+
 func (m *TransactionRepositoryMock) SaveTxn(req entities.Transaction) (uint, error) {
 	args := m.Called(req)
 	return args.Get(0).(uint), args.Error(1)
@@ -48,3 +69,30 @@ func (m *TransactionRepositoryMock) DeleteTxn(spenderId uint, txnId uint) error
 	args := m.Called(spenderId, txnId)
 	return args.Error(0)
 }
+
+func (m *TransactionRepositoryMock) SaveTxnWithItems(req entities.Transaction, items []entities.TransactionItem) (uint, error) {
+	args := m.Called(req, items)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+func (m *TransactionRepositoryMock) GetItems(txnId uint) ([]entities.TransactionItem, error) {
+	args := m.Called(txnId)
+	return args.Get(0).([]entities.TransactionItem), args.Error(1)
+}
+
+func (m *TransactionRepositoryMock) UpdateItemCategory(txnId, itemId uint, category string) error {
+	args := m.Called(txnId, itemId, category)
+	return args.Error(0)
+}
+
+// StreamTxns calls fn with each transaction given to Return, then returns
+// the error given after them.
+func (m *TransactionRepositoryMock) StreamTxns(spenderId uint, filter entities.GetAllTxnFilter, fn func(entities.ExportTxn) error) error {
+	args := m.Called(spenderId, filter, fn)
+	for _, txn := range args.Get(0).([]entities.ExportTxn) {
+		if err := fn(txn); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
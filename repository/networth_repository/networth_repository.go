@@ -0,0 +1,151 @@
+package networth_repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type INetWorthRepository interface {
+	GetSpenderIds() ([]uint, error)
+	GetItemsBySpenderId(spenderId uint) ([]entities.NetWorthItem, error)
+	SaveItem(req entities.NetWorthItem) (uint, error)
+	DeleteItem(spenderId uint, itemId uint) error
+	GetSnapshots(spenderId uint, filter entities.PeriodFilter) ([]entities.NetWorthSnapshot, error)
+	SaveSnapshots(req []entities.NetWorthSnapshot) error
+}
+
+type netWorthRepository struct {
+	db          *gorm.DB
+	logger      echo.Logger
+	redisClient *redis.Client
+}
+
+func NewNetWorthRepository(db *gorm.DB, logger echo.Logger, redisClient *redis.Client) INetWorthRepository {
+	return &netWorthRepository{
+		db:          db,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+func (r *netWorthRepository) GetSpenderIds() ([]uint, error) {
+	var res []uint
+	err := r.db.Model(&entities.Users{}).Pluck("id", &res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *netWorthRepository) GetItemsBySpenderId(spenderId uint) ([]entities.NetWorthItem, error) {
+	var res []entities.NetWorthItem
+	key := fmt.Sprintf("get-net-worth-items:%v", spenderId)
+	itemCache, err := r.redisClient.Get(context.Background(), key).Result()
+	if err == nil && itemCache != "" {
+		err = json.Unmarshal([]byte(itemCache), &res)
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	query := r.db.Model(&entities.NetWorthItem{}).Where("spender_id = ?", spenderId)
+	err = query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	cache, err := json.Marshal(res)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	err = r.redisClient.Set(context.Background(), key, string(cache), time.Hour*1).Err()
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *netWorthRepository) SaveItem(req entities.NetWorthItem) (uint, error) {
+	tx := r.db.Begin()
+	if err := tx.Create(&req).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	itemId := req.ID
+	key := fmt.Sprintf("get-net-worth-items:%v", req.SpenderId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+	return itemId, tx.Commit().Error
+}
+
+func (r *netWorthRepository) DeleteItem(spenderId uint, itemId uint) error {
+	tx := r.db.Begin()
+	query := tx.Model(&entities.NetWorthItem{}).Where("id = ? AND spender_id = ?", itemId, spenderId)
+	if err := query.Delete(&entities.NetWorthItem{}).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+
+	key := fmt.Sprintf("get-net-worth-items:%v", spenderId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (r *netWorthRepository) GetSnapshots(spenderId uint, filter entities.PeriodFilter) ([]entities.NetWorthSnapshot, error) {
+	var res []entities.NetWorthSnapshot
+	query := r.db.Model(&entities.NetWorthSnapshot{}).Where("spender_id = ?", spenderId)
+	if filter.StartDate != nil && !filter.StartDate.IsZero() {
+		query = query.Where("date >= ?", filter.StartDate)
+	}
+	if filter.EndDate != nil && !filter.EndDate.IsZero() {
+		query = query.Where("date <= ?", filter.EndDate)
+	}
+	err := query.Order("date").Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// SaveSnapshots upserts on (spender_id, date) so recomputing a day replaces
+// the figures recorded for it instead of adding a second row.
+func (r *netWorthRepository) SaveSnapshots(req []entities.NetWorthSnapshot) error {
+	if len(req) == 0 {
+		return nil
+	}
+
+	tx := r.db.Begin()
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "spender_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cash_balance", "assets", "liabilities", "net_worth", "updated_at"}),
+	}).CreateInBatches(&req, 500).Error
+	if err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
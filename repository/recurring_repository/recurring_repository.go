@@ -0,0 +1,135 @@
+package recurring_repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/go-redis/redis/v8"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"time"
+)
+
+type IRecurringRepository interface {
+	GetBySpenderId(spenderId uint) ([]entities.RecurringRule, error)
+	SaveRule(req entities.RecurringRule) (uint, error)
+	DeleteRule(spenderId uint, ruleId uint) error
+	GetSubscriptionsBySpenderId(spenderId uint) ([]entities.DetectedSubscription, error)
+	GetSubscription(spenderId uint, subscriptionId uint) (*entities.DetectedSubscription, error)
+	SaveSubscription(req entities.DetectedSubscription) (uint, error)
+}
+
+type recurringRepository struct {
+	db          *gorm.DB
+	logger      echo.Logger
+	redisClient *redis.Client
+}
+
+func NewRecurringRepository(db *gorm.DB, logger echo.Logger, redisClient *redis.Client) IRecurringRepository {
+	return &recurringRepository{
+		db:          db,
+		logger:      logger,
+		redisClient: redisClient,
+	}
+}
+
+func (r *recurringRepository) GetBySpenderId(spenderId uint) ([]entities.RecurringRule, error) {
+	var res []entities.RecurringRule
+	key := fmt.Sprintf("get-recurring:%v", spenderId)
+	ruleCache, err := r.redisClient.Get(context.Background(), key).Result()
+	if err == nil && ruleCache != "" {
+		err = json.Unmarshal([]byte(ruleCache), &res)
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	query := r.db.Model(&entities.RecurringRule{}).Where("spender_id = ?", spenderId).Order("next_date")
+	err = query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	cache, err := json.Marshal(res)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	err = r.redisClient.Set(context.Background(), key, string(cache), time.Hour*1).Err()
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *recurringRepository) SaveRule(req entities.RecurringRule) (uint, error) {
+	tx := r.db.Begin()
+	if err := tx.Create(&req).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	ruleId := req.ID
+	key := fmt.Sprintf("get-recurring:%v", req.SpenderId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+	return ruleId, tx.Commit().Error
+}
+
+func (r *recurringRepository) DeleteRule(spenderId uint, ruleId uint) error {
+	tx := r.db.Begin()
+	query := tx.Model(&entities.RecurringRule{}).Where("id = ? AND spender_id = ?", ruleId, spenderId)
+	if err := query.Delete(&entities.RecurringRule{}).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+
+	key := fmt.Sprintf("get-recurring:%v", spenderId)
+	if err := r.redisClient.Del(context.Background(), key).Err(); err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (r *recurringRepository) GetSubscriptionsBySpenderId(spenderId uint) ([]entities.DetectedSubscription, error) {
+	var res []entities.DetectedSubscription
+	query := r.db.Model(&entities.DetectedSubscription{}).Where("spender_id = ?", spenderId).Order("next_date")
+	err := query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *recurringRepository) GetSubscription(spenderId uint, subscriptionId uint) (*entities.DetectedSubscription, error) {
+	var res entities.DetectedSubscription
+	query := r.db.Model(&entities.DetectedSubscription{}).Where("id = ? AND spender_id = ?", subscriptionId, spenderId)
+	err := query.First(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *recurringRepository) SaveSubscription(req entities.DetectedSubscription) (uint, error) {
+	tx := r.db.Begin()
+	if err := tx.Save(&req).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return 0, err
+	}
+	return req.ID, tx.Commit().Error
+}
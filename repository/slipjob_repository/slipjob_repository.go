@@ -0,0 +1,154 @@
+package slipjob_repository
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/entities"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ISlipJobRepository interface {
+	CreateJob(req entities.SlipJob) (uint, error)
+	GetJob(jobId uint) (*entities.SlipJob, error)
+	GetJobsByStatus(spenderId uint, status string) ([]entities.SlipJob, error)
+	ClaimJob(states []string, now time.Time, lease time.Duration) (*entities.SlipJob, error)
+	UpdateJob(req entities.SlipJob) error
+	CreateBatch(req entities.SlipBatch, jobs []entities.SlipJob) (uint, []uint, error)
+	GetBatch(batchId uint) (*entities.SlipBatch, error)
+	GetJobsByBatch(batchId uint) ([]entities.SlipJob, error)
+}
+
+type slipJobRepository struct {
+	db     *gorm.DB
+	logger echo.Logger
+}
+
+func NewSlipJobRepository(db *gorm.DB, logger echo.Logger) ISlipJobRepository {
+	return &slipJobRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *slipJobRepository) CreateJob(req entities.SlipJob) (uint, error) {
+	if err := r.db.Create(&req).Error; err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+	return req.ID, nil
+}
+
+func (r *slipJobRepository) GetJob(jobId uint) (*entities.SlipJob, error) {
+	var res entities.SlipJob
+	query := r.db.Model(&entities.SlipJob{}).Where("id = ?", jobId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetJobsByStatus lists a spender's jobs in status, oldest first. Images are
+// left out since a list never shows them.
+func (r *slipJobRepository) GetJobsByStatus(spenderId uint, status string) ([]entities.SlipJob, error) {
+	var res []entities.SlipJob
+	query := r.db.Model(&entities.SlipJob{}).Omit("image").
+		Where("spender_id = ? AND status = ?", spenderId, status).
+		Order("created_at")
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// ClaimJob leases the oldest due job in one of states to the caller and
+// returns nil when none is due. A job stays leased until its lease runs out,
+// so one abandoned by a crashed worker is picked up again. SKIP LOCKED lets
+// workers in one process or many claim different rows at once.
+func (r *slipJobRepository) ClaimJob(states []string, now time.Time, lease time.Duration) (*entities.SlipJob, error) {
+	var res entities.SlipJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_run_at <= ?", states, now).
+			Where("locked_until IS NULL OR locked_until < ?", now).
+			Order("next_run_at").
+			Limit(1)
+		if err := query.Find(&res).Error; err != nil {
+			return err
+		}
+		if res.ID == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		lockedUntil := now.Add(lease)
+		res.LockedUntil = &lockedUntil
+		res.Attempts++
+		return tx.Model(&entities.SlipJob{}).Where("id = ?", res.ID).Updates(map[string]interface{}{
+			"locked_until": lockedUntil,
+			"attempts":     res.Attempts,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *slipJobRepository) UpdateJob(req entities.SlipJob) error {
+	if err := r.db.Save(&req).Error; err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+// CreateBatch saves a batch and its jobs together, so workers never see part
+// of a batch.
+func (r *slipJobRepository) CreateBatch(req entities.SlipBatch, jobs []entities.SlipJob) (uint, []uint, error) {
+	jobIds := make([]uint, 0, len(jobs))
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&req).Error; err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			job.BatchId = &req.ID
+			if err := tx.Create(&job).Error; err != nil {
+				return err
+			}
+			jobIds = append(jobIds, job.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		r.logger.Error(err)
+		return 0, nil, err
+	}
+	return req.ID, jobIds, nil
+}
+
+func (r *slipJobRepository) GetBatch(batchId uint) (*entities.SlipBatch, error) {
+	var res entities.SlipBatch
+	query := r.db.Model(&entities.SlipBatch{}).Where("id = ?", batchId)
+	if err := query.First(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *slipJobRepository) GetJobsByBatch(batchId uint) ([]entities.SlipJob, error) {
+	var res []entities.SlipJob
+	query := r.db.Model(&entities.SlipJob{}).Omit("image").Where("batch_id = ?", batchId).Order("id")
+	if err := query.Find(&res).Error; err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
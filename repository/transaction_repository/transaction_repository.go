@@ -17,7 +17,15 @@ type ITransactionRepository interface {
 	GetByTxnType(req entities.GetByTxnTypeRequest) ([]entities.GetAllByTxnTypeResponse, error)
 	GetByCategory(req entities.GetByCategoryRequest) ([]entities.GetByCategoryResponse, error)
 	GetByPeriod(req entities.GetByTxnTypeRequest, filter entities.PeriodFilter) ([]entities.GetAllByTxnTypeResponse, error)
+	GetTxn(txnId uint) (*entities.Transaction, error)
+	GetByTransRef(spenderId uint, transRef string) (*entities.Transaction, error)
+	GetSlipHashes(spenderId uint) ([]entities.SlipHash, error)
+	GetSimilar(spenderId uint, category, txnType string, from, to time.Time) ([]entities.Transaction, error)
+	StreamTxns(spenderId uint, filter entities.GetAllTxnFilter, fn func(entities.ExportTxn) error) error
 	SaveTxn(req entities.Transaction) (uint, error)
+	SaveTxnWithItems(req entities.Transaction, items []entities.TransactionItem) (uint, error)
+	GetItems(txnId uint) ([]entities.TransactionItem, error)
+	UpdateItemCategory(txnId, itemId uint, category string) error
 	UpdateTxn(txnId uint, req entities.Transaction) error
 	DeleteTxn(spenderId uint, txnId uint) error
 }
@@ -182,6 +190,93 @@ func (r *transactionRepository) GetByPeriod(req entities.GetByTxnTypeRequest, fi
 	return res, nil
 }
 
+func (r *transactionRepository) GetTxn(txnId uint) (*entities.Transaction, error) {
+	var res entities.Transaction
+	query := r.db.Model(&entities.Transaction{}).Where("id = ?", txnId)
+	err := query.First(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *transactionRepository) GetByTransRef(spenderId uint, transRef string) (*entities.Transaction, error) {
+	var res entities.Transaction
+	query := r.db.Model(&entities.Transaction{}).Where("spender_id = ? AND trans_ref = ?", spenderId, transRef)
+	err := query.First(&res).Error
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (r *transactionRepository) GetSlipHashes(spenderId uint) ([]entities.SlipHash, error) {
+	var res []entities.SlipHash
+	query := r.db.Model(&entities.Transaction{}).Where("spender_id = ? AND image_hash <> ''", spenderId)
+	err := query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+// StreamTxns calls fn with each of the spender's transactions that match
+// filter, oldest first, with the account each is on. Rows are read one at a
+// time so an export of years of history does not sit in memory. An error from
+// fn stops the stream.
+func (r *transactionRepository) StreamTxns(spenderId uint, filter entities.GetAllTxnFilter, fn func(entities.ExportTxn) error) error {
+	query := r.db.Model(&entities.Transaction{}).
+		Select("transactions.*, accounts.name AS account_name, accounts.kind AS account_kind, accounts.currency AS account_currency").
+		Joins("LEFT JOIN accounts ON accounts.id = transactions.account_id AND accounts.deleted_at IS NULL").
+		Where("transactions.spender_id = ?", spenderId)
+	if filter.Date != nil && !filter.Date.IsZero() {
+		query = query.Where("transactions.date >= ? AND transactions.date < ?", filter.Date, filter.Date.AddDate(0, 0, 1))
+	}
+	if filter.Category != "" {
+		query = query.Where("transactions.category = ?", filter.Category)
+	}
+	if filter.TxnType != "" {
+		query = query.Where("transactions.transaction_type = ?", filter.TxnType)
+	}
+
+	rows, err := query.Order("transactions.date, transactions.id").Rows()
+	if err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var txn entities.ExportTxn
+		if err = r.db.ScanRows(rows, &txn); err != nil {
+			r.logger.Error(err)
+			return err
+		}
+		if err = fn(txn); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error(err)
+		return err
+	}
+	return nil
+}
+
+func (r *transactionRepository) GetSimilar(spenderId uint, category, txnType string, from, to time.Time) ([]entities.Transaction, error) {
+	var res []entities.Transaction
+	query := r.db.Model(&entities.Transaction{}).Where("spender_id = ? AND LOWER(category) = LOWER(?) AND LOWER(transaction_type) = LOWER(?)", spenderId, category, txnType)
+	query = query.Where("date BETWEEN ? AND ?", from, to)
+	err := query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
 func (r *transactionRepository) SaveTxn(req entities.Transaction) (uint, error) {
 	tx := r.db.Begin()
 	result := r.db.Create(&req)
@@ -208,6 +303,94 @@ func (r *transactionRepository) SaveTxn(req entities.Transaction) (uint, error)
 	return txnId, tx.Commit().Error
 }
 
+// SaveTxnWithItems saves a transaction and its line items together, so a
+// receipt is never left half saved.
+func (r *transactionRepository) SaveTxnWithItems(req entities.Transaction, items []entities.TransactionItem) (uint, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&req).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].TransactionId = req.ID
+			items[i].SpenderId = req.SpenderId
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		r.logger.Error(err)
+		return 0, err
+	}
+
+	var keys []string
+	keys = append(keys, "get-all-txn")
+	keys = append(keys, fmt.Sprintf("get-all-spender:%v", req.SpenderId))
+	keys = append(keys, fmt.Sprintf("get-by-txn-type:%v", req.SpenderId))
+
+	for _, key := range keys {
+		if err = r.redisClient.Del(context.Background(), key).Err(); err != nil {
+			r.logger.Error(err)
+		}
+	}
+	return req.ID, nil
+}
+
+func (r *transactionRepository) GetItems(txnId uint) ([]entities.TransactionItem, error) {
+	var res []entities.TransactionItem
+	key := fmt.Sprintf("get-txn-items:%v", txnId)
+	itemCache, err := r.redisClient.Get(context.Background(), key).Result()
+	if err == nil && itemCache != "" {
+		err = json.Unmarshal([]byte(itemCache), &res)
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	query := r.db.Model(&entities.TransactionItem{}).Where("transaction_id = ?", txnId).Order("id")
+	err = query.Find(&res).Error
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	cache, err := json.Marshal(res)
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+
+	err = r.redisClient.Set(context.Background(), key, string(cache), time.Hour*1).Err()
+	if err != nil {
+		r.logger.Error(err)
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *transactionRepository) UpdateItemCategory(txnId, itemId uint, category string) error {
+	tx := r.db.Begin()
+	result := tx.Model(&entities.TransactionItem{}).Where("id = ? AND transaction_id = ?", itemId, txnId).Update("category", category)
+	if err := result.Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+
+	err := r.redisClient.Del(context.Background(), fmt.Sprintf("get-txn-items:%v", txnId)).Err()
+	if err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
+	return tx.Commit().Error
+}
+
 func (r *transactionRepository) UpdateTxn(txnId uint, req entities.Transaction) error {
 	var existingTxn entities.Transaction
 	tx := r.db.Begin()
@@ -218,6 +401,10 @@ func (r *transactionRepository) UpdateTxn(txnId uint, req entities.Transaction)
 		return err
 	}
 
+	if !req.Date.IsZero() {
+		existingTxn.Date = req.Date
+	}
+
 	if req.Amount != 0 {
 		existingTxn.Amount = req.Amount
 	}
@@ -263,11 +450,17 @@ func (r *transactionRepository) DeleteTxn(spenderId uint, txnId uint) error {
 		r.logger.Error(err)
 		return err
 	}
+	if err := r.db.Where("transaction_id = ?", txnId).Delete(&entities.TransactionItem{}).Error; err != nil {
+		tx.Rollback()
+		r.logger.Error(err)
+		return err
+	}
 
 	var keys []string
 	keys = append(keys, "get-all-txn")
 	keys = append(keys, fmt.Sprintf("get-all-spender:%v", spenderId))
 	keys = append(keys, fmt.Sprintf("get-by-txn-type:%v", spenderId))
+	keys = append(keys, fmt.Sprintf("get-txn-items:%v", txnId))
 
 	for _, key := range keys {
 		err := r.redisClient.Del(context.Background(), key).Err()
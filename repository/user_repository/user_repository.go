@@ -152,6 +152,14 @@ func (r *userRepository) UpdateUser(userId uint, req entities.Users) error {
 		existingUser.Email = req.Email
 	}
 
+	if req.Timezone != "" {
+		existingUser.Timezone = req.Timezone
+	}
+
+	if req.Language != "" {
+		existingUser.Language = req.Language
+	}
+
 	if err := r.db.Model(&entities.Users{}).Where("id = ?", userId).Save(&existingUser).Error; err != nil {
 		tx.Rollback()
 		r.logger.Error(err)
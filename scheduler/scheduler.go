@@ -0,0 +1,102 @@
+package scheduler
+
+import (
+	"github.com/labstack/echo/v4"
+	"sync"
+	"time"
+)
+
+type Job struct {
+	Name string
+	// Next returns the first run time strictly after now.
+	Next func(now time.Time) time.Time
+	Run  func(now time.Time) error
+}
+
+type IScheduler interface {
+	Schedule(job Job)
+	Start()
+	Stop()
+}
+
+type scheduler struct {
+	jobs   []Job
+	logger echo.Logger
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(logger echo.Logger) IScheduler {
+	return &scheduler{
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Schedule registers a job. Jobs added after Start are not picked up.
+func (s *scheduler) Schedule(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+func (s *scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	s.logger.Infof("scheduler started with %d jobs", len(s.jobs))
+}
+
+func (s *scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+	s.logger.Info("scheduler stopped")
+}
+
+func (s *scheduler) loop(job Job) {
+	defer s.wg.Done()
+	for {
+		next := job.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			s.run(job, now)
+		}
+	}
+}
+
+func (s *scheduler) run(job Job, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Errorf("job %s panicked: %v", job.Name, r)
+		}
+	}()
+
+	start := time.Now()
+	if err := job.Run(now); err != nil {
+		s.logger.Errorf("job %s failed: %v", job.Name, err)
+		return
+	}
+	s.logger.Infof("job %s finished in %s", job.Name, time.Since(start))
+}
+
+// Daily fires once a day at hour:minute in loc.
+func Daily(hour, minute int, loc *time.Location) func(now time.Time) time.Time {
+	return func(now time.Time) time.Time {
+		local := now.In(loc)
+		next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+		if !next.After(local) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+}
+
+// Every fires on each multiple of interval, e.g. at the top of every hour.
+func Every(interval time.Duration) func(now time.Time) time.Time {
+	return func(now time.Time) time.Time {
+		return now.Truncate(interval).Add(interval)
+	}
+}
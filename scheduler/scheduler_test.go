@@ -0,0 +1,31 @@
+package scheduler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDaily_LaterToday(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 8, 0, 0, 0, time.UTC)
+
+	next := Daily(9, 30, time.UTC)(now)
+
+	assert.Equal(t, time.Date(2024, time.June, 1, 9, 30, 0, 0, time.UTC), next)
+}
+
+func TestDaily_Tomorrow(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 9, 30, 0, 0, time.UTC)
+
+	next := Daily(9, 30, time.UTC)(now)
+
+	assert.Equal(t, time.Date(2024, time.June, 2, 9, 30, 0, 0, time.UTC), next)
+}
+
+func TestEvery_Hour(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 8, 15, 0, 0, time.UTC)
+
+	next := Every(time.Hour)(now)
+
+	assert.Equal(t, time.Date(2024, time.June, 1, 9, 0, 0, 0, time.UTC), next)
+}
@@ -0,0 +1,119 @@
+package account_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/account"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+type IAccountHandler interface {
+	GetAccounts(c echo.Context) error
+	GetAccount(c echo.Context) error
+	CreateAccount(c echo.Context) error
+	DeleteAccount(c echo.Context) error
+}
+
+type accountHandler struct {
+	accountService account.IAccountService
+	logger         echo.Logger
+}
+
+func NewAccountHandler(accountService account.IAccountService, logger echo.Logger) IAccountHandler {
+	return &accountHandler{
+		accountService: accountService,
+		logger:         logger,
+	}
+}
+
+func (h *accountHandler) GetAccounts(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.accountService.GetAccounts(spenderId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *accountHandler) GetAccount(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	accountId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "account id is invalid"})
+	}
+
+	result, err := h.accountService.GetAccount(spenderId, uint(accountId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "account not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *accountHandler) CreateAccount(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	var req account.Account
+	if err = c.Bind(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message": "request body is invalid",
+		})
+	}
+
+	if err = validator.New().Struct(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.accountService.CreateAccount(spenderId, req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"account_id": result})
+}
+
+func (h *accountHandler) DeleteAccount(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	accountId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "account id is invalid"})
+	}
+
+	err = h.accountService.DeleteAccount(spenderId, uint(accountId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "account not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("delete account with account id: %d success", accountId)})
+}
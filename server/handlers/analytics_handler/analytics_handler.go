@@ -0,0 +1,80 @@
+package analytics_handler
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/analytics"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+type IAnalyticsHandler interface {
+	GetChart(c echo.Context) error
+}
+
+type analyticsHandler struct {
+	analyticsService analytics.IAnalyticsService
+	logger           echo.Logger
+}
+
+func NewAnalyticsHandler(analyticsService analytics.IAnalyticsService, logger echo.Logger) IAnalyticsHandler {
+	return &analyticsHandler{
+		analyticsService: analyticsService,
+		logger:           logger,
+	}
+}
+
+// GetChart draws the chart named in the path as an SVG or PNG image.
+func (h *analyticsHandler) GetChart(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	req := analytics.GetChartRequest{
+		SpenderId: spenderId,
+		Chart:     c.Param("chart"),
+		Format:    c.QueryParam("format"),
+		Theme:     c.QueryParam("theme"),
+		Month:     c.QueryParam("month"),
+		TxnType:   c.QueryParam("type"),
+		Language:  c.QueryParam("lang"),
+	}
+	for name, value := range map[string]*int{"width": &req.Width, "height": &req.Height, "months": &req.Months} {
+		if c.QueryParam(name) == "" {
+			continue
+		}
+		*value, err = strconv.Atoi(c.QueryParam(name))
+		if err != nil {
+			h.logger.Error(name + " is invalid")
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": name + " is invalid"})
+		}
+	}
+
+	validate := validator.New()
+	err = validate.Struct(&req)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.analyticsService.GetChart(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "user not found"})
+		case errors.Is(err, analytics.ErrFutureMonth):
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=300")
+	return c.Blob(http.StatusOK, result.ContentType, result.Data)
+}
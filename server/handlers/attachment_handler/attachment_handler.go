@@ -0,0 +1,184 @@
+package attachment_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/attachment"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/Montheankul-K/jod-jod/virusscan"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+type IAttachmentHandler interface {
+	Upload(c echo.Context) error
+	GetAttachments(c echo.Context) error
+	GetFile(c echo.Context) error
+	Delete(c echo.Context) error
+}
+
+type attachmentHandler struct {
+	attachmentService attachment.IAttachmentService
+	logger            echo.Logger
+}
+
+func NewAttachmentHandler(attachmentService attachment.IAttachmentService, logger echo.Logger) IAttachmentHandler {
+	return &attachmentHandler{
+		attachmentService: attachmentService,
+		logger:            logger,
+	}
+}
+
+// Upload stores every "file" part of the form. Files are stored one by one,
+// so on failure the response lists those already stored.
+func (h *attachmentHandler) Upload(c echo.Context) error {
+	spenderId, txnId, err := params(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
+		h.logger.Error("file is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "file is required"})
+	}
+
+	result := []attachment.GetAttachmentResponse{}
+	for _, file := range form.File["file"] {
+		data, err := h.readFile(file)
+		if err != nil {
+			return h.errorResponse(c, fmt.Errorf("%s: %w", file.Filename, err), result)
+		}
+
+		uploaded, err := h.attachmentService.Upload(spenderId, txnId, file.Filename, data)
+		if err != nil {
+			return h.errorResponse(c, fmt.Errorf("%s: %w", file.Filename, err), result)
+		}
+		result = append(result, *uploaded)
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"attachments": result})
+}
+
+func (h *attachmentHandler) GetAttachments(c echo.Context) error {
+	spenderId, txnId, err := params(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.attachmentService.GetAttachments(spenderId, txnId)
+	if err != nil {
+		return h.errorResponse(c, err, nil)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// GetFile redirects to the file when the blob store can presign URLs and
+// streams it otherwise.
+func (h *attachmentHandler) GetFile(c echo.Context) error {
+	spenderId, txnId, err := params(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	attachmentId, err := strconv.ParseUint(c.Param("attachment-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("attachment-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "attachment id is invalid"})
+	}
+
+	result, err := h.attachmentService.GetFile(spenderId, txnId, uint(attachmentId))
+	if err != nil {
+		return h.errorResponse(c, err, nil)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=60")
+	if result.URL != "" {
+		return c.Redirect(http.StatusFound, result.URL)
+	}
+	defer result.Body.Close()
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("inline; filename=%q", result.Filename))
+	// Stored files are served as their sniffed type only.
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Stream(http.StatusOK, result.ContentType, result.Body)
+}
+
+func (h *attachmentHandler) Delete(c echo.Context) error {
+	spenderId, txnId, err := params(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	attachmentId, err := strconv.ParseUint(c.Param("attachment-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("attachment-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "attachment id is invalid"})
+	}
+
+	if err = h.attachmentService.Delete(spenderId, txnId, uint(attachmentId)); err != nil {
+		return h.errorResponse(c, err, nil)
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("delete attachment with attachment id: %d success", attachmentId)})
+}
+
+// readFile rejects an oversized part before reading it into memory.
+func (h *attachmentHandler) readFile(file *multipart.FileHeader) ([]byte, error) {
+	maxSize := h.attachmentService.MaxSize()
+	if file.Size > maxSize {
+		return nil, attachment.ErrTooLarge
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		h.logger.Error(err)
+		return nil, errors.New("file is invalid")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+	if err != nil {
+		h.logger.Error(err)
+		return nil, errors.New("file is invalid")
+	}
+	return data, nil
+}
+
+// params reads the caller and the :txn-id the attachments belong to.
+func params(c echo.Context) (uint, uint, error) {
+	txnId, err := strconv.ParseUint(c.Param("txn-id"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("txn-id is invalid")
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	return spenderId, uint(txnId), nil
+}
+
+func (h *attachmentHandler) errorResponse(c echo.Context, err error, uploaded []attachment.GetAttachmentResponse) error {
+	status := http.StatusInternalServerError
+	var infected *virusscan.InfectedError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, attachment.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, attachment.ErrUnsupportedType):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, attachment.ErrTooMany), errors.Is(err, attachment.ErrInvalidPDF), errors.As(err, &infected):
+		status = http.StatusUnprocessableEntity
+	}
+
+	response := echo.Map{"message": err.Error()}
+	if uploaded != nil {
+		response["attachments"] = uploaded
+	}
+	return c.JSON(status, response)
+}
@@ -0,0 +1,92 @@
+package budget_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/budget"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+type IBudgetHandler interface {
+	GetBudgets(c echo.Context) error
+	SetBudget(c echo.Context) error
+	DeleteBudget(c echo.Context) error
+}
+
+type budgetHandler struct {
+	budgetService budget.IBudgetService
+	logger        echo.Logger
+}
+
+func NewBudgetHandler(budgetService budget.IBudgetService, logger echo.Logger) IBudgetHandler {
+	return &budgetHandler{
+		budgetService: budgetService,
+		logger:        logger,
+	}
+}
+
+func (h *budgetHandler) GetBudgets(c echo.Context) error {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	result, err := h.budgetService.GetBudgets(uint(spenderId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "budget not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *budgetHandler) SetBudget(c echo.Context) error {
+	var req budget.Budget
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message": "request body is invalid",
+		})
+	}
+
+	validate := validator.New()
+	err := validate.Struct(&req)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.budgetService.SetBudget(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"budget_id": result})
+}
+
+func (h *budgetHandler) DeleteBudget(c echo.Context) error {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	budgetId, err := strconv.ParseUint(c.Param("budget-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("budget-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "budget-id is invalid"})
+	}
+
+	err = h.budgetService.DeleteBudget(uint(spenderId), uint(budgetId))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("delete budget with budget id: %d success", budgetId)})
+}
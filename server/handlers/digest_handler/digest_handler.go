@@ -0,0 +1,59 @@
+package digest_handler
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/digest"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+type IDigestHandler interface {
+	GetDigest(c echo.Context) error
+}
+
+type digestHandler struct {
+	digestService digest.IDigestService
+	logger        echo.Logger
+}
+
+func NewDigestHandler(digestService digest.IDigestService, logger echo.Logger) IDigestHandler {
+	return &digestHandler{
+		digestService: digestService,
+		logger:        logger,
+	}
+}
+
+func (h *digestHandler) GetDigest(c echo.Context) error {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	req := digest.GetDigestRequest{
+		SpenderId: uint(spenderId),
+		Month:     c.QueryParam("month"),
+		Language:  c.QueryParam("lang"),
+	}
+
+	validate := validator.New()
+	err = validate.Struct(&req)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.digestService.GetDigest(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "user not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,83 @@
+package forecast_handler
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/forecast"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+type IForecastHandler interface {
+	GetForecast(c echo.Context) error
+}
+
+type forecastHandler struct {
+	forecastService forecast.IForecastService
+	logger          echo.Logger
+}
+
+func NewForecastHandler(forecastService forecast.IForecastService, logger echo.Logger) IForecastHandler {
+	return &forecastHandler{
+		forecastService: forecastService,
+		logger:          logger,
+	}
+}
+
+func (h *forecastHandler) GetForecast(c echo.Context) error {
+	spenderIdStr := c.Param("spender-id")
+	if spenderIdStr == "" {
+		h.logger.Error("spender-id is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is required"})
+	}
+
+	spenderId, err := strconv.ParseUint(spenderIdStr, 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	req := forecast.ForecastRequest{
+		SpenderId:      uint(spenderId),
+		Days:           30,
+		LookbackMonths: 3,
+	}
+
+	days := c.QueryParam("days")
+	if days != "" {
+		req.Days, err = strconv.Atoi(days)
+		if err != nil {
+			h.logger.Error(err)
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "days is invalid"})
+		}
+	}
+
+	lookbackMonths := c.QueryParam("lookback-months")
+	if lookbackMonths != "" {
+		req.LookbackMonths, err = strconv.Atoi(lookbackMonths)
+		if err != nil {
+			h.logger.Error(err)
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": "lookback months is invalid"})
+		}
+	}
+
+	validate := validator.New()
+	err = validate.Struct(&req)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.forecastService.GetForecast(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "transaction not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,335 @@
+package import_handler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/imports"
+	"github.com/Montheankul-K/jod-jod/importfile"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type IImportHandler interface {
+	PreviewCSV(c echo.Context) error
+	CommitCSV(c echo.Context) error
+	PreviewStatement(c echo.Context) error
+	CommitStatement(c echo.Context) error
+	PreviewJournal(c echo.Context) error
+	CommitJournal(c echo.Context) error
+	GetBatches(c echo.Context) error
+	GetBatch(c echo.Context) error
+	Rollback(c echo.Context) error
+}
+
+type importHandler struct {
+	importService imports.IImportService
+	logger        echo.Logger
+}
+
+func NewImportHandler(importService imports.IImportService, logger echo.Logger) IImportHandler {
+	return &importHandler{
+		importService: importService,
+		logger:        logger,
+	}
+}
+
+// PreviewCSV reads the uploaded "file" and returns the mapping it would use
+// and every row with its errors, without saving anything.
+func (h *importHandler) PreviewCSV(c echo.Context) error {
+	if _, err := user_middleware.UserId(c); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	_, data, opts, err := h.readCSVRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.importService.PreviewCSV(data, opts)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// CommitCSV saves the uploaded "file" as one import. When rows are invalid
+// and skip_invalid is not set, the preview is returned with 422.
+func (h *importHandler) CommitCSV(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	filename, data, opts, err := h.readCSVRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.importService.CommitCSV(spenderId, filename, data, opts)
+	if err != nil {
+		var invalid *imports.InvalidRowsError
+		if errors.As(err, &invalid) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"message": err.Error(),
+				"preview": invalid.Preview,
+			})
+		}
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusCreated, result)
+}
+
+// PreviewStatement reads the uploaded OFX, QFX or QIF "file" for the
+// account_id form value and returns what importing each row would do.
+func (h *importHandler) PreviewStatement(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	filename, data, opts, err := h.readStatementRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.importService.PreviewStatement(spenderId, filename, data, opts)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// CommitStatement imports the uploaded statement into the account. When rows
+// are invalid and skip_invalid is not set, the preview is returned with 422.
+func (h *importHandler) CommitStatement(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	filename, data, opts, err := h.readStatementRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.importService.CommitStatement(spenderId, filename, data, opts)
+	if err != nil {
+		var invalid *imports.InvalidRowsError
+		if errors.As(err, &invalid) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"message": err.Error(),
+				"preview": invalid.Preview,
+			})
+		}
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusCreated, result)
+}
+
+// PreviewJournal reads the uploaded beancount "file" and returns what
+// importing each entry would do.
+func (h *importHandler) PreviewJournal(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	_, data, err := h.readFile(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.importService.PreviewJournal(spenderId, data)
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// CommitJournal imports the uploaded beancount journal. When entries are
+// invalid and skip_invalid is not set, the preview is returned with 422.
+func (h *importHandler) CommitJournal(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	filename, data, err := h.readFile(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	var opts imports.JournalOptions
+	opts.SkipInvalid, _ = strconv.ParseBool(c.FormValue("skip_invalid"))
+
+	result, err := h.importService.CommitJournal(spenderId, filename, data, opts)
+	if err != nil {
+		var invalid *imports.InvalidRowsError
+		if errors.As(err, &invalid) {
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"message": err.Error(),
+				"preview": invalid.Preview,
+			})
+		}
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusCreated, result)
+}
+
+func (h *importHandler) GetBatches(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.importService.GetBatches(spenderId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *importHandler) GetBatch(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	batchId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "import id is invalid"})
+	}
+
+	result, err := h.importService.GetBatch(spenderId, uint(batchId))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// Rollback deletes every transaction the import created.
+func (h *importHandler) Rollback(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	batchId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "import id is invalid"})
+	}
+
+	result, err := h.importService.Rollback(spenderId, uint(batchId))
+	if err != nil {
+		return h.errorResponse(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// readCSVRequest reads the "file" part and the mapping, date_order,
+// default_type and skip_invalid form values.
+func (h *importHandler) readCSVRequest(c echo.Context) (string, []byte, imports.CSVOptions, error) {
+	var opts imports.CSVOptions
+	filename, data, err := h.readFile(c)
+	if err != nil {
+		return "", nil, opts, err
+	}
+
+	if mapping := c.FormValue("mapping"); mapping != "" {
+		if err = json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			return "", nil, opts, errors.New("mapping is invalid")
+		}
+	}
+	opts.DateOrder = c.FormValue("date_order")
+	opts.DefaultType = c.FormValue("default_type")
+	opts.SkipInvalid, _ = strconv.ParseBool(c.FormValue("skip_invalid"))
+	if err = validator.New().Struct(opts); err != nil {
+		return "", nil, opts, err
+	}
+	return filename, data, opts, nil
+}
+
+// readStatementRequest reads the "file" part and the account_id, date_order
+// and skip_invalid form values.
+func (h *importHandler) readStatementRequest(c echo.Context) (string, []byte, imports.StatementOptions, error) {
+	var opts imports.StatementOptions
+	filename, data, err := h.readFile(c)
+	if err != nil {
+		return "", nil, opts, err
+	}
+
+	accountId, err := strconv.ParseUint(c.FormValue("account_id"), 10, 64)
+	if err != nil {
+		return "", nil, opts, errors.New("account_id is invalid")
+	}
+	opts.AccountId = uint(accountId)
+	opts.DateOrder = c.FormValue("date_order")
+	opts.SkipInvalid, _ = strconv.ParseBool(c.FormValue("skip_invalid"))
+	if err = validator.New().Struct(opts); err != nil {
+		return "", nil, opts, err
+	}
+	return filename, data, opts, nil
+}
+
+func (h *importHandler) readFile(c echo.Context) (string, []byte, error) {
+	part, err := c.FormFile("file")
+	if err != nil {
+		return "", nil, errors.New("file is required")
+	}
+	if part.Size > imports.MaxFileSize {
+		return "", nil, errors.New("file is too large")
+	}
+
+	file, err := part.Open()
+	if err != nil {
+		h.logger.Error(err)
+		return "", nil, errors.New("file is invalid")
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, imports.MaxFileSize))
+	if err != nil {
+		h.logger.Error(err)
+		return "", nil, errors.New("file is invalid")
+	}
+	return part.Filename, data, nil
+}
+
+func (h *importHandler) errorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "import not found"})
+	case errors.Is(err, imports.ErrAccountNotFound):
+		return c.JSON(http.StatusNotFound, echo.Map{"message": err.Error()})
+	case errors.Is(err, imports.ErrRolledBack),
+		errors.Is(err, imports.ErrNothingNew),
+		errors.Is(err, imports.ErrReconcileConflict):
+		return c.JSON(http.StatusConflict, echo.Map{"message": err.Error()})
+	case errors.Is(err, importfile.ErrEmptyFile),
+		errors.Is(err, importfile.ErrInvalidCSV),
+		errors.Is(err, importfile.ErrTooManyRows),
+		errors.Is(err, importfile.ErrUnknownFormat),
+		errors.Is(err, importfile.ErrInvalidOFX),
+		errors.Is(err, importfile.ErrInvalidQIF),
+		errors.Is(err, importfile.ErrInvalidBeancount),
+		errors.Is(err, imports.ErrAccountMismatch),
+		errors.Is(err, imports.ErrCurrencyMismatch),
+		errors.Is(err, imports.ErrInvalidMapping),
+		errors.Is(err, imports.ErrNothingToImport):
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+}
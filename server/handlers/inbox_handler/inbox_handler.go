@@ -0,0 +1,278 @@
+package inbox_handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/Montheankul-K/jod-jod/config"
+	"github.com/Montheankul-K/jod-jod/domains/inbox"
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"github.com/Montheankul-K/jod-jod/mailparse"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/Montheankul-K/jod-jod/smtpin"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// secretHeader carries the shared secret of the mail relay that posts to the
+// ingest endpoint.
+const secretHeader = "X-Inbox-Secret"
+
+type IInboxHandler interface {
+	Ingest(c echo.Context) error
+	ReceiveSMTP(from string, to []string, data []byte) error
+	GetInbox(c echo.Context) error
+	RotateAddress(c echo.Context) error
+	GetDrafts(c echo.Context) error
+	GetEmail(c echo.Context) error
+	GetRaw(c echo.Context) error
+	CorrectDraft(c echo.Context) error
+	ApproveDraft(c echo.Context) error
+	DiscardDraft(c echo.Context) error
+}
+
+type inboxHandler struct {
+	ingestSecret string
+	maxSize      int64
+	inboxService inbox.IInboxService
+	logger       echo.Logger
+}
+
+func NewInboxHandler(cfg *config.Inbox, inboxService inbox.IInboxService, logger echo.Logger) IInboxHandler {
+	maxSize := int64(inbox.DefaultMaxSize)
+	if cfg.MaxSize > 0 {
+		maxSize = cfg.MaxSize
+	}
+	return &inboxHandler{
+		ingestSecret: cfg.IngestSecret,
+		maxSize:      maxSize,
+		inboxService: inboxService,
+		logger:       logger,
+	}
+}
+
+// Ingest takes a raw RFC 5322 message from a mail relay or webhook. The
+// envelope recipients, which the message headers may not show, can be given
+// as "to" query parameters.
+func (h *inboxHandler) Ingest(c echo.Context) error {
+	secret := c.Request().Header.Get(secretHeader)
+	if h.ingestSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.ingestSecret)) != 1 {
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": "inbox secret is invalid"})
+	}
+
+	// Reading one byte past the limit tells an email that is exactly at it
+	// from one that is over.
+	raw, err := io.ReadAll(io.LimitReader(c.Request().Body, h.maxSize+1))
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "failed to read email"})
+	}
+	if len(raw) == 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "email is required"})
+	}
+
+	result, err := h.inboxService.Receive(c.QueryParams()["to"], raw)
+	if err != nil {
+		switch {
+		case errors.Is(err, inbox.ErrTooLarge):
+			return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": err.Error()})
+		case errors.Is(err, mailparse.ErrInvalidMessage):
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		case errors.Is(err, inbox.ErrUnknownRecipient):
+			return c.JSON(http.StatusNotFound, echo.Map{"message": err.Error()})
+		default:
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+		}
+	}
+	return c.JSON(http.StatusCreated, result)
+}
+
+// ReceiveSMTP is the SMTP listener's handler. Mail for an address no one has
+// is refused for good; other failures make the sender retry.
+func (h *inboxHandler) ReceiveSMTP(from string, to []string, data []byte) error {
+	_, err := h.inboxService.Receive(to, data)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, inbox.ErrUnknownRecipient):
+		return &smtpin.Error{Code: 550, Message: "No such mailbox"}
+	case errors.Is(err, inbox.ErrTooLarge):
+		return &smtpin.Error{Code: 552, Message: "Message too large"}
+	case errors.Is(err, mailparse.ErrInvalidMessage):
+		return &smtpin.Error{Code: 554, Message: "Message is not valid"}
+	default:
+		return err
+	}
+}
+
+func (h *inboxHandler) GetInbox(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.inboxService.GetInbox(spenderId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *inboxHandler) RotateAddress(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.inboxService.RotateToken(spenderId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *inboxHandler) GetDrafts(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.inboxService.GetDrafts(spenderId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *inboxHandler) GetEmail(c echo.Context) error {
+	spenderId, emailId, err := emailParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.inboxService.GetEmail(spenderId, emailId)
+	if err != nil {
+		return h.emailError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// GetRaw downloads the email as received, for the spender to check a draft
+// against.
+func (h *inboxHandler) GetRaw(c echo.Context) error {
+	spenderId, emailId, err := emailParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	body, err := h.inboxService.GetRaw(spenderId, emailId)
+	if err != nil {
+		return h.emailError(c, err)
+	}
+	defer body.Close()
+	c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=\"email.eml\"")
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Stream(http.StatusOK, "message/rfc822", body)
+}
+
+func (h *inboxHandler) CorrectDraft(c echo.Context) error {
+	spenderId, emailId, err := emailParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	var req transaction.DraftCorrection
+	if err = c.Bind(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message": "request body is invalid",
+		})
+	}
+
+	validate := validator.New()
+	if err = validate.Struct(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.inboxService.CorrectDraft(spenderId, emailId, req)
+	if err != nil {
+		return h.emailError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *inboxHandler) ApproveDraft(c echo.Context) error {
+	spenderId, emailId, err := emailParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	force, _ := strconv.ParseBool(c.QueryParam("force"))
+	result, err := h.inboxService.ApproveDraft(spenderId, emailId, force)
+	if err != nil {
+		var duplicate *transaction.DuplicateError
+		if errors.As(err, &duplicate) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message":            duplicate.Error(),
+				"transaction_id":     duplicate.TransactionId,
+				"reason":             duplicate.Reason,
+				"possible_duplicate": duplicate.Possible,
+			})
+		}
+		return h.emailError(c, err)
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"transaction_id": result})
+}
+
+func (h *inboxHandler) DiscardDraft(c echo.Context) error {
+	spenderId, emailId, err := emailParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	if err = h.inboxService.DiscardDraft(spenderId, emailId); err != nil {
+		return h.emailError(c, err)
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "email draft discarded"})
+}
+
+// emailParams reads the caller and the :id of the email.
+func emailParams(c echo.Context) (uint, uint, error) {
+	emailId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("email id is invalid")
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	return spenderId, uint(emailId), nil
+}
+
+func (h *inboxHandler) emailError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "email not found"})
+	case errors.Is(err, inbox.ErrNotDraft):
+		return c.JSON(http.StatusConflict, echo.Map{"message": err.Error()})
+	case errors.Is(err, transaction.ErrDraftIncomplete):
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+}
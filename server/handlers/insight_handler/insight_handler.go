@@ -0,0 +1,49 @@
+package insight_handler
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/insight"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+type IInsightHandler interface {
+	GetAnomalies(c echo.Context) error
+}
+
+type insightHandler struct {
+	insightService insight.IInsightService
+	logger         echo.Logger
+}
+
+func NewInsightHandler(insightService insight.IInsightService, logger echo.Logger) IInsightHandler {
+	return &insightHandler{
+		insightService: insightService,
+		logger:         logger,
+	}
+}
+
+func (h *insightHandler) GetAnomalies(c echo.Context) error {
+	spenderIdStr := c.Param("spender-id")
+	if spenderIdStr == "" {
+		h.logger.Error("spender-id is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is required"})
+	}
+
+	spenderId, err := strconv.ParseUint(spenderIdStr, 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	result, err := h.insightService.GetAnomalies(uint(spenderId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "anomaly not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,168 @@
+package networth_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/networth"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type INetWorthHandler interface {
+	GetItems(c echo.Context) error
+	CreateItem(c echo.Context) error
+	DeleteItem(c echo.Context) error
+	GetHistory(c echo.Context) error
+	RecomputeHistory(c echo.Context) error
+}
+
+type netWorthHandler struct {
+	netWorthService networth.INetWorthService
+	logger          echo.Logger
+}
+
+func NewNetWorthHandler(netWorthService networth.INetWorthService, logger echo.Logger) INetWorthHandler {
+	return &netWorthHandler{
+		netWorthService: netWorthService,
+		logger:          logger,
+	}
+}
+
+func (h *netWorthHandler) GetItems(c echo.Context) error {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	result, err := h.netWorthService.GetItems(uint(spenderId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "net worth item not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *netWorthHandler) CreateItem(c echo.Context) error {
+	var req networth.NetWorthItem
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message": "request body is invalid",
+		})
+	}
+
+	validate := validator.New()
+	err := validate.Struct(&req)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.netWorthService.CreateItem(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"item_id": result})
+}
+
+func (h *netWorthHandler) DeleteItem(c echo.Context) error {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	itemId, err := strconv.ParseUint(c.Param("item-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("item-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "item-id is invalid"})
+	}
+
+	err = h.netWorthService.DeleteItem(uint(spenderId), uint(itemId))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("delete net worth item with item id: %d success", itemId)})
+}
+
+func (h *netWorthHandler) GetHistory(c echo.Context) error {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	filter, err := h.parsePeriodFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.netWorthService.GetHistory(uint(spenderId), filter)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "net worth history not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *netWorthHandler) RecomputeHistory(c echo.Context) error {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	filter, err := h.parsePeriodFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	if filter.StartDate == nil {
+		h.logger.Error("start-date is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "start-date is required"})
+	}
+
+	endDate := time.Now().AddDate(0, 0, -1)
+	if filter.EndDate != nil {
+		endDate = *filter.EndDate
+	}
+	err = h.netWorthService.RecomputeSnapshots(uint(spenderId), *filter.StartDate, endDate)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("recompute net worth of spender id: %d success", spenderId)})
+}
+
+func (h *netWorthHandler) parsePeriodFilter(c echo.Context) (networth.PeriodFilter, error) {
+	var filter networth.PeriodFilter
+	startDateStr := c.QueryParam("start-date")
+	if startDateStr != "" {
+		startDate, err := time.ParseInLocation("2006-01-02", startDateStr, time.Local)
+		if err != nil {
+			h.logger.Error(err)
+			return filter, errors.New("start-date is invalid")
+		}
+		filter.StartDate = &startDate
+	}
+
+	endDateStr := c.QueryParam("end-date")
+	if endDateStr != "" {
+		endDate, err := time.ParseInLocation("2006-01-02", endDateStr, time.Local)
+		if err != nil {
+			h.logger.Error(err)
+			return filter, errors.New("end-date is invalid")
+		}
+		filter.EndDate = &endDate
+	}
+	return filter, nil
+}
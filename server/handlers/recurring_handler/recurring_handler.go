@@ -0,0 +1,183 @@
+package recurring_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/recurring"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+type IRecurringHandler interface {
+	GetRules(c echo.Context) error
+	CreateRule(c echo.Context) error
+	DeleteRule(c echo.Context) error
+	DetectSubscriptions(c echo.Context) error
+	ConfirmSubscription(c echo.Context) error
+	DismissSubscription(c echo.Context) error
+}
+
+type recurringHandler struct {
+	recurringService recurring.IRecurringService
+	logger           echo.Logger
+}
+
+func NewRecurringHandler(recurringService recurring.IRecurringService, logger echo.Logger) IRecurringHandler {
+	return &recurringHandler{
+		recurringService: recurringService,
+		logger:           logger,
+	}
+}
+
+func (h *recurringHandler) GetRules(c echo.Context) error {
+	spenderIdStr := c.Param("spender-id")
+	if spenderIdStr == "" {
+		h.logger.Error("spender-id is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is required"})
+	}
+
+	spenderId, err := strconv.ParseUint(spenderIdStr, 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	result, err := h.recurringService.GetRules(uint(spenderId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "recurring rule not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *recurringHandler) CreateRule(c echo.Context) error {
+	var req recurring.RecurringRule
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message": "request body is invalid",
+		})
+	}
+
+	validate := validator.New()
+	err := validate.Struct(&req)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.recurringService.CreateRule(req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"rule_id": result})
+}
+
+func (h *recurringHandler) DeleteRule(c echo.Context) error {
+	spenderIdStr := c.Param("spender-id")
+	if spenderIdStr == "" {
+		h.logger.Error("spender-id is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is required"})
+	}
+
+	ruleIdStr := c.Param("rule-id")
+	if ruleIdStr == "" {
+		h.logger.Error("rule-id is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "rule-id is required"})
+	}
+
+	spenderId, err := strconv.ParseUint(spenderIdStr, 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	ruleId, err := strconv.ParseUint(ruleIdStr, 10, 64)
+	if err != nil {
+		h.logger.Error("rule-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "rule-id is invalid"})
+	}
+
+	err = h.recurringService.DeleteRule(uint(spenderId), uint(ruleId))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("delete recurring rule with rule id: %d success", ruleId)})
+}
+
+func (h *recurringHandler) DetectSubscriptions(c echo.Context) error {
+	spenderIdStr := c.Param("spender-id")
+	if spenderIdStr == "" {
+		h.logger.Error("spender-id is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is required"})
+	}
+
+	spenderId, err := strconv.ParseUint(spenderIdStr, 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "spender id is invalid"})
+	}
+
+	result, err := h.recurringService.DetectSubscriptions(uint(spenderId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "transaction not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *recurringHandler) ConfirmSubscription(c echo.Context) error {
+	spenderId, subscriptionId, err := h.parseSubscriptionParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.recurringService.ConfirmSubscription(spenderId, subscriptionId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "subscription not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"rule_id": result})
+}
+
+func (h *recurringHandler) DismissSubscription(c echo.Context) error {
+	spenderId, subscriptionId, err := h.parseSubscriptionParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	err = h.recurringService.DismissSubscription(spenderId, subscriptionId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "subscription not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("dismiss subscription with subscription id: %d success", subscriptionId)})
+}
+
+func (h *recurringHandler) parseSubscriptionParams(c echo.Context) (uint, uint, error) {
+	spenderId, err := strconv.ParseUint(c.Param("spender-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("spender-id is invalid")
+		return 0, 0, errors.New("spender id is invalid")
+	}
+
+	subscriptionId, err := strconv.ParseUint(c.Param("subscription-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("subscription-id is invalid")
+		return 0, 0, errors.New("subscription-id is invalid")
+	}
+	return uint(spenderId), uint(subscriptionId), nil
+}
@@ -0,0 +1,347 @@
+package slip_handler
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/domains/slipjob"
+	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxBatchWait caps how long a batch upload may hold the request open
+// waiting for its report.
+const maxBatchWait = 25 * time.Second
+
+type ISlipHandler interface {
+	Upload(c echo.Context) error
+	GetJob(c echo.Context) error
+	UploadBatch(c echo.Context) error
+	GetBatch(c echo.Context) error
+	GetReviews(c echo.Context) error
+	GetReviewImage(c echo.Context) error
+	CorrectReview(c echo.Context) error
+	ApproveReview(c echo.Context) error
+	RejectReview(c echo.Context) error
+}
+
+type slipHandler struct {
+	slipJobService slipjob.ISlipJobService
+	logger         echo.Logger
+}
+
+func NewSlipHandler(slipJobService slipjob.ISlipJobService, logger echo.Logger) ISlipHandler {
+	return &slipHandler{
+		slipJobService: slipJobService,
+		logger:         logger,
+	}
+}
+
+func (h *slipHandler) Upload(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	slipImage, err := c.FormFile("slip")
+	if err != nil {
+		h.logger.Error("slip image is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "slip image is required",
+		})
+	}
+	if slipImage.Size > slipjob.MaxSlipSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{
+			"message": "slip image is too large",
+		})
+	}
+
+	file, err := slipImage.Open()
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "slip image is invalid",
+		})
+	}
+	defer file.Close()
+
+	image, err := io.ReadAll(io.LimitReader(file, slipjob.MaxSlipSize))
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": "slip image is invalid",
+		})
+	}
+
+	force, _ := strconv.ParseBool(c.QueryParam("force"))
+	result, err := h.slipJobService.Enqueue(spenderId, slipImage.Filename, image, force)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusAccepted, echo.Map{
+		"job_id": result,
+		"status": slipjob.StatusQueued,
+	})
+}
+
+// UploadBatch queues every slip sent as a "slip" part, expanding ZIP
+// archives. With ?wait=<seconds> it waits for the batch to finish and returns
+// the report instead of only the batch ID.
+func (h *slipHandler) UploadBatch(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["slip"]) == 0 {
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "slip is required"})
+	}
+
+	var files []slipjob.BatchFile
+	var total int64
+	for _, part := range form.File["slip"] {
+		total += part.Size
+		if total > slipjob.MaxBatchSize {
+			return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": slipjob.ErrBatchTooLarge.Error()})
+		}
+
+		data, err := readPart(part)
+		if err != nil {
+			files = append(files, slipjob.BatchFile{Filename: part.Filename, Err: err})
+			continue
+		}
+		if !slipjob.IsZip(data) {
+			files = append(files, slipjob.BatchFile{Filename: part.Filename, Data: data})
+			continue
+		}
+
+		entries, err := slipjob.ExtractZip(data)
+		if err != nil {
+			if errors.Is(err, slipjob.ErrBatchTooLarge) {
+				return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": err.Error()})
+			}
+			files = append(files, slipjob.BatchFile{Filename: part.Filename, Err: err})
+			continue
+		}
+		files = append(files, entries...)
+	}
+
+	force, _ := strconv.ParseBool(c.QueryParam("force"))
+	result, err := h.slipJobService.EnqueueBatch(spenderId, files, force)
+	if err != nil {
+		switch {
+		case errors.Is(err, slipjob.ErrBatchEmpty):
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		case errors.Is(err, slipjob.ErrBatchTooLarge):
+			return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+
+	wait, _ := strconv.Atoi(c.QueryParam("wait"))
+	if wait <= 0 || result.Done {
+		return c.JSON(http.StatusAccepted, result)
+	}
+
+	timeout := time.Duration(wait) * time.Second
+	if timeout > maxBatchWait {
+		timeout = maxBatchWait
+	}
+	result, err = h.slipJobService.AwaitBatch(spenderId, result.ID, timeout)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	if !result.Done {
+		return c.JSON(http.StatusAccepted, result)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *slipHandler) GetBatch(c echo.Context) error {
+	batchId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.logger.Error("id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "batch id is invalid"})
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.slipJobService.GetBatch(spenderId, uint(batchId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "slip batch not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// readPart reads one uploaded part. Archives may hold many slips, so a part
+// may be as large as a whole batch.
+func readPart(part *multipart.FileHeader) ([]byte, error) {
+	file, err := part.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(io.LimitReader(file, slipjob.MaxBatchSize))
+}
+
+func (h *slipHandler) GetJob(c echo.Context) error {
+	jobId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.logger.Error("id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "job id is invalid"})
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.slipJobService.GetJob(spenderId, uint(jobId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "slip job not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *slipHandler) GetReviews(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.slipJobService.GetReviews(spenderId)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *slipHandler) GetReviewImage(c echo.Context) error {
+	spenderId, jobId, err := reviewParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	image, err := h.slipJobService.GetReviewImage(spenderId, jobId)
+	if err != nil {
+		return h.reviewError(c, err)
+	}
+	return c.Blob(http.StatusOK, http.DetectContentType(image), image)
+}
+
+func (h *slipHandler) CorrectReview(c echo.Context) error {
+	spenderId, jobId, err := reviewParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	var req transaction.DraftCorrection
+	if err = c.Bind(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message": "request body is invalid",
+		})
+	}
+
+	validate := validator.New()
+	if err = validate.Struct(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.slipJobService.CorrectReview(spenderId, jobId, req)
+	if err != nil {
+		return h.reviewError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *slipHandler) ApproveReview(c echo.Context) error {
+	spenderId, jobId, err := reviewParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	force, _ := strconv.ParseBool(c.QueryParam("force"))
+	result, err := h.slipJobService.ApproveReview(spenderId, jobId, force)
+	if err != nil {
+		var duplicate *transaction.DuplicateError
+		if errors.As(err, &duplicate) {
+			return c.JSON(http.StatusConflict, echo.Map{
+				"message":            duplicate.Error(),
+				"transaction_id":     duplicate.TransactionId,
+				"reason":             duplicate.Reason,
+				"possible_duplicate": duplicate.Possible,
+			})
+		}
+		return h.reviewError(c, err)
+	}
+	return c.JSON(http.StatusCreated, echo.Map{"transaction_id": result})
+}
+
+func (h *slipHandler) RejectReview(c echo.Context) error {
+	spenderId, jobId, err := reviewParams(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	if err = h.slipJobService.RejectReview(spenderId, jobId); err != nil {
+		return h.reviewError(c, err)
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": "slip review rejected"})
+}
+
+// reviewParams reads the caller and the :id of the job under review.
+func reviewParams(c echo.Context) (uint, uint, error) {
+	jobId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("job id is invalid")
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	return spenderId, uint(jobId), nil
+}
+
+func (h *slipHandler) reviewError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.JSON(http.StatusNotFound, echo.Map{"message": "slip review not found"})
+	case errors.Is(err, slipjob.ErrNotInReview):
+		return c.JSON(http.StatusConflict, echo.Map{"message": err.Error()})
+	case errors.Is(err, transaction.ErrDraftIncomplete):
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{"message": err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+}
@@ -0,0 +1,69 @@
+package statement_handler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/domains/statement"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+type IStatementHandler interface {
+	GetStatement(c echo.Context) error
+}
+
+type statementHandler struct {
+	statementService statement.IStatementService
+	logger           echo.Logger
+}
+
+func NewStatementHandler(statementService statement.IStatementService, logger echo.Logger) IStatementHandler {
+	return &statementHandler{
+		statementService: statementService,
+		logger:           logger,
+	}
+}
+
+// GetStatement downloads the PDF statement of the month in the path, given
+// as YYYY-MM.
+func (h *statementHandler) GetStatement(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	req := statement.GetStatementRequest{
+		SpenderId: spenderId,
+		Month:     c.Param("month"),
+		Language:  c.QueryParam("lang"),
+	}
+
+	validate := validator.New()
+	err = validate.Struct(&req)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{
+			"message": err.Error(),
+		})
+	}
+
+	result, err := h.statementService.GetStatement(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "user not found"})
+		case errors.Is(err, statement.ErrFutureMonth):
+			return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	defer result.Body.Close()
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=60")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", result.Filename))
+	return c.Stream(http.StatusOK, result.ContentType, result.Body)
+}
@@ -4,16 +4,26 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Montheankul-K/jod-jod/domains/transaction"
+	"github.com/Montheankul-K/jod-jod/exportfile"
+	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
 	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// maxReceiptSize caps an uploaded receipt image.
+const maxReceiptSize = 10 << 20
+
 type ITransactionHandler interface {
 	SaveByManual(c echo.Context) error
-	SaveFromSlip(c echo.Context) error
+	SaveReceipt(c echo.Context) error
+	GetItems(c echo.Context) error
+	UpdateItem(c echo.Context) error
 	GetDetails(c echo.Context) error
 	GetSummary(c echo.Context) error
 	GetBalance(c echo.Context) error
@@ -22,6 +32,8 @@ type ITransactionHandler interface {
 	Update(c echo.Context) error
 	Delete(c echo.Context) error
 	GetAllTxn(c echo.Context) error
+	Export(c echo.Context) error
+	GetImage(c echo.Context) error
 }
 
 type transactionHandler struct {
@@ -54,45 +66,165 @@ func (h *transactionHandler) SaveByManual(c echo.Context) error {
 		})
 	}
 
-	result, err := h.transactionService.SaveByManual(req)
+	result, err := h.transactionService.SaveByManual(req, forceSave(c))
 	if err != nil {
+		var duplicate *transaction.DuplicateError
+		if errors.As(err, &duplicate) {
+			return duplicateResponse(c, duplicate)
+		}
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err})
 	}
 	return c.JSON(http.StatusCreated, echo.Map{"transaction_id": result})
 }
 
-func (h *transactionHandler) SaveFromSlip(c echo.Context) error {
-	spenderIdStr := c.FormValue("spender_id")
-	if spenderIdStr == "" {
-		h.logger.Error("spender_id is empty")
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "spender_id is required",
-		})
+// SaveReceipt reads an itemized store receipt sent as the "receipt" part and
+// saves it with its line items. With ?dry-run=true it only returns what was
+// read, so the client can show the items before saving. A receipt read with
+// issues comes back with 422 for the client to check, and is saved as read
+// with ?force=true.
+func (h *transactionHandler) SaveReceipt(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
 	}
 
-	slipImage, err := c.FormFile("slip")
+	receiptImage, err := c.FormFile("receipt")
 	if err != nil {
-		h.logger.Error("slip image is empty")
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "slip image is required",
-		})
+		h.logger.Error("receipt image is empty")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "receipt image is required"})
+	}
+	if receiptImage.Size > maxReceiptSize {
+		return c.JSON(http.StatusRequestEntityTooLarge, echo.Map{"message": "receipt image is too large"})
 	}
 
-	spenderId, err := strconv.ParseUint(spenderIdStr, 10, 64)
+	file, err := receiptImage.Open()
 	if err != nil {
 		h.logger.Error(err)
-		return c.JSON(http.StatusBadRequest, echo.Map{
-			"message": "spender id is invalid",
-		})
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "receipt image is invalid"})
 	}
+	defer file.Close()
 
-	result, err := h.transactionService.SaveFromSlip(uint(spenderId), slipImage)
+	image, err := io.ReadAll(io.LimitReader(file, maxReceiptSize))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, echo.Map{
-			"message": err,
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "receipt image is invalid"})
+	}
+
+	draft, err := h.transactionService.ParseReceipt(image)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	if dryRun, _ := strconv.ParseBool(c.QueryParam("dry-run")); dryRun {
+		return c.JSON(http.StatusOK, draft)
+	}
+
+	result, err := h.transactionService.SaveReceipt(spenderId, *draft, receiptImage.Filename, image, forceSave(c))
+	if err != nil {
+		var duplicate *transaction.DuplicateError
+		switch {
+		case errors.As(err, &duplicate):
+			return duplicateResponse(c, duplicate)
+		case errors.Is(err, transaction.ErrDraftIncomplete):
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"message": "receipt total could not be read",
+				"receipt": draft,
+			})
+		case errors.Is(err, transaction.ErrDraftNeedsReview):
+			return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+				"message": "receipt needs review",
+				"issues":  draft.Issues,
+				"receipt": draft,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusCreated, echo.Map{
+		"transaction_id": result,
+		"receipt":        draft,
+	})
+}
+
+func (h *transactionHandler) GetItems(c echo.Context) error {
+	txnId, err := strconv.ParseUint(c.Param("txn-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("txn-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "txn-id is invalid"})
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.transactionService.GetItems(spenderId, uint(txnId))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "transaction not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+func (h *transactionHandler) UpdateItem(c echo.Context) error {
+	txnId, err := strconv.ParseUint(c.Param("txn-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("txn-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "txn-id is invalid"})
+	}
+
+	itemId, err := strconv.ParseUint(c.Param("item-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("item-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "item-id is invalid"})
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	var req transaction.UpdateItemRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnprocessableEntity, echo.Map{
+			"message": "request body is invalid",
 		})
 	}
-	return c.JSON(http.StatusCreated, echo.Map{"transaction_id": result})
+
+	validate := validator.New()
+	if err = validate.Struct(&req); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	err = h.transactionService.UpdateItem(spenderId, uint(txnId), uint(itemId), req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "transaction item not found"})
+		}
+		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("update item with item id: %d success", itemId)})
+}
+
+// forceSave reads ?force=true, which saves a transaction even when it looks
+// like a duplicate.
+func forceSave(c echo.Context) bool {
+	force, _ := strconv.ParseBool(c.QueryParam("force"))
+	return force
+}
+
+func duplicateResponse(c echo.Context, duplicate *transaction.DuplicateError) error {
+	return c.JSON(http.StatusConflict, echo.Map{
+		"message":            duplicate.Error(),
+		"transaction_id":     duplicate.TransactionId,
+		"reason":             duplicate.Reason,
+		"possible_duplicate": duplicate.Possible,
+	})
 }
 
 func (h *transactionHandler) GetDetails(c echo.Context) error {
@@ -239,6 +371,9 @@ func (h *transactionHandler) Update(c echo.Context) error {
 
 	err = h.transactionService.Update(uint(txnId), req)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "transaction not found"})
+		}
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err})
 	}
 	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("update transaction with transaction id: %d success", txnId)})
@@ -270,6 +405,9 @@ func (h *transactionHandler) Delete(c echo.Context) error {
 	}
 	err = h.transactionService.Delete(uint(spenderId), uint(txnId))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "transaction not found"})
+		}
 		return c.JSON(http.StatusInternalServerError, echo.Map{"message": err})
 	}
 	return c.JSON(http.StatusOK, echo.Map{"message": fmt.Sprintf("delete transaction with transaction id: %d success", txnId)})
@@ -287,3 +425,98 @@ func (h *transactionHandler) GetAllTxn(c echo.Context) error {
 	}
 	return c.JSON(http.StatusOK, result)
 }
+
+// Export streams the caller's transactions as ?format=csv (the default),
+// xlsx, ndjson, or a beancount or hledger journal, filtered like GetAllTxn.
+// ?columns=date,amount,note picks and orders the columns, ?locale=th formats
+// dates and numbers for people rather than programs, and ?tz= sets the zone
+// dates are written in.
+func (h *transactionHandler) Export(c echo.Context) error {
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	filter := c.Get("filter").(transaction.GetAllTxnFilter)
+	opts := transaction.ExportOptions{
+		Format:   c.QueryParam("format"),
+		Locale:   c.QueryParam("locale"),
+		Timezone: c.QueryParam("tz"),
+	}
+	if opts.Format == "" {
+		opts.Format = exportfile.FormatCSV
+	}
+	if columns := c.QueryParam("columns"); columns != "" {
+		for _, column := range strings.Split(columns, ",") {
+			opts.Columns = append(opts.Columns, strings.TrimSpace(column))
+		}
+	}
+	if err = validator.New().Struct(opts); err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+
+	res := c.Response()
+	filename := fmt.Sprintf("transactions-%s.%s", time.Now().Format("20060102"), exportfile.Extension(opts.Format))
+	res.Header().Set(echo.HeaderContentType, exportfile.ContentType(opts.Format))
+	res.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	res.Header().Set(echo.HeaderCacheControl, "private, no-store")
+
+	err = h.transactionService.Export(spenderId, filter, opts, res)
+	if err == nil {
+		return nil
+	}
+	// Once rows have gone out the status is sent, so all that is left is to
+	// stop; the client sees a cut-off file.
+	if res.Committed {
+		h.logger.Error(err)
+		return nil
+	}
+	res.Header().Del(echo.HeaderContentDisposition)
+	switch {
+	case errors.Is(err, exportfile.ErrUnknownFormat),
+		errors.Is(err, exportfile.ErrUnknownColumn),
+		errors.Is(err, exportfile.ErrUnknownLocale):
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+}
+
+// GetImage redirects to the transaction's image when the blob store can
+// presign URLs and streams it otherwise. ?size=thumbnail asks for the
+// preview.
+func (h *transactionHandler) GetImage(c echo.Context) error {
+	txnId, err := strconv.ParseUint(c.Param("txn-id"), 10, 64)
+	if err != nil {
+		h.logger.Error("txn-id is invalid")
+		return c.JSON(http.StatusBadRequest, echo.Map{"message": "txn-id is invalid"})
+	}
+
+	spenderId, err := user_middleware.UserId(c)
+	if err != nil {
+		h.logger.Error(err)
+		return c.JSON(http.StatusUnauthorized, echo.Map{"message": err.Error()})
+	}
+
+	result, err := h.transactionService.GetImage(spenderId, uint(txnId), c.QueryParam("size") == "thumbnail")
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.JSON(http.StatusNotFound, echo.Map{"message": "transaction not found"})
+		case errors.Is(err, transaction.ErrImageNotFound):
+			return c.JSON(http.StatusNotFound, echo.Map{"message": err.Error()})
+		default:
+			return c.JSON(http.StatusInternalServerError, echo.Map{"message": err.Error()})
+		}
+	}
+
+	// The image is private, so neither the redirect nor the content may be
+	// cached by shared caches.
+	c.Response().Header().Set(echo.HeaderCacheControl, "private, max-age=60")
+	if result.URL != "" {
+		return c.Redirect(http.StatusFound, result.URL)
+	}
+	defer result.Body.Close()
+	return c.Stream(http.StatusOK, result.ContentType, result.Body)
+}
@@ -214,6 +214,8 @@ func (h *userHandler) UpdateInfo(c echo.Context) error {
 		Firstname: req.Firstname,
 		Lastname:  req.Lastname,
 		Email:     req.Email,
+		Timezone:  req.Timezone,
+		Language:  req.Language,
 	}
 	err = h.userService.UpdateInfo(uint(userId), newReq)
 	if err != nil {
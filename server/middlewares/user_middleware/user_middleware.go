@@ -132,3 +132,16 @@ func (m *userMiddleware) ValidateToken(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+// UserId reads the user the token was issued to, as set by ValidateToken.
+func UserId(c echo.Context) (uint, error) {
+	claims, ok := c.Get("claims").(*user.Claims)
+	if !ok {
+		return 0, errors.New("token claims are missing")
+	}
+	userId, err := strconv.ParseUint(claims.UserID, 10, 64)
+	if err != nil {
+		return 0, errors.New("token user id is invalid")
+	}
+	return uint(userId), nil
+}
@@ -1,16 +1,59 @@
 package server
 
 import (
+	"github.com/Montheankul-K/jod-jod/blobstore"
+	"github.com/Montheankul-K/jod-jod/domains/account"
+	"github.com/Montheankul-K/jod-jod/domains/analytics"
+	"github.com/Montheankul-K/jod-jod/domains/attachment"
+	"github.com/Montheankul-K/jod-jod/domains/budget"
+	"github.com/Montheankul-K/jod-jod/domains/digest"
+	"github.com/Montheankul-K/jod-jod/domains/forecast"
+	"github.com/Montheankul-K/jod-jod/domains/imports"
+	"github.com/Montheankul-K/jod-jod/domains/inbox"
+	"github.com/Montheankul-K/jod-jod/domains/insight"
+	"github.com/Montheankul-K/jod-jod/domains/networth"
+	"github.com/Montheankul-K/jod-jod/domains/recurring"
+	"github.com/Montheankul-K/jod-jod/domains/slipjob"
+	"github.com/Montheankul-K/jod-jod/domains/statement"
 	"github.com/Montheankul-K/jod-jod/domains/transaction"
 	"github.com/Montheankul-K/jod-jod/domains/user"
+	"github.com/Montheankul-K/jod-jod/notification"
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"github.com/Montheankul-K/jod-jod/repository/account_repository"
+	"github.com/Montheankul-K/jod-jod/repository/attachment_repository"
+	"github.com/Montheankul-K/jod-jod/repository/budget_repository"
+	"github.com/Montheankul-K/jod-jod/repository/digest_repository"
+	"github.com/Montheankul-K/jod-jod/repository/import_repository"
+	"github.com/Montheankul-K/jod-jod/repository/inbox_repository"
+	"github.com/Montheankul-K/jod-jod/repository/insight_repository"
+	"github.com/Montheankul-K/jod-jod/repository/networth_repository"
+	"github.com/Montheankul-K/jod-jod/repository/recurring_repository"
+	"github.com/Montheankul-K/jod-jod/repository/slipjob_repository"
 	"github.com/Montheankul-K/jod-jod/repository/transaction_repository"
 	"github.com/Montheankul-K/jod-jod/repository/user_repository"
+	"github.com/Montheankul-K/jod-jod/scheduler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/account_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/analytics_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/attachment_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/budget_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/digest_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/forecast_handler"
 	"github.com/Montheankul-K/jod-jod/server/handlers/health"
+	"github.com/Montheankul-K/jod-jod/server/handlers/import_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/inbox_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/insight_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/networth_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/recurring_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/slip_handler"
+	"github.com/Montheankul-K/jod-jod/server/handlers/statement_handler"
 	"github.com/Montheankul-K/jod-jod/server/handlers/transaction_handler"
 	"github.com/Montheankul-K/jod-jod/server/handlers/user_handler"
 	"github.com/Montheankul-K/jod-jod/server/middlewares/transaction_middleware"
 	"github.com/Montheankul-K/jod-jod/server/middlewares/user_middleware"
+	"github.com/Montheankul-K/jod-jod/smtpin"
+	"github.com/Montheankul-K/jod-jod/virusscan"
 	"github.com/go-redis/redis/v8"
+	"time"
 )
 
 func (s *server) healthCheckRouter() {
@@ -52,7 +95,20 @@ func (s *server) transactionRouter() {
 	transactionMiddleware := transaction_middleware.NewTransactionMiddleware(s.app.Logger)
 
 	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
-	transactionService := transaction.NewTransactionService(s.cfg, transactionRepository, s.app.Logger)
+	insightRepository := insight_repository.NewInsightRepository(s.db.Connect(), s.app.Logger, redisClient)
+	insightService := insight.NewInsightService(transactionRepository, insightRepository, s.app.Logger)
+	netWorthRepository := networth_repository.NewNetWorthRepository(s.db.Connect(), s.app.Logger, redisClient)
+	netWorthService := networth.NewNetWorthService(netWorthRepository, transactionRepository, s.app.Logger)
+	ocrProvider, err := ocr.NewOCRProvider(s.cfg.OCR, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	blobStore, err := blobstore.NewBlobStore(s.cfg.Storage, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	attachmentRepository := attachment_repository.NewAttachmentRepository(s.db.Connect(), s.app.Logger, redisClient)
+	transactionService := transaction.NewTransactionService(s.cfg, transactionRepository, attachmentRepository, insightService, netWorthService, ocrProvider, blobStore, s.app.Logger)
 	transactionHandler := transaction_handler.NewTransactionHandler(transactionService, s.app.Logger)
 
 	router.GET("/detail/:spender-id", transactionHandler.GetDetails, userMiddleware.ValidateToken, transactionMiddleware.SetGetByTxnTypeRequest)
@@ -61,8 +117,365 @@ func (s *server) transactionRouter() {
 	router.GET("/category/:spender-id", transactionHandler.GetByCategory, userMiddleware.ValidateToken, transactionMiddleware.SetGetByCategoryRequest)
 	router.GET("/period/:spender-id", transactionHandler.GetByPeriod, userMiddleware.ValidateToken, transactionMiddleware.SetGetByTxnTypeRequest, transactionMiddleware.SetPeriodFilter)
 	router.GET("/all", transactionHandler.GetAllTxn, userMiddleware.ValidateToken, transactionMiddleware.SetGetAllTxnFilter, transactionMiddleware.SetTxnPagination)
+	router.GET("/export", transactionHandler.Export, userMiddleware.ValidateToken, transactionMiddleware.SetGetAllTxnFilter)
+	router.GET("/:txn-id/image", transactionHandler.GetImage, userMiddleware.ValidateToken)
+	router.GET("/:txn-id/items", transactionHandler.GetItems, userMiddleware.ValidateToken)
+	router.PUT("/:txn-id/items/:item-id", transactionHandler.UpdateItem, userMiddleware.ValidateToken)
 	router.POST("/save/manual", transactionHandler.SaveByManual, userMiddleware.ValidateToken)
-	router.POST("/save/slip", transactionHandler.SaveFromSlip, userMiddleware.ValidateToken)
+	router.POST("/save/receipt", transactionHandler.SaveReceipt, userMiddleware.ValidateToken)
 	router.PUT("/update/:txn-id", transactionHandler.Update, userMiddleware.ValidateToken)
 	router.DELETE("/delete/:spender-id/:txn-id", transactionHandler.Delete, userMiddleware.ValidateToken)
 }
+
+func (s *server) recurringRouter() {
+	router := s.app.Group("/v1/recurring")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	recurringRepository := recurring_repository.NewRecurringRepository(s.db.Connect(), s.app.Logger, redisClient)
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	recurringService := recurring.NewRecurringService(recurringRepository, transactionRepository, s.app.Logger)
+	recurringHandler := recurring_handler.NewRecurringHandler(recurringService, s.app.Logger)
+
+	router.GET("/get/:spender-id", recurringHandler.GetRules, userMiddleware.ValidateToken)
+	router.POST("/create", recurringHandler.CreateRule, userMiddleware.ValidateToken)
+	router.DELETE("/delete/:spender-id/:rule-id", recurringHandler.DeleteRule, userMiddleware.ValidateToken)
+	router.GET("/subscriptions/:spender-id", recurringHandler.DetectSubscriptions, userMiddleware.ValidateToken)
+	router.POST("/subscriptions/confirm/:spender-id/:subscription-id", recurringHandler.ConfirmSubscription, userMiddleware.ValidateToken)
+	router.POST("/subscriptions/dismiss/:spender-id/:subscription-id", recurringHandler.DismissSubscription, userMiddleware.ValidateToken)
+}
+
+func (s *server) forecastRouter() {
+	router := s.app.Group("/v1/forecasts")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	recurringRepository := recurring_repository.NewRecurringRepository(s.db.Connect(), s.app.Logger, redisClient)
+	forecastService := forecast.NewForecastService(transactionRepository, recurringRepository, s.app.Logger)
+	forecastHandler := forecast_handler.NewForecastHandler(forecastService, s.app.Logger)
+
+	router.GET("/get/:spender-id", forecastHandler.GetForecast, userMiddleware.ValidateToken)
+}
+
+func (s *server) insightRouter() {
+	router := s.app.Group("/v1/insights")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	insightRepository := insight_repository.NewInsightRepository(s.db.Connect(), s.app.Logger, redisClient)
+	insightService := insight.NewInsightService(transactionRepository, insightRepository, s.app.Logger)
+	insightHandler := insight_handler.NewInsightHandler(insightService, s.app.Logger)
+
+	router.GET("/anomalies/:spender-id", insightHandler.GetAnomalies, userMiddleware.ValidateToken)
+}
+
+func (s *server) netWorthRouter() {
+	router := s.app.Group("/v1/net-worth")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	netWorthRepository := networth_repository.NewNetWorthRepository(s.db.Connect(), s.app.Logger, redisClient)
+	netWorthService := networth.NewNetWorthService(netWorthRepository, transactionRepository, s.app.Logger)
+	netWorthHandler := networth_handler.NewNetWorthHandler(netWorthService, s.app.Logger)
+
+	router.GET("/items/:spender-id", netWorthHandler.GetItems, userMiddleware.ValidateToken)
+	router.POST("/items/create", netWorthHandler.CreateItem, userMiddleware.ValidateToken)
+	router.DELETE("/items/delete/:spender-id/:item-id", netWorthHandler.DeleteItem, userMiddleware.ValidateToken)
+	router.GET("/history/:spender-id", netWorthHandler.GetHistory, userMiddleware.ValidateToken)
+	router.POST("/recompute/:spender-id", netWorthHandler.RecomputeHistory, userMiddleware.ValidateToken)
+
+	s.scheduler.Schedule(scheduler.Job{
+		Name: "net-worth-snapshot",
+		Next: scheduler.Daily(0, 5, time.Local),
+		Run: func(now time.Time) error {
+			return netWorthService.RecordSnapshots(now.AddDate(0, 0, -1))
+		},
+	})
+}
+
+func (s *server) budgetRouter() {
+	router := s.app.Group("/v1/budgets")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	budgetRepository := budget_repository.NewBudgetRepository(s.db.Connect(), s.app.Logger, redisClient)
+	budgetService := budget.NewBudgetService(budgetRepository, s.app.Logger)
+	budgetHandler := budget_handler.NewBudgetHandler(budgetService, s.app.Logger)
+
+	router.GET("/get/:spender-id", budgetHandler.GetBudgets, userMiddleware.ValidateToken)
+	router.POST("/set", budgetHandler.SetBudget, userMiddleware.ValidateToken)
+	router.DELETE("/delete/:spender-id/:budget-id", budgetHandler.DeleteBudget, userMiddleware.ValidateToken)
+}
+
+func (s *server) digestRouter() {
+	router := s.app.Group("/v1/digests")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	budgetRepository := budget_repository.NewBudgetRepository(s.db.Connect(), s.app.Logger, redisClient)
+	digestRepository := digest_repository.NewDigestRepository(s.db.Connect(), s.app.Logger)
+	notifier := notification.NewNotifier(s.cfg.Notification, s.app.Logger)
+	// The chart sent with a digest is set in the statement fonts, as the
+	// chart API's are.
+	fonts, err := statement.LoadFonts(s.cfg.Statement)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	digestService := digest.NewDigestService(transactionRepository, budgetRepository, digestRepository, notifier, fonts.Regular, s.app.Logger)
+	digestHandler := digest_handler.NewDigestHandler(digestService, s.app.Logger)
+
+	router.GET("/get/:spender-id", digestHandler.GetDigest, userMiddleware.ValidateToken)
+
+	// Users span timezones, so the job runs hourly and the service decides who
+	// is due.
+	s.scheduler.Schedule(scheduler.Job{
+		Name: "monthly-digest",
+		Next: scheduler.Every(time.Hour),
+		Run:  digestService.SendDueDigests,
+	})
+}
+
+func (s *server) slipRouter() {
+	router := s.app.Group("/v1/slips")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	insightRepository := insight_repository.NewInsightRepository(s.db.Connect(), s.app.Logger, redisClient)
+	insightService := insight.NewInsightService(transactionRepository, insightRepository, s.app.Logger)
+	netWorthRepository := networth_repository.NewNetWorthRepository(s.db.Connect(), s.app.Logger, redisClient)
+	netWorthService := networth.NewNetWorthService(netWorthRepository, transactionRepository, s.app.Logger)
+	ocrProvider, err := ocr.NewOCRProvider(s.cfg.OCR, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	blobStore, err := blobstore.NewBlobStore(s.cfg.Storage, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	attachmentRepository := attachment_repository.NewAttachmentRepository(s.db.Connect(), s.app.Logger, redisClient)
+	transactionService := transaction.NewTransactionService(s.cfg, transactionRepository, attachmentRepository, insightService, netWorthService, ocrProvider, blobStore, s.app.Logger)
+	slipJobRepository := slipjob_repository.NewSlipJobRepository(s.db.Connect(), s.app.Logger)
+	slipJobService := slipjob.NewSlipJobService(slipJobRepository, transactionService, s.app.Logger)
+	slipHandler := slip_handler.NewSlipHandler(slipJobService, s.app.Logger)
+
+	router.POST("/upload", slipHandler.Upload, userMiddleware.ValidateToken)
+	router.GET("/jobs/:id", slipHandler.GetJob, userMiddleware.ValidateToken)
+	router.POST("/batches", slipHandler.UploadBatch, userMiddleware.ValidateToken)
+	router.GET("/batches/:id", slipHandler.GetBatch, userMiddleware.ValidateToken)
+	router.GET("/reviews", slipHandler.GetReviews, userMiddleware.ValidateToken)
+	router.GET("/reviews/:id/image", slipHandler.GetReviewImage, userMiddleware.ValidateToken)
+	router.PUT("/reviews/:id", slipHandler.CorrectReview, userMiddleware.ValidateToken)
+	router.POST("/reviews/:id/approve", slipHandler.ApproveReview, userMiddleware.ValidateToken)
+	router.POST("/reviews/:id/reject", slipHandler.RejectReview, userMiddleware.ValidateToken)
+	// Kept for clients of the old synchronous endpoint, which now get a job
+	// back as well.
+	s.app.POST("/v1/transactions/save/slip", slipHandler.Upload, userMiddleware.ValidateToken)
+
+	slipJobService.Start(slipjob.DefaultWorkers)
+	s.onShutdown = append(s.onShutdown, slipJobService.Stop)
+}
+
+func (s *server) attachmentRouter() {
+	router := s.app.Group("/v1/transactions/:txn-id/attachments")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	attachmentRepository := attachment_repository.NewAttachmentRepository(s.db.Connect(), s.app.Logger, redisClient)
+	blobStore, err := blobstore.NewBlobStore(s.cfg.Storage, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	scanner, err := virusscan.NewScanner(s.cfg.Attachment)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	attachmentService := attachment.NewAttachmentService(s.cfg.Attachment, attachmentRepository, transactionRepository, blobStore, scanner, s.app.Logger)
+	attachmentHandler := attachment_handler.NewAttachmentHandler(attachmentService, s.app.Logger)
+
+	router.GET("", attachmentHandler.GetAttachments, userMiddleware.ValidateToken)
+	router.POST("", attachmentHandler.Upload, userMiddleware.ValidateToken)
+	router.GET("/:attachment-id", attachmentHandler.GetFile, userMiddleware.ValidateToken)
+	router.DELETE("/:attachment-id", attachmentHandler.Delete, userMiddleware.ValidateToken)
+}
+
+func (s *server) inboxRouter() {
+	if s.cfg.Inbox == nil {
+		return
+	}
+	router := s.app.Group("/v1/inbox")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	insightRepository := insight_repository.NewInsightRepository(s.db.Connect(), s.app.Logger, redisClient)
+	insightService := insight.NewInsightService(transactionRepository, insightRepository, s.app.Logger)
+	netWorthRepository := networth_repository.NewNetWorthRepository(s.db.Connect(), s.app.Logger, redisClient)
+	netWorthService := networth.NewNetWorthService(netWorthRepository, transactionRepository, s.app.Logger)
+	ocrProvider, err := ocr.NewOCRProvider(s.cfg.OCR, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	blobStore, err := blobstore.NewBlobStore(s.cfg.Storage, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	attachmentRepository := attachment_repository.NewAttachmentRepository(s.db.Connect(), s.app.Logger, redisClient)
+	transactionService := transaction.NewTransactionService(s.cfg, transactionRepository, attachmentRepository, insightService, netWorthService, ocrProvider, blobStore, s.app.Logger)
+	inboxRepository := inbox_repository.NewInboxRepository(s.db.Connect(), s.app.Logger)
+	inboxService := inbox.NewInboxService(s.cfg.Inbox, inboxRepository, attachmentRepository, transactionService, blobStore, s.app.Logger)
+	inboxHandler := inbox_handler.NewInboxHandler(s.cfg.Inbox, inboxService, s.app.Logger)
+
+	// Mail relays post here with the shared secret instead of a user token.
+	if s.cfg.Inbox.IngestSecret != "" {
+		router.POST("/messages", inboxHandler.Ingest)
+	}
+	router.GET("", inboxHandler.GetInbox, userMiddleware.ValidateToken)
+	router.POST("/rotate", inboxHandler.RotateAddress, userMiddleware.ValidateToken)
+	router.GET("/drafts", inboxHandler.GetDrafts, userMiddleware.ValidateToken)
+	router.GET("/emails/:id", inboxHandler.GetEmail, userMiddleware.ValidateToken)
+	router.GET("/emails/:id/raw", inboxHandler.GetRaw, userMiddleware.ValidateToken)
+	router.PUT("/emails/:id", inboxHandler.CorrectDraft, userMiddleware.ValidateToken)
+	router.POST("/emails/:id/approve", inboxHandler.ApproveDraft, userMiddleware.ValidateToken)
+	router.POST("/emails/:id/discard", inboxHandler.DiscardDraft, userMiddleware.ValidateToken)
+
+	if s.cfg.Inbox.SMTPAddress == "" {
+		return
+	}
+	maxSize := int64(inbox.DefaultMaxSize)
+	if s.cfg.Inbox.MaxSize > 0 {
+		maxSize = s.cfg.Inbox.MaxSize
+	}
+	smtpServer := smtpin.NewServer(s.cfg.Inbox.SMTPAddress, s.cfg.Inbox.Domain, maxSize, inboxHandler.ReceiveSMTP, s.app.Logger)
+	if err = smtpServer.Start(); err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	s.onShutdown = append(s.onShutdown, smtpServer.Stop)
+}
+
+func (s *server) importRouter() {
+	router := s.app.Group("/v1/imports")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	netWorthRepository := networth_repository.NewNetWorthRepository(s.db.Connect(), s.app.Logger, redisClient)
+	netWorthService := networth.NewNetWorthService(netWorthRepository, transactionRepository, s.app.Logger)
+	importRepository := import_repository.NewImportRepository(s.db.Connect(), s.app.Logger, redisClient)
+	accountRepository := account_repository.NewAccountRepository(s.db.Connect(), s.app.Logger)
+	blobStore, err := blobstore.NewBlobStore(s.cfg.Storage, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	importService := imports.NewImportService(importRepository, accountRepository, netWorthService, blobStore, s.app.Logger)
+	importHandler := import_handler.NewImportHandler(importService, s.app.Logger)
+
+	router.POST("/csv/preview", importHandler.PreviewCSV, userMiddleware.ValidateToken)
+	router.POST("/csv", importHandler.CommitCSV, userMiddleware.ValidateToken)
+	router.POST("/statement/preview", importHandler.PreviewStatement, userMiddleware.ValidateToken)
+	router.POST("/statement", importHandler.CommitStatement, userMiddleware.ValidateToken)
+	router.POST("/beancount/preview", importHandler.PreviewJournal, userMiddleware.ValidateToken)
+	router.POST("/beancount", importHandler.CommitJournal, userMiddleware.ValidateToken)
+	router.GET("", importHandler.GetBatches, userMiddleware.ValidateToken)
+	router.GET("/:id", importHandler.GetBatch, userMiddleware.ValidateToken)
+	router.DELETE("/:id", importHandler.Rollback, userMiddleware.ValidateToken)
+}
+
+func (s *server) accountRouter() {
+	router := s.app.Group("/v1/accounts")
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	accountRepository := account_repository.NewAccountRepository(s.db.Connect(), s.app.Logger)
+	accountService := account.NewAccountService(accountRepository, s.app.Logger)
+	accountHandler := account_handler.NewAccountHandler(accountService, s.app.Logger)
+
+	router.GET("", accountHandler.GetAccounts, userMiddleware.ValidateToken)
+	router.POST("", accountHandler.CreateAccount, userMiddleware.ValidateToken)
+	router.GET("/:id", accountHandler.GetAccount, userMiddleware.ValidateToken)
+	router.DELETE("/:id", accountHandler.DeleteAccount, userMiddleware.ValidateToken)
+}
+
+func (s *server) statementRouter() {
+	router := s.app.Group("/v1/statements")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	digestRepository := digest_repository.NewDigestRepository(s.db.Connect(), s.app.Logger)
+	blobStore, err := blobstore.NewBlobStore(s.cfg.Storage, s.cfg.AWS)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	fonts, err := statement.LoadFonts(s.cfg.Statement)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	if !fonts.HasThai() {
+		s.app.Logger.Warn("no Thai font found, set statement.font_path so Thai shows on statements")
+	}
+	statementService := statement.NewStatementService(transactionRepository, digestRepository, blobStore, fonts, s.app.Logger)
+	statementHandler := statement_handler.NewStatementHandler(statementService, s.app.Logger)
+
+	router.GET("/:month", statementHandler.GetStatement, userMiddleware.ValidateToken)
+}
+
+func (s *server) analyticsRouter() {
+	router := s.app.Group("/v1/charts")
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+
+	userMiddleware := user_middleware.NewUserMiddleware(s.cfg, s.app.Logger)
+
+	transactionRepository := transaction_repository.NewTransactionRepository(s.db.Connect(), s.app.Logger, redisClient)
+	digestRepository := digest_repository.NewDigestRepository(s.db.Connect(), s.app.Logger)
+	// Charts share the statement fonts, so PNG labels show Thai wherever
+	// statements do.
+	fonts, err := statement.LoadFonts(s.cfg.Statement)
+	if err != nil {
+		s.app.Logger.Fatal(err)
+	}
+	analyticsService := analytics.NewAnalyticsService(transactionRepository, digestRepository, fonts.Regular, s.app.Logger)
+	analyticsHandler := analytics_handler.NewAnalyticsHandler(analyticsService, s.app.Logger)
+
+	router.GET("/:chart", analyticsHandler.GetChart, userMiddleware.ValidateToken)
+}
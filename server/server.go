@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/Montheankul-K/jod-jod/config"
 	"github.com/Montheankul-K/jod-jod/db"
+	"github.com/Montheankul-K/jod-jod/scheduler"
 	"github.com/go-redis/redis/v8"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -26,6 +27,8 @@ type server struct {
 	db          db.DB
 	cfg         *config.Config
 	redisClient *redis.Client
+	scheduler   scheduler.IScheduler
+	onShutdown  []func()
 }
 
 var (
@@ -42,6 +45,7 @@ func InitServer(cfg *config.Config, db db.DB) Server {
 			db:          db,
 			cfg:         cfg,
 			redisClient: redisClient,
+			scheduler:   scheduler.NewScheduler(app.Logger),
 		}
 	})
 	return srv
@@ -56,6 +60,20 @@ func (s *server) Start() error {
 	s.healthCheckRouter()
 	s.userRouter()
 	s.transactionRouter()
+	s.recurringRouter()
+	s.forecastRouter()
+	s.insightRouter()
+	s.netWorthRouter()
+	s.budgetRouter()
+	s.digestRouter()
+	s.slipRouter()
+	s.attachmentRouter()
+	s.inboxRouter()
+	s.accountRouter()
+	s.importRouter()
+	s.statementRouter()
+	s.analyticsRouter()
+	s.scheduler.Start()
 
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
@@ -67,9 +85,17 @@ func (s *server) Start() error {
 	return nil
 }
 
+// streamingRoutes are skipped by the timeout middleware, which buffers the
+// whole response and so would hold a streamed export in memory.
+var streamingRoutes = map[string]bool{
+	"/v1/transactions/export": true,
+}
+
 func setTimeoutMiddleware(timeout time.Duration) echo.MiddlewareFunc {
 	return middleware.TimeoutWithConfig(middleware.TimeoutConfig{
-		Skipper:      middleware.DefaultSkipper,
+		Skipper: func(c echo.Context) bool {
+			return streamingRoutes[c.Path()]
+		},
 		ErrorMessage: "error: request timeout",
 		Timeout:      timeout * time.Second,
 	})
@@ -88,6 +114,10 @@ func (s *server) listenAndServe() error {
 func (s *server) gracefullyShutdown(shutdown <-chan os.Signal) {
 	<-shutdown
 	s.app.Logger.Info("shutting down the server")
+	s.scheduler.Stop()
+	for _, stop := range s.onShutdown {
+		stop()
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := s.app.Shutdown(ctx); err != nil {
@@ -0,0 +1,228 @@
+package slip
+
+import (
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"strings"
+	"unicode"
+)
+
+// bankAliases are the ways a bank is written on slips, including on the
+// receiver's side of a transfer from another bank.
+var bankAliases = map[string][]string{
+	BankKBank:       {"กสิกรไทย", "ธ.กสิกร", "kasikornbank", "kasikorn", "kbank"},
+	BankSCB:         {"ไทยพาณิชย์", "scb"},
+	BankKrungthai:   {"กรุงไทย", "krungthai", "ktb"},
+	BankBangkokBank: {"ธนาคารกรุงเทพ", "ธ.กรุงเทพ", "bangkok bank", "bualuang", "bbl"},
+	BankTTB:         {"ทหารไทยธนชาต", "ทีทีบี", "ttb"},
+	BankKrungsri:    {"กรุงศรีอยุธยา", "กรุงศรี", "krungsri", "bank of ayudhya"},
+	BankTrueMoney:   {"truemoney", "ทรูมันนี่"},
+	BankShopeePay:   {"shopeepay", "ช้อปปี้เพย์"},
+	BankLinePay:     {"rabbit line pay", "line pay"},
+	BankPromptPay:   {"พร้อมเพย์", "promptpay"},
+}
+
+// lookupBank returns the bank named in text, preferring the longest alias so
+// "ธนาคารกรุงเทพ" is not mistaken for a shorter match.
+func lookupBank(text string) string {
+	lower := strings.ToLower(text)
+	var bank string
+	var longest int
+	for code, aliases := range bankAliases {
+		for _, alias := range aliases {
+			if len(alias) > longest && containsWord(lower, alias) {
+				bank, longest = code, len(alias)
+			}
+		}
+	}
+	return bank
+}
+
+// containsWord matches Latin aliases on word boundaries so "scb" is not found
+// inside a longer word. Thai is written without spaces, so Thai aliases match
+// anywhere.
+func containsWord(text, word string) bool {
+	from := 0
+	for {
+		i := strings.Index(text[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		if !isLatin(word) || (!latinBefore(text, start) && !latinAfter(text, end)) {
+			return true
+		}
+		from = start + 1
+	}
+}
+
+func isLatin(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func latinBefore(text string, i int) bool {
+	return i > 0 && isAlnum(rune(text[i-1]))
+}
+
+func latinAfter(text string, i int) bool {
+	return i < len(text) && isAlnum(rune(text[i]))
+}
+
+func isAlnum(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Labels every bank shares. Bank parsers add their own wording on top.
+var (
+	commonAmountLabels    = []string{"จำนวนเงิน", "จำนวน", "amount"}
+	commonFeeLabels       = []string{"ค่าธรรมเนียม", "fee"}
+	commonReferenceLabels = []string{"รหัสอ้างอิง", "เลขที่อ้างอิง", "เลขที่รายการ", "หมายเลขอ้างอิง", "reference no", "ref no"}
+	commonMemoLabels      = []string{"บันทึกช่วยจำ", "บันทึก", "memo", "note"}
+	commonDateLabels      = []string{"วันที่ทำรายการ", "วันที่", "date"}
+)
+
+type labels struct {
+	amount    []string
+	fee       []string
+	reference []string
+	memo      []string
+	date      []string
+}
+
+func withCommon(extra labels) labels {
+	return labels{
+		amount:    append(extra.amount, commonAmountLabels...),
+		fee:       append(extra.fee, commonFeeLabels...),
+		reference: append(extra.reference, commonReferenceLabels...),
+		memo:      append(extra.memo, commonMemoLabels...),
+		date:      append(extra.date, commonDateLabels...),
+	}
+}
+
+type bankParser struct {
+	bank    string
+	markers []string
+	labels  labels
+}
+
+func (p *bankParser) Bank() string {
+	return p.bank
+}
+
+func (p *bankParser) Detect(text string) bool {
+	lower := strings.ToLower(text)
+	for _, marker := range p.markers {
+		if containsWord(lower, marker) {
+			return true
+		}
+	}
+	return lookupBank(text) == p.bank
+}
+
+func (p *bankParser) Parse(lines []ocr.Line) (*Slip, error) {
+	return extract(p.bank, p.labels, lines)
+}
+
+// K PLUS prints "เลขที่รายการ" for the reference and puts the memo under
+// "บันทึกช่วยจำ".
+func newKBankParser() Parser {
+	return &bankParser{
+		bank:    BankKBank,
+		markers: []string{"k plus", "k+"},
+		labels:  withCommon(labels{}),
+	}
+}
+
+// SCB EASY heads the parties with "จาก" and "ไปยัง" and writes the time after
+// a dash, e.g. "12 มี.ค. 2567 - 10:15".
+func newSCBParser() Parser {
+	return &bankParser{
+		bank:    BankSCB,
+		markers: []string{"scb easy"},
+		labels:  withCommon(labels{}),
+	}
+}
+
+// Krungthai NEXT puts the date at the bottom under "วันที่ทำรายการ".
+func newKrungthaiParser() Parser {
+	return &bankParser{
+		bank:    BankKrungthai,
+		markers: []string{"krungthai next"},
+		labels:  withCommon(labels{}),
+	}
+}
+
+// Bualuang mBanking slips are usually in English.
+func newBangkokBankParser() Parser {
+	return &bankParser{
+		bank:    BankBangkokBank,
+		markers: []string{"bualuang mbanking"},
+		labels: withCommon(labels{
+			amount:    []string{"transfer amount"},
+			reference: []string{"transaction reference", "reference"},
+			date:      []string{"transaction date"},
+		}),
+	}
+}
+
+// ttb touch labels the reference "รหัสอ้างอิงธนาคาร".
+func newTTBParser() Parser {
+	return &bankParser{
+		bank:    BankTTB,
+		markers: []string{"ttb touch"},
+		labels: withCommon(labels{
+			reference: []string{"รหัสอ้างอิงธนาคาร"},
+		}),
+	}
+}
+
+// Krungsri KMA prints seconds in the time and uses "เลขที่อ้างอิง".
+func newKrungsriParser() Parser {
+	return &bankParser{
+		bank:    BankKrungsri,
+		markers: []string{"kma", "krungsri app"},
+		labels:  withCommon(labels{}),
+	}
+}
+
+// E-wallets send through PromptPay and share one layout: the parties are
+// wallet IDs under "จาก" and "ไปยัง" and the reference is "รหัสอ้างอิง".
+func newEWalletParser(bank string, markers ...string) Parser {
+	return &bankParser{
+		bank:    bank,
+		markers: markers,
+		labels: withCommon(labels{
+			reference: []string{"เลขที่ธุรกรรม", "transaction id"},
+		}),
+	}
+}
+
+type genericParser struct {
+	labels labels
+}
+
+func newGenericParser() Parser {
+	return &genericParser{
+		labels: withCommon(labels{
+			amount:    []string{"transfer amount"},
+			reference: []string{"รหัสอ้างอิงธนาคาร", "เลขที่ธุรกรรม", "transaction reference", "transaction id", "reference"},
+			date:      []string{"transaction date"},
+		}),
+	}
+}
+
+func (p *genericParser) Bank() string {
+	return BankUnknown
+}
+
+func (p *genericParser) Detect(text string) bool {
+	return false
+}
+
+func (p *genericParser) Parse(lines []ocr.Line) (*Slip, error) {
+	return extract(BankUnknown, p.labels, lines)
+}
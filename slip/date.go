@@ -0,0 +1,140 @@
+package slip
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	textDateRe    = regexp.MustCompile(`(\d{1,2})\s*([\p{Thai}A-Za-z\.]+)\s*(\d{4}|\d{2})`)
+	numericDateRe = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4}|\d{2})\b`)
+	timeRe        = regexp.MustCompile(`\b(\d{1,2})[:\.](\d{2})(?:[:\.](\d{2}))?\b`)
+)
+
+// thaiMonths maps month names and abbreviations, with the dots removed since
+// OCR often drops them, to their month.
+var thaiMonths = map[string]time.Month{
+	"มค": time.January, "มกราคม": time.January,
+	"กพ": time.February, "กุมภาพันธ์": time.February,
+	"มีค": time.March, "มีนาคม": time.March,
+	"เมย": time.April, "เมษายน": time.April,
+	"พค": time.May, "พฤษภาคม": time.May,
+	"มิย": time.June, "มิถุนายน": time.June,
+	"กค": time.July, "กรกฎาคม": time.July,
+	"สค": time.August, "สิงหาคม": time.August,
+	"กย": time.September, "กันยายน": time.September,
+	"ตค": time.October, "ตุลาคม": time.October,
+	"พย": time.November, "พฤศจิกายน": time.November,
+	"ธค": time.December, "ธันวาคม": time.December,
+}
+
+var englishMonths = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+var bangkok = loadBangkok()
+
+func loadBangkok() *time.Location {
+	loc, err := time.LoadLocation("Asia/Bangkok")
+	if err != nil {
+		return time.FixedZone("Asia/Bangkok", 7*60*60)
+	}
+	return loc
+}
+
+// ParseDate reads a date the way slips print them, for other documents from
+// the same banks and stores.
+func ParseDate(text string) (time.Time, bool) {
+	return parseDate(text)
+}
+
+// parseDate reads a slip date such as "12 มี.ค. 67 10:15 น.",
+// "12 Mar 2024, 10:15" or "12/03/2567 10:15:30" in Bangkok time. Thai slips
+// count years in the Buddhist era, often as two digits.
+func parseDate(text string) (time.Time, bool) {
+	day, month, year, rest, ok := parseDay(text)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	var hour, minute, second int
+	if match := timeRe.FindStringSubmatch(rest); match != nil {
+		hour, _ = strconv.Atoi(match[1])
+		minute, _ = strconv.Atoi(match[2])
+		if match[3] != "" {
+			second, _ = strconv.Atoi(match[3])
+		}
+		if hour > 23 || minute > 59 || second > 59 {
+			hour, minute, second = 0, 0, 0
+		}
+	}
+
+	date := time.Date(year, month, day, hour, minute, second, 0, bangkok)
+	if date.Day() != day {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// parseDay returns the date in text and whatever follows it, where the time
+// is looked for.
+func parseDay(text string) (int, time.Month, int, string, bool) {
+	for _, match := range textDateRe.FindAllStringSubmatchIndex(text, -1) {
+		day, _ := strconv.Atoi(text[match[2]:match[3]])
+		token := text[match[4]:match[5]]
+		yearText := text[match[6]:match[7]]
+
+		month, thai := monthFromToken(token)
+		if month == 0 || day < 1 || day > 31 {
+			continue
+		}
+		year, _ := strconv.Atoi(yearText)
+		return day, month, normalizeYear(year, len(yearText), thai), text[match[1]:], true
+	}
+
+	if match := numericDateRe.FindStringSubmatchIndex(text); match != nil {
+		day, _ := strconv.Atoi(text[match[2]:match[3]])
+		month, _ := strconv.Atoi(text[match[4]:match[5]])
+		yearText := text[match[6]:match[7]]
+		year, _ := strconv.Atoi(yearText)
+		if day < 1 || day > 31 || month < 1 || month > 12 {
+			return 0, 0, 0, "", false
+		}
+		// A two digit numeric year is ambiguous; 50 and above can only be
+		// Buddhist era for any slip this app will see.
+		return day, time.Month(month), normalizeYear(year, len(yearText), year >= 50), text[match[1]:], true
+	}
+	return 0, 0, 0, "", false
+}
+
+func monthFromToken(token string) (time.Month, bool) {
+	key := strings.ReplaceAll(token, ".", "")
+	if month, ok := thaiMonths[key]; ok {
+		return month, true
+	}
+	if len(key) >= 3 {
+		if month, ok := englishMonths[strings.ToLower(key[:3])]; ok {
+			return month, false
+		}
+	}
+	return 0, false
+}
+
+// normalizeYear converts Buddhist-era years to Gregorian. Four digit years
+// are unambiguous; two digit years follow the month's language.
+func normalizeYear(year, digits int, buddhist bool) int {
+	if digits == 4 {
+		if year >= 2400 {
+			return year - 543
+		}
+		return year
+	}
+	if buddhist {
+		return 2500 + year - 543
+	}
+	return 2000 + year
+}
@@ -0,0 +1,371 @@
+package slip
+
+import (
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	moneyRe    = regexp.MustCompile(`\d{1,3}(?:,\d{3})+(?:\.\d{1,2})?|\d+\.\d{1,2}|\d+`)
+	currencyRe = regexp.MustCompile(`(?i)บาท|thb|baht|bath|฿`)
+	accountRe  = regexp.MustCompile(`^[xX*\d][xX*\d-]{5,}$`)
+	spaceRe    = regexp.MustCompile(`\s+`)
+)
+
+var (
+	billPaymentKeywords = []string{"ชำระเงิน", "ชำระบิล", "จ่ายบิล", "bill payment", "payment successful"}
+	partyLabels         = []string{"จาก", "ไปยัง", "ผู้โอน", "ผู้รับ", "from", "to"}
+	statusKeywords      = []string{"สำเร็จ", "successful", "success", "completed"}
+)
+
+// extract reads a slip by its labels. A label's value is the rest of its own
+// line or, when that is empty, the next line, which covers both "จำนวน: 100.00"
+// and layouts that print the value beside or under the label.
+func extract(bank string, labels labels, lines []ocr.Line) (*Slip, error) {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = strings.TrimSpace(spaceRe.ReplaceAllString(line.Text, " "))
+	}
+
+	result := &Slip{Bank: bank, Kind: KindTransfer}
+	used := make(map[int]bool)
+	var sources []int
+	for _, text := range texts {
+		if containsAny(strings.ToLower(text), billPaymentKeywords) {
+			result.Kind = KindBillPayment
+			break
+		}
+	}
+
+	amountIdx := -1
+	if value, i, ok := findLabel(texts, labels.amount, used); ok {
+		if amount, ok := parseMoney(value); ok {
+			result.Amount, amountIdx = amount, i
+		}
+	}
+	if amountIdx < 0 {
+		// Without a recognisable label, take the first amount written with a
+		// currency that is not the fee.
+		feeLabels := sortedLabels(labels.fee)
+		for i, text := range texts {
+			if used[i] || !currencyRe.MatchString(text) || hasLabel(text, feeLabels) || (i > 0 && isLabelOnly(texts[i-1], feeLabels)) {
+				continue
+			}
+			if amount, ok := parseMoney(text); ok {
+				result.Amount, amountIdx = amount, i
+				break
+			}
+		}
+	}
+	if amountIdx < 0 {
+		return nil, ErrAmountNotFound
+	}
+	used[amountIdx] = true
+	sources = append(sources, amountIdx)
+	result.addRegion(FieldAmount, lines[amountIdx])
+
+	if value, i, ok := findLabel(texts, labels.fee, used); ok {
+		if fee, ok := parseMoney(value); ok {
+			result.Fee = fee
+			result.addRegion(FieldFee, lines[i])
+		}
+	}
+	if value, i, ok := findLabel(texts, labels.reference, used); ok {
+		result.Reference = strings.ReplaceAll(value, " ", "")
+		sources = append(sources, i)
+		result.addRegion(FieldReference, lines[i])
+	}
+	if value, i, ok := findLabel(texts, labels.memo, used); ok {
+		result.Memo = value
+		result.addRegion(FieldMemo, lines[i])
+	}
+
+	dateIdx := -1
+	if value, i, ok := findLabel(texts, labels.date, used); ok {
+		if date, ok := parseDateNear(texts, value, i); ok {
+			result.Date, dateIdx = date, i
+		}
+	}
+	if dateIdx < 0 {
+		for i, text := range texts {
+			if used[i] || accountRe.MatchString(strings.ReplaceAll(text, " ", "")) {
+				continue
+			}
+			if date, ok := parseDateNear(texts, text, i); ok {
+				result.Date, dateIdx = date, i
+				break
+			}
+		}
+	}
+	if dateIdx >= 0 {
+		used[dateIdx] = true
+		sources = append(sources, dateIdx)
+		result.addRegion(FieldDate, lines[dateIdx])
+	}
+
+	result.Sender, result.Receiver = findParties(texts, used)
+	if result.Sender.Bank == "" && bank != BankUnknown {
+		result.Sender.Bank = bank
+	}
+
+	result.Confidence = 100
+	for _, i := range sources {
+		if lines[i].Confidence > 0 {
+			result.Confidence = math.Min(result.Confidence, lines[i].Confidence)
+		}
+	}
+	return result, nil
+}
+
+func (s *Slip) addRegion(field string, line ocr.Line) {
+	s.Regions = append(s.Regions, Region{
+		Field:      field,
+		Text:       line.Text,
+		Box:        line.Box,
+		Confidence: line.Confidence,
+	})
+}
+
+// findLabel returns the value of the first line that starts with one of the
+// labels, marking the lines it read as used.
+func findLabel(texts []string, labels []string, used map[int]bool) (string, int, bool) {
+	sorted := sortedLabels(labels)
+	for i, text := range texts {
+		if used[i] {
+			continue
+		}
+		rest, ok := cutLabel(text, sorted)
+		if !ok {
+			continue
+		}
+		used[i] = true
+		if rest != "" {
+			return rest, i, true
+		}
+		if i+1 < len(texts) && !used[i+1] {
+			used[i+1] = true
+			return texts[i+1], i + 1, true
+		}
+		return "", i, false
+	}
+	return "", -1, false
+}
+
+// sortedLabels puts longer labels first so "บันทึกช่วยจำ" wins over "บันทึก".
+func sortedLabels(labels []string) []string {
+	sorted := append([]string(nil), labels...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	return sorted
+}
+
+func cutLabel(text string, labels []string) (string, bool) {
+	lower := strings.ToLower(text)
+	for _, label := range labels {
+		if !strings.HasPrefix(lower, label) {
+			continue
+		}
+		// "to" must not match "total", but Thai labels run straight into
+		// their values.
+		if isLatin(label) && latinAfter(lower, len(label)) {
+			continue
+		}
+		return strings.Trim(text[len(label):], " :：.-"), true
+	}
+	return "", false
+}
+
+func hasLabel(text string, labels []string) bool {
+	_, ok := cutLabel(text, labels)
+	return ok
+}
+
+func isLabelOnly(text string, labels []string) bool {
+	rest, ok := cutLabel(text, labels)
+	return ok && rest == ""
+}
+
+func parseMoney(text string) (float64, bool) {
+	match := moneyRe.FindString(text)
+	if match == "" {
+		return 0, false
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+	return amount, true
+}
+
+// parseDateNear parses a date, taking the time from the next line when the
+// slip prints them apart.
+func parseDateNear(texts []string, text string, i int) (time.Time, bool) {
+	date, ok := parseDate(text)
+	if !ok {
+		return time.Time{}, false
+	}
+	if date.Hour() == 0 && date.Minute() == 0 && i+1 < len(texts) {
+		if match := timeRe.FindString(texts[i+1]); match != "" && len(strings.TrimSpace(texts[i+1])) <= len(match)+3 {
+			if withTime, ok := parseDate(text + " " + match); ok {
+				return withTime, true
+			}
+		}
+	}
+	return date, true
+}
+
+// findParties reads sender and receiver from the account numbers, which every
+// layout prints masked as e.g. "xxx-x-x1234-x". The name sits above the
+// account with the bank name, if any, between them. A bill payment has no
+// receiver account, so the receiver is the first name after the sender.
+func findParties(texts []string, used map[int]bool) (Party, Party) {
+	var accounts []int
+	for i, text := range texts {
+		if !used[i] && isAccount(text) {
+			accounts = append(accounts, i)
+		}
+	}
+
+	var parties []Party
+	floor := -1
+	for _, a := range accounts {
+		party := Party{Account: strings.ReplaceAll(texts[a], " ", "")}
+		for i := a - 1; i > floor; i-- {
+			if used[i] {
+				continue
+			}
+			if bank := lookupBank(texts[i]); bank != "" && party.Bank == "" && !hasNameTitle(texts[i]) {
+				party.Bank = bank
+				continue
+			}
+			if name, ok := nameCandidate(texts[i]); ok {
+				party.Name = name
+				break
+			}
+		}
+		parties = append(parties, party)
+		floor = a
+		if len(parties) == 2 {
+			break
+		}
+	}
+
+	var sender, receiver Party
+	if len(parties) > 0 {
+		sender = parties[0]
+	}
+	if len(parties) > 1 {
+		receiver = parties[1]
+	} else if len(accounts) == 1 {
+		for i := accounts[0] + 1; i < len(texts); i++ {
+			if used[i] {
+				continue
+			}
+			if name, ok := nameCandidate(texts[i]); ok {
+				receiver.Name = name
+				break
+			}
+		}
+	}
+	return sender, receiver
+}
+
+func isAccount(text string) bool {
+	compact := strings.ReplaceAll(text, " ", "")
+	if !accountRe.MatchString(compact) {
+		return false
+	}
+	return strings.ContainsAny(compact, "xX*-")
+}
+
+// nameCandidate strips a leading "จาก"/"To" style label and rejects lines that
+// are labels, amounts, dates, statuses or bank names.
+func nameCandidate(text string) (string, bool) {
+	if rest, ok := cutLabel(text, sortedLabels(partyLabels)); ok {
+		text = rest
+	}
+	lower := strings.ToLower(text)
+	switch {
+	case text == "":
+		return "", false
+	case containsAny(lower, statusKeywords):
+		return "", false
+	case strings.HasSuffix(text, ":"):
+		return "", false
+	case currencyRe.MatchString(text) && moneyRe.MatchString(text):
+		return "", false
+	case !strings.ContainsFunc(text, isLetter):
+		return "", false
+	case lookupBank(text) != "" && !hasNameTitle(text):
+		return "", false
+	}
+	if _, ok := parseDate(text); ok {
+		return "", false
+	}
+	return text, true
+}
+
+var nameTitles = []string{"นาย", "นาง", "น.ส.", "mr", "mrs", "ms", "miss"}
+
+func hasNameTitle(text string) bool {
+	lower := strings.ToLower(text)
+	for _, title := range nameTitles {
+		if strings.HasPrefix(lower, title) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= 0x0E01 && r <= 0x0E2E)
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadingOrder puts OCR lines of any document in reading order.
+func ReadingOrder(lines []ocr.Line) []ocr.Line {
+	return readingOrder(lines)
+}
+
+// readingOrder sorts lines top to bottom, and left to right within a row.
+// Lines count as one row when their tops are closer than half a line height.
+// Lines without boxes keep the provider's order.
+func readingOrder(lines []ocr.Line) []ocr.Line {
+	ordered := append([]ocr.Line(nil), lines...)
+	for _, line := range ordered {
+		if line.Box == (ocr.BoundingBox{}) {
+			return ordered
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Box.Top < ordered[j].Box.Top
+	})
+	for start := 0; start < len(ordered); {
+		end := start + 1
+		tolerance := ordered[start].Box.Height / 2
+		for end < len(ordered) && ordered[end].Box.Top-ordered[start].Box.Top < tolerance {
+			end++
+		}
+		row := ordered[start:end]
+		sort.SliceStable(row, func(i, j int) bool {
+			return row[i].Box.Left < row[j].Box.Left
+		})
+		start = end
+	}
+	return ordered
+}
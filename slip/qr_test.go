@@ -0,0 +1,86 @@
+package slip
+
+import (
+	"bytes"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+const kbankPayload = "0041000600000101030040220015071101522BPM054235102TH910455E0"
+
+// slipImage draws the payload as a small QR in the corner of a larger white
+// page, the way it sits on a real slip.
+func slipImage(t *testing.T, payload string) []byte {
+	matrix, err := qrcode.NewQRCodeWriter().Encode(payload, gozxing.BarcodeFormat_QR_CODE, 120, 120, nil)
+	assert.NoError(t, err)
+
+	page := image.NewGray(image.Rect(0, 0, 600, 900))
+	draw.Draw(page, page.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(page, image.Rect(440, 740, 560, 860), matrix, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, page))
+	return buf.Bytes()
+}
+
+func TestDecodeQR(t *testing.T) {
+	result, err := DecodeQR(slipImage(t, kbankPayload))
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Verification{BankCode: "004", TransRef: "015071101522BPM05423", Country: "TH"}, result)
+	assert.Equal(t, BankKBank, result.Bank())
+}
+
+func TestDecodeQR_NotFound(t *testing.T) {
+	page := image.NewGray(image.Rect(0, 0, 300, 300))
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, page))
+
+	_, err := DecodeQR(buf.Bytes())
+
+	assert.ErrorIs(t, err, ErrQRNotFound)
+}
+
+func TestParseVerification_CRCMismatch(t *testing.T) {
+	_, err := ParseVerification(kbankPayload[:len(kbankPayload)-4] + "0000")
+
+	assert.ErrorContains(t, err, "crc mismatch")
+}
+
+func TestParseVerification_Invalid(t *testing.T) {
+	for _, payload := range []string{
+		"0041000600",
+		"00xx",
+		"5102TH",
+		"0014000600000201030045102TH",
+	} {
+		_, err := ParseVerification(payload)
+		assert.Error(t, err, payload)
+	}
+}
+
+func TestParseTLV_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"negative length": "00-1abc",
+		"signed length":   "00+1a",
+		"letters":         "00ab",
+		"one digit":       "000",
+		"truncated":       "0",
+		"overrun":         "0005abc",
+		"trailing bytes":  "00020101",
+	} {
+		_, err := parseTLV(data)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestBankCode(t *testing.T) {
+	assert.Equal(t, "014", BankCode(BankSCB))
+	assert.Equal(t, "", BankCode(BankTrueMoney))
+}
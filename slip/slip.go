@@ -0,0 +1,123 @@
+package slip
+
+import (
+	"errors"
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"time"
+)
+
+const (
+	BankKBank       = "KBANK"
+	BankSCB         = "SCB"
+	BankKrungthai   = "KTB"
+	BankBangkokBank = "BBL"
+	BankTTB         = "TTB"
+	BankKrungsri    = "BAY"
+	BankTrueMoney   = "TRUEMONEY"
+	BankShopeePay   = "SHOPEEPAY"
+	BankLinePay     = "LINEPAY"
+	BankPromptPay   = "PROMPTPAY"
+	BankUnknown     = "UNKNOWN"
+
+	KindTransfer    = "transfer"
+	KindBillPayment = "bill payment"
+)
+
+var ErrAmountNotFound = errors.New("slip amount not found")
+
+type Party struct {
+	Name    string `json:"name"`
+	Bank    string `json:"bank"`
+	Account string `json:"account"`
+}
+
+// Region marks where on the image a field was read, so a reviewer can see
+// what the value came from.
+type Region struct {
+	Field      string          `json:"field"`
+	Text       string          `json:"text"`
+	Box        ocr.BoundingBox `json:"box"`
+	Confidence float64         `json:"confidence"`
+}
+
+const (
+	FieldAmount    = "amount"
+	FieldFee       = "fee"
+	FieldDate      = "date"
+	FieldReference = "reference"
+	FieldMemo      = "memo"
+)
+
+// Slip is what could be read off a transfer or payment slip. Fields that were
+// not found are left empty; only a missing amount is an error.
+type Slip struct {
+	Bank      string    `json:"bank"`
+	Kind      string    `json:"kind"`
+	Amount    float64   `json:"amount"`
+	Fee       float64   `json:"fee"`
+	Date      time.Time `json:"date"`
+	Sender    Party     `json:"sender"`
+	Receiver  Party     `json:"receiver"`
+	Reference string    `json:"reference"`
+	Memo      string    `json:"memo"`
+	// Confidence is the lowest OCR confidence among the lines the amount,
+	// date and reference were read from.
+	Confidence float64  `json:"confidence"`
+	Regions    []Region `json:"regions"`
+}
+
+type Parser interface {
+	Bank() string
+	// Detect reports whether a line names this parser's bank or app.
+	Detect(text string) bool
+	Parse(lines []ocr.Line) (*Slip, error)
+}
+
+type Registry struct {
+	parsers  []Parser
+	fallback Parser
+}
+
+// NewRegistry tries parsers in order and falls back to a parser that knows
+// every bank's labels when none of them recognises the slip.
+func NewRegistry(parsers ...Parser) *Registry {
+	return &Registry{
+		parsers:  parsers,
+		fallback: newGenericParser(),
+	}
+}
+
+// DefaultRegistry holds a parser for every supported bank and e-wallet.
+func DefaultRegistry() *Registry {
+	return NewRegistry(
+		newKBankParser(),
+		newSCBParser(),
+		newKrungthaiParser(),
+		newBangkokBankParser(),
+		newTTBParser(),
+		newKrungsriParser(),
+		newEWalletParser(BankTrueMoney, "truemoney", "ทรูมันนี่"),
+		newEWalletParser(BankShopeePay, "shopeepay", "ช้อปปี้เพย์"),
+		newEWalletParser(BankLinePay, "rabbit line pay", "line pay"),
+	)
+}
+
+// Parse picks the parser for the issuing bank. Lines are put in reading order
+// first; the issuer's logo or the sender's bank is then the first bank named,
+// ahead of the receiver's bank further down the slip.
+func (r *Registry) Parse(lines []ocr.Line) (*Slip, error) {
+	ordered := readingOrder(lines)
+	return r.Detect(ordered).Parse(ordered)
+}
+
+// Detect returns the parser for the first bank named on the slip.
+func (r *Registry) Detect(lines []ocr.Line) Parser {
+	for _, line := range lines {
+		for _, parser := range r.parsers {
+			if parser.Detect(line.Text) {
+				return parser
+			}
+		}
+	}
+	return r.fallback
+}
@@ -0,0 +1,112 @@
+package slip
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/Montheankul-K/jod-jod/ocr"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestRegistry_Parse_Golden parses every testdata/*.txt, one OCR line per
+// text line, and compares the result with the matching .golden.json. Run with
+// -update to regenerate the golden files after a deliberate change.
+func TestRegistry_Parse_Golden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.txt")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, inputs)
+
+	registry := DefaultRegistry()
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			assert.NoError(t, err)
+
+			var lines []ocr.Line
+			for _, text := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				lines = append(lines, ocr.Line{Text: text, Confidence: 99})
+			}
+
+			result, err := registry.Parse(lines)
+			assert.NoError(t, err)
+			got, err := json.MarshalIndent(result, "", "  ")
+			assert.NoError(t, err)
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				assert.NoError(t, os.WriteFile(golden, append(got, '\n'), 0o644))
+				return
+			}
+			want, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestRegistry_Parse_AmountNotFound(t *testing.T) {
+	lines := []ocr.Line{{Text: "โอนเงินสำเร็จ"}, {Text: "นาย สมชาย ใจดี"}}
+
+	_, err := DefaultRegistry().Parse(lines)
+
+	assert.ErrorIs(t, err, ErrAmountNotFound)
+}
+
+func TestRegistry_Parse_ReadingOrder(t *testing.T) {
+	// Textract can return the value column before its labels; sorting by
+	// position pairs each label with the value beside it.
+	lines := []ocr.Line{
+		{Text: "1,250.00 บาท", Box: ocr.BoundingBox{Left: 0.6, Top: 0.5, Width: 0.3, Height: 0.04}},
+		{Text: "0.00 บาท", Box: ocr.BoundingBox{Left: 0.6, Top: 0.56, Width: 0.3, Height: 0.04}},
+		{Text: "ธ.กสิกรไทย", Box: ocr.BoundingBox{Left: 0.1, Top: 0.05, Width: 0.3, Height: 0.04}},
+		{Text: "จำนวน:", Box: ocr.BoundingBox{Left: 0.1, Top: 0.51, Width: 0.2, Height: 0.04}},
+		{Text: "ค่าธรรมเนียม:", Box: ocr.BoundingBox{Left: 0.1, Top: 0.57, Width: 0.2, Height: 0.04}},
+	}
+
+	result, err := DefaultRegistry().Parse(lines)
+
+	assert.NoError(t, err)
+	assert.Equal(t, BankKBank, result.Bank)
+	assert.Equal(t, 1250.0, result.Amount)
+	assert.Equal(t, 0.0, result.Fee)
+}
+
+func TestRegistry_Detect_IssuerBeforeReceiverBank(t *testing.T) {
+	lines := []ocr.Line{{Text: "โอนเงินสำเร็จ"}, {Text: "กรุงไทย"}, {Text: "ไทยพาณิชย์"}}
+
+	parser := DefaultRegistry().Detect(lines)
+
+	assert.Equal(t, BankKrungthai, parser.Bank())
+}
+
+func TestParseDate(t *testing.T) {
+	cases := map[string]time.Time{
+		"12 มี.ค. 67 10:15 น.":     time.Date(2024, 3, 12, 10, 15, 0, 0, bangkok),
+		"28 ก.พ. 2567 - 21:47":     time.Date(2024, 2, 28, 21, 47, 0, 0, bangkok),
+		"1 มกราคม 2568":            time.Date(2025, 1, 1, 0, 0, 0, 0, bangkok),
+		"3 มค 68 8.05":             time.Date(2025, 1, 3, 8, 5, 0, 0, bangkok),
+		"12 Mar 2024, 18:02":       time.Date(2024, 3, 12, 18, 2, 0, 0, bangkok),
+		"7 Sep 24 12:31":           time.Date(2024, 9, 7, 12, 31, 0, 0, bangkok),
+		"15/08/2567 13:22:41":      time.Date(2024, 8, 15, 13, 22, 41, 0, bangkok),
+		"15/08/67":                 time.Date(2024, 8, 15, 0, 0, 0, 0, bangkok),
+		"15/08/2024":               time.Date(2024, 8, 15, 0, 0, 0, 0, bangkok),
+		"ref 22BPM0542 12 ต.ค. 66": time.Date(2023, 10, 12, 0, 0, 0, 0, bangkok),
+	}
+	for text, want := range cases {
+		got, ok := parseDate(text)
+		assert.True(t, ok, text)
+		assert.True(t, want.Equal(got), "%s: got %s", text, got)
+	}
+
+	for _, text := range []string{"1,250.00 บาท", "31 ก.พ. 2567", "xxx-x-x1234-x", "นาย สมชาย"} {
+		_, ok := parseDate(text)
+		assert.False(t, ok, text)
+	}
+}
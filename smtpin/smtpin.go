@@ -0,0 +1,318 @@
+package smtpin
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// commandTimeout bounds how long a client may idle between commands.
+	commandTimeout = 5 * time.Minute
+	// dataTimeout bounds a whole DATA transfer.
+	dataTimeout   = 10 * time.Minute
+	maxRecipients = 50
+)
+
+// Handler receives each accepted message. Returning an *Error sets the reply
+// the client gets; any other error is reported as a temporary failure so the
+// sending server retries.
+type Handler func(from string, to []string, data []byte) error
+
+// Error is a reply to send instead of accepting a message, e.g. 550 for an
+// unknown recipient.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s", e.Code, e.Message)
+}
+
+// Server accepts mail for one domain over plain SMTP. It is meant to sit
+// behind an MX or relay that handles TLS and spam filtering, so it does not
+// authenticate or relay.
+type Server struct {
+	addr     string
+	domain   string
+	maxSize  int64
+	handler  Handler
+	logger   echo.Logger
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+func NewServer(addr, domain string, maxSize int64, handler Handler, logger echo.Logger) *Server {
+	return &Server{
+		addr:    addr,
+		domain:  strings.ToLower(domain),
+		maxSize: maxSize,
+		handler: handler,
+		logger:  logger,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Start listens and serves in the background.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.wg.Add(1)
+	go s.serve()
+	s.logger.Infof("smtp listener started at %s", listener.Addr())
+	return nil
+}
+
+// Addr is the address listened on, which differs from the configured one
+// when that had port 0.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Stop closes the listener and open sessions and waits for them to end.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.listener.Close()
+	s.wg.Wait()
+	s.logger.Info("smtp listener stopped")
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Errorf("smtp accept failed: %v", err)
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+type session struct {
+	server *Server
+	conn   net.Conn
+	text   *textproto.Conn
+	helo   bool
+	from   string
+	to     []string
+	// mailStarted is set by MAIL FROM, whose sender may be empty for bounces.
+	mailStarted bool
+}
+
+func (s *Server) handle(conn net.Conn) {
+	sess := &session{server: s, conn: conn, text: textproto.NewConn(conn)}
+	sess.reply(220, fmt.Sprintf("%s ESMTP ready", s.domain))
+	for {
+		conn.SetDeadline(time.Now().Add(commandTimeout))
+		line, err := sess.text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		if !sess.command(strings.ToUpper(verb), strings.TrimSpace(arg)) {
+			return
+		}
+	}
+}
+
+// command runs one command and reports whether the session goes on.
+func (sess *session) command(verb, arg string) bool {
+	switch verb {
+	case "HELO":
+		sess.helo = true
+		sess.reset()
+		sess.reply(250, sess.server.domain)
+	case "EHLO":
+		sess.helo = true
+		sess.reset()
+		sess.text.PrintfLine("250-%s", sess.server.domain)
+		sess.text.PrintfLine("250-SIZE %d", sess.server.maxSize)
+		sess.text.PrintfLine("250-8BITMIME")
+		sess.reply(250, "PIPELINING")
+	case "MAIL":
+		sess.mail(arg)
+	case "RCPT":
+		sess.rcpt(arg)
+	case "DATA":
+		return sess.data()
+	case "RSET":
+		sess.reset()
+		sess.reply(250, "OK")
+	case "NOOP":
+		sess.reply(250, "OK")
+	case "QUIT":
+		sess.reply(221, "Bye")
+		return false
+	default:
+		sess.reply(502, "Command not implemented")
+	}
+	return true
+}
+
+func (sess *session) mail(arg string) {
+	if !sess.helo {
+		sess.reply(503, "Say HELO first")
+		return
+	}
+	if sess.mailStarted {
+		sess.reply(503, "Sender already given")
+		return
+	}
+	address, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		sess.reply(501, "Syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, param := range params {
+		key, value, _ := strings.Cut(strings.ToUpper(param), "=")
+		if key != "SIZE" {
+			continue
+		}
+		var size int64
+		if _, err := fmt.Sscan(value, &size); err == nil && size > sess.server.maxSize {
+			sess.reply(552, "Message too large")
+			return
+		}
+	}
+	sess.from = address
+	sess.mailStarted = true
+	sess.reply(250, "OK")
+}
+
+func (sess *session) rcpt(arg string) {
+	if !sess.mailStarted {
+		sess.reply(503, "Need MAIL first")
+		return
+	}
+	address, _, ok := parsePath(arg, "TO:")
+	if !ok || address == "" {
+		sess.reply(501, "Syntax: RCPT TO:<address>")
+		return
+	}
+	if !strings.HasSuffix(address, "@"+sess.server.domain) {
+		sess.reply(550, "Relay not permitted")
+		return
+	}
+	if len(sess.to) >= maxRecipients {
+		sess.reply(452, "Too many recipients")
+		return
+	}
+	sess.to = append(sess.to, address)
+	sess.reply(250, "OK")
+}
+
+func (sess *session) data() bool {
+	if len(sess.to) == 0 {
+		sess.reply(503, "Need RCPT first")
+		return true
+	}
+	sess.reply(354, "End data with <CR><LF>.<CR><LF>")
+	sess.conn.SetDeadline(time.Now().Add(dataTimeout))
+
+	// Reading one byte past the limit tells a message that is exactly at it
+	// from one that is over.
+	reader := sess.text.DotReader()
+	data, err := io.ReadAll(io.LimitReader(reader, sess.server.maxSize+1))
+	if err != nil {
+		return false
+	}
+	if int64(len(data)) > sess.server.maxSize {
+		if _, err = io.Copy(io.Discard, reader); err != nil {
+			return false
+		}
+		sess.reset()
+		sess.reply(552, "Message too large")
+		return true
+	}
+
+	from, to := sess.from, sess.to
+	sess.reset()
+	err = sess.server.handler(from, to, data)
+	var replyErr *Error
+	switch {
+	case err == nil:
+		sess.reply(250, "OK queued")
+	case errors.As(err, &replyErr):
+		sess.reply(replyErr.Code, replyErr.Message)
+	default:
+		sess.server.logger.Errorf("smtp message from %s failed: %v", from, err)
+		sess.reply(451, "Temporary failure, try again later")
+	}
+	return true
+}
+
+func (sess *session) reset() {
+	sess.from = ""
+	sess.to = nil
+	sess.mailStarted = false
+}
+
+func (sess *session) reply(code int, message string) {
+	sess.text.PrintfLine("%d %s", code, message)
+}
+
+// parsePath reads "FROM:<a@b> SIZE=123" into the lowercased address and the
+// ESMTP parameters after it.
+func parsePath(arg, prefix string) (string, []string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	fields := strings.Fields(strings.TrimSpace(arg[len(prefix):]))
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	path := fields[0]
+	if !strings.HasPrefix(path, "<") || !strings.HasSuffix(path, ">") {
+		return "", nil, false
+	}
+	path = path[1 : len(path)-1]
+	if path == "" {
+		return "", fields[1:], true
+	}
+	address, err := mail.ParseAddress(path)
+	if err != nil {
+		return "", nil, false
+	}
+	return strings.ToLower(address.Address), fields[1:], true
+}
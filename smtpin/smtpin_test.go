@@ -0,0 +1,96 @@
+package smtpin
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type received struct {
+	from string
+	to   []string
+	data string
+}
+
+func startServer(t *testing.T, maxSize int64, handler Handler) *Server {
+	server := NewServer("127.0.0.1:0", "in.example.com", maxSize, handler, echo.New().Logger)
+	assert.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+	return server
+}
+
+func TestServer_Deliver(t *testing.T) {
+	var mu sync.Mutex
+	var got []received
+	server := startServer(t, 1<<20, func(from string, to []string, data []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, received{from: from, to: to, data: string(data)})
+		return nil
+	})
+	message := "Subject: Receipt\r\n\r\nTotal 120.00\r\n.leading dot\r\n"
+
+	err := smtp.SendMail(server.Addr(), nil, "Shop@Example.com", []string{"abc123@in.example.com"}, []byte(message))
+
+	assert.NoError(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, got, 1)
+	assert.Equal(t, "shop@example.com", got[0].from)
+	assert.Equal(t, []string{"abc123@in.example.com"}, got[0].to)
+	assert.Equal(t, strings.ReplaceAll(message, "\r\n", "\n"), got[0].data)
+}
+
+func TestServer_RejectsOtherDomains(t *testing.T) {
+	server := startServer(t, 1<<20, func(from string, to []string, data []byte) error {
+		t.Fatal("handler called")
+		return nil
+	})
+
+	err := smtp.SendMail(server.Addr(), nil, "shop@example.com", []string{"someone@elsewhere.com"}, []byte("Subject: x\r\n\r\nx\r\n"))
+
+	var protoErr *textproto.Error
+	assert.ErrorAs(t, err, &protoErr)
+	assert.Equal(t, 550, protoErr.Code)
+}
+
+func TestServer_MessageTooLarge(t *testing.T) {
+	server := startServer(t, 64, func(from string, to []string, data []byte) error {
+		t.Fatal("handler called")
+		return nil
+	})
+
+	err := smtp.SendMail(server.Addr(), nil, "shop@example.com", []string{"abc123@in.example.com"}, []byte("Subject: x\r\n\r\n"+strings.Repeat("a", 200)+"\r\n"))
+
+	var protoErr *textproto.Error
+	assert.ErrorAs(t, err, &protoErr)
+	assert.Equal(t, 552, protoErr.Code)
+}
+
+func TestServer_HandlerError(t *testing.T) {
+	server := startServer(t, 1<<20, func(from string, to []string, data []byte) error {
+		return &Error{Code: 550, Message: "No such mailbox"}
+	})
+
+	err := smtp.SendMail(server.Addr(), nil, "shop@example.com", []string{"unknown@in.example.com"}, []byte("Subject: x\r\n\r\nx\r\n"))
+
+	var protoErr *textproto.Error
+	assert.ErrorAs(t, err, &protoErr)
+	assert.Equal(t, 550, protoErr.Code)
+	assert.Equal(t, "No such mailbox", protoErr.Msg)
+}
+
+func TestParsePath(t *testing.T) {
+	address, params, ok := parsePath("FROM:<Shop@Example.com> SIZE=100 BODY=8BITMIME", "FROM:")
+
+	assert.True(t, ok)
+	assert.Equal(t, "shop@example.com", address)
+	assert.Equal(t, []string{"SIZE=100", "BODY=8BITMIME"}, params)
+
+	_, _, ok = parsePath("FROM:shop@example.com", "FROM:")
+	assert.False(t, ok)
+}
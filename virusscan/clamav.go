@@ -0,0 +1,82 @@
+package virusscan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	clamAVTimeout = 30 * time.Second
+	// clamAVChunkSize stays below clamd's default StreamMaxLength chunks.
+	clamAVChunkSize = 64 << 10
+)
+
+type clamAVScanner struct {
+	address string
+}
+
+// NewClamAVScanner talks to clamd over TCP, e.g. "localhost:3310".
+func NewClamAVScanner(address string) Scanner {
+	return &clamAVScanner{address: address}
+}
+
+func (s *clamAVScanner) Name() string {
+	return ScannerClamAV
+}
+
+// Scan streams the file with clamd's INSTREAM command: each chunk is sent
+// after its length as a 4-byte big-endian number, and a zero length ends the
+// stream.
+func (s *clamAVScanner) Scan(data []byte) error {
+	conn, err := net.DialTimeout("tcp", s.address, clamAVTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(clamAVTimeout)); err != nil {
+		return err
+	}
+
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+	size := make([]byte, 4)
+	for start := 0; start < len(data); start += clamAVChunkSize {
+		end := min(start+clamAVChunkSize, len(data))
+		binary.BigEndian.PutUint32(size, uint32(end-start))
+		if _, err = conn.Write(size); err != nil {
+			return err
+		}
+		if _, err = conn.Write(data[start:end]); err != nil {
+			return err
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err = conn.Write(size); err != nil {
+		return err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return err
+	}
+	return parseClamAVReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamAVReply reads "stream: OK", "stream: <signature> FOUND" or
+// "... ERROR".
+func parseClamAVReply(reply string) error {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &InfectedError{Signature: strings.TrimSuffix(result, " FOUND")}
+	default:
+		return fmt.Errorf("clamav scan failed: %s", result)
+	}
+}
@@ -0,0 +1,65 @@
+package virusscan
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+)
+
+// fakeClamd accepts one INSTREAM scan and replies infected when the stream
+// holds the EICAR marker.
+func fakeClamd(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			command := make([]byte, len("zINSTREAM\x00"))
+			io.ReadFull(conn, command)
+			var stream []byte
+			size := make([]byte, 4)
+			for {
+				io.ReadFull(conn, size)
+				n := binary.BigEndian.Uint32(size)
+				if n == 0 {
+					break
+				}
+				chunk := make([]byte, n)
+				io.ReadFull(conn, chunk)
+				stream = append(stream, chunk...)
+			}
+			if string(stream) == "EICAR" {
+				conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			} else {
+				conn.Write([]byte("stream: OK\x00"))
+			}
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestClamAVScanner_Scan(t *testing.T) {
+	scanner := NewClamAVScanner(fakeClamd(t))
+
+	assert.NoError(t, scanner.Scan(make([]byte, clamAVChunkSize*2+10)))
+
+	err := scanner.Scan([]byte("EICAR"))
+	var infected *InfectedError
+	assert.True(t, errors.As(err, &infected))
+	assert.Equal(t, "Eicar-Test-Signature", infected.Signature)
+}
+
+func TestParseClamAVReply_Error(t *testing.T) {
+	err := parseClamAVReply("INSTREAM size limit exceeded. ERROR")
+
+	assert.EqualError(t, err, "clamav scan failed: INSTREAM size limit exceeded. ERROR")
+}
@@ -0,0 +1,51 @@
+package virusscan
+
+import (
+	"fmt"
+	"github.com/Montheankul-K/jod-jod/config"
+)
+
+const (
+	ScannerNone   = "none"
+	ScannerClamAV = "clamav"
+)
+
+// InfectedError is returned for a file the scanner flagged.
+type InfectedError struct {
+	Signature string
+}
+
+func (e *InfectedError) Error() string {
+	return fmt.Sprintf("file is infected: %s", e.Signature)
+}
+
+// Scanner checks an uploaded file before it is stored.
+type Scanner interface {
+	Name() string
+	Scan(data []byte) error
+}
+
+// NewScanner builds the scanner named in the attachment config. Without one,
+// files are stored unscanned.
+func NewScanner(cfg *config.Attachment) (Scanner, error) {
+	if cfg == nil || cfg.Scanner == "" || cfg.Scanner == ScannerNone {
+		return noopScanner{}, nil
+	}
+
+	switch cfg.Scanner {
+	case ScannerClamAV:
+		return NewClamAVScanner(cfg.ClamAVAddress), nil
+	default:
+		return nil, fmt.Errorf("unknown virus scanner: %s", cfg.Scanner)
+	}
+}
+
+type noopScanner struct{}
+
+func (noopScanner) Name() string {
+	return ScannerNone
+}
+
+func (noopScanner) Scan(data []byte) error {
+	return nil
+}